@@ -170,8 +170,7 @@ func sumBondStrengths(bonds []*db.Bond, bondAssets map[uint32]*msgjson.BondAsset
 		}
 		// v1. Gotta hope the server didn't change the bond amount.
 		if ba := bondAssets[bond.AssetID]; ba != nil && ba.Amt > 0 {
-			strength := bond.Amount / ba.Amt
-			total += int64(strength)
+			total += int64(ba.Strength(bond.Amount))
 		}
 	}
 	return
@@ -286,7 +285,7 @@ func (c *Core) minBondReserves(dc *dexConnection, bondAsset *BondAsset) uint64 {
 		}
 
 		// We won't count any active bond strength > our tier target.
-		tiers := min(bond.Amount/ba.Amt, targetTier-tierSum)
+		tiers := min(uint64(ba.Strength(bond.Amount)), targetTier-tierSum)
 		tierSum += tiers
 		activeTiers = append(activeTiers, [2]uint64{weakTime, tiers})
 		if tierSum == targetTier {
@@ -310,7 +309,7 @@ out:
 		if bond.AssetID != bondAsset.ID {
 			continue
 		}
-		strength := bond.Amount / bondAsset.Amt
+		strength := uint64(bondAsset.Strength(bond.Amount))
 		refundableTime := bond.LockTime + sBuffer
 		for i, pair := range activeTiers {
 			weakTime, tiers := pair[0], pair[1]
@@ -338,7 +337,7 @@ out:
 	for _, pair := range activeTiers {
 		reserveTiers += pair[1]
 	}
-	return reserveTiers * bondAsset.Amt
+	return bondAsset.Amount(reserveTiers)
 }
 
 // dexBondConfig retrieves a dex's configuration related to bonds.
@@ -662,20 +661,26 @@ func (c *Core) postRequiredBonds(
 	// For the max bonded limit, we'll normalize all bonds to the
 	// currently selected bond asset.
 	toPost := state.mustPost
-	amt := bondAsset.Amt * uint64(state.mustPost)
-	currentlyBondedAmt := uint64(state.PendingStrength+state.LiveStrength+expiredStrength) * bondAsset.Amt
+	if maxStrength := int64(bondAsset.MaxStrength()); maxStrength > 0 && toPost > maxStrength {
+		// A single bond can't be any stronger under the DEX's tier schedule.
+		// The remainder is posted with the next rotation.
+		toPost = maxStrength
+	}
+	wantPost := toPost
+	amt := bondAsset.Amount(uint64(toPost))
+	currentlyBondedAmt := bondAsset.Amount(uint64(state.PendingStrength + state.LiveStrength + expiredStrength))
 	for state.MaxBondedAmt > 0 && amt+currentlyBondedAmt > state.MaxBondedAmt && toPost > 0 {
 		toPost-- // dumber, but reads easier
-		amt = bondAsset.Amt * uint64(toPost)
+		amt = bondAsset.Amount(uint64(toPost))
 	}
 	if toPost == 0 {
 		c.log.Warnf("Unable to post new bond with equivalent of %s currently bonded (limit of %s)",
 			wallet.amtString(currentlyBondedAmt), wallet.amtString(state.MaxBondedAmt))
 		return
 	}
-	if toPost < state.mustPost {
+	if toPost < wantPost {
 		c.log.Warnf("Only posting %d bond increments instead of %d because of current bonding limit of %s",
-			toPost, state.mustPost, wallet.amtString(state.MaxBondedAmt))
+			toPost, wantPost, wallet.amtString(state.MaxBondedAmt))
 	}
 
 	lockTime, err := c.calculateMergingLockTime(dc)
@@ -1213,15 +1218,12 @@ func (c *Core) UpdateBondOptions(form *BondOptionsForm) error {
 	dc.acct.penaltyComps = penaltyComps
 	dbAcct.PenaltyComps = penaltyComps
 
-	var bondAssetAmt uint64 // because to disable we must proceed even with no config
 	bondAsset := bondAssets[bondAssetID]
 	if bondAsset == nil {
 		if targetTier > 0 || assetChanged {
 			return fmt.Errorf("dex %v is does not support %v as a bond asset (or we lack their config)",
 				dbAcct.Host, unbip(bondAssetID))
 		} // else disable, attempting to unreserve funds if wallet is available
-	} else {
-		bondAssetAmt = bondAsset.Amt
 	}
 
 	// If we're lowering our bond, we can't set the max bonded amount too low.
@@ -1230,7 +1232,10 @@ func (c *Core) UpdateBondOptions(form *BondOptionsForm) error {
 		tierForDefaultMaxBonded = targetTier0
 	}
 
-	maxBonded := maxBondedMult * bondAssetAmt * (tierForDefaultMaxBonded + uint64(penaltyComps)) // the min if none specified
+	var maxBonded uint64 // because to disable we must proceed even with no config
+	if bondAsset != nil {
+		maxBonded = maxBondedMult * bondAsset.Amount(tierForDefaultMaxBonded+uint64(penaltyComps)) // the min if none specified
+	}
 	if form.MaxBondedAmt != nil {
 		requested := *form.MaxBondedAmt
 		if requested < maxBonded {
@@ -1501,13 +1506,19 @@ func (c *Core) PostBond(form *PostBondForm) (*PostBondResult, error) {
 	}
 
 	// Check that the bond amount matches the caller's expectations.
-	if form.Bond < bondAsset.Amt {
+	if minAmt := bondAsset.Amount(1); form.Bond < minAmt {
 		return nil, newError(bondAmtErr, "specified bond amount is less than the DEX-provided amount. %d < %d",
-			form.Bond, bondAsset.Amt)
+			form.Bond, minAmt)
 	}
-	if rem := form.Bond % bondAsset.Amt; rem != 0 {
-		return nil, newError(bondAmtErr, "specified bond amount is not a multiple of the DEX-provided amount. %d %% %d = %d",
-			form.Bond, bondAsset.Amt, rem)
+	bondStrength := bondAsset.Strength(form.Bond)
+	if len(bondAsset.BondTierSchedule) == 0 {
+		if rem := form.Bond % bondAsset.Amt; rem != 0 {
+			return nil, newError(bondAmtErr, "specified bond amount is not a multiple of the DEX-provided amount. %d %% %d = %d",
+				form.Bond, bondAsset.Amt, rem)
+		}
+	} else if tierAmt := bondAsset.Amount(uint64(bondStrength)); form.Bond != tierAmt {
+		return nil, newError(bondAmtErr, "specified bond amount is not a DEX-provided tier amount. %d != %d (strength %d)",
+			form.Bond, tierAmt, bondStrength)
 	}
 	if acctExists { // if account exists, advise using UpdateBondOptions
 		autoBondAsset, targetTier, maxBondedAmt := dc.bondOpts()
@@ -1526,7 +1537,7 @@ func (c *Core) PostBond(form *PostBondForm) (*PostBondResult, error) {
 		}
 		dc.acct.authMtx.Lock()
 		dc.acct.bondAsset = bondAssetID
-		dc.acct.targetTier = form.Bond / bondAsset.Amt
+		dc.acct.targetTier = uint64(bondStrength)
 		dc.acct.maxBondedAmt = maxBondedAmt
 		dc.acct.authMtx.Unlock()
 	}
@@ -1612,7 +1623,7 @@ func (c *Core) makeAndPostBond(dc *dexConnection, acctExists bool, wallet *xcWal
 	reqConfs := bondAsset.Confs
 	bondCoinStr := coinIDString(bond.AssetID, bond.CoinID)
 	c.log.Infof("DEX %v has validated our bond %v (%s) with strength %d. %d confirmations required to trade.",
-		dc.acct.host, bondCoinStr, unbip(bond.AssetID), amt/bondAsset.Amt, reqConfs)
+		dc.acct.host, bondCoinStr, unbip(bond.AssetID), bondAsset.Strength(amt), reqConfs)
 
 	// Store the account and bond info.
 	dbBond := &db.Bond{
@@ -1626,7 +1637,7 @@ func (c *Core) makeAndPostBond(dc *dexConnection, acctExists bool, wallet *xcWal
 		LockTime:   uint64(lockTime.Unix()),
 		KeyIndex:   keyIndex,
 		RefundTx:   bond.RedeemTx,
-		Strength:   uint32(amt / bondAsset.Amt),
+		Strength:   bondAsset.Strength(amt),
 		// Confirmed and Refunded are false (new bond tx)
 	}
 
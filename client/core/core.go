@@ -6821,7 +6821,7 @@ func (c *Core) findBond(dc *dexConnection, bond *msgjson.Bond) (strength, bondAs
 		c.log.Warnf("Dex does not support fidelity bonds in asset %s", symb)
 		strength = bond.Strength
 	} else {
-		strength = uint32(bondDetails.Amount / bondAsset.Amt)
+		strength = bondAsset.Strength(bondDetails.Amount)
 	}
 
 	idx, err := c.findBondKeyIdx(bondDetails.CheckPrivKey, bond.AssetID)
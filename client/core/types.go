@@ -647,6 +647,21 @@ type BondAsset struct {
 	ID      uint32 `json:"id"`
 	Confs   uint32 `json:"confs"`
 	Amt     uint64 `json:"amount"`
+	// BondTierSchedule, if set, is the cumulative bond amount required for
+	// each bond strength. See msgjson.BondAsset.BondTierSchedule.
+	BondTierSchedule []uint64 `json:"bondTierSchedule,omitempty"`
+}
+
+// Strength is the strength of a single bond of amount amt. See
+// msgjson.BondAsset.Strength.
+func (ba *BondAsset) Strength(amt uint64) uint32 {
+	return (*msgjson.BondAsset)(ba).Strength(amt)
+}
+
+// Amount is the total bond amount required for the specified strength. See
+// msgjson.BondAsset.Amount.
+func (ba *BondAsset) Amount(strength uint64) uint64 {
+	return (*msgjson.BondAsset)(ba).Amount(strength)
 }
 
 // PendingBondState conveys a pending bond's asset and current confirmation
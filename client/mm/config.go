@@ -1,29 +1,288 @@
 package mm
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/utils"
+	"golang.org/x/time/rate"
 )
 
 // MarketMakingConfig is the overall configuration of the market maker.
 type MarketMakingConfig struct {
 	BotConfigs []*BotConfig `json:"botConfigs"`
 	CexConfigs []*CEXConfig `json:"cexConfigs"`
+	// WalletProfiles are named sets of wallet options that can be shared by
+	// bots. See BotConfig.BaseWalletProfile and QuoteWalletProfile.
+	WalletProfiles map[string]map[string]string `json:"walletProfiles,omitempty"`
+	// MaxActiveBots is the maximum number of bots that may run at once. Each
+	// bot has its own wallet and CEX connections, so this protects hosts with
+	// limited resources. Zero means unlimited.
+	MaxActiveBots int `json:"maxActiveBots,omitempty"`
 }
 
 func (cfg *MarketMakingConfig) Copy() *MarketMakingConfig {
 	c := &MarketMakingConfig{
-		BotConfigs: make([]*BotConfig, len(cfg.BotConfigs)),
-		CexConfigs: make([]*CEXConfig, len(cfg.CexConfigs)),
+		BotConfigs:    make([]*BotConfig, len(cfg.BotConfigs)),
+		CexConfigs:    make([]*CEXConfig, len(cfg.CexConfigs)),
+		MaxActiveBots: cfg.MaxActiveBots,
+	}
+	if cfg.WalletProfiles != nil {
+		c.WalletProfiles = make(map[string]map[string]string, len(cfg.WalletProfiles))
+		for name, opts := range cfg.WalletProfiles {
+			c.WalletProfiles[name] = utils.CopyMap(opts)
+		}
 	}
 	copy(c.BotConfigs, cfg.BotConfigs)
-	copy(c.CexConfigs, cfg.CexConfigs)
+	for i, cexCfg := range cfg.CexConfigs {
+		cc := *cexCfg
+		c.CexConfigs[i] = &cc
+	}
 	return c
 }
 
+// ValidateActiveCount checks that another bot may be started while the bots
+// identified by runningBotIDs are running. Bots are identified by the market
+// ID from dexMarketID.
+func (cfg *MarketMakingConfig) ValidateActiveCount(runningBotIDs []string) error {
+	if cfg.MaxActiveBots <= 0 {
+		return nil
+	}
+	running := make(map[string]struct{}, len(runningBotIDs))
+	for _, id := range runningBotIDs {
+		running[id] = struct{}{}
+	}
+	if len(running) >= cfg.MaxActiveBots {
+		return fmt.Errorf("cannot start another bot with %d running, the maximum is %d", len(running), cfg.MaxActiveBots)
+	}
+	return nil
+}
+
+// ActiveBots returns the configs of the bots that are not paused.
+func (cfg *MarketMakingConfig) ActiveBots() []*BotConfig {
+	bots := make([]*BotConfig, 0, len(cfg.BotConfigs))
+	for _, botCfg := range cfg.BotConfigs {
+		if !botCfg.Paused {
+			bots = append(bots, botCfg)
+		}
+	}
+	return bots
+}
+
+// Validate checks that each bot and CEX config is valid, and that no market
+// has more than one bot configured.
+func (cfg *MarketMakingConfig) Validate() error {
+	if cfg.MaxActiveBots < 0 {
+		return fmt.Errorf("negative max active bots %d", cfg.MaxActiveBots)
+	}
+	bots := make(map[string]bool, len(cfg.BotConfigs))
+	for _, botCfg := range cfg.BotConfigs {
+		mktID := dexMarketID(botCfg.Host, botCfg.BaseID, botCfg.QuoteID)
+		if bots[mktID] {
+			return fmt.Errorf("multiple bots configured for %s", mktID)
+		}
+		bots[mktID] = true
+		if err := botCfg.validate(); err != nil {
+			return fmt.Errorf("invalid bot config for %s: %w", mktID, err)
+		}
+	}
+	for _, cexCfg := range cfg.CexConfigs {
+		if err := cexCfg.validate(); err != nil {
+			return fmt.Errorf("invalid %s config: %w", cexCfg.Name, err)
+		}
+	}
+	return nil
+}
+
+// configWatchInterval is how often WatchConfig checks the config file for
+// changes.
+var configWatchInterval = 2 * time.Second
+
+// WatchConfig watches the config file at path, calling onChange with the new
+// config whenever the file's contents change and the new config passes
+// Validate. Invalid edits are logged and not applied. The file must exist and
+// hold a valid config when WatchConfig is called. Read errors and rejected
+// edits are logged to the package logger set with UseLogger. Call stop to stop
+// watching.
+func WatchConfig(path string, onChange func(*MarketMakingConfig)) (stop func(), err error) {
+	return watchConfig(path, configWatchInterval, pkgLog, onChange)
+}
+
+func watchConfig(path string, interval time.Duration, log dex.Logger, onChange func(*MarketMakingConfig)) (stop func(), err error) {
+	readConfig := func() ([]byte, *MarketMakingConfig, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg := new(MarketMakingConfig)
+		if err := json.Unmarshal(b, cfg); err != nil {
+			return b, nil, fmt.Errorf("error parsing config: %w", err)
+		}
+		if err := cfg.Validate(); err != nil {
+			return b, nil, err
+		}
+		return b, cfg, nil
+	}
+
+	lastData, _, err := readConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config file %q: %w", path, err)
+	}
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var readFailed bool
+		for {
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return
+			}
+			b, cfg, err := readConfig()
+			if b == nil {
+				if !readFailed { // don't repeat every interval
+					log.Errorf("Error reading config file %q: %v", path, err)
+					readFailed = true
+				}
+				continue
+			}
+			readFailed = false
+			if bytes.Equal(b, lastData) {
+				continue
+			}
+			lastData = b
+			if err != nil {
+				log.Errorf("Rejecting invalid edit of config file %q: %v", path, err)
+				continue
+			}
+			log.Infof("Config file %q changed. Applying new config.", path)
+			onChange(cfg)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(quit)
+			<-done
+		})
+	}, nil
+}
+
+// ResolveAllocations checks that the DEX allocations of all bots, summed per
+// asset, do not exceed the available DEX wallet balances. Bots that share a
+// wallet draw from the same balance, so individually sufficient allocations
+// can over-commit the wallet. CEX allocations are drawn from CEX balances, so
+// they are summed per CEX and asset and checked against cexAvailable, keyed by
+// CEX name. Only allocations set in a bot's RPCConfig are considered.
+func (cfg *MarketMakingConfig) ResolveAllocations(available map[uint32]uint64, cexAvailable map[string]map[uint32]uint64) error {
+	dexAllocs := make(map[uint32]uint64)
+	cexAllocs := make(map[string]map[uint32]uint64)
+	for _, botCfg := range cfg.BotConfigs {
+		if botCfg.RPCConfig == nil || botCfg.RPCConfig.Alloc == nil {
+			continue
+		}
+		for assetID, amt := range botCfg.RPCConfig.Alloc.DEX {
+			dexAllocs[assetID] += amt
+		}
+		if len(botCfg.RPCConfig.Alloc.CEX) == 0 {
+			continue
+		}
+		allocs := cexAllocs[botCfg.CEXName]
+		if allocs == nil {
+			allocs = make(map[uint32]uint64)
+			cexAllocs[botCfg.CEXName] = allocs
+		}
+		for assetID, amt := range botCfg.RPCConfig.Alloc.CEX {
+			allocs[assetID] += amt
+		}
+	}
+
+	if err := checkAllocations("DEX", dexAllocs, available); err != nil {
+		return err
+	}
+
+	cexNames := make([]string, 0, len(cexAllocs))
+	for cexName := range cexAllocs {
+		cexNames = append(cexNames, cexName)
+	}
+	sort.Strings(cexNames)
+
+	for _, cexName := range cexNames {
+		if err := checkAllocations(cexName, cexAllocs[cexName], cexAvailable[cexName]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAllocations checks that the allocations do not exceed the available
+// balances of the named exchange, checking assets in ascending ID order.
+func checkAllocations(exchange string, allocs, available map[uint32]uint64) error {
+	assetIDs := make([]uint32, 0, len(allocs))
+	for assetID := range allocs {
+		assetIDs = append(assetIDs, assetID)
+	}
+	sort.Slice(assetIDs, func(i, j int) bool { return assetIDs[i] < assetIDs[j] })
+
+	for _, assetID := range assetIDs {
+		allocated, avail := allocs[assetID], available[assetID]
+		if allocated > avail {
+			return fmt.Errorf("%s allocations for %s exceed available balance by %d: %d allocated, %d available",
+				exchange, dex.BipIDSymbol(assetID), allocated-avail, allocated, avail)
+		}
+	}
+	return nil
+}
+
+// CEXCheckResult is the result of a connection attempt to a configured CEX.
+type CEXCheckResult struct {
+	Name string `json:"name"`
+	// Err is the error connecting to the CEX, or nil if the connection
+	// succeeded.
+	Err error `json:"-"`
+}
+
+// CheckCEXConnections attempts a connection to each configured CEX using
+// connect, so that bad credentials or an unreachable CEX are found before any
+// bots are started. The connections are attempted concurrently, and the
+// results are in the order of CexConfigs. CEXes not yet attempted when ctx is
+// canceled have the context's error.
+func (cfg *MarketMakingConfig) CheckCEXConnections(ctx context.Context, connect func(*CEXConfig) error) []CEXCheckResult {
+	results := make([]CEXCheckResult, len(cfg.CexConfigs))
+	var wg sync.WaitGroup
+	for i, cexCfg := range cfg.CexConfigs {
+		results[i].Name = cexCfg.Name
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(i int, cexCfg *CEXConfig) {
+			defer wg.Done()
+			results[i].Err = connect(cexCfg)
+		}(i, cexCfg)
+	}
+	wg.Wait()
+	return results
+}
+
 // CEXConfig is a configuration for connecting to a CEX API.
 type CEXConfig struct {
 	// Name is the name of the cex.
@@ -32,6 +291,51 @@ type CEXConfig struct {
 	APIKey string `json:"apiKey"`
 	// APISecret is the API secret for the CEX.
 	APISecret string `json:"apiSecret"`
+	// RateLimitPerMin is the maximum number of API requests per minute that
+	// will be made to the CEX. Zero means no client-side limiting.
+	RateLimitPerMin int `json:"rateLimitPerMin,omitempty"`
+	// BurstSize is the number of requests that may be made at once before
+	// RateLimitPerMin applies. If zero and RateLimitPerMin is set, a burst of
+	// 1 is used.
+	BurstSize int `json:"burstSize,omitempty"`
+	// CEXBalanceCacheTTL is the number of milliseconds for which balances
+	// read from the CEX are reused before the CEX is queried again. Zero
+	// disables caching.
+	CEXBalanceCacheTTL int64 `json:"cexBalanceCacheTTL,omitempty"`
+}
+
+func (c *CEXConfig) validate() error {
+	if c.RateLimitPerMin < 0 {
+		return fmt.Errorf("negative rate limit %d", c.RateLimitPerMin)
+	}
+	if c.BurstSize < 0 {
+		return fmt.Errorf("negative burst size %d", c.BurstSize)
+	}
+	if c.RateLimitPerMin > 0 && c.BurstSize > c.RateLimitPerMin {
+		return fmt.Errorf("burst size %d exceeds rate limit of %d per minute", c.BurstSize, c.RateLimitPerMin)
+	}
+	if c.CEXBalanceCacheTTL < 0 {
+		return fmt.Errorf("negative balance cache TTL %d", c.CEXBalanceCacheTTL)
+	}
+	return nil
+}
+
+// balanceCacheTTL is the CEXBalanceCacheTTL as a time.Duration.
+func (c *CEXConfig) balanceCacheTTL() time.Duration {
+	return time.Duration(c.CEXBalanceCacheTTL) * time.Millisecond
+}
+
+// rateLimiter creates a token bucket for the CEX's API requests. If no rate
+// limit is configured, nil is returned.
+func (c *CEXConfig) rateLimiter() *rate.Limiter {
+	if c.RateLimitPerMin <= 0 {
+		return nil
+	}
+	burst := c.BurstSize
+	if burst <= 0 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Every(time.Minute/time.Duration(c.RateLimitPerMin)), burst)
 }
 
 // AutoRebalanceConfig configures deposits and withdrawals by setting minimum
@@ -54,13 +358,42 @@ type AutoRebalanceConfig struct {
 	// allocating unallocated funds to the bot's balance and never actually
 	// perform deposits and withdrawals with the CEX.
 	InternalOnly bool `json:"internalOnly"`
+	// WithdrawAddresses, if non-empty, restricts CEX withdrawals to the
+	// listed address for each asset ID. Withdrawals are sent to the listed
+	// address rather than to a new wallet address, and withdrawals of an
+	// asset without an entry are refused. This keeps a compromised CEX API
+	// key from redirecting funds through the bot.
+	WithdrawAddresses map[uint32]string `json:"withdrawAddresses,omitempty"`
 }
 
 func (a *AutoRebalanceConfig) copy() *AutoRebalanceConfig {
 	return &AutoRebalanceConfig{
-		MinBaseTransfer:  a.MinBaseTransfer,
-		MinQuoteTransfer: a.MinQuoteTransfer,
+		MinBaseTransfer:   a.MinBaseTransfer,
+		MinQuoteTransfer:  a.MinQuoteTransfer,
+		InternalOnly:      a.InternalOnly,
+		WithdrawAddresses: utils.CopyMap(a.WithdrawAddresses),
+	}
+}
+
+func (a *AutoRebalanceConfig) validate() error {
+	for assetID, addr := range a.WithdrawAddresses {
+		if addr == "" {
+			return newConfigError(fmt.Sprintf("withdrawAddresses[%d]", assetID),
+				"empty withdraw address for asset %d", assetID)
+		}
+	}
+	return nil
+}
+
+// withdrawAddress returns the address from WithdrawAddresses that CEX
+// withdrawals of the asset must be sent to. If WithdrawAddresses is empty, addr
+// is empty and allowed is true, and withdrawals may go to any address.
+func (a *AutoRebalanceConfig) withdrawAddress(assetID uint32) (addr string, allowed bool) {
+	if len(a.WithdrawAddresses) == 0 {
+		return "", true
 	}
+	addr, allowed = a.WithdrawAddresses[assetID]
+	return addr, allowed
 }
 
 // BotBalanceAllocation is the initial allocation of funds for a bot.
@@ -112,6 +445,61 @@ func balanceDiffsToAllocation(diffs *BotInventoryDiffs) *BotBalanceAllocation {
 	return allocations
 }
 
+// balanceDiffsToWithdrawals converts a BotInventoryDiffs to a
+// BotBalanceAllocation of the amounts to withdraw, i.e. the magnitudes of
+// the negative diffs. It is the complement of balanceDiffsToAllocation.
+func balanceDiffsToWithdrawals(diffs *BotInventoryDiffs) *BotBalanceAllocation {
+	withdrawals := &BotBalanceAllocation{
+		DEX: make(map[uint32]uint64, len(diffs.DEX)),
+		CEX: make(map[uint32]uint64, len(diffs.CEX)),
+	}
+
+	for assetID, diff := range diffs.DEX {
+		if diff < 0 {
+			withdrawals.DEX[assetID] += uint64(-diff)
+		}
+	}
+	for assetID, diff := range diffs.CEX {
+		if diff < 0 {
+			withdrawals.CEX[assetID] += uint64(-diff)
+		}
+	}
+
+	return withdrawals
+}
+
+// Apply returns the allocation that results from adding the signed diffs to
+// b. An error is returned if any asset's allocation would become negative.
+// The receiver is not modified.
+func (b *BotBalanceAllocation) Apply(diffs *BotInventoryDiffs) (*BotBalanceAllocation, error) {
+	apply := func(alloc map[uint32]uint64, diffs map[uint32]int64, loc string) (map[uint32]uint64, error) {
+		res := utils.CopyMap(alloc)
+		for assetID, diff := range diffs {
+			bal := res[assetID]
+			if diff < 0 {
+				if uint64(-diff) > bal {
+					return nil, fmt.Errorf("%s %s allocation of %d cannot be reduced by %d",
+						loc, dex.BipIDSymbol(assetID), bal, -diff)
+				}
+				res[assetID] = bal - uint64(-diff)
+			} else {
+				res[assetID] = bal + uint64(diff)
+			}
+		}
+		return res, nil
+	}
+
+	dexAlloc, err := apply(b.DEX, diffs.DEX, "DEX")
+	if err != nil {
+		return nil, err
+	}
+	cexAlloc, err := apply(b.CEX, diffs.CEX, "CEX")
+	if err != nil {
+		return nil, err
+	}
+	return &BotBalanceAllocation{DEX: dexAlloc, CEX: cexAlloc}, nil
+}
+
 // #### IMPORTANT ###
 // If non-backwards compatible changes are made to the BotConfig, a new version
 // should be created and the event log db should be updated to support both
@@ -123,10 +511,14 @@ type rpcConfig struct {
 }
 
 func (r *rpcConfig) copy() *rpcConfig {
-	return &rpcConfig{
-		Alloc:         r.Alloc.copy(),
-		AutoRebalance: r.AutoRebalance.copy(),
+	c := new(rpcConfig)
+	if r.Alloc != nil {
+		c.Alloc = r.Alloc.copy()
 	}
+	if r.AutoRebalance != nil {
+		c.AutoRebalance = r.AutoRebalance.copy()
+	}
+	return c
 }
 
 // BotConfig is the configuration for a market making bot.
@@ -140,6 +532,13 @@ type BotConfig struct {
 	BaseWalletOptions  map[string]string `json:"baseWalletOptions"`
 	QuoteWalletOptions map[string]string `json:"quoteWalletOptions"`
 
+	// BaseWalletProfile and QuoteWalletProfile name entries in the
+	// MarketMakingConfig's WalletProfiles. The profile's options are merged
+	// with the inline wallet options, with the inline options taking
+	// precedence.
+	BaseWalletProfile  string `json:"baseWalletProfile,omitempty"`
+	QuoteWalletProfile string `json:"quoteWalletProfile,omitempty"`
+
 	CEXName string `json:"cexName"`
 
 	// UIConfig is settings defined and used by the front end to determine
@@ -155,6 +554,43 @@ type BotConfig struct {
 	// when they are starting the bot.
 	LotSize uint64 `json:"lotSize"`
 
+	// DryRun causes the bot to compute and log its intended orders at info
+	// level without placing them. This is useful for validating a new
+	// strategy configuration without committing funds.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// EpochThrottle causes the market making strategies to only re-place
+	// orders every EpochThrottle epochs, reducing order churn and fees on slow
+	// markets. Orders that are already booked are left in place between
+	// placements. 0 or 1 means every epoch.
+	EpochThrottle int `json:"epochThrottle,omitempty"`
+
+	// IdleShutdownEpochs stops the bot after this many consecutive epochs
+	// with no matches for its orders. The bot's balances are saved to
+	// IdleShutdownAlloc so that it can be restarted later. 0 disables.
+	IdleShutdownEpochs int `json:"idleShutdownEpochs,omitempty"`
+
+	// IdleShutdownAlloc is the allocation and auto-rebalance config saved
+	// when the bot was stopped for being idle. It takes precedence over the
+	// RPCConfig on the bot's next start, and is then cleared.
+	IdleShutdownAlloc *rpcConfig `json:"idleShutdownAlloc,omitempty"`
+
+	// DisableBuys and DisableSells keep the bot from placing orders on
+	// the buy or sell side of the DEX market. Any booked orders on a
+	// disabled side are cancelled. At most one side may be disabled.
+	DisableBuys  bool `json:"disableBuys,omitempty"`
+	DisableSells bool `json:"disableSells,omitempty"`
+
+	// Paused keeps the bot from being started while retaining its
+	// configuration.
+	Paused bool `json:"paused,omitempty"`
+
+	// MaxFeeBudget caps the transaction fees, by asset ID, that the bot will
+	// spend on DEX orders while running. Once the fees paid in an asset reach
+	// its budget, the bot stops placing new orders that pay fees in that
+	// asset. Assets without a budget are not limited.
+	MaxFeeBudget map[uint32]uint64 `json:"maxFeeBudget,omitempty"`
+
 	// Only one of the following configs should be set
 	BasicMMConfig        *BasicMarketMakingConfig `json:"basicMarketMakingConfig,omitempty"`
 	SimpleArbConfig      *SimpleArbConfig         `json:"simpleArbConfig,omitempty"`
@@ -166,6 +602,9 @@ func (c *BotConfig) copy() *BotConfig {
 
 	b.BaseWalletOptions = utils.CopyMap(c.BaseWalletOptions)
 	b.QuoteWalletOptions = utils.CopyMap(c.QuoteWalletOptions)
+	if c.MaxFeeBudget != nil {
+		b.MaxFeeBudget = utils.CopyMap(c.MaxFeeBudget)
+	}
 
 	if c.UIConfig != nil {
 		b.UIConfig = make(json.RawMessage, len(c.UIConfig))
@@ -174,6 +613,9 @@ func (c *BotConfig) copy() *BotConfig {
 	if c.RPCConfig != nil {
 		b.RPCConfig = c.RPCConfig.copy()
 	}
+	if c.IdleShutdownAlloc != nil {
+		b.IdleShutdownAlloc = c.IdleShutdownAlloc.copy()
+	}
 	if c.BasicMMConfig != nil {
 		b.BasicMMConfig = c.BasicMMConfig.copy()
 	}
@@ -187,6 +629,37 @@ func (c *BotConfig) copy() *BotConfig {
 	return &b
 }
 
+// resolveWalletOptions merges the wallet options of the bot's wallet profiles
+// into the inline wallet options. Inline options take precedence. An error is
+// returned if a profile is not found in the MarketMakingConfig.
+func (c *BotConfig) resolveWalletOptions(cfg *MarketMakingConfig) error {
+	resolve := func(profile string, inline map[string]string) (map[string]string, error) {
+		if profile == "" {
+			return inline, nil
+		}
+		opts, found := cfg.WalletProfiles[profile]
+		if !found {
+			return nil, fmt.Errorf("wallet profile %q not found", profile)
+		}
+		merged := utils.CopyMap(opts)
+		for k, v := range inline {
+			merged[k] = v
+		}
+		return merged, nil
+	}
+
+	baseOpts, err := resolve(c.BaseWalletProfile, c.BaseWalletOptions)
+	if err != nil {
+		return fmt.Errorf("error resolving base wallet options: %w", err)
+	}
+	quoteOpts, err := resolve(c.QuoteWalletProfile, c.QuoteWalletOptions)
+	if err != nil {
+		return fmt.Errorf("error resolving quote wallet options: %w", err)
+	}
+	c.BaseWalletOptions, c.QuoteWalletOptions = baseOpts, quoteOpts
+	return nil
+}
+
 // updateLotSize modifies the bot's configuration based on an update to the
 // market's lot size.
 func (c *BotConfig) updateLotSize(oldLotSize, newLotSize uint64) {
@@ -197,16 +670,241 @@ func (c *BotConfig) updateLotSize(oldLotSize, newLotSize uint64) {
 	}
 }
 
+// LotSizeChangeReport describes how the bot's placements would be rescaled by
+// a change in the market's lot size. The config is not modified.
+func (c *BotConfig) LotSizeChangeReport(oldLotSize, newLotSize uint64) string {
+	if newLotSize == 0 {
+		return "invalid lot size 0"
+	}
+	if oldLotSize == newLotSize {
+		return "lot size unchanged"
+	}
+
+	var buys, sells []*OrderPlacement
+	var mode RoundingMode
+	switch {
+	case c.BasicMMConfig != nil:
+		buys, sells = c.BasicMMConfig.BuyPlacements, c.BasicMMConfig.SellPlacements
+		mode = c.BasicMMConfig.RoundingMode
+	case c.ArbMarketMakerConfig != nil:
+		toBasic := func(p *ArbMarketMakingPlacement) *OrderPlacement {
+			return &OrderPlacement{Lots: p.Lots, GapFactor: p.Multiplier}
+		}
+		buys = utils.Map(c.ArbMarketMakerConfig.BuyPlacements, toBasic)
+		sells = utils.Map(c.ArbMarketMakerConfig.SellPlacements, toBasic)
+		mode = c.ArbMarketMakerConfig.RoundingMode
+	default:
+		return "no placements to rescale"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "lot size %d -> %d\n", oldLotSize, newLotSize)
+	report := func(side string, placements []*OrderPlacement) {
+		fmt.Fprintf(&sb, "%s placements:\n", side)
+		if len(placements) == 0 {
+			sb.WriteString("  none\n")
+			return
+		}
+		var oldQty, newQty uint64
+		newLots := rescaledLots(placements, oldLotSize, newLotSize, mode)
+		for i, p := range placements {
+			oldQty += p.Lots * oldLotSize
+			newQty += newLots[i] * newLotSize
+			if newLots[i] == 0 {
+				fmt.Fprintf(&sb, "  [%d] gap factor %g: %d lots -> removed\n", i, p.GapFactor, p.Lots)
+				continue
+			}
+			fmt.Fprintf(&sb, "  [%d] gap factor %g: %d lots -> %d lots\n", i, p.GapFactor, p.Lots, newLots[i])
+		}
+		fmt.Fprintf(&sb, "  total quantity %d -> %d\n", oldQty, newQty)
+	}
+	report("sell", sells)
+	report("buy", buys)
+	return sb.String()
+}
+
+// ConfigError is a BotConfig validation error. Field is the path of the
+// invalid field using json names, like ConfigChange.Field, so that config
+// editors can highlight the offending input, e.g.
+// basicMarketMakingConfig.buyPlacements[2].gapFactor. Field is empty if the
+// error is not specific to a field.
+type ConfigError struct {
+	Field string `json:"field"`
+	Msg   string `json:"msg"`
+}
+
+func (e *ConfigError) Error() string {
+	if e.Field == "" {
+		return e.Msg
+	}
+	return e.Field + ": " + e.Msg
+}
+
+func newConfigError(field, format string, args ...any) error {
+	return &ConfigError{Field: field, Msg: fmt.Sprintf(format, args...)}
+}
+
+// configFieldError prefixes the Field of a ConfigError with the path of the
+// field that contains it. Any other error is converted to a ConfigError for
+// the field.
+func configFieldError(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		return &ConfigError{Field: field, Msg: err.Error()}
+	}
+	switch {
+	case cfgErr.Field == "":
+	case strings.HasPrefix(cfgErr.Field, "["):
+		field += cfgErr.Field
+	default:
+		field += "." + cfgErr.Field
+	}
+	return &ConfigError{Field: field, Msg: cfgErr.Msg}
+}
+
+// validate checks the BotConfig. A returned error is a *ConfigError.
 func (c *BotConfig) validate() error {
+	if c.EpochThrottle < 0 {
+		return newConfigError("epochThrottle", "negative epoch throttle %d", c.EpochThrottle)
+	}
+	if c.IdleShutdownEpochs < 0 {
+		return newConfigError("idleShutdownEpochs", "negative idle shutdown epochs %d", c.IdleShutdownEpochs)
+	}
+	if c.DisableBuys && c.DisableSells {
+		return newConfigError("disableSells", "buys and sells cannot both be disabled")
+	}
+	for assetID, budget := range c.MaxFeeBudget {
+		if budget == 0 {
+			return newConfigError(fmt.Sprintf("maxFeeBudget.%d", assetID),
+				"zero fee budget for %s. omit the asset to not limit fees", dex.BipIDSymbol(assetID))
+		}
+	}
+	if c.RPCConfig != nil && c.RPCConfig.AutoRebalance != nil {
+		if err := c.RPCConfig.AutoRebalance.validate(); err != nil {
+			return configFieldError("rpcConfig.autoRebalance", err)
+		}
+	}
+
 	if c.BasicMMConfig != nil {
-		return c.BasicMMConfig.validate()
+		return configFieldError("basicMarketMakingConfig", c.BasicMMConfig.validate())
 	} else if c.SimpleArbConfig != nil {
-		return c.SimpleArbConfig.validate()
+		return configFieldError("simpleArbConfig", c.SimpleArbConfig.validate())
 	} else if c.ArbMarketMakerConfig != nil {
-		return c.ArbMarketMakerConfig.validate(c.BaseID, c.QuoteID)
+		return configFieldError("arbMarketMakingConfig", c.ArbMarketMakerConfig.validate(c.BaseID, c.QuoteID))
+	}
+
+	return newConfigError("", "no bot config set")
+}
+
+// ConfigChange is a change to a BotConfig field. Field is the path of the
+// field using json names, e.g. basicMarketMakingConfig.buyPlacements[0].lots.
+// Old or New is nil if the value was added or removed.
+type ConfigChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// Diff lists the changes that updating the BotConfig to the new config would
+// make. Neither config is modified.
+func (c *BotConfig) Diff(new *BotConfig) []ConfigChange {
+	var changes []ConfigChange
+	diffConfigValues("", reflect.ValueOf(c).Elem(), reflect.ValueOf(new).Elem(), &changes)
+	return changes
+}
+
+// diffConfigValues appends the differences between the old and new values to
+// changes. Structs, slices, and maps are compared field by field, element by
+// element, and key by key.
+func diffConfigValues(path string, old, new reflect.Value, changes *[]ConfigChange) {
+	addChange := func(path string, old, new reflect.Value) {
+		change := ConfigChange{Field: path}
+		if old.IsValid() {
+			change.Old = old.Interface()
+		}
+		if new.IsValid() {
+			change.New = new.Interface()
+		}
+		*changes = append(*changes, change)
+	}
+
+	if old.Type() == reflect.TypeOf(json.RawMessage{}) {
+		if !bytes.Equal(old.Bytes(), new.Bytes()) {
+			*changes = append(*changes, ConfigChange{Field: path, Old: string(old.Bytes()), New: string(new.Bytes())})
+		}
+		return
 	}
 
-	return fmt.Errorf("no bot config set")
+	switch old.Kind() {
+	case reflect.Pointer:
+		switch {
+		case old.IsNil() && new.IsNil():
+		case old.IsNil():
+			addChange(path, reflect.Value{}, new)
+		case new.IsNil():
+			addChange(path, old, reflect.Value{})
+		default:
+			diffConfigValues(path, old.Elem(), new.Elem(), changes)
+		}
+	case reflect.Struct:
+		t := old.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			diffConfigValues(name, old.Field(i), new.Field(i), changes)
+		}
+	case reflect.Slice, reflect.Array:
+		n := max(old.Len(), new.Len())
+		for i := 0; i < n; i++ {
+			elPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= old.Len():
+				addChange(elPath, reflect.Value{}, new.Index(i))
+			case i >= new.Len():
+				addChange(elPath, old.Index(i), reflect.Value{})
+			default:
+				diffConfigValues(elPath, old.Index(i), new.Index(i), changes)
+			}
+		}
+	case reflect.Map:
+		keys := make(map[string]reflect.Value, old.Len()+new.Len())
+		for _, k := range append(old.MapKeys(), new.MapKeys()...) {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for k := range keys {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+		for _, k := range sortedKeys {
+			oldV, newV := old.MapIndex(keys[k]), new.MapIndex(keys[k])
+			elPath := path + "." + k
+			switch {
+			case !oldV.IsValid():
+				addChange(elPath, reflect.Value{}, newV)
+			case !newV.IsValid():
+				addChange(elPath, oldV, reflect.Value{})
+			default:
+				diffConfigValues(elPath, oldV, newV, changes)
+			}
+		}
+	default:
+		if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+			addChange(path, old, new)
+		}
+	}
 }
 
 func validateConfigUpdate(old, new *BotConfig) error {
@@ -244,9 +942,47 @@ func (c *BotConfig) multiSplitBuffer() float64 {
 	return multiSplitBufferFloat
 }
 
+// LoggerName returns the name of the bot's logging subsystem, which is the
+// bot type followed by the DEX market ID, e.g. MM-dex.com-42-0. The log level
+// of a single bot can be set with this name.
+func (c *BotConfig) LoggerName() string {
+	mktID := dexMarketID(c.Host, c.BaseID, c.QuoteID)
+	switch {
+	case c.BasicMMConfig != nil:
+		return "MM-" + mktID
+	case c.SimpleArbConfig != nil:
+		return "ARB-" + mktID
+	case c.ArbMarketMakerConfig != nil:
+		return "AMM-" + mktID
+	}
+	return "Bot-" + mktID
+}
+
+// sideDisabled returns true if the bot is configured not to place orders on
+// the specified side of the DEX market.
+func (c *BotConfig) sideDisabled(sell bool) bool {
+	if sell {
+		return c.DisableSells
+	}
+	return c.DisableBuys
+}
+
 // maxPlacements returns the max amount of placements this bot will place on
-// either side of the market in an epoch.
+// either side of the market in an epoch. A disabled side has no placements.
 func (c *BotConfig) maxPlacements() (buy, sell uint32) {
+	buy, sell = c.configuredPlacements()
+	if c.DisableBuys {
+		buy = 0
+	}
+	if c.DisableSells {
+		sell = 0
+	}
+	return buy, sell
+}
+
+// configuredPlacements returns the number of placements on either side of
+// the market for the bot's strategy.
+func (c *BotConfig) configuredPlacements() (buy, sell uint32) {
 	switch {
 	case c.SimpleArbConfig != nil:
 		return 1, 1
@@ -0,0 +1,1119 @@
+package mm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/mm/libxc"
+)
+
+func TestResolveAllocations(t *testing.T) {
+	const dcrID, btcID = 42, 0
+
+	botCfg := func(dexAlloc, cexAlloc map[uint32]uint64) *BotConfig {
+		return &BotConfig{
+			CEXName: libxc.Binance,
+			RPCConfig: &rpcConfig{
+				Alloc: &BotBalanceAllocation{
+					DEX: dexAlloc,
+					CEX: cexAlloc,
+				},
+			},
+		}
+	}
+
+	available := map[uint32]uint64{
+		dcrID: 100e8,
+		btcID: 1e8,
+	}
+	cexAvailable := map[string]map[uint32]uint64{
+		libxc.Binance: {
+			dcrID: 200e8,
+			btcID: 2e8,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		botCfgs    []*BotConfig
+		wantErrStr string
+	}{
+		{
+			name: "balanced",
+			botCfgs: []*BotConfig{
+				botCfg(map[uint32]uint64{dcrID: 60e8, btcID: 5e7}, nil),
+				botCfg(map[uint32]uint64{dcrID: 40e8, btcID: 5e7}, nil),
+				{}, // no rpc config
+			},
+		},
+		{
+			name: "cex allocations not counted against dex",
+			botCfgs: []*BotConfig{
+				botCfg(map[uint32]uint64{dcrID: 100e8}, map[uint32]uint64{dcrID: 100e8}),
+				botCfg(nil, map[uint32]uint64{dcrID: 100e8, btcID: 2e8}),
+			},
+		},
+		{
+			name: "cex over-committed",
+			botCfgs: []*BotConfig{
+				botCfg(nil, map[uint32]uint64{dcrID: 150e8}),
+				botCfg(nil, map[uint32]uint64{dcrID: 100e8}),
+			},
+			wantErrStr: "Binance allocations for dcr exceed available balance by 5000000000",
+		},
+		{
+			name: "cex not available",
+			botCfgs: []*BotConfig{
+				{
+					CEXName: "OtherCEX",
+					RPCConfig: &rpcConfig{
+						Alloc: &BotBalanceAllocation{CEX: map[uint32]uint64{dcrID: 1}},
+					},
+				},
+			},
+			wantErrStr: "OtherCEX allocations for dcr exceed available balance by 1",
+		},
+		{
+			name: "over-committed",
+			botCfgs: []*BotConfig{
+				botCfg(map[uint32]uint64{dcrID: 60e8, btcID: 5e7}, nil),
+				botCfg(map[uint32]uint64{dcrID: 50e8, btcID: 5e7}, nil),
+			},
+			wantErrStr: "DEX allocations for dcr exceed available balance by 1000000000",
+		},
+		{
+			name: "asset not available",
+			botCfgs: []*BotConfig{
+				botCfg(map[uint32]uint64{60: 1}, nil),
+			},
+			wantErrStr: "DEX allocations for eth exceed available balance by 1",
+		},
+	}
+
+	for _, test := range tests {
+		cfg := &MarketMakingConfig{BotConfigs: test.botCfgs}
+		err := cfg.ResolveAllocations(available, cexAvailable)
+		if test.wantErrStr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.wantErrStr) {
+			t.Fatalf("%s: expected error containing %q, got %v", test.name, test.wantErrStr, err)
+		}
+	}
+}
+
+func TestCEXConfigRateLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		perMin  int
+		burst   int
+		wantErr bool
+	}{
+		{name: "no limit"},
+		{name: "limit only", perMin: 60},
+		{name: "limit and burst", perMin: 60, burst: 10},
+		{name: "burst equals limit", perMin: 60, burst: 60},
+		{name: "negative limit", perMin: -1, wantErr: true},
+		{name: "negative burst", perMin: 60, burst: -1, wantErr: true},
+		{name: "burst exceeds limit", perMin: 10, burst: 11, wantErr: true},
+	}
+	for _, test := range tests {
+		cfg := &CEXConfig{RateLimitPerMin: test.perMin, BurstSize: test.burst}
+		err := cfg.validate()
+		if test.wantErr != (err != nil) {
+			t.Fatalf("%s: wantErr = %t, got err = %v", test.name, test.wantErr, err)
+		}
+	}
+
+	if (&CEXConfig{}).rateLimiter() != nil {
+		t.Fatalf("limiter created with no rate limit")
+	}
+	limiter := (&CEXConfig{RateLimitPerMin: 120}).rateLimiter()
+	if limiter.Burst() != 1 {
+		t.Fatalf("expected default burst of 1, got %d", limiter.Burst())
+	}
+	if limiter.Limit() != 2 {
+		t.Fatalf("expected limit of 2 per second, got %v", limiter.Limit())
+	}
+
+	// Copy duplicates the CEX configs.
+	mmCfg := &MarketMakingConfig{
+		CexConfigs: []*CEXConfig{{Name: "Binance", RateLimitPerMin: 60, BurstSize: 5}},
+	}
+	cp := mmCfg.Copy()
+	if *cp.CexConfigs[0] != *mmCfg.CexConfigs[0] {
+		t.Fatalf("CEX config not copied")
+	}
+	cp.CexConfigs[0].BurstSize = 6
+	if mmCfg.CexConfigs[0].BurstSize != 5 {
+		t.Fatalf("copy modified the original")
+	}
+}
+
+func TestCEXBalanceCache(t *testing.T) {
+	for _, tt := range []struct {
+		ttl     int64
+		wantErr bool
+	}{{0, false}, {500, false}, {-1, true}} {
+		err := (&CEXConfig{CEXBalanceCacheTTL: tt.ttl}).validate()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ttl %d: wantErr = %t, got err = %v", tt.ttl, tt.wantErr, err)
+		}
+	}
+
+	mmCfg := &MarketMakingConfig{CexConfigs: []*CEXConfig{{Name: "Binance", CEXBalanceCacheTTL: 1500}}}
+	if mmCfg.Copy().CexConfigs[0].CEXBalanceCacheTTL != 1500 {
+		t.Fatalf("CEXBalanceCacheTTL not copied")
+	}
+	b, err := json.Marshal(mmCfg.CexConfigs[0])
+	if err != nil {
+		t.Fatalf("error encoding config: %v", err)
+	}
+	var decoded CEXConfig
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("error decoding config: %v", err)
+	}
+	if decoded.balanceCacheTTL() != 1500*time.Millisecond {
+		t.Fatalf("wrong decoded TTL %v", decoded.balanceCacheTTL())
+	}
+
+	tc := newTCEX()
+	tc.balances[42] = &libxc.ExchangeBalance{Available: 1}
+	cex := newBalanceCachingCEX(tc, time.Minute)
+	checkBal := func(want uint64) {
+		t.Helper()
+		bal, err := cex.Balance(42)
+		if err != nil {
+			t.Fatalf("Balance error: %v", err)
+		}
+		if bal.Available != want {
+			t.Fatalf("wanted balance %d, got %d", want, bal.Available)
+		}
+	}
+	checkBal(1)
+
+	// The cached balance is used until it expires or is invalidated.
+	tc.balances[42] = &libxc.ExchangeBalance{Available: 2}
+	checkBal(1)
+	if _, _, err := cex.Withdraw(context.Background(), 42, 1, "addr"); err != nil {
+		t.Fatalf("Withdraw error: %v", err)
+	}
+	checkBal(2)
+
+	tc.balances[42] = &libxc.ExchangeBalance{Available: 3}
+	cex.ttl = 0
+	checkBal(3)
+
+	// All balances are cached by Balances.
+	cex.ttl = time.Minute
+	if _, err := cex.Balances(context.Background()); err != nil {
+		t.Fatalf("Balances error: %v", err)
+	}
+	tc.balances[42] = &libxc.ExchangeBalance{Available: 4}
+	bals, err := cex.Balances(context.Background())
+	if err != nil {
+		t.Fatalf("Balances error: %v", err)
+	}
+	if bals[42].Available != 3 {
+		t.Fatalf("cached balances not used")
+	}
+	checkBal(3)
+}
+
+func TestRateLimitedCEX(t *testing.T) {
+	tc := newTCEX()
+	tc.tradeStatus = &libxc.Trade{ID: "abc"}
+	cex := &rateLimitedCEX{
+		CEX:     tc,
+		limiter: (&CEXConfig{RateLimitPerMin: 1, BurstSize: 1}).rateLimiter(),
+	}
+
+	// The first request uses the burst.
+	if _, err := cex.TradeStatus(context.Background(), "abc", 42, 0); err != nil {
+		t.Fatalf("TradeStatus error: %v", err)
+	}
+
+	// The next request must wait a minute, which exceeds the context's
+	// deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := cex.TradeStatus(ctx, "abc", 42, 0); err == nil {
+		t.Fatalf("no error for rate limited request")
+	}
+}
+
+func TestResolveWalletOptions(t *testing.T) {
+	mmCfg := &MarketMakingConfig{
+		WalletProfiles: map[string]map[string]string{
+			"split": {"multisplit": "true", "multisplitbuffer": "5"},
+		},
+	}
+
+	// Inline options take precedence over the profile.
+	botCfg := &BotConfig{
+		BaseWalletProfile:  "split",
+		BaseWalletOptions:  map[string]string{"multisplitbuffer": "10", "other": "x"},
+		QuoteWalletOptions: map[string]string{"quote": "y"},
+	}
+	if err := botCfg.resolveWalletOptions(mmCfg); err != nil {
+		t.Fatalf("resolveWalletOptions error: %v", err)
+	}
+	wantBase := map[string]string{"multisplit": "true", "multisplitbuffer": "10", "other": "x"}
+	if !reflect.DeepEqual(botCfg.BaseWalletOptions, wantBase) {
+		t.Fatalf("wrong base options. wanted %v, got %v", wantBase, botCfg.BaseWalletOptions)
+	}
+	if wantQuote := map[string]string{"quote": "y"}; !reflect.DeepEqual(botCfg.QuoteWalletOptions, wantQuote) {
+		t.Fatalf("wrong quote options. wanted %v, got %v", wantQuote, botCfg.QuoteWalletOptions)
+	}
+	if mmCfg.WalletProfiles["split"]["multisplitbuffer"] != "5" {
+		t.Fatalf("profile modified")
+	}
+
+	// Profile with no inline options.
+	botCfg = &BotConfig{QuoteWalletProfile: "split"}
+	if err := botCfg.resolveWalletOptions(mmCfg); err != nil {
+		t.Fatalf("resolveWalletOptions error: %v", err)
+	}
+	if !reflect.DeepEqual(botCfg.QuoteWalletOptions, mmCfg.WalletProfiles["split"]) {
+		t.Fatalf("wrong quote options: %v", botCfg.QuoteWalletOptions)
+	}
+
+	// Missing profile.
+	botCfg = &BotConfig{BaseWalletProfile: "missing"}
+	if err := botCfg.resolveWalletOptions(mmCfg); err == nil || !strings.Contains(err.Error(), `"missing" not found`) {
+		t.Fatalf("expected missing profile error, got %v", err)
+	}
+}
+
+func TestBotConfigDiff(t *testing.T) {
+	oldCfg := &BotConfig{
+		Host:               "dex.example.com:7232",
+		BaseID:             42,
+		QuoteID:            0,
+		BaseWalletOptions:  map[string]string{"multisplit": "true"},
+		QuoteWalletOptions: map[string]string{"multisplitbuffer": "5"},
+		LotSize:            1e8,
+		BasicMMConfig: &BasicMarketMakingConfig{
+			GapStrategy:    GapStrategyPercent,
+			BuyPlacements:  []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+			SellPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}, {Lots: 2, GapFactor: 0.02}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		update func(*BotConfig)
+		want   []ConfigChange
+	}{
+		{
+			name:   "no changes",
+			update: func(*BotConfig) {},
+		},
+		{
+			name: "placements",
+			update: func(c *BotConfig) {
+				c.BasicMMConfig.BuyPlacements[0].Lots = 3
+				c.BasicMMConfig.SellPlacements = c.BasicMMConfig.SellPlacements[:1]
+				c.BasicMMConfig.BuyPlacements = append(c.BasicMMConfig.BuyPlacements, &OrderPlacement{Lots: 1, GapFactor: 0.03})
+			},
+			want: []ConfigChange{
+				{Field: "basicMarketMakingConfig.sellPlacements[1]", Old: &OrderPlacement{Lots: 2, GapFactor: 0.02}},
+				{Field: "basicMarketMakingConfig.buyPlacements[0].lots", Old: uint64(1), New: uint64(3)},
+				{Field: "basicMarketMakingConfig.buyPlacements[1]", New: &OrderPlacement{Lots: 1, GapFactor: 0.03}},
+			},
+		},
+		{
+			name: "lot size",
+			update: func(c *BotConfig) {
+				c.LotSize = 2e8
+			},
+			want: []ConfigChange{
+				{Field: "lotSize", Old: uint64(1e8), New: uint64(2e8)},
+			},
+		},
+		{
+			name: "wallet options",
+			update: func(c *BotConfig) {
+				c.BaseWalletOptions["multisplit"] = "false"
+				delete(c.QuoteWalletOptions, "multisplitbuffer")
+				c.QuoteWalletOptions["multisplit"] = "true"
+			},
+			want: []ConfigChange{
+				{Field: "baseWalletOptions.multisplit", Old: "true", New: "false"},
+				{Field: "quoteWalletOptions.multisplit", New: "true"},
+				{Field: "quoteWalletOptions.multisplitbuffer", Old: "5"},
+			},
+		},
+		{
+			name: "allocations",
+			update: func(c *BotConfig) {
+				c.RPCConfig = &rpcConfig{Alloc: &BotBalanceAllocation{DEX: map[uint32]uint64{42: 1e8}}}
+			},
+			want: []ConfigChange{
+				{Field: "rpcConfig", New: &rpcConfig{Alloc: &BotBalanceAllocation{DEX: map[uint32]uint64{42: 1e8}}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCopy := oldCfg.copy()
+			newCfg := oldCfg.copy()
+			tt.update(newCfg)
+			changes := oldCfg.Diff(newCfg)
+			if len(changes) != len(tt.want) {
+				t.Fatalf("wanted %d changes, got %d: %+v", len(tt.want), len(changes), changes)
+			}
+			for i, change := range changes {
+				if !reflect.DeepEqual(change, tt.want[i]) {
+					t.Fatalf("change %d: wanted %+v, got %+v", i, tt.want[i], change)
+				}
+			}
+			if !reflect.DeepEqual(oldCfg, oldCopy) {
+				t.Fatalf("Diff modified the old config")
+			}
+		})
+	}
+}
+
+func TestBotConfigFieldRoundTrip(t *testing.T) {
+	newCfg := func() *BotConfig {
+		return &BotConfig{
+			Host:    "dex.example.com:7232",
+			BaseID:  42,
+			QuoteID: 0,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy:    GapStrategyPercent,
+				BuyPlacements:  []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+				SellPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		set   func(*BotConfig)
+		field func(*BotConfig) any
+		// invalid, if set, makes the config fail validation.
+		invalid func(*BotConfig)
+	}{
+		{
+			name:  "dry run",
+			key:   "dryRun",
+			set:   func(c *BotConfig) { c.DryRun = true },
+			field: func(c *BotConfig) any { return c.DryRun },
+		},
+		{
+			name:    "epoch throttle",
+			key:     "epochThrottle",
+			set:     func(c *BotConfig) { c.EpochThrottle = 3 },
+			field:   func(c *BotConfig) any { return c.EpochThrottle },
+			invalid: func(c *BotConfig) { c.EpochThrottle = -1 },
+		},
+		{
+			name:    "idle shutdown",
+			key:     "idleShutdownEpochs",
+			set:     func(c *BotConfig) { c.IdleShutdownEpochs = 10 },
+			field:   func(c *BotConfig) any { return c.IdleShutdownEpochs },
+			invalid: func(c *BotConfig) { c.IdleShutdownEpochs = -1 },
+		},
+		{
+			name:  "disable buys",
+			key:   "disableBuys",
+			set:   func(c *BotConfig) { c.DisableBuys = true },
+			field: func(c *BotConfig) any { return c.DisableBuys },
+		},
+		{
+			name:  "disable sells",
+			key:   "disableSells",
+			set:   func(c *BotConfig) { c.DisableSells = true },
+			field: func(c *BotConfig) any { return c.DisableSells },
+		},
+		{
+			name:    "max fee budget",
+			key:     "maxFeeBudget",
+			set:     func(c *BotConfig) { c.MaxFeeBudget = map[uint32]uint64{42: 1e7, 0: 5e5} },
+			field:   func(c *BotConfig) any { return c.MaxFeeBudget },
+			invalid: func(c *BotConfig) { c.MaxFeeBudget = map[uint32]uint64{42: 0} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Omitted when unset.
+			b, err := json.Marshal(newCfg())
+			if err != nil {
+				t.Fatalf("error encoding config: %v", err)
+			}
+			if strings.Contains(string(b), tt.key) {
+				t.Fatalf("%s encoded when unset: %s", tt.key, b)
+			}
+
+			cfg := newCfg()
+			tt.set(cfg)
+			if err := cfg.validate(); err != nil {
+				t.Fatalf("unexpected validation error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.field(cfg.copy()), tt.field(cfg)) {
+				t.Fatalf("%s not copied", tt.key)
+			}
+
+			if b, err = json.Marshal(cfg); err != nil {
+				t.Fatalf("error encoding config: %v", err)
+			}
+			if !strings.Contains(string(b), tt.key) {
+				t.Fatalf("%s not encoded: %s", tt.key, b)
+			}
+			var decoded BotConfig
+			if err := json.Unmarshal(b, &decoded); err != nil {
+				t.Fatalf("error decoding config: %v", err)
+			}
+			if !reflect.DeepEqual(tt.field(&decoded), tt.field(cfg)) {
+				t.Fatalf("wrong %s after round trip: %v", tt.key, tt.field(&decoded))
+			}
+
+			if tt.invalid != nil {
+				cfg = newCfg()
+				tt.invalid(cfg)
+				if err := cfg.validate(); err == nil {
+					t.Fatalf("no validation error for invalid %s", tt.key)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateActiveCount(t *testing.T) {
+	ids := []string{
+		dexMarketID("dex.example.com:7232", 42, 0),
+		dexMarketID("dex.example.com:7232", 60, 0),
+	}
+
+	tests := []struct {
+		name    string
+		max     int
+		running []string
+		wantErr bool
+	}{
+		{"unlimited", 0, ids, false},
+		{"below cap", 3, ids, false},
+		{"at cap", 2, ids, true},
+		{"above cap", 1, ids, true},
+		{"duplicate ids", 2, []string{ids[0], ids[0]}, false},
+		{"none running", 1, nil, false},
+	}
+	for _, tt := range tests {
+		cfg := &MarketMakingConfig{MaxActiveBots: tt.max}
+		err := cfg.ValidateActiveCount(tt.running)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: wantErr = %t, got err = %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	cfg := &MarketMakingConfig{MaxActiveBots: 4}
+	if cfg.Copy().MaxActiveBots != 4 {
+		t.Fatalf("MaxActiveBots not copied")
+	}
+}
+
+func TestBotConfigEpochThrottle(t *testing.T) {
+	newCfg := func(throttle int) *BotConfig {
+		return &BotConfig{
+			Host:          "dex.example.com:7232",
+			BaseID:        42,
+			EpochThrottle: throttle,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy:   GapStrategyPercent,
+				BuyPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+			},
+		}
+	}
+
+	// Only every Nth epoch is used for placements.
+	u := &unifiedExchangeAdaptor{}
+	for _, tt := range []struct {
+		throttle int
+		epoch    uint64
+		want     bool
+	}{
+		{0, 7, false},
+		{1, 7, false},
+		{3, 7, true},
+		{3, 9, false},
+	} {
+		u.botCfgV.Store(newCfg(tt.throttle))
+		if got := u.epochThrottled(tt.epoch); got != tt.want {
+			t.Fatalf("throttle %d, epoch %d: wanted throttled = %t, got %t", tt.throttle, tt.epoch, tt.want, got)
+		}
+	}
+}
+
+func TestActiveBots(t *testing.T) {
+	newBot := func(baseID uint32, paused bool) *BotConfig {
+		return &BotConfig{
+			Host:          "dex.example.com:7232",
+			BaseID:        baseID,
+			Paused:        paused,
+			BasicMMConfig: &BasicMarketMakingConfig{GapStrategy: GapStrategyPercent},
+		}
+	}
+	cfg := &MarketMakingConfig{
+		BotConfigs: []*BotConfig{newBot(42, false), newBot(60, true), newBot(2, false)},
+	}
+
+	active := cfg.ActiveBots()
+	if len(active) != 2 {
+		t.Fatalf("expected 2 active bots, got %d", len(active))
+	}
+	for _, botCfg := range active {
+		if botCfg.Paused || botCfg.BaseID == 60 {
+			t.Fatalf("paused bot %d returned as active", botCfg.BaseID)
+		}
+	}
+
+	if !cfg.Copy().BotConfigs[1].Paused {
+		t.Fatalf("Paused not copied")
+	}
+
+	b, err := json.Marshal(cfg.BotConfigs[1])
+	if err != nil {
+		t.Fatalf("error encoding config: %v", err)
+	}
+	if !strings.Contains(string(b), `"paused":true`) {
+		t.Fatalf("paused not encoded: %s", b)
+	}
+	var decoded BotConfig
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("error decoding config: %v", err)
+	}
+	if !decoded.Paused {
+		t.Fatalf("Paused not decoded")
+	}
+	if b, _ = json.Marshal(cfg.BotConfigs[0]); strings.Contains(string(b), "paused") {
+		t.Fatalf("paused encoded when false: %s", b)
+	}
+}
+
+func TestLotSizeChangeReport(t *testing.T) {
+	cfg := &BotConfig{
+		BasicMMConfig: &BasicMarketMakingConfig{
+			SellPlacements: []*OrderPlacement{{Lots: 4, GapFactor: 1}, {Lots: 1, GapFactor: 2}},
+			BuyPlacements:  []*OrderPlacement{{Lots: 3, GapFactor: 1.5}},
+		},
+	}
+	orig := cfg.copy()
+
+	ensureContains := func(report string, exps ...string) {
+		t.Helper()
+		for _, exp := range exps {
+			if !strings.Contains(report, exp) {
+				t.Fatalf("report missing %q:\n%s", exp, report)
+			}
+		}
+	}
+
+	// Doubling the lot size halves the lots, and the trailing single-lot sell
+	// placement no longer fits in the original quantity.
+	report := cfg.LotSizeChangeReport(100, 200)
+	ensureContains(report,
+		"lot size 100 -> 200",
+		"[0] gap factor 1: 4 lots -> 2 lots",
+		"[1] gap factor 2: 1 lots -> removed",
+		"total quantity 500 -> 400",
+		"[0] gap factor 1.5: 3 lots -> 1 lots",
+	)
+
+	// Halving the lot size doubles the lots.
+	report = cfg.LotSizeChangeReport(100, 50)
+	ensureContains(report,
+		"lot size 100 -> 50",
+		"[0] gap factor 1: 4 lots -> 8 lots",
+		"[1] gap factor 2: 1 lots -> 2 lots",
+		"total quantity 500 -> 500",
+		"[0] gap factor 1.5: 3 lots -> 6 lots",
+	)
+
+	if diffs := orig.Diff(cfg); len(diffs) != 0 {
+		t.Fatalf("config modified: %+v", diffs)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mm.json")
+	writeCfg := func(cfg *MarketMakingConfig) {
+		t.Helper()
+		b, err := json.Marshal(cfg)
+		if err != nil {
+			t.Fatalf("error marshalling config: %v", err)
+		}
+		if err := os.WriteFile(path, b, 0644); err != nil {
+			t.Fatalf("error writing config: %v", err)
+		}
+	}
+	newCfg := func(epochThrottle int) *MarketMakingConfig {
+		return &MarketMakingConfig{
+			BotConfigs: []*BotConfig{{
+				Host:          "dex.com",
+				BaseID:        42,
+				QuoteID:       0,
+				EpochThrottle: epochThrottle,
+				BasicMMConfig: &BasicMarketMakingConfig{
+					GapStrategy:    GapStrategyPercent,
+					SellPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+				},
+			}},
+		}
+	}
+
+	writeCfg(newCfg(1))
+
+	changes := make(chan *MarketMakingConfig, 1)
+	stop, err := watchConfig(path, time.Millisecond*5, tLogger, func(cfg *MarketMakingConfig) {
+		changes <- cfg
+	})
+	if err != nil {
+		t.Fatalf("watchConfig error: %v", err)
+	}
+	defer stop()
+
+	// A valid edit is applied.
+	writeCfg(newCfg(2))
+	select {
+	case cfg := <-changes:
+		if cfg.BotConfigs[0].EpochThrottle != 2 {
+			t.Fatalf("wrong epoch throttle %d", cfg.BotConfigs[0].EpochThrottle)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no change reported for a valid edit")
+	}
+
+	// An invalid edit is not.
+	writeCfg(newCfg(-1))
+	select {
+	case cfg := <-changes:
+		t.Fatalf("change reported for an invalid edit: %+v", cfg)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	// Nor is an unparseable one.
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatalf("error writing config: %v", err)
+	}
+	select {
+	case <-changes:
+		t.Fatalf("change reported for an unparseable edit")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	stop()
+	stop() // idempotent
+}
+
+func TestBotConfigIdleShutdown(t *testing.T) {
+	newCfg := func(epochs int) *BotConfig {
+		return &BotConfig{
+			Host:               "dex.example.com:7232",
+			BaseID:             42,
+			IdleShutdownEpochs: epochs,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy:   GapStrategyPercent,
+				BuyPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+			},
+		}
+	}
+
+	// The bot stops and saves its allocation after the configured number of
+	// epochs without a match.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var saved *BotBalanceAllocation
+	u := &unifiedExchangeAdaptor{
+		ctx:             ctx,
+		kill:            cancel,
+		log:             tLogger,
+		mwh:             &MarketWithHost{Host: "dex.example.com:7232", BaseID: 42},
+		baseDexBalances: map[uint32]int64{42: 1e8, 0: 2e8},
+		saveAllocation: func(_ *MarketWithHost, alloc *BotBalanceAllocation, _ *AutoRebalanceConfig) {
+			saved = alloc
+		},
+	}
+	u.botCfgV.Store(newCfg(2))
+	for epoch := uint64(1); epoch <= 2; epoch++ {
+		if u.idleShutdown(epoch) {
+			t.Fatalf("stopped early at epoch %d", epoch)
+		}
+	}
+	u.idleEpochs.Store(0) // matched
+	for epoch := uint64(3); epoch <= 4; epoch++ {
+		if u.idleShutdown(epoch) {
+			t.Fatalf("stopped early at epoch %d after a match", epoch)
+		}
+	}
+	if !u.idleShutdown(5) {
+		t.Fatalf("bot not stopped")
+	}
+	if ctx.Err() == nil {
+		t.Fatalf("bot not killed")
+	}
+	if saved == nil || saved.DEX[42] != 1e8 || saved.DEX[0] != 2e8 {
+		t.Fatalf("wrong saved allocation %+v", saved)
+	}
+}
+
+func TestBotConfigDisabledSide(t *testing.T) {
+	newCfg := func(disableBuys, disableSells bool) *BotConfig {
+		return &BotConfig{
+			Host:         "dex.example.com:7232",
+			BaseID:       42,
+			DisableBuys:  disableBuys,
+			DisableSells: disableSells,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy:    GapStrategyPercent,
+				BuyPlacements:  []*OrderPlacement{{Lots: 1, GapFactor: 0.01}, {Lots: 1, GapFactor: 0.02}},
+				SellPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+			},
+		}
+	}
+
+	for _, tt := range []struct {
+		disableBuys, disableSells bool
+		expBuy, expSell           uint32
+	}{
+		{false, false, 2, 1},
+		{true, false, 0, 1},
+		{false, true, 2, 0},
+	} {
+		cfg := newCfg(tt.disableBuys, tt.disableSells)
+		if err := cfg.validate(); err != nil {
+			t.Fatalf("unexpected validation error: %v", err)
+		}
+		buy, sell := cfg.maxPlacements()
+		if buy != tt.expBuy || sell != tt.expSell {
+			t.Fatalf("disable buys = %t, sells = %t: expected %d buy, %d sell placements, got %d, %d",
+				tt.disableBuys, tt.disableSells, tt.expBuy, tt.expSell, buy, sell)
+		}
+	}
+
+	if err := newCfg(true, true).validate(); err == nil {
+		t.Fatalf("no error with both sides disabled")
+	}
+}
+
+func TestAutoRebalanceWithdrawAddresses(t *testing.T) {
+	cfg := &AutoRebalanceConfig{
+		MinBaseTransfer:   1e6,
+		MinQuoteTransfer:  2e6,
+		InternalOnly:      true,
+		WithdrawAddresses: map[uint32]string{42: "DsExampleAddr", 0: "bc1example"},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	cp := cfg.copy()
+	if !reflect.DeepEqual(cp, cfg) {
+		t.Fatalf("copy differs: %+v != %+v", cp, cfg)
+	}
+	cp.WithdrawAddresses[42] = "DsOtherAddr"
+	if cfg.WithdrawAddresses[42] != "DsExampleAddr" {
+		t.Fatalf("copy shares the withdraw address map")
+	}
+
+	if addr, allowed := cfg.withdrawAddress(42); !allowed || addr != "DsExampleAddr" {
+		t.Fatalf("wrong withdraw address %q, allowed = %t", addr, allowed)
+	}
+	if _, allowed := cfg.withdrawAddress(60); allowed {
+		t.Fatalf("withdrawal allowed for asset without an entry")
+	}
+	if addr, allowed := (&AutoRebalanceConfig{}).withdrawAddress(60); !allowed || addr != "" {
+		t.Fatalf("withdrawal restricted with no list. addr = %q, allowed = %t", addr, allowed)
+	}
+
+	cfg.WithdrawAddresses[60] = ""
+	if err := cfg.validate(); err == nil {
+		t.Fatalf("no error for empty withdraw address")
+	}
+}
+
+func TestBotBalanceAllocationApply(t *testing.T) {
+	alloc := &BotBalanceAllocation{
+		DEX: map[uint32]uint64{42: 1000, 0: 500},
+		CEX: map[uint32]uint64{42: 2000},
+	}
+
+	tests := []struct {
+		name    string
+		diffs   *BotInventoryDiffs
+		exp     *BotBalanceAllocation
+		wantErr bool
+	}{
+		{
+			name: "positive",
+			diffs: &BotInventoryDiffs{
+				DEX: map[uint32]int64{42: 100, 60: 50},
+				CEX: map[uint32]int64{0: 300},
+			},
+			exp: &BotBalanceAllocation{
+				DEX: map[uint32]uint64{42: 1100, 0: 500, 60: 50},
+				CEX: map[uint32]uint64{42: 2000, 0: 300},
+			},
+		},
+		{
+			name: "negative",
+			diffs: &BotInventoryDiffs{
+				DEX: map[uint32]int64{42: -1000, 0: -200},
+				CEX: map[uint32]int64{42: -500},
+			},
+			exp: &BotBalanceAllocation{
+				DEX: map[uint32]uint64{42: 0, 0: 300},
+				CEX: map[uint32]uint64{42: 1500},
+			},
+		},
+		{
+			name: "over-subtracting DEX",
+			diffs: &BotInventoryDiffs{
+				DEX: map[uint32]int64{0: -501},
+			},
+			wantErr: true,
+		},
+		{
+			name: "over-subtracting missing CEX asset",
+			diffs: &BotInventoryDiffs{
+				CEX: map[uint32]int64{60: -1},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		before := alloc.copy()
+		res, err := alloc.Apply(tt.diffs)
+		if !reflect.DeepEqual(before, alloc) {
+			t.Fatalf("%s: receiver was modified", tt.name)
+		}
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(res, tt.exp) {
+			t.Fatalf("%s: expected %+v, got %+v", tt.name, tt.exp, res)
+		}
+	}
+}
+
+func TestBotConfigLoggerName(t *testing.T) {
+	newCfg := func() *BotConfig {
+		return &BotConfig{Host: "dex.com", BaseID: 42, QuoteID: 0}
+	}
+	tests := []struct {
+		name string
+		cfg  func(*BotConfig)
+		exp  string
+	}{
+		{"basic", func(c *BotConfig) { c.BasicMMConfig = &BasicMarketMakingConfig{} }, "MM-dex.com-42-0"},
+		{"simple arb", func(c *BotConfig) { c.SimpleArbConfig = &SimpleArbConfig{} }, "ARB-dex.com-42-0"},
+		{"arb mm", func(c *BotConfig) { c.ArbMarketMakerConfig = &ArbMarketMakerConfig{} }, "AMM-dex.com-42-0"},
+		{"unknown", func(c *BotConfig) {}, "Bot-dex.com-42-0"},
+	}
+	for _, tt := range tests {
+		cfg := newCfg()
+		tt.cfg(cfg)
+		if name := cfg.LoggerName(); name != tt.exp {
+			t.Fatalf("%s: expected logger name %q, got %q", tt.name, tt.exp, name)
+		}
+	}
+}
+
+func TestBalanceDiffsToWithdrawals(t *testing.T) {
+	diffs := &BotInventoryDiffs{
+		DEX: map[uint32]int64{42: 100, 0: -200, 60: 0},
+		CEX: map[uint32]int64{42: -300, 0: 400},
+	}
+
+	deposits := balanceDiffsToAllocation(diffs)
+	expDeposits := &BotBalanceAllocation{
+		DEX: map[uint32]uint64{42: 100},
+		CEX: map[uint32]uint64{0: 400},
+	}
+	if !reflect.DeepEqual(deposits, expDeposits) {
+		t.Fatalf("wrong deposits. expected %+v, got %+v", expDeposits, deposits)
+	}
+
+	withdrawals := balanceDiffsToWithdrawals(diffs)
+	expWithdrawals := &BotBalanceAllocation{
+		DEX: map[uint32]uint64{0: 200},
+		CEX: map[uint32]uint64{42: 300},
+	}
+	if !reflect.DeepEqual(withdrawals, expWithdrawals) {
+		t.Fatalf("wrong withdrawals. expected %+v, got %+v", expWithdrawals, withdrawals)
+	}
+}
+
+func TestConfigErrorFieldPaths(t *testing.T) {
+	basicCfg := func() *BotConfig {
+		return &BotConfig{
+			Host:    "dex.com",
+			BaseID:  42,
+			QuoteID: 0,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy: GapStrategyPercent,
+				BuyPlacements: []*OrderPlacement{
+					{Lots: 1, GapFactor: 0.01},
+					{Lots: 1, GapFactor: 0.02},
+					{Lots: 1, GapFactor: 0.03},
+				},
+				SellPlacements: []*OrderPlacement{
+					{Lots: 1, GapFactor: 0.01},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*BotConfig)
+		expField string
+	}{
+		{
+			name:     "buy placement out of bounds",
+			modify:   func(c *BotConfig) { c.BasicMMConfig.BuyPlacements[2].GapFactor = 0.5 },
+			expField: "basicMarketMakingConfig.buyPlacements[2].gapFactor",
+		},
+		{
+			name: "duplicate sell placement",
+			modify: func(c *BotConfig) {
+				c.BasicMMConfig.SellPlacements = append(c.BasicMMConfig.SellPlacements, &OrderPlacement{Lots: 1, GapFactor: 0.01})
+			},
+			expField: "basicMarketMakingConfig.sellPlacements[1].gapFactor",
+		},
+		{
+			name:     "empty oracle source",
+			modify:   func(c *BotConfig) { c.BasicMMConfig.OracleConfig = &OracleConfig{Sources: []string{"binance.com", ""}} },
+			expField: "basicMarketMakingConfig.oracleConfig.sources[1]",
+		},
+		{
+			name:     "negative epoch throttle",
+			modify:   func(c *BotConfig) { c.EpochThrottle = -1 },
+			expField: "epochThrottle",
+		},
+		{
+			name: "empty withdraw address",
+			modify: func(c *BotConfig) {
+				c.RPCConfig = &rpcConfig{AutoRebalance: &AutoRebalanceConfig{WithdrawAddresses: map[uint32]string{42: ""}}}
+			},
+			expField: "rpcConfig.autoRebalance.withdrawAddresses[42]",
+		},
+		{
+			name: "simple arb",
+			modify: func(c *BotConfig) {
+				c.BasicMMConfig = nil
+				c.SimpleArbConfig = &SimpleArbConfig{ProfitTrigger: 0.01, MaxActiveArbs: 0, NumEpochsLeaveOpen: 2}
+			},
+			expField: "simpleArbConfig.maxActiveArbs",
+		},
+	}
+
+	if err := basicCfg().validate(); err != nil {
+		t.Fatalf("unexpected error for valid config: %v", err)
+	}
+
+	for _, tt := range tests {
+		cfg := basicCfg()
+		tt.modify(cfg)
+		err := cfg.validate()
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Fatalf("%s: expected a ConfigError, got %v", tt.name, err)
+		}
+		if cfgErr.Field != tt.expField {
+			t.Fatalf("%s: expected field %q, got %q", tt.name, tt.expField, cfgErr.Field)
+		}
+		if !strings.HasPrefix(err.Error(), tt.expField+": ") {
+			t.Fatalf("%s: field path not in error message %q", tt.name, err.Error())
+		}
+	}
+}
+
+func TestCheckCEXConnections(t *testing.T) {
+	cfg := &MarketMakingConfig{
+		CexConfigs: []*CEXConfig{
+			{Name: libxc.Binance, APIKey: "good"},
+			{Name: libxc.BinanceUS, APIKey: "bad"},
+		},
+	}
+	errBadKey := errors.New("invalid API key")
+	connect := func(cexCfg *CEXConfig) error {
+		if cexCfg.APIKey == "bad" {
+			return errBadKey
+		}
+		return nil
+	}
+
+	results := cfg.CheckCEXConnections(context.Background(), connect)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != libxc.Binance || results[0].Err != nil {
+		t.Fatalf("unexpected result for %s: %+v", libxc.Binance, results[0])
+	}
+	if results[1].Name != libxc.BinanceUS || !errors.Is(results[1].Err, errBadKey) {
+		t.Fatalf("unexpected result for %s: %+v", libxc.BinanceUS, results[1])
+	}
+
+	// A canceled context attempts no connections.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = cfg.CheckCEXConnections(ctx, func(*CEXConfig) error {
+		t.Fatalf("connect called with canceled context")
+		return nil
+	})
+	for _, res := range results {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Fatalf("expected context.Canceled for %s, got %v", res.Name, res.Err)
+		}
+	}
+}
+
+func TestMaxFeeBudget(t *testing.T) {
+	const dcrID, btcID = 42, 0
+	cfg := &BotConfig{
+		Host:          "dex.com",
+		BaseID:        dcrID,
+		QuoteID:       btcID,
+		MaxFeeBudget:  map[uint32]uint64{dcrID: 1e7, btcID: 5e5},
+		BasicMMConfig: &BasicMarketMakingConfig{GapStrategy: GapStrategyPercent, BuyPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}}},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	// The copy does not share the map.
+	c := cfg.copy()
+	c.MaxFeeBudget[dcrID] = 1
+	if cfg.MaxFeeBudget[dcrID] != 1e7 {
+		t.Fatalf("copy modified the original budget")
+	}
+
+	// A zero budget is rejected.
+	cfg.MaxFeeBudget[btcID] = 0
+	var cfgErr *ConfigError
+	if err := cfg.validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "maxFeeBudget.0" {
+		t.Fatalf("expected maxFeeBudget.0 config error, got %v", err)
+	}
+}
@@ -432,6 +432,9 @@ type unifiedExchangeAdaptor struct {
 	// ** IMPORTANT ** No mutexes should be locked when calling this
 	// function.
 	internalTransfer func(*MarketWithHost, doInternalTransferFunc) error
+	// saveAllocation persists the bot's allocation and auto-rebalance config
+	// when it stops itself after being idle. It may be nil.
+	saveAllocation func(*MarketWithHost, *BotBalanceAllocation, *AutoRebalanceConfig)
 
 	botLooper dex.Connector
 	botLoop   *dex.ConnectionMaster
@@ -462,6 +465,9 @@ type unifiedExchangeAdaptor struct {
 	pendingWithdrawals map[string]*pendingWithdrawal
 	pendingDeposits    map[string]*pendingDeposit
 	inventoryMods      map[uint32]int64
+	// feesSpent is the fees paid for the bot's completed DEX orders, by
+	// asset. See BotConfig.MaxFeeBudget.
+	feesSpent map[uint32]uint64
 
 	// If pendingBaseRebalance/pendingQuoteRebalance are true, it means
 	// there is a pending deposit/withdrawal of the base/quote asset,
@@ -484,6 +490,10 @@ type unifiedExchangeAdaptor struct {
 
 	epochReport atomic.Value // *EpochReport
 
+	// idleEpochs is the number of epochs since the bot's orders were last
+	// matched. See BotConfig.IdleShutdownEpochs.
+	idleEpochs atomic.Int64
+
 	cexProblemsMtx sync.RWMutex
 	cexProblems    *CEXProblems
 }
@@ -957,7 +967,125 @@ func withinTolerance(rate, target uint64, driftTolerance float64) bool {
 	return rate >= lowerBound && rate <= upperBound
 }
 
+// epochThrottled checks if order placement should be skipped for the epoch
+// because of the bot's EpochThrottle.
+func (u *unifiedExchangeAdaptor) epochThrottled(epoch uint64) bool {
+	n := u.botCfg().EpochThrottle
+	return n > 1 && epoch%uint64(n) != 0
+}
+
+// recordFeesSpent adds the fees paid for a completed DEX order to the bot's
+// fees spent, logging a warning when an asset's MaxFeeBudget is reached.
+// balancesMtx must be held.
+func (u *unifiedExchangeAdaptor) recordFeesSpent(o *pendingDEXOrder) {
+	o.txsMtx.RLock()
+	defer o.txsMtx.RUnlock()
+	ord := o.currentState().order
+	_, fromFeeAsset, _, toFeeAsset := orderAssets(u.baseID, u.quoteID, ord.Sell)
+	fees := make(map[uint32]uint64, 2)
+	if ord.FeesPaid != nil {
+		fees[fromFeeAsset] += ord.FeesPaid.Funding
+	}
+	for _, tx := range o.swaps {
+		fees[fromFeeAsset] += tx.Fees
+	}
+	for _, tx := range o.refunds {
+		fees[fromFeeAsset] += tx.Fees
+	}
+	for _, tx := range o.redeems {
+		fees[toFeeAsset] += tx.Fees
+	}
+
+	budgets := u.botCfg().MaxFeeBudget
+	for assetID, fee := range fees {
+		spent := u.feesSpent[assetID]
+		u.feesSpent[assetID] = spent + fee
+		if budget, found := budgets[assetID]; found && spent < budget && spent+fee >= budget {
+			fmtBudget := u.fmtQuoteFees(budget)
+			if assetID == u.baseFeeID {
+				fmtBudget = u.fmtBaseFees(budget)
+			}
+			u.log.Warnf("Fee budget of %s reached. No new orders paying fees in %s will be placed.",
+				fmtBudget, dex.BipIDSymbol(assetID))
+		}
+	}
+}
+
+// feeBudgetReached returns true if either of the fee assets of an order on the
+// specified side of the market has reached its MaxFeeBudget.
+func (u *unifiedExchangeAdaptor) feeBudgetReached(sell bool) bool {
+	budgets := u.botCfg().MaxFeeBudget
+	if len(budgets) == 0 {
+		return false
+	}
+	_, fromFeeAsset, _, toFeeAsset := orderAssets(u.baseID, u.quoteID, sell)
+	u.balancesMtx.RLock()
+	defer u.balancesMtx.RUnlock()
+	for _, assetID := range []uint32{fromFeeAsset, toFeeAsset} {
+		if budget, found := budgets[assetID]; found && u.feesSpent[assetID] >= budget {
+			return true
+		}
+	}
+	return false
+}
+
+// idleShutdown checks if the bot has gone more than IdleShutdownEpochs
+// epochs without a match. If so, the bot's balances are saved as its
+// allocation and the bot is stopped. idleShutdown should be called once per
+// epoch.
+func (u *unifiedExchangeAdaptor) idleShutdown(epoch uint64) bool {
+	n := u.botCfg().IdleShutdownEpochs
+	if n <= 0 || u.idleEpochs.Add(1) <= int64(n) {
+		return false
+	}
+	u.log.Infof("Stopping bot at epoch %d after %d epochs with no matches", epoch, n)
+	if u.saveAllocation != nil {
+		u.saveAllocation(u.mwh, u.allocation(), u.autoRebalanceCfg())
+	}
+	u.kill()
+	return true
+}
+
+// allocation is the bot's total balance of each asset on the DEX and CEX,
+// suitable for restarting the bot.
+func (u *unifiedExchangeAdaptor) allocation() *BotBalanceAllocation {
+	u.balancesMtx.RLock()
+	defer u.balancesMtx.RUnlock()
+	total := func(b *BotBalance) uint64 {
+		return b.Available + b.Locked + b.Pending + b.Reserved
+	}
+	alloc := &BotBalanceAllocation{
+		DEX: make(map[uint32]uint64, len(u.baseDexBalances)),
+		CEX: make(map[uint32]uint64, len(u.baseCexBalances)),
+	}
+	for assetID := range u.baseDexBalances {
+		alloc.DEX[assetID] = total(u.dexBalance(assetID))
+	}
+	for assetID := range u.baseCexBalances {
+		alloc.CEX[assetID] = total(u.cexBalance(assetID))
+	}
+	return alloc
+}
+
+// errDryRun is the error for orders that were not placed because the bot is
+// configured for a dry run.
+var errDryRun = errors.New("dry run, order not placed")
+
 func (u *unifiedExchangeAdaptor) placeMultiTrade(placements []*dexOrderInfo, sell bool) []*core.MultiTradeResult {
+	if u.botCfg().DryRun {
+		side := "buy"
+		if sell {
+			side = "sell"
+		}
+		results := make([]*core.MultiTradeResult, 0, len(placements))
+		for _, p := range placements {
+			u.log.Infof("Dry run: would place %s order on %s for %s at rate %s", side, u.mwh,
+				u.fmtQty(u.baseID, p.placement.Qty), u.fmtRate(p.placement.Rate))
+			results = append(results, &core.MultiTradeResult{Error: errDryRun})
+		}
+		return results
+	}
+
 	corePlacements := make([]*core.QtyRate, 0, len(placements))
 	for _, p := range placements {
 		corePlacements = append(corePlacements, p.placement)
@@ -1177,6 +1305,15 @@ func (u *unifiedExchangeAdaptor) multiTrade(
 	driftTolerance float64,
 	currEpoch uint64,
 ) (_ map[order.OrderID]*dexOrderInfo, or *OrderReport) {
+	if u.botCfg().sideDisabled(sell) || u.feeBudgetReached(sell) {
+		// Keep the placement indexes, but request no lots so that any
+		// standing orders on this side are cancelled.
+		disabled := make([]*TradePlacement, 0, len(placements))
+		for _, p := range placements {
+			disabled = append(disabled, &TradePlacement{Rate: p.Rate, CounterTradeRate: p.CounterTradeRate})
+		}
+		placements = disabled
+	}
 	or = newOrderReport(placements)
 	if len(placements) == 0 {
 		return nil, or
@@ -1980,22 +2117,33 @@ func (u *unifiedExchangeAdaptor) withdraw(ctx context.Context, assetID uint32, a
 		return fmt.Errorf("bot has insufficient balance to withdraw %s. required: %v, have: %v", symbol, amount, balance.Available)
 	}
 
-	addr, err := u.clientCore.NewDepositAddress(assetID)
-	if err != nil {
-		return err
+	addr, allowed := "", true
+	if cfg := u.autoRebalanceCfg(); cfg != nil {
+		addr, allowed = cfg.withdrawAddress(assetID)
+	}
+	if !allowed {
+		return fmt.Errorf("withdrawal of %s is not allowed by the withdraw address list", symbol)
 	}
 
-	// Pull transparent address out of unified address. There may be a different
-	// field "exchangeAddress" once we add support for the new special encoding
-	// required on binance global for zec and firo.
-	if strings.HasPrefix(addr, "unified:") {
-		var addrs struct {
-			Transparent string `json:"transparent"`
+	if addr == "" {
+		var err error
+		addr, err = u.clientCore.NewDepositAddress(assetID)
+		if err != nil {
+			return err
 		}
-		if err := json.Unmarshal([]byte(addr[len("unified:"):]), &addrs); err != nil {
-			return fmt.Errorf("error decoding unified address %q: %v", addr, err)
+
+		// Pull transparent address out of unified address. There may be a different
+		// field "exchangeAddress" once we add support for the new special encoding
+		// required on binance global for zec and firo.
+		if strings.HasPrefix(addr, "unified:") {
+			var addrs struct {
+				Transparent string `json:"transparent"`
+			}
+			if err := json.Unmarshal([]byte(addr[len("unified:"):]), &addrs); err != nil {
+				return fmt.Errorf("error decoding unified address %q: %v", addr, err)
+			}
+			addr = addrs.Transparent
 		}
-		addr = addrs.Transparent
 	}
 
 	u.balancesMtx.Lock()
@@ -2231,6 +2379,12 @@ func (u *unifiedExchangeAdaptor) CEXTrade(ctx context.Context, baseID, quoteID u
 		return nil, fmt.Errorf("insufficient balance")
 	}
 
+	if cfg := u.botCfg(); cfg.DryRun {
+		u.log.Infof("Dry run: would place CEX trade on %s for %s-%s, sell = %t, type = %d, qty = %d, rate = %d",
+			cfg.CEXName, dex.BipIDSymbol(baseID), dex.BipIDSymbol(quoteID), sell, orderType, qty, rate)
+		return nil, errDryRun
+	}
+
 	u.subscriptionIDMtx.RLock()
 	subscriptionID := u.subscriptionID
 	u.subscriptionIDMtx.RUnlock()
@@ -2801,6 +2955,7 @@ func (u *unifiedExchangeAdaptor) handleDEXOrderUpdate(o *core.Order) {
 		if adjustedBals {
 			u.logBalanceAdjustments(dexEffects.Settled, nil, fmt.Sprintf("DEX order %s complete.", orderID))
 		}
+		u.recordFeesSpent(pendingOrder)
 		u.balancesMtx.Unlock()
 	}
 
@@ -2854,6 +3009,7 @@ func (u *unifiedExchangeAdaptor) handleDEXNotification(n core.Notification) {
 		if cfg.Host != note.Host || u.mwh.ID() != note.MarketID {
 			return
 		}
+		u.idleEpochs.Store(0)
 		if note.Topic() == core.TopicRedemptionConfirmed {
 			u.runStats.completedMatches.Add(1)
 			fiatRates := u.fiatRates.Load().(map[uint32]float64)
@@ -4030,6 +4186,7 @@ type exchangeAdaptorCfg struct {
 	eventLogDB          eventLogDB
 	botCfg              *BotConfig
 	internalTransfer    func(*MarketWithHost, doInternalTransferFunc) error
+	saveAllocation      func(*MarketWithHost, *BotBalanceAllocation, *AutoRebalanceConfig)
 }
 
 // newUnifiedExchangeAdaptor is the constructor for a unifiedExchangeAdaptor.
@@ -4081,6 +4238,7 @@ func newUnifiedExchangeAdaptor(cfg *exchangeAdaptorCfg) (*unifiedExchangeAdaptor
 		baseTraits:       baseTraits,
 		quoteTraits:      quoteTraits,
 		internalTransfer: cfg.internalTransfer,
+		saveAllocation:   cfg.saveAllocation,
 
 		baseDexBalances:    baseDEXBalances,
 		baseCexBalances:    baseCEXBalances,
@@ -4090,6 +4248,7 @@ func newUnifiedExchangeAdaptor(cfg *exchangeAdaptorCfg) (*unifiedExchangeAdaptor
 		pendingWithdrawals: make(map[string]*pendingWithdrawal),
 		mwh:                cfg.mwh,
 		inventoryMods:      make(map[uint32]int64),
+		feesSpent:          make(map[uint32]uint64),
 		cexProblems:        newCEXProblems(),
 	}
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
@@ -6411,3 +6412,97 @@ func TestRefreshPendingEvents(t *testing.T) {
 	expectedCEXAvailableBalance[42] -= 2e7
 	checkAvailableBalances()
 }
+
+func TestDryRun(t *testing.T) {
+	const baseID, quoteID = 42, 0
+	tCore := newTCore()
+	tCEX := newTCEX()
+	adaptor := mustParseAdaptor(&exchangeAdaptorCfg{
+		core:            tCore,
+		cex:             tCEX,
+		baseDexBalances: map[uint32]uint64{baseID: 1e9, quoteID: 1e9},
+		baseCexBalances: map[uint32]uint64{baseID: 1e9, quoteID: 1e9},
+		mwh: &MarketWithHost{
+			Host:    "dex.com",
+			BaseID:  baseID,
+			QuoteID: quoteID,
+		},
+		eventLogDB: newTEventLogDB(),
+	})
+	adaptor.botCfgV.Store(&BotConfig{
+		Host:    "dex.com",
+		BaseID:  baseID,
+		QuoteID: quoteID,
+		CEXName: libxc.Binance,
+		DryRun:  true,
+	})
+
+	placements := []*dexOrderInfo{
+		{placement: &core.QtyRate{Qty: 1e8, Rate: 5e7}},
+		{placement: &core.QtyRate{Qty: 2e8, Rate: 4e7}},
+	}
+	results := adaptor.placeMultiTrade(placements, false)
+	if len(results) != len(placements) {
+		t.Fatalf("expected %d results, got %d", len(placements), len(results))
+	}
+	for i, res := range results {
+		if !errors.Is(res.Error, errDryRun) {
+			t.Fatalf("result %d: expected dry run error, got %v", i, res.Error)
+		}
+	}
+	if len(tCore.multiTradesPlaced) != 0 {
+		t.Fatalf("MultiTrade called during a dry run")
+	}
+
+	_, err := adaptor.CEXTrade(context.Background(), baseID, quoteID, true, 5e7, 1e8, libxc.OrderTypeLimit)
+	if !errors.Is(err, errDryRun) {
+		t.Fatalf("expected dry run error from CEXTrade, got %v", err)
+	}
+	if tCEX.lastTrade != nil {
+		t.Fatalf("CEX Trade called during a dry run")
+	}
+}
+
+func TestWithdrawAddresses(t *testing.T) {
+	const baseID, quoteID = 42, 0
+	tCore := newTCore()
+	tCore.newDepositAddress = "DsNewAddr"
+	tCEX := newTCEX()
+	adaptor := mustParseAdaptor(&exchangeAdaptorCfg{
+		core:            tCore,
+		cex:             tCEX,
+		baseDexBalances: map[uint32]uint64{baseID: 1e8, quoteID: 1e8},
+		baseCexBalances: map[uint32]uint64{baseID: 1e8, quoteID: 1e8},
+		mwh: &MarketWithHost{
+			Host:    "dex.com",
+			BaseID:  baseID,
+			QuoteID: quoteID,
+		},
+		eventLogDB: newTEventLogDB(),
+		autoRebalanceConfig: &AutoRebalanceConfig{
+			WithdrawAddresses: map[uint32]string{baseID: "DsListedAddr"},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Withdrawals go to the listed address, not a new deposit address.
+	if err := adaptor.withdraw(ctx, baseID, 1e6); err != nil {
+		t.Fatalf("withdraw error: %v", err)
+	}
+	if len(tCEX.withdrawals) != 1 || tCEX.withdrawals[0].address != "DsListedAddr" {
+		t.Fatalf("expected a withdrawal to the listed address, got %+v", tCEX.withdrawals)
+	}
+
+	// An asset without an entry is refused.
+	if err := adaptor.withdraw(ctx, quoteID, 1e6); err == nil {
+		t.Fatalf("no error withdrawing an asset without a listed address")
+	}
+	if len(tCEX.withdrawals) != 1 {
+		t.Fatalf("withdrawal made for an asset without a listed address")
+	}
+
+	cancel()
+	adaptor.wg.Wait()
+}
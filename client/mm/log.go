@@ -0,0 +1,15 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import "decred.org/dcrdex/dex"
+
+// pkgLog is the logger for package-level functions that do not belong to a
+// MarketMaker, such as WatchConfig. It is disabled until UseLogger is called.
+var pkgLog = dex.Disabled
+
+// UseLogger sets the logger used by package-level functions.
+func UseLogger(logger dex.Logger) {
+	pkgLog = logger
+}
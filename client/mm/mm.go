@@ -19,6 +19,7 @@ import (
 	"decred.org/dcrdex/client/orderbook"
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/order"
+	"golang.org/x/time/rate"
 )
 
 // clientCore is satisfied by core.Core.
@@ -86,6 +87,165 @@ type centralizedExchange struct {
 	connectErr string
 }
 
+// rateLimitedCEX is a libxc.CEX that waits on a token bucket before making
+// requests to the CEX's API.
+type rateLimitedCEX struct {
+	libxc.CEX
+	limiter *rate.Limiter
+}
+
+func (c *rateLimitedCEX) Balances(ctx context.Context) (map[uint32]*libxc.ExchangeBalance, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.CEX.Balances(ctx)
+}
+
+func (c *rateLimitedCEX) CancelTrade(ctx context.Context, baseID, quoteID uint32, tradeID string) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.CEX.CancelTrade(ctx, baseID, quoteID, tradeID)
+}
+
+func (c *rateLimitedCEX) Markets(ctx context.Context) (map[string]*libxc.Market, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.CEX.Markets(ctx)
+}
+
+func (c *rateLimitedCEX) SubscribeMarket(ctx context.Context, baseID, quoteID uint32) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.CEX.SubscribeMarket(ctx, baseID, quoteID)
+}
+
+func (c *rateLimitedCEX) Trade(ctx context.Context, baseID, quoteID uint32, sell bool, rate, qty uint64, orderType libxc.OrderType, subscriptionID int) (*libxc.Trade, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.CEX.Trade(ctx, baseID, quoteID, sell, rate, qty, orderType, subscriptionID)
+}
+
+func (c *rateLimitedCEX) GetDepositAddress(ctx context.Context, assetID uint32) (string, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+	return c.CEX.GetDepositAddress(ctx, assetID)
+}
+
+func (c *rateLimitedCEX) ConfirmDeposit(ctx context.Context, deposit *libxc.DepositData) (bool, uint64) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return false, 0
+	}
+	return c.CEX.ConfirmDeposit(ctx, deposit)
+}
+
+func (c *rateLimitedCEX) Withdraw(ctx context.Context, assetID uint32, amt uint64, address string) (string, uint64, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return "", 0, err
+	}
+	return c.CEX.Withdraw(ctx, assetID, amt, address)
+}
+
+func (c *rateLimitedCEX) ConfirmWithdrawal(ctx context.Context, withdrawalID string, assetID uint32) (uint64, string, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return 0, "", err
+	}
+	return c.CEX.ConfirmWithdrawal(ctx, withdrawalID, assetID)
+}
+
+func (c *rateLimitedCEX) TradeStatus(ctx context.Context, id string, baseID, quoteID uint32) (*libxc.Trade, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.CEX.TradeStatus(ctx, id, baseID, quoteID)
+}
+
+// cachedBalance is a balance read from a CEX.
+type cachedBalance struct {
+	bal   libxc.ExchangeBalance
+	stamp time.Time
+}
+
+// balanceCachingCEX is a libxc.CEX that reuses balances read from the CEX for
+// the ttl. Trades and withdrawals invalidate the cache.
+type balanceCachingCEX struct {
+	libxc.CEX
+	ttl time.Duration
+
+	mtx      sync.Mutex
+	bals     map[uint32]*cachedBalance
+	allStamp time.Time // when all balances were last read with Balances
+}
+
+func newBalanceCachingCEX(cex libxc.CEX, ttl time.Duration) *balanceCachingCEX {
+	return &balanceCachingCEX{
+		CEX:  cex,
+		ttl:  ttl,
+		bals: make(map[uint32]*cachedBalance),
+	}
+}
+
+func (c *balanceCachingCEX) Balance(assetID uint32) (*libxc.ExchangeBalance, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if cb, found := c.bals[assetID]; found && time.Since(cb.stamp) < c.ttl {
+		bal := cb.bal
+		return &bal, nil
+	}
+	bal, err := c.CEX.Balance(assetID)
+	if err != nil {
+		return nil, err
+	}
+	c.bals[assetID] = &cachedBalance{bal: *bal, stamp: time.Now()}
+	return bal, nil
+}
+
+func (c *balanceCachingCEX) Balances(ctx context.Context) (map[uint32]*libxc.ExchangeBalance, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if time.Since(c.allStamp) < c.ttl {
+		bals := make(map[uint32]*libxc.ExchangeBalance, len(c.bals))
+		for assetID, cb := range c.bals {
+			bal := cb.bal
+			bals[assetID] = &bal
+		}
+		return bals, nil
+	}
+	bals, err := c.CEX.Balances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	c.bals = make(map[uint32]*cachedBalance, len(bals))
+	for assetID, bal := range bals {
+		c.bals[assetID] = &cachedBalance{bal: *bal, stamp: now}
+	}
+	c.allStamp = now
+	return bals, nil
+}
+
+// invalidate clears the cached balances.
+func (c *balanceCachingCEX) invalidate() {
+	c.mtx.Lock()
+	c.bals = make(map[uint32]*cachedBalance)
+	c.allStamp = time.Time{}
+	c.mtx.Unlock()
+}
+
+func (c *balanceCachingCEX) Trade(ctx context.Context, baseID, quoteID uint32, sell bool, rate, qty uint64, orderType libxc.OrderType, subscriptionID int) (*libxc.Trade, error) {
+	defer c.invalidate()
+	return c.CEX.Trade(ctx, baseID, quoteID, sell, rate, qty, orderType, subscriptionID)
+}
+
+func (c *balanceCachingCEX) Withdraw(ctx context.Context, assetID uint32, amt uint64, address string) (string, uint64, error) {
+	defer c.invalidate()
+	return c.CEX.Withdraw(ctx, assetID, amt, address)
+}
+
 // mtx must be locked
 func (c *centralizedExchange) balancesCopy() map[uint32]*libxc.ExchangeBalance {
 	bs := make(map[uint32]*libxc.ExchangeBalance, len(c.balances))
@@ -566,6 +726,9 @@ func (m *MarketMaker) loadAndConnectCEX(ctx context.Context, cfg *CEXConfig) (*c
 
 // loadCEX initializes the cex if required and returns the centralizedExchange.
 func (m *MarketMaker) loadCEX(ctx context.Context, cfg *CEXConfig) (*centralizedExchange, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", cfg.Name, err)
+	}
 	m.cexMtx.Lock()
 	defer m.cexMtx.Unlock()
 	var success bool
@@ -599,6 +762,12 @@ func (m *MarketMaker) loadCEX(ctx context.Context, cfg *CEXConfig) (*centralized
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CEX: %v", err)
 	}
+	if limiter := cfg.rateLimiter(); limiter != nil {
+		cex = &rateLimitedCEX{CEX: cex, limiter: limiter}
+	}
+	if ttl := cfg.balanceCacheTTL(); ttl > 0 {
+		cex = newBalanceCachingCEX(cex, ttl)
+	}
 	c := &centralizedExchange{
 		CEX:       cex,
 		CEXConfig: cfg,
@@ -729,23 +898,31 @@ func (m *MarketMaker) balancesSufficient(balances *BotBalanceAllocation, mkt *Ma
 // botCfgForMarket returns the configuration for a bot on a specific market.
 // If alternateConfigPath is not nil, the configuration will be loaded from the
 // file at that path.
-func (m *MarketMaker) configsForMarket(mkt *MarketWithHost, alternateConfigPath *string) (botConfig *BotConfig, cexConfig *CEXConfig, err error) {
-	fullCfg := m.defaultConfig()
-	if alternateConfigPath != nil {
-		fullCfg, err = getMarketMakingConfig(*alternateConfigPath)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error loading custom market making config: %v", err)
-		}
+// fullConfig loads the market making config from the alternate config path,
+// if provided, or else returns the default config.
+func (m *MarketMaker) fullConfig(alternateConfigPath *string) (*MarketMakingConfig, error) {
+	if alternateConfigPath == nil {
+		return m.defaultConfig(), nil
 	}
+	fullCfg, err := getMarketMakingConfig(*alternateConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading custom market making config: %v", err)
+	}
+	return fullCfg, nil
+}
 
+func (m *MarketMaker) configsForMarket(mkt *MarketWithHost, fullCfg *MarketMakingConfig) (botConfig *BotConfig, cexConfig *CEXConfig, err error) {
 	for _, c := range fullCfg.BotConfigs {
 		if c.Host == mkt.Host && c.BaseID == mkt.BaseID && c.QuoteID == mkt.QuoteID {
-			botConfig = c
+			botConfig = c.copy()
 		}
 	}
 	if botConfig == nil {
 		return nil, nil, fmt.Errorf("no bot config found for %s", mkt)
 	}
+	if err := botConfig.resolveWalletOptions(fullCfg); err != nil {
+		return nil, nil, err
+	}
 
 	if botConfig.CEXName != "" {
 		for _, c := range fullCfg.CexConfigs {
@@ -762,17 +939,7 @@ func (m *MarketMaker) configsForMarket(mkt *MarketWithHost, alternateConfigPath
 }
 
 func (m *MarketMaker) botSubLogger(cfg *BotConfig) dex.Logger {
-	mktID := dexMarketID(cfg.Host, cfg.BaseID, cfg.QuoteID)
-	switch {
-	case cfg.BasicMMConfig != nil:
-		return m.log.SubLogger(fmt.Sprintf("MM-%s", mktID))
-	case cfg.SimpleArbConfig != nil:
-		return m.log.SubLogger(fmt.Sprintf("ARB-%s", mktID))
-	case cfg.ArbMarketMakerConfig != nil:
-		return m.log.SubLogger(fmt.Sprintf("AMM-%s", mktID))
-	}
-	// This will error in the caller.
-	return m.log.SubLogger(fmt.Sprintf("Bot-%s", mktID))
+	return m.log.SubLogger(cfg.LoggerName())
 }
 
 func (m *MarketMaker) cexInUse(cexName string) bool {
@@ -786,19 +953,17 @@ func (m *MarketMaker) cexInUse(cexName string) bool {
 }
 
 func (m *MarketMaker) newBot(cfg *BotConfig, adaptorCfg *exchangeAdaptorCfg) (bot, error) {
-	mktID := dexMarketID(cfg.Host, cfg.BaseID, cfg.QuoteID)
-
 	if err := cfg.validate(); err != nil {
 		return nil, fmt.Errorf("invalid bot config: %w", err)
 	}
 
 	switch {
 	case cfg.ArbMarketMakerConfig != nil:
-		return newArbMarketMaker(cfg, adaptorCfg, m.log.SubLogger(fmt.Sprintf("AMM-%s", mktID)))
+		return newArbMarketMaker(cfg, adaptorCfg, m.botSubLogger(cfg))
 	case cfg.BasicMMConfig != nil:
-		return newBasicMarketMaker(cfg, adaptorCfg, m.oracle, m.log.SubLogger(fmt.Sprintf("MM-%s", mktID)))
+		return newBasicMarketMaker(cfg, adaptorCfg, m.oracle, m.botSubLogger(cfg))
 	case cfg.SimpleArbConfig != nil:
-		return newSimpleArbMarketMaker(cfg, adaptorCfg, m.log.SubLogger(fmt.Sprintf("ARB-%s", mktID)))
+		return newSimpleArbMarketMaker(cfg, adaptorCfg, m.botSubLogger(cfg))
 	default:
 		return nil, fmt.Errorf("not bot config found")
 	}
@@ -820,11 +985,31 @@ func (m *MarketMaker) StartBot(startCfg *StartConfig, alternateConfigPath *strin
 
 	m.runningBotsMtx.RLock()
 	_, found := m.runningBots[startCfg.MarketWithHost]
+	runningBotIDs := make([]string, 0, len(m.runningBots))
+	for mwh := range m.runningBots {
+		runningBotIDs = append(runningBotIDs, dexMarketID(mwh.Host, mwh.BaseID, mwh.QuoteID))
+	}
 	m.runningBotsMtx.RUnlock()
 	if found {
 		return fmt.Errorf("bot for %s already running", mkt)
 	}
 
+	fullCfg, err := m.fullConfig(alternateConfigPath)
+	if err != nil {
+		return err
+	}
+	if err := fullCfg.ValidateActiveCount(runningBotIDs); err != nil {
+		return err
+	}
+
+	botCfg, cexCfg, err := m.configsForMarket(&startCfg.MarketWithHost, fullCfg)
+	if err != nil {
+		return err
+	}
+	if botCfg.Paused {
+		return fmt.Errorf("bot for %s is paused", mkt)
+	}
+
 	coreMkt, err := m.core.ExchangeMarket(startCfg.Host, startCfg.BaseID, startCfg.QuoteID)
 	if err != nil {
 		return fmt.Errorf("error getting market: %v", err)
@@ -839,15 +1024,19 @@ func (m *MarketMaker) StartBot(startCfg *StartConfig, alternateConfigPath *strin
 		}
 	}
 
-	botCfg, cexCfg, err := m.configsForMarket(&startCfg.MarketWithHost, alternateConfigPath)
-	if err != nil {
-		return err
-	}
-
 	if botCfg.RPCConfig != nil {
 		startCfg.Alloc = botCfg.RPCConfig.Alloc
 		startCfg.AutoRebalance = botCfg.RPCConfig.AutoRebalance
 	}
+	if botCfg.IdleShutdownAlloc != nil {
+		startCfg.Alloc = botCfg.IdleShutdownAlloc.Alloc
+		startCfg.AutoRebalance = botCfg.IdleShutdownAlloc.AutoRebalance
+	}
+	if startCfg.AutoRebalance != nil {
+		if err := startCfg.AutoRebalance.validate(); err != nil {
+			return err
+		}
+	}
 
 	// Lot size may be zero if started from RPC. If the lot size in the config
 	// is set, then we check if the lot size has changed since the configuration
@@ -862,7 +1051,7 @@ func (m *MarketMaker) StartBot(startCfg *StartConfig, alternateConfigPath *strin
 		if botCfg.LotSize != mktInfo.LotSize {
 			if overrideLotSizeChange {
 				botCfg.LotSize = mktInfo.LotSize
-				m.updateDefaultBotConfig(botCfg)
+				m.saveBotLotSize(&mkt, mktInfo.LotSize)
 			} else {
 				return fmt.Errorf("lot size changed since configuration")
 			}
@@ -874,10 +1063,23 @@ func (m *MarketMaker) StartBot(startCfg *StartConfig, alternateConfigPath *strin
 		botCfg.LotSize = mktInfo.LotSize
 	}
 
-	return m.startBot(startCfg, botCfg, cexCfg, appPW)
+	if err := m.startBot(startCfg, botCfg, cexCfg, alternateConfigPath, appPW); err != nil {
+		return err
+	}
+
+	// The idle shutdown allocation is only used once.
+	if botCfg.IdleShutdownAlloc != nil {
+		err := m.updateBotConfigFile(alternateConfigPath, &mkt, func(c *BotConfig) {
+			c.IdleShutdownAlloc = nil
+		})
+		if err != nil {
+			m.log.Errorf("Error clearing idle shutdown allocation for %s: %v", mkt, err)
+		}
+	}
+	return nil
 }
 
-func (m *MarketMaker) startBot(startCfg *StartConfig, botCfg *BotConfig, cexCfg *CEXConfig, appPW []byte) (err error) {
+func (m *MarketMaker) startBot(startCfg *StartConfig, botCfg *BotConfig, cexCfg *CEXConfig, cfgPath *string, appPW []byte) (err error) {
 	mwh := &startCfg.MarketWithHost
 	if err := m.balancesSufficient(startCfg.Alloc, mwh, cexCfg); err != nil {
 		return err
@@ -922,6 +1124,9 @@ func (m *MarketMaker) startBot(startCfg *StartConfig, botCfg *BotConfig, cexCfg
 		botCfg:              botCfg,
 		eventLogDB:          m.eventLogDB,
 		internalTransfer:    m.internalTransfer,
+		saveAllocation: func(mwh *MarketWithHost, alloc *BotBalanceAllocation, autoRebalance *AutoRebalanceConfig) {
+			m.saveBotAllocation(cfgPath, mwh, alloc, autoRebalance)
+		},
 	}
 
 	bot, err := m.newBot(botCfg, adaptorCfg)
@@ -1029,6 +1234,61 @@ func (m *MarketMaker) updateDefaultBotConfig(updatedCfg *BotConfig) {
 	}
 }
 
+// saveBotLotSize updates the lot size of the bot's config in the default
+// config file. The saved config is taken from the default config rather than
+// the resolved config of a starting bot, so that wallet profile references
+// are preserved.
+func (m *MarketMaker) saveBotLotSize(mwh *MarketWithHost, lotSize uint64) {
+	err := m.updateBotConfigFile(nil, mwh, func(c *BotConfig) {
+		c.LotSize = lotSize
+	})
+	if err != nil {
+		m.log.Errorf("Error saving lot size for %s: %v", mwh, err)
+	}
+}
+
+// updateBotConfigFile applies update to the config of the bot on the market
+// in the config file at cfgPath, or in the default config file if cfgPath is
+// nil.
+func (m *MarketMaker) updateBotConfigFile(cfgPath *string, mwh *MarketWithHost, update func(*BotConfig)) error {
+	fullCfg, err := m.fullConfig(cfgPath)
+	if err != nil {
+		return err
+	}
+	for _, c := range fullCfg.BotConfigs {
+		if c.Host != mwh.Host || c.BaseID != mwh.BaseID || c.QuoteID != mwh.QuoteID {
+			continue
+		}
+		update(c)
+		if cfgPath == nil {
+			m.updateDefaultBotConfig(c)
+			return nil
+		}
+		data, err := json.MarshalIndent(fullCfg, "", "    ")
+		if err != nil {
+			return fmt.Errorf("error marshalling market making config: %v", err)
+		}
+		return os.WriteFile(*cfgPath, data, 0644)
+	}
+	return fmt.Errorf("no bot config found for %s", mwh)
+}
+
+// saveBotAllocation saves the allocation and auto-rebalance config as the
+// bot's IdleShutdownAlloc in the config file at cfgPath, or in the default
+// config file if cfgPath is nil, so that the bot starts with them when
+// restarted.
+func (m *MarketMaker) saveBotAllocation(cfgPath *string, mwh *MarketWithHost, alloc *BotBalanceAllocation, autoRebalance *AutoRebalanceConfig) {
+	err := m.updateBotConfigFile(cfgPath, mwh, func(c *BotConfig) {
+		c.IdleShutdownAlloc = &rpcConfig{
+			Alloc:         alloc,
+			AutoRebalance: autoRebalance,
+		}
+	})
+	if err != nil {
+		m.log.Errorf("Error saving allocation for %s: %v", mwh, err)
+	}
+}
+
 // UpdateBotConfig updates the configuration for one of the bots.
 func (m *MarketMaker) UpdateBotConfig(updatedCfg *BotConfig) error {
 	m.runningBotsMtx.RLock()
@@ -1182,6 +1442,11 @@ func (m *MarketMaker) UpdateRunningBotCfg(cfg *BotConfig, balanceDiffs *BotInven
 	if cfg == nil {
 		return fmt.Errorf("nil config")
 	}
+	if autoRebalanceCfg != nil {
+		if err := autoRebalanceCfg.validate(); err != nil {
+			return err
+		}
+	}
 
 	mkt := MarketWithHost{cfg.Host, cfg.BaseID, cfg.QuoteID}
 	m.runningBotsMtx.RLock()
@@ -1196,6 +1461,13 @@ func (m *MarketMaker) UpdateRunningBotCfg(cfg *BotConfig, balanceDiffs *BotInven
 		return err
 	}
 
+	// The running bot uses the resolved wallet options, but the config is
+	// saved with the profile references.
+	runCfg := cfg.copy()
+	if err := runCfg.resolveWalletOptions(m.defaultConfig()); err != nil {
+		return err
+	}
+
 	if balanceDiffs != nil {
 		if err := m.balancesSufficient(balanceDiffsToAllocation(balanceDiffs), &mkt, rb.cexCfg); err != nil {
 			return err
@@ -1232,7 +1504,7 @@ func (m *MarketMaker) UpdateRunningBotCfg(cfg *BotConfig, balanceDiffs *BotInven
 	}
 
 	if err := rb.withPause(func() error {
-		if err := rb.updateConfig(cfg, autoRebalanceCfg); err != nil {
+		if err := rb.updateConfig(runCfg, autoRebalanceCfg); err != nil {
 			return err
 		}
 		if balanceDiffs != nil {
@@ -95,6 +95,9 @@ type ArbMarketMakerConfig struct {
 	DriftTolerance     float64                     `json:"driftTolerance"`
 	NumEpochsLeaveOpen uint64                      `json:"orderPersistence"`
 	MultiHop           *MultiHopCfg                `json:"multiHop"`
+	// RoundingMode selects how placement lots are rounded when the market's
+	// lot size changes. See rescaledLots.
+	RoundingMode RoundingMode `json:"roundingMode,omitempty"`
 }
 
 func (c *ArbMarketMakerConfig) isMultiHop() bool {
@@ -118,27 +121,31 @@ func (a *ArbMarketMakerConfig) copy() *ArbMarketMakerConfig {
 
 func (a *ArbMarketMakerConfig) validate(baseID, quoteID uint32) error {
 	if len(a.BuyPlacements) == 0 && len(a.SellPlacements) == 0 {
-		return fmt.Errorf("no placements")
+		return newConfigError("", "no placements")
 	}
 
 	if a.Profit <= 0 {
-		return fmt.Errorf("profit must be greater than 0")
+		return newConfigError("profit", "profit must be greater than 0")
 	}
 
 	if a.DriftTolerance < 0 || a.DriftTolerance > 0.01 {
-		return fmt.Errorf("drift tolerance %f out of bounds", a.DriftTolerance)
+		return newConfigError("driftTolerance", "drift tolerance %f out of bounds", a.DriftTolerance)
+	}
+
+	if err := a.RoundingMode.validate(); err != nil {
+		return err
 	}
 
 	if a.NumEpochsLeaveOpen < 2 {
-		return fmt.Errorf("arbs must be left open for at least 2 epochs")
+		return newConfigError("orderPersistence", "arbs must be left open for at least 2 epochs")
 	}
 
 	if a.MultiHop != nil {
 		if a.MultiHop.BaseAssetMarket[0] != baseID && a.MultiHop.BaseAssetMarket[1] != baseID {
-			return fmt.Errorf("multi-hop base asset market must involve the DEX base asset")
+			return newConfigError("multiHop.baseAssetMarket", "multi-hop base asset market must involve the DEX base asset")
 		}
 		if a.MultiHop.QuoteAssetMarket[0] != quoteID && a.MultiHop.QuoteAssetMarket[1] != quoteID {
-			return fmt.Errorf("multi-hop quote asset market must involve the DEX quote asset")
+			return newConfigError("multiHop.quoteAssetMarket", "multi-hop quote asset market must involve the DEX quote asset")
 		}
 		var baseIntermediateID, quoteIntermediateID uint32
 		if a.MultiHop.BaseAssetMarket[0] == baseID {
@@ -152,7 +159,7 @@ func (a *ArbMarketMakerConfig) validate(baseID, quoteID uint32) error {
 			quoteIntermediateID = a.MultiHop.QuoteAssetMarket[0]
 		}
 		if baseIntermediateID != quoteIntermediateID {
-			return fmt.Errorf("multi-hop markets do not share an intermediate asset")
+			return newConfigError("multiHop", "multi-hop markets do not share an intermediate asset")
 		}
 	}
 
@@ -160,8 +167,9 @@ func (a *ArbMarketMakerConfig) validate(baseID, quoteID uint32) error {
 }
 
 // updateLotSize modifies the number of lots in each placement in the event
-// of a lot size change. It will place as many lots as possible without
-// exceeding the total quantity placed using the original lot size.
+// of a lot size change, rounding as specified by the RoundingMode. By default,
+// it will place as many lots as possible without exceeding the total quantity
+// placed using the original lot size.
 //
 // This function is NOT thread safe.
 func (c *ArbMarketMakerConfig) updateLotSize(originalLotSize, newLotSize uint64) {
@@ -178,7 +186,7 @@ func (c *ArbMarketMakerConfig) updateLotSize(originalLotSize, newLotSize uint64)
 		}
 	}
 	update := func(placements []*ArbMarketMakingPlacement) []*ArbMarketMakingPlacement {
-		return utils.Map(updateLotSize(utils.Map(placements, a2b), originalLotSize, newLotSize), b2a)
+		return utils.Map(updateLotSize(utils.Map(placements, a2b), originalLotSize, newLotSize, c.RoundingMode), b2a)
 	}
 	c.SellPlacements = update(c.SellPlacements)
 	c.BuyPlacements = update(c.BuyPlacements)
@@ -775,6 +783,10 @@ func (a *arbMarketMaker) rebalance(epoch uint64, book *orderbook.OrderBook) {
 	}
 	a.currEpoch.Store(epoch)
 
+	if a.idleShutdown(epoch) {
+		return
+	}
+
 	if !a.checkBotHealth(epoch) {
 		a.tryCancelOrders(a.ctx, &epoch, false)
 		return
@@ -787,6 +799,13 @@ func (a *arbMarketMaker) rebalance(epoch uint64, book *orderbook.OrderBook) {
 		return
 	}
 
+	if a.epochThrottled(epoch) {
+		a.log.Tracef("rebalance: skipping order placement for throttled epoch %d", epoch)
+		a.cancelExpiredCEXTrades()
+		a.registerFeeGap()
+		return
+	}
+
 	var buysReport, sellsReport *OrderReport
 	buyOrders, sellOrders, determinePlacementsErr := a.ordersToPlace()
 	if determinePlacementsErr != nil {
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -39,6 +40,30 @@ const (
 	GapStrategyPercentPlus GapStrategy = "percent-plus"
 )
 
+// RoundingMode selects how the lots of placements are rounded when they are
+// rescaled for a change in the market's lot size.
+type RoundingMode string
+
+const (
+	// RoundingModeDown rounds rescaled lots down.
+	RoundingModeDown RoundingMode = "down"
+	// RoundingModeUp rounds rescaled lots up.
+	RoundingModeUp RoundingMode = "up"
+	// RoundingModeNearest rounds rescaled lots to the nearest lot, with
+	// halves rounded up.
+	RoundingModeNearest RoundingMode = "nearest"
+)
+
+// validate checks that the RoundingMode is known. The empty RoundingMode is
+// valid, and selects the default rescaling. See rescaledLots.
+func (m RoundingMode) validate() error {
+	switch m {
+	case "", RoundingModeDown, RoundingModeUp, RoundingModeNearest:
+		return nil
+	}
+	return newConfigError("roundingMode", "unknown rounding mode %q", m)
+}
+
 // OrderPlacement represents the distance from the mid-gap and the
 // amount of lots that should be placed at this distance.
 type OrderPlacement struct {
@@ -72,6 +97,48 @@ type BasicMarketMakingConfig struct {
 	// before they are replaced (units: ratio of price). Default: 0.1%.
 	// 0 <= x <= 0.01.
 	DriftTolerance float64 `json:"driftTolerance"`
+
+	// OracleConfig optionally restricts the oracle sources used for the
+	// basis price and sets a staleness tolerance. If nil, all available
+	// sources are used.
+	OracleConfig *OracleConfig `json:"oracleConfig,omitempty"`
+
+	// RoundingMode selects how placement lots are rounded when the market's
+	// lot size changes. See rescaledLots.
+	RoundingMode RoundingMode `json:"roundingMode,omitempty"`
+}
+
+// OracleConfig selects the price oracle sources for a basic market maker.
+type OracleConfig struct {
+	// Sources are the hosts of the exchanges whose markets are averaged for
+	// the oracle price, e.g. "binance.com". See OracleReport.Host.
+	Sources []string `json:"sources"`
+	// MaxStaleness is the maximum age of the oracle data. If the data is
+	// older, the bot will not place orders until it is refreshed. Zero
+	// means no limit beyond the oracle's own expiration.
+	MaxStaleness time.Duration `json:"maxStaleness,omitempty"`
+}
+
+func (c *OracleConfig) validate() error {
+	if len(c.Sources) == 0 {
+		return newConfigError("sources", "no oracle sources")
+	}
+	for i, src := range c.Sources {
+		if src == "" {
+			return newConfigError(fmt.Sprintf("sources[%d]", i), "empty oracle source")
+		}
+	}
+	if c.MaxStaleness < 0 {
+		return newConfigError("maxStaleness", "negative oracle max staleness %s", c.MaxStaleness)
+	}
+	return nil
+}
+
+func (c *OracleConfig) copy() *OracleConfig {
+	return &OracleConfig{
+		Sources:      slices.Clone(c.Sources),
+		MaxStaleness: c.MaxStaleness,
+	}
 }
 
 func needBreakEvenHalfSpread(strat GapStrategy) bool {
@@ -83,7 +150,7 @@ func (c *BasicMarketMakingConfig) validate() error {
 		c.DriftTolerance = 0.001
 	}
 	if c.DriftTolerance < 0 || c.DriftTolerance > 0.01 {
-		return fmt.Errorf("drift tolerance %f out of bounds", c.DriftTolerance)
+		return newConfigError("driftTolerance", "drift tolerance %f out of bounds", c.DriftTolerance)
 	}
 
 	if c.GapStrategy != GapStrategyMultiplier &&
@@ -91,7 +158,17 @@ func (c *BasicMarketMakingConfig) validate() error {
 		c.GapStrategy != GapStrategyPercentPlus &&
 		c.GapStrategy != GapStrategyAbsolute &&
 		c.GapStrategy != GapStrategyAbsolutePlus {
-		return fmt.Errorf("unknown gap strategy %q", c.GapStrategy)
+		return newConfigError("gapStrategy", "unknown gap strategy %q", c.GapStrategy)
+	}
+
+	if err := c.RoundingMode.validate(); err != nil {
+		return err
+	}
+
+	if c.OracleConfig != nil {
+		if err := c.OracleConfig.validate(); err != nil {
+			return configFieldError("oracleConfig", err)
+		}
 	}
 
 	validatePlacement := func(p *OrderPlacement) error {
@@ -114,29 +191,60 @@ func (c *BasicMarketMakingConfig) validate() error {
 		return nil
 	}
 
-	sellPlacements := make(map[float64]bool, len(c.SellPlacements))
-	for _, p := range c.SellPlacements {
-		if _, duplicate := sellPlacements[p.GapFactor]; duplicate {
-			return fmt.Errorf("duplicate sell placement %f", p.GapFactor)
-		}
-		sellPlacements[p.GapFactor] = true
-		if err := validatePlacement(p); err != nil {
-			return fmt.Errorf("invalid sell placement: %w", err)
+	validatePlacements := func(placements []*OrderPlacement, side string) error {
+		field := side + "Placements"
+		gapFactors := make(map[float64]bool, len(placements))
+		for i, p := range placements {
+			gapField := fmt.Sprintf("%s[%d].gapFactor", field, i)
+			if _, duplicate := gapFactors[p.GapFactor]; duplicate {
+				return newConfigError(gapField, "duplicate %s placement %f", side, p.GapFactor)
+			}
+			gapFactors[p.GapFactor] = true
+			if err := validatePlacement(p); err != nil {
+				return newConfigError(gapField, "invalid %s placement: %v", side, err)
+			}
 		}
+		return nil
 	}
 
-	buyPlacements := make(map[float64]bool, len(c.BuyPlacements))
-	for _, p := range c.BuyPlacements {
-		if _, duplicate := buyPlacements[p.GapFactor]; duplicate {
-			return fmt.Errorf("duplicate buy placement %f", p.GapFactor)
-		}
-		buyPlacements[p.GapFactor] = true
-		if err := validatePlacement(p); err != nil {
-			return fmt.Errorf("invalid buy placement: %w", err)
+	if err := validatePlacements(c.SellPlacements, "sell"); err != nil {
+		return err
+	}
+	return validatePlacements(c.BuyPlacements, "buy")
+}
+
+// SpreadPreview computes the tightest and widest spreads between the buy and
+// sell orders that the placements would produce around the midRate, a
+// message-rate encoded mid-gap rate. The tightest spread is between the
+// closest buy and sell placements, and the widest is between the farthest.
+// The break-even fee gap added by the "-plus" strategies is not included, so
+// only the percent strategies can be previewed. Zero spreads are returned for
+// the other strategies, which depend on fees or market units, or if either
+// side has no placements.
+func (c *BasicMarketMakingConfig) SpreadPreview(midRate uint64) (minSpread, maxSpread uint64) {
+	if c.GapStrategy != GapStrategyPercent && c.GapStrategy != GapStrategyPercentPlus {
+		return 0, 0
+	}
+	if len(c.BuyPlacements) == 0 || len(c.SellPlacements) == 0 {
+		return 0, 0
+	}
+
+	// adjRange is the range of distances from the midRate for the placements.
+	adjRange := func(placements []*OrderPlacement, sell bool) (lo, hi uint64) {
+		lo = math.MaxUint64
+		for _, p := range placements {
+			adj := uint64(math.Round(p.GapFactor * float64(midRate)))
+			if !sell && adj > midRate {
+				adj = midRate // buy rate can't go below zero
+			}
+			lo, hi = min(lo, adj), max(hi, adj)
 		}
+		return lo, hi
 	}
 
-	return nil
+	buyLo, buyHi := adjRange(c.BuyPlacements, false)
+	sellLo, sellHi := adjRange(c.SellPlacements, true)
+	return buyLo + sellLo, buyHi + sellHi
 }
 
 func (c *BasicMarketMakingConfig) copy() *BasicMarketMakingConfig {
@@ -151,27 +259,70 @@ func (c *BasicMarketMakingConfig) copy() *BasicMarketMakingConfig {
 
 	cfg.SellPlacements = utils.Map(c.SellPlacements, copyOrderPlacement)
 	cfg.BuyPlacements = utils.Map(c.BuyPlacements, copyOrderPlacement)
+	if c.OracleConfig != nil {
+		cfg.OracleConfig = c.OracleConfig.copy()
+	}
 
 	return &cfg
 }
 
-func updateLotSize(placements []*OrderPlacement, originalLotSize, newLotSize uint64) (updatedPlacements []*OrderPlacement) {
+// rescaledLots returns the number of lots for each placement after a lot size
+// change.
+//
+// With the empty RoundingMode, lots are rounded to the nearest lot, but the
+// total quantity of the placements is not allowed to exceed the original total
+// quantity, so a placement that can no longer be filled has zero lots.
+//
+// With any other RoundingMode, each placement is rounded independently as
+// specified, and a placement with lots never rounds to zero lots, so no
+// placements are dropped. The total quantity may exceed the original.
+func rescaledLots(placements []*OrderPlacement, originalLotSize, newLotSize uint64, mode RoundingMode) []uint64 {
+	if mode != "" {
+		newLots := make([]uint64, len(placements))
+		for i, p := range placements {
+			if p.Lots == 0 {
+				continue
+			}
+			qty := p.Lots * originalLotSize
+			var lots uint64
+			switch mode {
+			case RoundingModeDown:
+				lots = qty / newLotSize
+			case RoundingModeUp:
+				lots = (qty + newLotSize - 1) / newLotSize
+			default: // RoundingModeNearest
+				lots = (qty + newLotSize/2) / newLotSize
+			}
+			newLots[i] = max(lots, 1)
+		}
+		return newLots
+	}
+
 	var qtyCounter uint64
 	for _, p := range placements {
 		qtyCounter += p.Lots * originalLotSize
 	}
-	newPlacements := make([]*OrderPlacement, 0, len(placements))
-	for _, p := range placements {
+	newLots := make([]uint64, len(placements))
+	for i, p := range placements {
 		lots := uint64(math.Round((float64(p.Lots) * float64(originalLotSize)) / float64(newLotSize)))
 		lots = max(lots, 1)
 		maxLots := qtyCounter / newLotSize
 		lots = min(lots, maxLots)
-		if lots == 0 {
+		qtyCounter -= lots * newLotSize
+		newLots[i] = lots
+	}
+	return newLots
+}
+
+func updateLotSize(placements []*OrderPlacement, originalLotSize, newLotSize uint64, mode RoundingMode) (updatedPlacements []*OrderPlacement) {
+	newLots := rescaledLots(placements, originalLotSize, newLotSize, mode)
+	newPlacements := make([]*OrderPlacement, 0, len(placements))
+	for i, p := range placements {
+		if newLots[i] == 0 {
 			continue
 		}
-		qtyCounter -= lots * newLotSize
 		newPlacements = append(newPlacements, &OrderPlacement{
-			Lots:      lots,
+			Lots:      newLots[i],
 			GapFactor: p.GapFactor,
 		})
 	}
@@ -180,13 +331,14 @@ func updateLotSize(placements []*OrderPlacement, originalLotSize, newLotSize uin
 }
 
 // updateLotSize modifies the number of lots in each placement in the event
-// of a lot size change. It will place as many lots as possible without
-// exceeding the total quantity placed using the original lot size.
+// of a lot size change, rounding as specified by the RoundingMode. By default,
+// it will place as many lots as possible without exceeding the total quantity
+// placed using the original lot size.
 //
 // This function is NOT thread safe.
 func (c *BasicMarketMakingConfig) updateLotSize(originalLotSize, newLotSize uint64) {
-	c.SellPlacements = updateLotSize(c.SellPlacements, originalLotSize, newLotSize)
-	c.BuyPlacements = updateLotSize(c.BuyPlacements, originalLotSize, newLotSize)
+	c.SellPlacements = updateLotSize(c.SellPlacements, originalLotSize, newLotSize, c.RoundingMode)
+	c.BuyPlacements = updateLotSize(c.BuyPlacements, originalLotSize, newLotSize, c.RoundingMode)
 }
 
 type basicMMCalculator interface {
@@ -216,8 +368,23 @@ var errOracleFiatMismatch = errors.New("oracle rate and fiat rate mismatch")
 // or oracle weighting is 0, the fiat rate is used.
 // If there is no fiat rate available, the empty market rate in the
 // configuration is used.
+// If an OracleConfig is set, only its sources are used for the oracle price,
+// and no basis price is returned if the oracle data is stale.
 func (b *basicMMCalculatorImpl) basisPrice() (uint64, error) {
-	oracleRate := b.msgRate(b.oracle.getMarketPrice(b.baseID, b.quoteID))
+	var oraclePrice float64
+	if oc := b.cfg.OracleConfig; oc != nil {
+		var err error
+		oraclePrice, err = b.oracle.getSourcedMarketPrice(b.baseID, b.quoteID, oc)
+		if err != nil {
+			b.log.Meter("basisPrice_oracle_"+b.market.name, time.Minute*20).Warnf(
+				"Not quoting %s without a usable oracle price: %v", b.market.name, err,
+			)
+			return 0, err
+		}
+	} else {
+		oraclePrice = b.oracle.getMarketPrice(b.baseID, b.quoteID)
+	}
+	oracleRate := b.msgRate(oraclePrice)
 	b.log.Tracef("oracle rate = %s", b.fmtRate(oracleRate))
 
 	rateFromFiat := b.core.ExchangeRateFromFiatSources()
@@ -429,11 +596,20 @@ func (m *basicMarketMaker) rebalance(newEpoch uint64) {
 
 	m.log.Tracef("rebalance: epoch %d", newEpoch)
 
+	if m.idleShutdown(newEpoch) {
+		return
+	}
+
 	if !m.checkBotHealth(newEpoch) {
 		m.tryCancelOrders(m.ctx, &newEpoch, false)
 		return
 	}
 
+	if m.epochThrottled(newEpoch) {
+		m.log.Tracef("rebalance: skipping order placement for throttled epoch %d", newEpoch)
+		return
+	}
+
 	var buysReport, sellsReport *OrderReport
 	buyOrders, sellOrders, determinePlacementsErr := m.ordersToPlace()
 	if determinePlacementsErr != nil {
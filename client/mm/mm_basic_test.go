@@ -3,8 +3,10 @@
 package mm
 
 import (
+	"errors"
 	"math"
 	"testing"
+	"time"
 
 	"decred.org/dcrdex/client/core"
 	"decred.org/dcrdex/dex/calc"
@@ -93,6 +95,128 @@ func TestBasisPrice(t *testing.T) {
 	}
 }
 
+func TestOracleConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		cfg     *OracleConfig
+		wantErr bool
+	}{
+		{"ok", &OracleConfig{Sources: []string{"binance.com"}, MaxStaleness: time.Minute}, false},
+		{"no staleness limit", &OracleConfig{Sources: []string{"binance.com"}}, false},
+		{"no sources", &OracleConfig{MaxStaleness: time.Minute}, true},
+		{"empty source", &OracleConfig{Sources: []string{""}}, true},
+		{"negative staleness", &OracleConfig{Sources: []string{"binance.com"}, MaxStaleness: -time.Second}, true},
+	} {
+		if err := tt.cfg.validate(); (err != nil) != tt.wantErr {
+			t.Fatalf("%s: wanted error = %t, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	// Only the configured sources are averaged.
+	now := time.Now()
+	cached := &cachedPrice{
+		stamp: now.Add(-time.Minute),
+		oracles: []*OracleReport{
+			{Host: "binance.com", USDVol: 1, BestBuy: 99, BestSell: 101},
+			{Host: "coinex.com", USDVol: 1, BestBuy: 199, BestSell: 201},
+		},
+	}
+	cfg := &OracleConfig{Sources: []string{"binance.com"}, MaxStaleness: time.Minute * 2}
+	price, err := sourcedPrice(cached, cfg, now, tLogger)
+	if err != nil {
+		t.Fatalf("sourcedPrice error: %v", err)
+	}
+	if price != 100 {
+		t.Fatalf("wrong sourced price %f", price)
+	}
+
+	// Stale data is rejected.
+	cfg.MaxStaleness = time.Second * 30
+	if _, err = sourcedPrice(cached, cfg, now, tLogger); !errors.Is(err, errStaleOracle) {
+		t.Fatalf("expected stale oracle error, got %v", err)
+	}
+
+	// The calculator won't return a basis price from a stale oracle, even
+	// if a fiat rate is available.
+	mkt := &core.Market{RateStep: 1, BaseID: 42, QuoteID: 0, AtomToConv: 1}
+	adaptor := newTBotCoreAdaptor(newTCore())
+	adaptor.fiatExchangeRate = 2000
+	calculator := &basicMMCalculatorImpl{
+		market: mustParseMarket(mkt),
+		oracle: &tOracle{marketPrice: 2000, sourcedErr: errStaleOracle},
+		cfg:    &BasicMarketMakingConfig{OracleConfig: cfg},
+		log:    tLogger,
+		core:   adaptor,
+	}
+	if _, err := calculator.basisPrice(); !errors.Is(err, errStaleOracle) {
+		t.Fatalf("expected stale oracle error from basisPrice, got %v", err)
+	}
+}
+
+func TestSpreadPreview(t *testing.T) {
+	placements := func(gapFactors ...float64) []*OrderPlacement {
+		ps := make([]*OrderPlacement, len(gapFactors))
+		for i, gf := range gapFactors {
+			ps[i] = &OrderPlacement{Lots: 1, GapFactor: gf}
+		}
+		return ps
+	}
+
+	tests := []struct {
+		name           string
+		cfg            *BasicMarketMakingConfig
+		midRate        uint64
+		expMin, expMax uint64
+	}{
+		{
+			name: "symmetric",
+			cfg: &BasicMarketMakingConfig{
+				GapStrategy:    GapStrategyPercent,
+				BuyPlacements:  placements(0.01, 0.02, 0.03),
+				SellPlacements: placements(0.03, 0.01, 0.02),
+			},
+			midRate: 1e8,
+			expMin:  2e6,
+			expMax:  6e6,
+		},
+		{
+			name: "asymmetric",
+			cfg: &BasicMarketMakingConfig{
+				GapStrategy:    GapStrategyPercentPlus,
+				BuyPlacements:  placements(0.005),
+				SellPlacements: placements(0.02, 0.05),
+			},
+			midRate: 1e8,
+			expMin:  2.5e6,
+			expMax:  5.5e6,
+		},
+		{
+			name: "one side",
+			cfg: &BasicMarketMakingConfig{
+				GapStrategy:    GapStrategyPercent,
+				SellPlacements: placements(0.02),
+			},
+			midRate: 1e8,
+		},
+		{
+			name: "multiplier",
+			cfg: &BasicMarketMakingConfig{
+				GapStrategy:    GapStrategyMultiplier,
+				BuyPlacements:  placements(2),
+				SellPlacements: placements(2),
+			},
+			midRate: 1e8,
+		},
+	}
+
+	for _, tt := range tests {
+		minSpread, maxSpread := tt.cfg.SpreadPreview(tt.midRate)
+		if minSpread != tt.expMin || maxSpread != tt.expMax {
+			t.Fatalf("%s: expected spreads %d-%d, got %d-%d", tt.name, tt.expMin, tt.expMax, minSpread, maxSpread)
+		}
+	}
+}
+
 func TestBreakEvenHalfSpread(t *testing.T) {
 	tests := []*struct {
 		name                 string
@@ -226,7 +350,7 @@ func TestUpdateLotSize(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := updateLotSize(tt.placements, tt.originalSize, tt.newSize)
+			got := updateLotSize(tt.placements, tt.originalSize, tt.newSize, "")
 			if len(got) != len(tt.wantPlacements) {
 				t.Fatalf("got %d placements, want %d", len(got), len(tt.wantPlacements))
 			}
@@ -242,6 +366,135 @@ func TestUpdateLotSize(t *testing.T) {
 	}
 }
 
+func TestRoundingModes(t *testing.T) {
+	placements := func(lots ...uint64) []*OrderPlacement {
+		ps := make([]*OrderPlacement, 0, len(lots))
+		for i, l := range lots {
+			ps = append(ps, &OrderPlacement{Lots: l, GapFactor: float64(i + 1)})
+		}
+		return ps
+	}
+
+	tests := []struct {
+		name         string
+		lots         []uint64
+		originalSize uint64
+		newSize      uint64
+		wantLots     map[RoundingMode][]uint64
+	}{
+		{
+			name:         "exact ratio",
+			lots:         []uint64{2, 4},
+			originalSize: 100,
+			newSize:      200,
+			wantLots: map[RoundingMode][]uint64{
+				RoundingModeDown:    {1, 2},
+				RoundingModeUp:      {1, 2},
+				RoundingModeNearest: {1, 2},
+			},
+		},
+		{
+			name:         "exactly half",
+			lots:         []uint64{1, 3},
+			originalSize: 100,
+			newSize:      200,
+			wantLots: map[RoundingMode][]uint64{
+				RoundingModeDown:    {1, 1}, // 0.5 -> 0 -> minimum 1
+				RoundingModeUp:      {1, 2},
+				RoundingModeNearest: {1, 2},
+			},
+		},
+		{
+			name:         "just below a lot",
+			lots:         []uint64{3, 1},
+			originalSize: 99,
+			newSize:      100,
+			wantLots: map[RoundingMode][]uint64{
+				RoundingModeDown:    {2, 1}, // 0.99 -> 0 -> minimum 1
+				RoundingModeUp:      {3, 1},
+				RoundingModeNearest: {3, 1},
+			},
+		},
+		{
+			name:         "lot size much larger",
+			lots:         []uint64{1, 1, 1},
+			originalSize: 100,
+			newSize:      250,
+			wantLots: map[RoundingMode][]uint64{
+				RoundingModeDown:    {1, 1, 1},
+				RoundingModeUp:      {1, 1, 1},
+				RoundingModeNearest: {1, 1, 1},
+			},
+		},
+		{
+			name:         "lot size smaller",
+			lots:         []uint64{3, 5},
+			originalSize: 100,
+			newSize:      40,
+			wantLots: map[RoundingMode][]uint64{
+				RoundingModeDown:    {7, 12}, // 7.5, 12.5
+				RoundingModeUp:      {8, 13},
+				RoundingModeNearest: {8, 13},
+			},
+		},
+		{
+			name:         "odd new lot size",
+			lots:         []uint64{1},
+			originalSize: 100,
+			newSize:      301,
+			wantLots: map[RoundingMode][]uint64{
+				RoundingModeDown:    {1},
+				RoundingModeUp:      {1},
+				RoundingModeNearest: {1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for mode, wantLots := range tt.wantLots {
+			got := updateLotSize(placements(tt.lots...), tt.originalSize, tt.newSize, mode)
+			if len(got) != len(wantLots) {
+				t.Fatalf("%s, %s: got %d placements, want %d", tt.name, mode, len(got), len(wantLots))
+			}
+			for i, p := range got {
+				if p.Lots != wantLots[i] {
+					t.Fatalf("%s, %s: placement %d: got %d lots, want %d", tt.name, mode, i, p.Lots, wantLots[i])
+				}
+				if p.GapFactor != float64(i+1) {
+					t.Fatalf("%s, %s: placement %d: wrong gap factor %f", tt.name, mode, i, p.GapFactor)
+				}
+			}
+		}
+	}
+
+	// The mode is validated and used by the strategy configs.
+	cfg := &BasicMarketMakingConfig{
+		GapStrategy:    GapStrategyMultiplier,
+		SellPlacements: placements(1, 1),
+		RoundingMode:   RoundingModeUp,
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	cfg.updateLotSize(100, 150)
+	if len(cfg.SellPlacements) != 2 || cfg.SellPlacements[1].Lots != 1 {
+		t.Fatalf("placement dropped with rounding mode: %+v", cfg.SellPlacements)
+	}
+	cfg.RoundingMode = "sideways"
+	var cfgErr *ConfigError
+	if err := cfg.validate(); !errors.As(err, &cfgErr) || cfgErr.Field != "roundingMode" {
+		t.Fatalf("expected roundingMode config error, got %v", err)
+	}
+	arbCfg := &ArbMarketMakerConfig{
+		SellPlacements: []*ArbMarketMakingPlacement{{Lots: 1, Multiplier: 1.5}, {Lots: 1, Multiplier: 2}},
+		RoundingMode:   RoundingModeDown,
+	}
+	arbCfg.updateLotSize(100, 150)
+	if len(arbCfg.SellPlacements) != 2 || arbCfg.SellPlacements[0].Lots != 1 || arbCfg.SellPlacements[1].Lots != 1 {
+		t.Fatalf("arb placements not rescaled with rounding mode: %+v", arbCfg.SellPlacements)
+	}
+}
+
 func TestBasicMMRebalance(t *testing.T) {
 	const basisPrice uint64 = 5e6
 	const halfSpread uint64 = 2e5
@@ -33,6 +33,14 @@ type SimpleArbConfig struct {
 	// NumEpochsLeaveOpen is the number of epochs an arbitrage sequence will
 	// stay open if one or both of the orders were not filled.
 	NumEpochsLeaveOpen uint32 `json:"numEpochsLeaveOpen"`
+	// MinProfitBps is the minimum profit, after fees, in basis points of the
+	// traded quantity that an arbitrage sequence must produce. This is
+	// checked in addition to ProfitTrigger. Zero means no additional
+	// minimum.
+	MinProfitBps uint64 `json:"minProfitBps,omitempty"`
+	// MaxTradeLots is the maximum number of lots that will be traded in a
+	// single arbitrage sequence. Zero means no limit.
+	MaxTradeLots uint64 `json:"maxTradeLots,omitempty"`
 }
 
 func (c *SimpleArbConfig) copy() *SimpleArbConfig {
@@ -40,20 +48,26 @@ func (c *SimpleArbConfig) copy() *SimpleArbConfig {
 		ProfitTrigger:      c.ProfitTrigger,
 		MaxActiveArbs:      c.MaxActiveArbs,
 		NumEpochsLeaveOpen: c.NumEpochsLeaveOpen,
+		MinProfitBps:       c.MinProfitBps,
+		MaxTradeLots:       c.MaxTradeLots,
 	}
 }
 
 func (c *SimpleArbConfig) validate() error {
 	if c.ProfitTrigger <= 0 || c.ProfitTrigger > 1 {
-		return fmt.Errorf("profit trigger must be 0 < t <= 1, but got %v", c.ProfitTrigger)
+		return newConfigError("profitTrigger", "profit trigger must be 0 < t <= 1, but got %v", c.ProfitTrigger)
 	}
 
 	if c.MaxActiveArbs == 0 {
-		return fmt.Errorf("must allow at least 1 active arb")
+		return newConfigError("maxActiveArbs", "must allow at least 1 active arb")
 	}
 
 	if c.NumEpochsLeaveOpen < 2 {
-		return fmt.Errorf("arbs must be left open for at least 2 epochs")
+		return newConfigError("numEpochsLeaveOpen", "arbs must be left open for at least 2 epochs")
+	}
+
+	if c.MinProfitBps >= 10000 {
+		return newConfigError("minProfitBps", "min profit must be < 10000 bps, but got %d", c.MinProfitBps)
 	}
 
 	return nil
@@ -108,10 +122,15 @@ func (a *simpleArbMarketMaker) arbExists() (exists, sellOnDex bool, lotsToArb, d
 // arbExistsOnSide checks if an arbitrage opportunity exists either when
 // buying or selling on the dex.
 func (a *simpleArbMarketMaker) arbExistsOnSide(sellOnDEX bool) (exists bool, lotsToArb, dexRate, cexRate uint64, err error) {
+	if a.botCfg().sideDisabled(sellOnDEX) {
+		return false, 0, 0, 0, nil
+	}
+
 	lotSize := a.lotSize.Load()
+	cfg := a.cfg()
 	var prevProfit uint64
 
-	for numLots := uint64(1); ; numLots++ {
+	for numLots := uint64(1); cfg.MaxTradeLots == 0 || numLots <= cfg.MaxTradeLots; numLots++ {
 		dexAvg, dexExtrema, dexFilled, err := a.book.VWAP(numLots, lotSize, !sellOnDEX)
 		if err != nil {
 			return false, 0, 0, 0, fmt.Errorf("error calculating dex VWAP: %w", err)
@@ -174,7 +193,10 @@ func (a *simpleArbMarketMaker) arbExistsOnSide(sellOnDEX bool) (exists bool, lot
 		}
 		profitInQuote := quoteFromSell - quoteForBuy - feesInQuoteUnits
 		profitInBase := calc.QuoteToBase((buyRate+sellRate)/2, profitInQuote)
-		if profitInBase < prevProfit || float64(profitInBase)/float64(qty) < a.cfg().ProfitTrigger {
+		if profitInBase < prevProfit || float64(profitInBase)/float64(qty) < cfg.ProfitTrigger {
+			break
+		}
+		if profitInBase*10000 < cfg.MinProfitBps*qty {
 			break
 		}
 
@@ -389,6 +411,10 @@ func (a *simpleArbMarketMaker) rebalance(newEpoch uint64) {
 	defer a.rebalanceRunning.Store(false)
 	a.log.Tracef("rebalance: epoch %d", newEpoch)
 
+	if a.idleShutdown(newEpoch) {
+		return
+	}
+
 	actionTaken, err := a.tryTransfers(newEpoch, a.distribution)
 	if err != nil {
 		a.log.Errorf("Error performing transfers: %v", err)
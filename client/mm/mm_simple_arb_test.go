@@ -1012,3 +1012,57 @@ func TestCexTradeUpdates(t *testing.T) {
 	}
 }
 */
+
+func TestSimpleArbConfigValidate(t *testing.T) {
+	newCfg := func() *SimpleArbConfig {
+		return &SimpleArbConfig{
+			ProfitTrigger:      0.01,
+			MaxActiveArbs:      5,
+			NumEpochsLeaveOpen: 10,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mod     func(*SimpleArbConfig)
+		wantErr bool
+	}{
+		{
+			name: "zero min profit and max lots",
+			mod:  func(*SimpleArbConfig) {},
+		},
+		{
+			name: "valid min profit and max lots",
+			mod: func(c *SimpleArbConfig) {
+				c.MinProfitBps = 50
+				c.MaxTradeLots = 10
+			},
+		},
+		{
+			name: "min profit too high",
+			mod: func(c *SimpleArbConfig) {
+				c.MinProfitBps = 10000
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero profit trigger",
+			mod: func(c *SimpleArbConfig) {
+				c.ProfitTrigger = 0
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		cfg := newCfg()
+		test.mod(cfg)
+		err := cfg.validate()
+		if test.wantErr != (err != nil) {
+			t.Fatalf("%s: wantErr = %t, got err = %v", test.name, test.wantErr, err)
+		}
+		if cp := cfg.copy(); *cp != *cfg {
+			t.Fatalf("%s: copy mismatch: %+v != %+v", test.name, cp, cfg)
+		}
+	}
+}
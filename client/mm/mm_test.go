@@ -3,7 +3,10 @@ package mm
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
@@ -388,12 +391,17 @@ func (o *tOrderBook) MidGap() (uint64, error) {
 
 type tOracle struct {
 	marketPrice float64
+	sourcedErr  error
 }
 
 func (o *tOracle) getMarketPrice(base, quote uint32) float64 {
 	return o.marketPrice
 }
 
+func (o *tOracle) getSourcedMarketPrice(base, quote uint32, cfg *OracleConfig) (float64, error) {
+	return o.marketPrice, o.sourcedErr
+}
+
 type vwapResult struct {
 	avg     uint64
 	extrema uint64
@@ -844,3 +852,97 @@ func TestAvailableBalances(t *testing.T) {
 	checkAvailableBalances(btcUsdc, &binanceName, map[uint32]uint64{0: 3e5, 60: 7e5, 60001: 4e5}, map[uint32]uint64{0: 5e5, 60001: 4e5})
 	checkAvailableBalances(dcrUsdc, &binanceUSName, map[uint32]uint64{42: 9e5, 60: 7e5, 60001: 4e5}, map[uint32]uint64{42: 7e5, 60001: 6e5})
 }
+
+func TestSaveBotLotSize(t *testing.T) {
+	savedCfg := &BotConfig{
+		Host:              "dex.com",
+		BaseID:            42,
+		QuoteID:           0,
+		LotSize:           1e8,
+		BaseWalletProfile: "split",
+	}
+	m := &MarketMaker{
+		log:            tLogger,
+		defaultCfgPath: filepath.Join(t.TempDir(), "mm.json"),
+		defaultCfg: &MarketMakingConfig{
+			BotConfigs:     []*BotConfig{savedCfg},
+			WalletProfiles: map[string]map[string]string{"split": {"multisplitbuffer": "5"}},
+		},
+	}
+
+	m.saveBotLotSize(&MarketWithHost{Host: "dex.com", BaseID: 42, QuoteID: 0}, 2e8)
+
+	botCfg := m.defaultConfig().BotConfigs[0]
+	if botCfg.LotSize != 2e8 {
+		t.Fatalf("lot size not updated. wanted %d, got %d", uint64(2e8), botCfg.LotSize)
+	}
+	// The profile reference is kept, and the profile's options are not saved
+	// inline.
+	if botCfg.BaseWalletProfile != "split" || len(botCfg.BaseWalletOptions) != 0 {
+		t.Fatalf("profile reference not preserved. profile = %q, options = %v",
+			botCfg.BaseWalletProfile, botCfg.BaseWalletOptions)
+	}
+}
+
+func TestSaveBotAllocation(t *testing.T) {
+	mwh := &MarketWithHost{Host: "dex.com", BaseID: 42, QuoteID: 0}
+	rpcCfg := &rpcConfig{Alloc: &BotBalanceAllocation{
+		DEX: map[uint32]uint64{42: 5e8},
+		CEX: map[uint32]uint64{0: 1e8},
+	}}
+	newCfg := func() *MarketMakingConfig {
+		return &MarketMakingConfig{
+			BotConfigs: []*BotConfig{{
+				Host:      mwh.Host,
+				BaseID:    mwh.BaseID,
+				QuoteID:   mwh.QuoteID,
+				RPCConfig: rpcCfg.copy(),
+			}},
+		}
+	}
+	alloc := &BotBalanceAllocation{DEX: map[uint32]uint64{42: 1e8, 0: 2e8}}
+
+	checkSaved := func(cfg *MarketMakingConfig, exp *BotBalanceAllocation) {
+		t.Helper()
+		botCfg := cfg.BotConfigs[0]
+		if !reflect.DeepEqual(botCfg.RPCConfig, rpcCfg) {
+			t.Fatalf("RPCConfig modified: %+v", botCfg.RPCConfig)
+		}
+		var saved *BotBalanceAllocation
+		if botCfg.IdleShutdownAlloc != nil {
+			saved = botCfg.IdleShutdownAlloc.Alloc
+		}
+		if !reflect.DeepEqual(saved, exp) {
+			t.Fatalf("wrong idle shutdown allocation. wanted %+v, got %+v", exp, saved)
+		}
+	}
+
+	tmpDir := t.TempDir()
+	m := &MarketMaker{
+		log:            tLogger,
+		defaultCfgPath: filepath.Join(tmpDir, "mm.json"),
+		defaultCfg:     newCfg(),
+	}
+
+	// Saved to, and cleared from, the default config.
+	m.saveBotAllocation(nil, mwh, alloc, nil)
+	checkSaved(m.defaultConfig(), alloc)
+	if err := m.updateBotConfigFile(nil, mwh, func(c *BotConfig) { c.IdleShutdownAlloc = nil }); err != nil {
+		t.Fatalf("error clearing allocation: %v", err)
+	}
+	checkSaved(m.defaultConfig(), nil)
+
+	// A bot started from an alternate config saves to that file.
+	altPath := filepath.Join(tmpDir, "alt.json")
+	b, _ := json.Marshal(newCfg())
+	if err := os.WriteFile(altPath, b, 0644); err != nil {
+		t.Fatalf("error writing alternate config: %v", err)
+	}
+	m.saveBotAllocation(&altPath, mwh, alloc, nil)
+	altCfg, err := getMarketMakingConfig(altPath)
+	if err != nil {
+		t.Fatalf("error loading alternate config: %v", err)
+	}
+	checkSaved(altCfg, alloc)
+	checkSaved(m.defaultConfig(), nil)
+}
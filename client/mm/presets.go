@@ -0,0 +1,103 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Preset names. See PresetConfigs.
+const (
+	PresetConservativeBasicMM = "conservative-basic-mm"
+	PresetAggressiveBasicMM   = "aggressive-basic-mm"
+	PresetConservativeArbMM   = "conservative-arb-mm"
+	PresetAggressiveArb       = "aggressive-arb"
+)
+
+// PresetConfigs returns named starting templates for bot configurations. The
+// presets only set the strategy configuration and related settings. The
+// market, CEX, and wallet settings are left unset, so a preset should be
+// applied to a bot with BotConfig.FromPreset. A new map is returned with each
+// call, so the presets may be modified by the caller.
+func PresetConfigs() map[string]*BotConfig {
+	return map[string]*BotConfig{
+		// Few, wide orders that are only re-placed every other epoch.
+		PresetConservativeBasicMM: {
+			EpochThrottle: 2,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy:    GapStrategyPercentPlus,
+				SellPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.02}, {Lots: 1, GapFactor: 0.04}},
+				BuyPlacements:  []*OrderPlacement{{Lots: 1, GapFactor: 0.02}, {Lots: 1, GapFactor: 0.04}},
+				DriftTolerance: 0.005,
+			},
+		},
+		// More, tighter orders that are re-placed every epoch.
+		PresetAggressiveBasicMM: {
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy: GapStrategyPercentPlus,
+				SellPlacements: []*OrderPlacement{{Lots: 2, GapFactor: 0.005}, {Lots: 2, GapFactor: 0.01},
+					{Lots: 3, GapFactor: 0.02}},
+				BuyPlacements: []*OrderPlacement{{Lots: 2, GapFactor: 0.005}, {Lots: 2, GapFactor: 0.01},
+					{Lots: 3, GapFactor: 0.02}},
+				DriftTolerance: 0.001,
+			},
+		},
+		// Orders with a high profit margin over the CEX order book.
+		PresetConservativeArbMM: {
+			EpochThrottle: 2,
+			ArbMarketMakerConfig: &ArbMarketMakerConfig{
+				SellPlacements:     []*ArbMarketMakingPlacement{{Lots: 1, Multiplier: 1.5}, {Lots: 1, Multiplier: 2}},
+				BuyPlacements:      []*ArbMarketMakingPlacement{{Lots: 1, Multiplier: 1.5}, {Lots: 1, Multiplier: 2}},
+				Profit:             0.02,
+				DriftTolerance:     0.005,
+				NumEpochsLeaveOpen: 5,
+			},
+		},
+		// Frequent arbitrage with a low profit trigger.
+		PresetAggressiveArb: {
+			SimpleArbConfig: &SimpleArbConfig{
+				ProfitTrigger:      0.005,
+				MaxActiveArbs:      5,
+				NumEpochsLeaveOpen: 2,
+			},
+		},
+	}
+}
+
+// PresetNames returns the sorted names of the PresetConfigs.
+func PresetNames() []string {
+	presets := PresetConfigs()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FromPreset returns a copy of the BotConfig with the strategy configuration
+// of the named preset. The market, CEX, wallet, and allocation settings of the
+// BotConfig are retained, and any existing strategy configuration is
+// replaced. The new config is validated.
+func (c *BotConfig) FromPreset(name string) (*BotConfig, error) {
+	preset, found := PresetConfigs()[name]
+	if !found {
+		return nil, fmt.Errorf("unknown preset %q, available presets: %s", name, strings.Join(PresetNames(), ", "))
+	}
+	if (preset.SimpleArbConfig != nil || preset.ArbMarketMakerConfig != nil) && c.CEXName == "" {
+		return nil, fmt.Errorf("preset %q requires a CEX", name)
+	}
+
+	cfg := c.copy()
+	cfg.EpochThrottle = preset.EpochThrottle
+	cfg.BasicMMConfig = preset.BasicMMConfig
+	cfg.SimpleArbConfig = preset.SimpleArbConfig
+	cfg.ArbMarketMakerConfig = preset.ArbMarketMakerConfig
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("preset %q is not valid for the %d-%d market: %w", name, c.BaseID, c.QuoteID, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,75 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"testing"
+)
+
+func TestPresetConfigs(t *testing.T) {
+	bot := &BotConfig{
+		Host:              "dex.example.com:7232",
+		BaseID:            42,
+		QuoteID:           0,
+		CEXName:           "Binance",
+		BaseWalletOptions: map[string]string{"multisplit": "true"},
+		LotSize:           1e8,
+	}
+
+	presets := PresetConfigs()
+	if len(presets) == 0 {
+		t.Fatalf("no presets")
+	}
+	if names := PresetNames(); len(names) != len(presets) {
+		t.Fatalf("expected %d preset names, got %d", len(presets), len(names))
+	}
+	for name := range presets {
+		cfg, err := bot.FromPreset(name)
+		if err != nil {
+			t.Fatalf("%s: FromPreset error: %v", name, err)
+		}
+		if cfg.Host != bot.Host || cfg.BaseID != bot.BaseID || cfg.QuoteID != bot.QuoteID ||
+			cfg.CEXName != bot.CEXName || cfg.LotSize != bot.LotSize ||
+			cfg.BaseWalletOptions["multisplit"] != "true" {
+			t.Fatalf("%s: market settings not retained: %+v", name, cfg)
+		}
+		if err := cfg.validate(); err != nil {
+			t.Fatalf("%s: preset does not validate: %v", name, err)
+		}
+	}
+
+	// Presets are not shared between calls.
+	presets[PresetConservativeBasicMM].BasicMMConfig.BuyPlacements[0].Lots = 100
+	if PresetConfigs()[PresetConservativeBasicMM].BasicMMConfig.BuyPlacements[0].Lots == 100 {
+		t.Fatalf("preset modified by caller")
+	}
+
+	// Existing strategy configs are replaced.
+	arbBot := bot.copy()
+	arbBot.SimpleArbConfig = &SimpleArbConfig{ProfitTrigger: 0.01}
+	cfg, err := arbBot.FromPreset(PresetAggressiveBasicMM)
+	if err != nil {
+		t.Fatalf("FromPreset error: %v", err)
+	}
+	if cfg.SimpleArbConfig != nil || cfg.BasicMMConfig == nil {
+		t.Fatalf("strategy config not replaced")
+	}
+	if arbBot.SimpleArbConfig == nil {
+		t.Fatalf("original config modified")
+	}
+
+	if _, err := bot.FromPreset("moon"); err == nil {
+		t.Fatalf("no error for unknown preset")
+	}
+
+	// Arb presets require a CEX.
+	noCEX := bot.copy()
+	noCEX.CEXName = ""
+	if _, err := noCEX.FromPreset(PresetAggressiveArb); err == nil {
+		t.Fatalf("no error for arb preset without a CEX")
+	}
+	if _, err := noCEX.FromPreset(PresetConservativeBasicMM); err != nil {
+		t.Fatalf("error for basic preset without a CEX: %v", err)
+	}
+}
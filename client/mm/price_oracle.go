@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -104,6 +105,7 @@ func newPriceOracle(ctx context.Context, log dex.Logger) *priceOracle {
 
 type oracle interface {
 	getMarketPrice(baseID, quoteID uint32) float64
+	getSourcedMarketPrice(baseID, quoteID uint32, cfg *OracleConfig) (float64, error)
 }
 
 var _ oracle = (*priceOracle)(nil)
@@ -119,6 +121,41 @@ func (o *priceOracle) getMarketPrice(baseID, quoteID uint32) float64 {
 	return price
 }
 
+// errStaleOracle is returned when the oracle data is older than an
+// OracleConfig's MaxStaleness.
+var errStaleOracle = errors.New("oracle data is stale")
+
+// getSourcedMarketPrice is like getMarketPrice, but the price is averaged from
+// the configured sources only. An error is returned if the oracle data can't
+// be fetched or is older than the configured MaxStaleness. Zero is returned if
+// none of the sources have data for the market.
+func (o *priceOracle) getSourcedMarketPrice(baseID, quoteID uint32, cfg *OracleConfig) (float64, error) {
+	if _, _, err := o.getOracleInfo(baseID, quoteID); err != nil {
+		return 0, err
+	}
+	cached := o.getCachedPrice(baseID, quoteID)
+	if cached == nil {
+		return 0, fmt.Errorf("no oracle data for %s", marketPair{baseID, quoteID})
+	}
+	return sourcedPrice(cached, cfg, time.Now(), o.log)
+}
+
+// sourcedPrice computes the volume weighted price from the cached oracle
+// reports of the configured sources.
+func sourcedPrice(cached *cachedPrice, cfg *OracleConfig, now time.Time, log dex.Logger) (float64, error) {
+	if age := now.Sub(cached.stamp); cfg.MaxStaleness > 0 && age > cfg.MaxStaleness {
+		return 0, fmt.Errorf("%w: age %s > max staleness %s", errStaleOracle, age, cfg.MaxStaleness)
+	}
+	oracles := make([]*OracleReport, 0, len(cfg.Sources))
+	for _, o := range cached.oracles {
+		if slices.Contains(cfg.Sources, o.Host) {
+			oracles = append(oracles, o)
+		}
+	}
+	price, _, err := oracleAverage(oracles, log)
+	return price, err
+}
+
 func (o *priceOracle) getCachedPrice(baseID, quoteID uint32) *cachedPrice {
 	o.cachedPricesMtx.RLock()
 	defer o.cachedPricesMtx.RUnlock()
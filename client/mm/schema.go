@@ -0,0 +1,146 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// jsonSchemaDraft is the JSON Schema dialect of the ConfigSchema document.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// ConfigSchema generates a JSON Schema document describing MarketMakingConfig
+// and the types it contains, derived from their json struct tags. Fields
+// without omitempty are always present in a marshaled config, so they are
+// required. Exactly one of the strategy configs of a BotConfig may be set.
+func ConfigSchema() ([]byte, error) {
+	g := &schemaGenerator{defs: make(map[string]any)}
+	root := g.schema(reflect.TypeOf(MarketMakingConfig{}))
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	// Strategy configs are mutually exclusive.
+	botDef := g.defs["BotConfig"].(map[string]any)
+	botDef["oneOf"] = []any{
+		map[string]any{"required": []string{"basicMarketMakingConfig"}},
+		map[string]any{"required": []string{"simpleArbConfig"}},
+		map[string]any{"required": []string{"arbMarketMakingConfig"}},
+	}
+	// Gap strategies are a known set of strings.
+	basicDef := g.defs["BasicMarketMakingConfig"].(map[string]any)
+	basicDef["properties"].(map[string]any)["gapStrategy"] = map[string]any{
+		"type": "string",
+		"enum": []GapStrategy{GapStrategyMultiplier, GapStrategyAbsolute, GapStrategyAbsolutePlus,
+			GapStrategyPercent, GapStrategyPercentPlus},
+	}
+
+	root["$schema"] = jsonSchemaDraft
+	root["$defs"] = g.defs
+	return json.MarshalIndent(root, "", "    ")
+}
+
+// schemaGenerator generates JSON Schema for Go types, collecting struct
+// schemas in defs by type name.
+type schemaGenerator struct {
+	defs map[string]any
+	err  error
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// schema generates the schema for the type. Struct types are added to defs and
+// referenced.
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	if t == rawMessageType {
+		return map[string]any{} // any JSON value
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.Struct:
+		name := t.Name()
+		if _, found := g.defs[name]; !found {
+			g.defs[name] = nil // placeholder for recursive types
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + name}
+	case reflect.Slice:
+		return nullable(map[string]any{"type": "array", "items": g.schema(t.Elem())})
+	case reflect.Array:
+		return map[string]any{
+			"type":     "array",
+			"items":    g.schema(t.Elem()),
+			"minItems": t.Len(),
+			"maxItems": t.Len(),
+		}
+	case reflect.Map:
+		s := map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+		switch t.Key().Kind() {
+		case reflect.String:
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s["propertyNames"] = map[string]any{"pattern": "^[0-9]+$"}
+		default:
+			g.fail(fmt.Errorf("unsupported map key type %s", t.Key()))
+		}
+		return nullable(s)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	g.fail(fmt.Errorf("unsupported type %s", t))
+	return map[string]any{}
+}
+
+// structSchema generates the schema for the exported, json-tagged fields of
+// the struct type.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	required := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func (g *schemaGenerator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// nullable allows the schema's value to also be null, as with a nil pointer,
+// slice, or map.
+func nullable(s map[string]any) map[string]any {
+	return map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+}
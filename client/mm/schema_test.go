@@ -0,0 +1,243 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// schemaValidator validates JSON documents against the subset of JSON Schema
+// produced by ConfigSchema.
+type schemaValidator struct {
+	defs map[string]any
+}
+
+func (v *schemaValidator) validate(s map[string]any, doc any, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		def, ok := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: unknown ref %s", path, ref)
+		}
+		if err := v.validate(def, doc, path); err != nil {
+			return err
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		var matched bool
+		for _, sub := range anyOf {
+			if v.validate(sub.(map[string]any), doc, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: no anyOf schema matched", path)
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		var matches int
+		for _, sub := range oneOf {
+			if v.validate(sub.(map[string]any), doc, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: %d oneOf schemas matched", path, matches)
+		}
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		var found bool
+		for _, e := range enum {
+			found = found || e == doc
+		}
+		if !found {
+			return fmt.Errorf("%s: %v not in enum %v", path, doc, enum)
+		}
+	}
+	if typ, ok := s["type"].(string); ok {
+		if err := checkSchemaType(typ, doc); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if min, ok := s["minimum"].(float64); ok && doc.(float64) < min {
+		return fmt.Errorf("%s: %v is less than minimum %v", path, doc, min)
+	}
+
+	switch d := doc.(type) {
+	case map[string]any:
+		if req, ok := s["required"].([]any); ok {
+			for _, name := range req {
+				if _, found := d[name.(string)]; !found {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		if pn, ok := s["propertyNames"].(map[string]any); ok {
+			re := regexp.MustCompile(pn["pattern"].(string))
+			for k := range d {
+				if !re.MatchString(k) {
+					return fmt.Errorf("%s: property name %q does not match %s", path, k, re)
+				}
+			}
+		}
+		props, _ := s["properties"].(map[string]any)
+		for k, val := range d {
+			if ps, ok := props[k].(map[string]any); ok {
+				if err := v.validate(ps, val, path+"."+k); err != nil {
+					return err
+				}
+				continue
+			}
+			switch ap := s["additionalProperties"].(type) {
+			case bool:
+				if !ap && props != nil {
+					return fmt.Errorf("%s: unknown property %q", path, k)
+				}
+			case map[string]any:
+				if err := v.validate(ap, val, path+"."+k); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, val := range d {
+				if err := v.validate(items, val, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+		if min, ok := s["minItems"].(float64); ok && float64(len(d)) < min {
+			return fmt.Errorf("%s: too few items", path)
+		}
+		if max, ok := s["maxItems"].(float64); ok && float64(len(d)) > max {
+			return fmt.Errorf("%s: too many items", path)
+		}
+	}
+	return nil
+}
+
+func checkSchemaType(typ string, doc any) error {
+	var ok bool
+	switch typ {
+	case "object":
+		_, ok = doc.(map[string]any)
+	case "array":
+		_, ok = doc.([]any)
+	case "string":
+		_, ok = doc.(string)
+	case "boolean":
+		_, ok = doc.(bool)
+	case "number":
+		_, ok = doc.(float64)
+	case "integer":
+		f, isNum := doc.(float64)
+		ok = isNum && f == float64(int64(f))
+	case "null":
+		ok = doc == nil
+	}
+	if !ok {
+		return fmt.Errorf("%v is not of type %s", doc, typ)
+	}
+	return nil
+}
+
+func TestConfigSchema(t *testing.T) {
+	b, err := ConfigSchema()
+	if err != nil {
+		t.Fatalf("ConfigSchema error: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("error decoding schema: %v", err)
+	}
+	if schema["$schema"] != jsonSchemaDraft {
+		t.Fatalf("wrong $schema %v", schema["$schema"])
+	}
+	defs, _ := schema["$defs"].(map[string]any)
+	for _, name := range []string{"MarketMakingConfig", "BotConfig", "CEXConfig", "BasicMarketMakingConfig",
+		"SimpleArbConfig", "ArbMarketMakerConfig"} {
+		if defs[name] == nil {
+			t.Fatalf("schema missing %s", name)
+		}
+	}
+	v := &schemaValidator{defs: defs}
+
+	goodCfg := &MarketMakingConfig{
+		BotConfigs: []*BotConfig{{
+			Host:               "dex.example.com:7232",
+			BaseID:             42,
+			QuoteID:            0,
+			BaseWalletOptions:  map[string]string{"multisplit": "true"},
+			QuoteWalletProfile: "split",
+			CEXName:            "Binance",
+			RPCConfig: &rpcConfig{
+				Alloc: &BotBalanceAllocation{
+					DEX: map[uint32]uint64{42: 1e8, 0: 1e6},
+					CEX: map[uint32]uint64{42: 1e8},
+				},
+				AutoRebalance: &AutoRebalanceConfig{MinBaseTransfer: 1e7},
+			},
+			LotSize: 1e8,
+			ArbMarketMakerConfig: &ArbMarketMakerConfig{
+				BuyPlacements:  []*ArbMarketMakingPlacement{{Lots: 1, Multiplier: 1.5}},
+				SellPlacements: []*ArbMarketMakingPlacement{{Lots: 2, Multiplier: 1}},
+				Profit:         0.01,
+				MultiHop:       &MultiHopCfg{BaseAssetMarket: [2]uint32{42, 60}, QuoteAssetMarket: [2]uint32{0, 60}},
+			},
+		}, {
+			Host:    "dex.example.com:7232",
+			BaseID:  60,
+			QuoteID: 0,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy:   GapStrategyPercentPlus,
+				BuyPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+			},
+		}},
+		CexConfigs: []*CEXConfig{{
+			Name:            "Binance",
+			APIKey:          "key",
+			APISecret:       "secret",
+			RateLimitPerMin: 600,
+		}},
+		WalletProfiles: map[string]map[string]string{"split": {"multisplitbuffer": "5"}},
+	}
+
+	checkCfg := func(cfg *MarketMakingConfig, wantErr bool) {
+		t.Helper()
+		b, err := json.Marshal(cfg)
+		if err != nil {
+			t.Fatalf("error encoding config: %v", err)
+		}
+		var doc any
+		if err := json.Unmarshal(b, &doc); err != nil {
+			t.Fatalf("error decoding config: %v", err)
+		}
+		err = v.validate(schema, doc, "config")
+		if (err != nil) != wantErr {
+			t.Fatalf("wantErr = %t, got err = %v", wantErr, err)
+		}
+	}
+
+	checkCfg(goodCfg, false)
+
+	// Strategy configs are mutually exclusive.
+	badCfg := goodCfg.Copy()
+	bot := badCfg.BotConfigs[1].copy()
+	bot.SimpleArbConfig = &SimpleArbConfig{ProfitTrigger: 0.01}
+	badCfg.BotConfigs[1] = bot
+	checkCfg(badCfg, true)
+
+	// A strategy config is required.
+	bot.BasicMMConfig, bot.SimpleArbConfig = nil, nil
+	checkCfg(badCfg, true)
+
+	// Unknown gap strategy.
+	bot.BasicMMConfig = &BasicMarketMakingConfig{GapStrategy: "spread"}
+	checkCfg(badCfg, true)
+}
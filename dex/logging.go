@@ -4,17 +4,23 @@
 package dex
 
 import (
+	"bytes"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/decred/slog"
 	"github.com/jrick/logrotate/rotator"
+	"golang.org/x/term"
 )
 
 // Disabled is a Logger that will never output anything.
@@ -43,6 +49,156 @@ type Logger interface {
 	SubLogger(name string) Logger
 	FileLogger(r *rotator.Rotator) Logger
 	Meter(callerID string, delay time.Duration) Logger
+	// Dedup creates a Logger that suppresses repeats of a message within
+	// window of its first occurrence. See (*logger).Dedup.
+	Dedup(window time.Duration) Logger
+	// With creates a Logger that includes the provided key/value pairs in
+	// every message. Subloggers inherit the fields.
+	With(kv ...any) Logger
+}
+
+// NameStyle determines how the name of a SubLogger is combined with the name
+// of its parent.
+type NameStyle uint8
+
+const (
+	// NameStyleBracket combines names as parent[child], e.g. A[B][C].
+	NameStyleBracket NameStyle = iota
+	// NameStyleDotted combines names as parent.child, e.g. A.B.C. This is
+	// friendlier to log systems that parse hierarchical names.
+	NameStyleDotted
+)
+
+// combine combines the parent and child logger names.
+func (s NameStyle) combine(parent, child string) string {
+	if s == NameStyleDotted {
+		return parent + "." + child
+	}
+	return fmt.Sprintf("%s[%s]", parent, child)
+}
+
+// TimeFormat is the format of log message timestamps.
+type TimeFormat uint8
+
+const (
+	// TimeFormatMillis is the default slog timestamp format, with millisecond
+	// precision, e.g. 2006-01-02 15:04:05.000.
+	TimeFormatMillis TimeFormat = iota
+	// TimeFormatMicros is the default format with microsecond precision, e.g.
+	// 2006-01-02 15:04:05.000000.
+	TimeFormatMicros
+	// TimeFormatRFC3339Nano is time.RFC3339Nano, e.g.
+	// 2006-01-02T15:04:05.999999999Z07:00.
+	TimeFormatRFC3339Nano
+)
+
+// layout is the time layout string for the TimeFormat.
+func (tf TimeFormat) layout() string {
+	switch tf {
+	case TimeFormatMicros:
+		return "2006-01-02 15:04:05.000000"
+	case TimeFormatRFC3339Nano:
+		return time.RFC3339Nano
+	default:
+		return "2006-01-02 15:04:05.000"
+	}
+}
+
+// timestampWriter is an io.Writer that prefixes each write with a timestamp.
+// slog.Backend writes each message with a single Write call.
+type timestampWriter struct {
+	w      io.Writer
+	layout string
+	utc    bool
+}
+
+func (tw *timestampWriter) Write(p []byte) (int, error) {
+	t := time.Now()
+	if tw.utc {
+		t = t.UTC()
+	}
+	b := make([]byte, 0, len(tw.layout)+1+len(p))
+	b = t.AppendFormat(b, tw.layout)
+	b = append(b, ' ')
+	if _, err := tw.w.Write(append(b, p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// levelColors are the ANSI color codes for the level tags written by slog.
+var levelColors = map[string]string{
+	"TRC": "\x1b[90m",   // gray
+	"DBG": "\x1b[36m",   // cyan
+	"WRN": "\x1b[33m",   // yellow
+	"ERR": "\x1b[31m",   // red
+	"CRT": "\x1b[1;31m", // bold red
+}
+
+const colorReset = "\x1b[0m"
+
+// colorWriter is an io.Writer that colors the level tag, e.g. [ERR], of each
+// message. slog writes each message with a single Write.
+type colorWriter struct {
+	w io.Writer
+}
+
+func (cw *colorWriter) Write(p []byte) (int, error) {
+	i := bytes.IndexByte(p, '[')
+	if i < 0 || len(p) < i+5 || p[i+4] != ']' {
+		_, err := cw.w.Write(p)
+		return len(p), err
+	}
+	color, found := levelColors[string(p[i+1:i+4])]
+	if !found {
+		_, err := cw.w.Write(p)
+		return len(p), err
+	}
+	b := make([]byte, 0, len(p)+len(color)+len(colorReset))
+	b = append(b, p[:i]...)
+	b = append(b, color...)
+	b = append(b, p[i:i+5]...)
+	b = append(b, colorReset...)
+	b = append(b, p[i+5:]...)
+	if _, err := cw.w.Write(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// swapWriter is an io.Writer whose underlying io.Writer can be replaced while
+// in use.
+type swapWriter struct {
+	mtx sync.RWMutex
+	w   io.Writer
+}
+
+func (sw *swapWriter) Write(p []byte) (int, error) {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.Write(p)
+}
+
+func (sw *swapWriter) set(w io.Writer) {
+	sw.mtx.Lock()
+	sw.w = w
+	sw.mtx.Unlock()
+}
+
+// newBackend creates a slog.Backend that writes messages with timestamps in
+// the specified format. slog only formats timestamps with millisecond
+// precision, so for other formats slog's timestamp is disabled and the
+// timestamp is written by a timestampWriter instead.
+func newBackend(w io.Writer, tf TimeFormat, utc bool) *slog.Backend {
+	if tf == TimeFormatMillis {
+		var opts []slog.BackendOption
+		if utc {
+			opts = append(opts, inUTC())
+		}
+		return slog.NewBackend(w, opts...)
+	}
+	tw := &timestampWriter{w: w, layout: tf.layout(), utc: utc}
+	return slog.NewBackend(tw, slog.WithFlags(slog.Lnodatetime))
 }
 
 // LoggerMaker allows creation of new log subsystems with predefined levels.
@@ -50,6 +206,21 @@ type LoggerMaker struct {
 	*slog.Backend
 	DefaultLevel slog.Level
 	Levels       map[string]slog.Level
+	// NameStyle is the naming style for subloggers of the created loggers.
+	NameStyle NameStyle
+	// TimeFormat is the timestamp format of the Backend, used for file loggers
+	// of the created loggers. See NewLoggerMakerWithTimeFormat.
+	TimeFormat TimeFormat
+	// CountLevels enables per-level message counters for the created loggers
+	// and their subloggers. See LevelCounter.
+	CountLevels bool
+	// WithCaller prefixes the messages of the created loggers and their
+	// subloggers with the file name and line number of the logging call, e.g.
+	// "swap.go:123:". Finding the caller has a cost for every message, so this
+	// is off by default.
+	WithCaller bool
+
+	writer *swapWriter // nil unless created with a constructor
 }
 
 // logger contains the slog.Logger and fields needed to spawn subloggers. It
@@ -60,6 +231,11 @@ type logger struct {
 	level   slog.Level
 	levels  map[string]slog.Level
 	backend *slog.Backend
+	fields  string // formatted key/value pairs, see With
+	style   NameStyle
+	timeFmt TimeFormat
+	counts  *levelCounts // nil if not counting
+	caller  bool         // messages are prefixed with the callsite
 
 	meterMtx sync.Mutex
 	meters   map[string]time.Time
@@ -75,7 +251,7 @@ func (lggr *logger) SubLogger(name string) Logger {
 // FileLogger creates a logger that logs to a file rotator. Subloggers will also
 // log to the file only.
 func (lggr *logger) FileLogger(r *rotator.Rotator) Logger {
-	return lggr.newLoggerWithBackend(slog.NewBackend(r), "F")
+	return lggr.newLoggerWithBackend(newBackend(r, lggr.timeFmt, false), "F")
 }
 
 func (lggr *logger) newLoggerWithBackend(backend *slog.Backend, name string) *logger {
@@ -85,18 +261,267 @@ func (lggr *logger) newLoggerWithBackend(backend *slog.Backend, name string) *lo
 		level = lvl
 	}
 
-	combinedName := fmt.Sprintf("%s[%s]", lggr.name, name)
+	combinedName := lggr.style.combine(lggr.name, name)
 	newLggr := backend.Logger(combinedName)
 	newLggr.SetLevel(level)
+	var counts *levelCounts
+	if lggr.counts != nil {
+		counts = new(levelCounts)
+	}
 	return &logger{
-		Logger:  newLggr,
+		Logger:  withCounts(withFields(withCaller(newLggr, lggr.caller), lggr.fields), counts),
 		name:    combinedName,
 		level:   level,
 		levels:  lggr.levels,
 		backend: backend,
+		fields:  lggr.fields,
+		style:   lggr.style,
+		timeFmt: lggr.timeFmt,
+		counts:  counts,
+		caller:  lggr.caller,
+	}
+}
+
+// With creates a Logger that prefixes every message with the provided
+// key/value pairs, e.g. With("acct", acctID, "market", mktName). Fields are
+// appended to any fields of the parent logger, and are inherited by
+// subloggers. The Logger shares the parent's level counts, if counting.
+func (lggr *logger) With(kv ...any) Logger {
+	fields := formatFields(kv)
+	if lggr.fields != "" {
+		fields = lggr.fields + " " + fields
+	}
+	newLggr := lggr.backend.Logger(lggr.name)
+	newLggr.SetLevel(lggr.Logger.Level())
+	return &logger{
+		Logger:  withCounts(withFields(withCaller(newLggr, lggr.caller), fields), lggr.counts),
+		name:    lggr.name,
+		level:   lggr.level,
+		levels:  lggr.levels,
+		backend: lggr.backend,
+		fields:  fields,
+		style:   lggr.style,
+		timeFmt: lggr.timeFmt,
+		counts:  lggr.counts,
+		caller:  lggr.caller,
+	}
+}
+
+// LevelCounter is satisfied by a Logger that counts its messages by level. A
+// Logger created by a LoggerMaker with CountLevels set is a LevelCounter.
+// Each sublogger has its own counts, so the counts of a subsystem can be
+// monitored, e.g. to detect a spike in errors.
+type LevelCounter interface {
+	// Counts returns the number of messages logged at each level. Messages
+	// below the logger's level are not counted.
+	Counts() map[slog.Level]uint64
+}
+
+// Counts returns the number of messages logged at each level. If the logger
+// is not counting, Counts returns nil.
+func (lggr *logger) Counts() map[slog.Level]uint64 {
+	if lggr.counts == nil {
+		return nil
+	}
+	return lggr.counts.snapshot()
+}
+
+// levelCounts is a concurrency-safe counter of messages per level.
+type levelCounts [LevelCritical + 1]atomic.Uint64
+
+func (c *levelCounts) snapshot() map[slog.Level]uint64 {
+	counts := make(map[slog.Level]uint64, len(c))
+	for lvl := range c {
+		counts[slog.Level(lvl)] = c[lvl].Load()
+	}
+	return counts
+}
+
+// countingLogger is a slog.Logger that counts the messages that are logged at
+// or above the logger's level.
+type countingLogger struct {
+	slog.Logger
+	counts *levelCounts
+}
+
+// withCounts wraps the slog.Logger so that messages are counted. If counts is
+// nil, the slog.Logger is returned as is.
+func withCounts(lggr slog.Logger, counts *levelCounts) slog.Logger {
+	if counts == nil {
+		return lggr
+	}
+	return &countingLogger{Logger: lggr, counts: counts}
+}
+
+func (l *countingLogger) count(lvl slog.Level) {
+	if l.Level() <= lvl {
+		l.counts[lvl].Add(1)
 	}
 }
 
+func (l *countingLogger) Tracef(format string, params ...any) {
+	l.count(LevelTrace)
+	l.Logger.Tracef(format, params...)
+}
+func (l *countingLogger) Debugf(format string, params ...any) {
+	l.count(LevelDebug)
+	l.Logger.Debugf(format, params...)
+}
+func (l *countingLogger) Infof(format string, params ...any) {
+	l.count(LevelInfo)
+	l.Logger.Infof(format, params...)
+}
+func (l *countingLogger) Warnf(format string, params ...any) {
+	l.count(LevelWarn)
+	l.Logger.Warnf(format, params...)
+}
+func (l *countingLogger) Errorf(format string, params ...any) {
+	l.count(LevelError)
+	l.Logger.Errorf(format, params...)
+}
+func (l *countingLogger) Criticalf(format string, params ...any) {
+	l.count(LevelCritical)
+	l.Logger.Criticalf(format, params...)
+}
+func (l *countingLogger) Trace(v ...any)    { l.count(LevelTrace); l.Logger.Trace(v...) }
+func (l *countingLogger) Debug(v ...any)    { l.count(LevelDebug); l.Logger.Debug(v...) }
+func (l *countingLogger) Info(v ...any)     { l.count(LevelInfo); l.Logger.Info(v...) }
+func (l *countingLogger) Warn(v ...any)     { l.count(LevelWarn); l.Logger.Warn(v...) }
+func (l *countingLogger) Error(v ...any)    { l.count(LevelError); l.Logger.Error(v...) }
+func (l *countingLogger) Critical(v ...any) { l.count(LevelCritical); l.Logger.Critical(v...) }
+
+// formatFields formats key/value pairs as "k1=v1 k2=v2". A key without a
+// value is paired with "MISSING".
+func formatFields(kv []any) string {
+	pairs := make([]string, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			pairs = append(pairs, fmt.Sprintf("%v=MISSING", kv[i]))
+			break
+		}
+		pairs = append(pairs, fmt.Sprintf("%v=%v", kv[i], kv[i+1]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// fieldsLogger is a slog.Logger that prefixes messages with formatted
+// key/value pairs.
+type fieldsLogger struct {
+	slog.Logger
+	prefix string
+}
+
+// withFields wraps the slog.Logger so that messages are prefixed with the
+// formatted fields. If fields is empty, the slog.Logger is returned as is.
+func withFields(lggr slog.Logger, fields string) slog.Logger {
+	if fields == "" {
+		return lggr
+	}
+	return &fieldsLogger{Logger: lggr, prefix: fields + ":"}
+}
+
+func (l *fieldsLogger) prepend(params []any) []any {
+	return append([]any{l.prefix}, params...)
+}
+
+func (l *fieldsLogger) Tracef(format string, params ...any) {
+	l.Logger.Tracef("%s "+format, l.prepend(params)...)
+}
+func (l *fieldsLogger) Debugf(format string, params ...any) {
+	l.Logger.Debugf("%s "+format, l.prepend(params)...)
+}
+func (l *fieldsLogger) Infof(format string, params ...any) {
+	l.Logger.Infof("%s "+format, l.prepend(params)...)
+}
+func (l *fieldsLogger) Warnf(format string, params ...any) {
+	l.Logger.Warnf("%s "+format, l.prepend(params)...)
+}
+func (l *fieldsLogger) Errorf(format string, params ...any) {
+	l.Logger.Errorf("%s "+format, l.prepend(params)...)
+}
+func (l *fieldsLogger) Criticalf(format string, params ...any) {
+	l.Logger.Criticalf("%s "+format, l.prepend(params)...)
+}
+func (l *fieldsLogger) Trace(v ...any)    { l.Logger.Trace(l.prepend(v)...) }
+func (l *fieldsLogger) Debug(v ...any)    { l.Logger.Debug(l.prepend(v)...) }
+func (l *fieldsLogger) Info(v ...any)     { l.Logger.Info(l.prepend(v)...) }
+func (l *fieldsLogger) Warn(v ...any)     { l.Logger.Warn(l.prepend(v)...) }
+func (l *fieldsLogger) Error(v ...any)    { l.Logger.Error(l.prepend(v)...) }
+func (l *fieldsLogger) Critical(v ...any) { l.Logger.Critical(l.prepend(v)...) }
+
+// loggingFile is the path of this file. Frames in this file are skipped when
+// finding the callsite of a message.
+var loggingFile = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file
+}()
+
+// callsite returns the "file.go:line:" of the first caller outside of this
+// file. slog's own file flags can't be used because its call depth is fixed,
+// and the wrapping loggers in this file add frames.
+func callsite() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:]) // skip Callers, callsite, and the callerLogger method
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.File != loggingFile {
+			return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line) + ":"
+		}
+		if !more {
+			return "???:0:"
+		}
+	}
+}
+
+// callerLogger is a slog.Logger that prefixes messages with the callsite.
+type callerLogger struct {
+	slog.Logger
+}
+
+// withCaller wraps the slog.Logger so that messages are prefixed with the
+// callsite if enabled is true. Otherwise the slog.Logger is returned as is.
+func withCaller(lggr slog.Logger, enabled bool) slog.Logger {
+	if !enabled {
+		return lggr
+	}
+	return &callerLogger{Logger: lggr}
+}
+
+// prepend prepends the callsite to params if a message at lvl will be logged.
+// The callsite is not looked up for messages that will be discarded.
+func (l *callerLogger) prepend(lvl slog.Level, params []any) []any {
+	if l.Level() > lvl {
+		return params
+	}
+	return append([]any{callsite()}, params...)
+}
+
+func (l *callerLogger) Tracef(format string, params ...any) {
+	l.Logger.Tracef("%s "+format, l.prepend(LevelTrace, params)...)
+}
+func (l *callerLogger) Debugf(format string, params ...any) {
+	l.Logger.Debugf("%s "+format, l.prepend(LevelDebug, params)...)
+}
+func (l *callerLogger) Infof(format string, params ...any) {
+	l.Logger.Infof("%s "+format, l.prepend(LevelInfo, params)...)
+}
+func (l *callerLogger) Warnf(format string, params ...any) {
+	l.Logger.Warnf("%s "+format, l.prepend(LevelWarn, params)...)
+}
+func (l *callerLogger) Errorf(format string, params ...any) {
+	l.Logger.Errorf("%s "+format, l.prepend(LevelError, params)...)
+}
+func (l *callerLogger) Criticalf(format string, params ...any) {
+	l.Logger.Criticalf("%s "+format, l.prepend(LevelCritical, params)...)
+}
+func (l *callerLogger) Trace(v ...any)    { l.Logger.Trace(l.prepend(LevelTrace, v)...) }
+func (l *callerLogger) Debug(v ...any)    { l.Logger.Debug(l.prepend(LevelDebug, v)...) }
+func (l *callerLogger) Info(v ...any)     { l.Logger.Info(l.prepend(LevelInfo, v)...) }
+func (l *callerLogger) Warn(v ...any)     { l.Logger.Warn(l.prepend(LevelWarn, v)...) }
+func (l *callerLogger) Error(v ...any)    { l.Logger.Error(l.prepend(LevelError, v)...) }
+func (l *callerLogger) Critical(v ...any) { l.Logger.Critical(l.prepend(LevelCritical, v)...) }
+
 // Meter enforces a time delay on logging. The first call to a metered logger
 // always logs. Subsequent calls for the same callerID are ignored until the
 // delay is surpassed.
@@ -113,15 +538,42 @@ func (log *logger) Meter(callerID string, delay time.Duration) Logger {
 	return log
 }
 
+// Dedup creates a Logger that suppresses duplicate messages. The first
+// occurrence of a message at a level is logged, and identical messages at the
+// same level are dropped until window has passed. When the window closes, a
+// "(repeated N times)" summary is logged if there were any duplicates. This
+// prevents log floods, e.g. the same error for every failed request while an
+// RPC node is down. A window <= 0 disables suppression.
+func (lggr *logger) Dedup(window time.Duration) Logger {
+	return newDedupLogger(lggr, window)
+}
+
+// LogRotator limits.
+const (
+	defaultLogRotatorMaxKB    = 32 * 1024
+	defaultLogRotatorMaxRolls = 8
+)
+
 // LogRotator creates a file logger that rotates up to 8 files of 32 MiB each.
 func LogRotator(dir, name string) (*rotator.Rotator, error) {
-	const maxLogRolls = 8
+	return LogRotatorCustom(dir, name, defaultLogRotatorMaxKB, defaultLogRotatorMaxRolls)
+}
+
+// LogRotatorCustom creates a file logger that rotates up to maxRolls files of
+// maxKB kilobytes each.
+func LogRotatorCustom(dir, name string, maxKB, maxRolls int) (*rotator.Rotator, error) {
+	if maxKB <= 0 {
+		return nil, fmt.Errorf("invalid max log file size %d KB", maxKB)
+	}
+	if maxRolls <= 0 {
+		return nil, fmt.Errorf("invalid max log rolls %d", maxRolls)
+	}
 	if err := os.MkdirAll(dir, 0744); err != nil {
 		return nil, fmt.Errorf("error creating log directory: %w", err)
 	}
 
 	logFilename := filepath.Join(dir, name)
-	return rotator.New(logFilename, 32*1024, false, maxLogRolls)
+	return rotator.New(logFilename, int64(maxKB), false, maxRolls)
 }
 
 func inUTC() slog.BackendOption {
@@ -130,11 +582,14 @@ func inUTC() slog.BackendOption {
 
 // NewLogger creates a new Logger with the given name, log level, and io.Writer.
 func NewLogger(name string, lvl slog.Level, writer io.Writer, utc ...bool) Logger {
-	var opts []slog.BackendOption
-	if len(utc) > 0 && utc[0] {
-		opts = append(opts, inUTC())
-	}
-	backend := slog.NewBackend(writer, opts...)
+	return NewLoggerWithTimeFormat(name, lvl, writer, TimeFormatMillis, len(utc) > 0 && utc[0])
+}
+
+// NewLoggerWithTimeFormat is like NewLogger, but message timestamps are written
+// in the specified TimeFormat. Subloggers and file loggers use the same
+// TimeFormat.
+func NewLoggerWithTimeFormat(name string, lvl slog.Level, writer io.Writer, tf TimeFormat, utc bool) Logger {
+	backend := newBackend(writer, tf, utc)
 	lggr := backend.Logger(name)
 	lggr.SetLevel(lvl)
 	return &logger{
@@ -143,6 +598,7 @@ func NewLogger(name string, lvl slog.Level, writer io.Writer, utc ...bool) Logge
 		level:   lvl,
 		levels:  make(map[string]slog.Level),
 		backend: backend,
+		timeFmt: tf,
 	}
 }
 
@@ -165,17 +621,96 @@ func StdOutLogger(name string, lvl slog.Level, utc ...bool) Logger {
 	}
 }
 
+// ColorStdOutLogger is like StdOutLogger, but the level tags of messages are
+// colored if standard out is a terminal. Subloggers are colored too.
+func ColorStdOutLogger(name string, lvl slog.Level) Logger {
+	return newColorLogger(name, lvl, os.Stdout, term.IsTerminal(int(os.Stdout.Fd())))
+}
+
+// newColorLogger creates a Logger that writes to w, coloring level tags if
+// color is true.
+func newColorLogger(name string, lvl slog.Level, w io.Writer, color bool) Logger {
+	if color {
+		w = &colorWriter{w: w}
+	}
+	return NewLogger(name, lvl, w)
+}
+
+// LogBuffer is a concurrency-safe io.Writer that records log lines for
+// inspection in tests. See NewMemoryLogger.
+type LogBuffer struct {
+	mtx   sync.Mutex
+	lines []string
+	part  []byte
+}
+
+// Write records the newline-delimited lines in p.
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.part = append(b.part, p...)
+	for {
+		i := bytes.IndexByte(b.part, '\n')
+		if i < 0 {
+			break
+		}
+		b.lines = append(b.lines, string(b.part[:i]))
+		b.part = b.part[i+1:]
+	}
+	return len(p), nil
+}
+
+// Lines returns a copy of the recorded lines.
+func (b *LogBuffer) Lines() []string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return append([]string(nil), b.lines...)
+}
+
+// Contains checks whether any recorded line contains substr.
+func (b *LogBuffer) Contains(substr string) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for _, line := range b.lines {
+		if strings.Contains(line, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset discards all recorded lines.
+func (b *LogBuffer) Reset() {
+	b.mtx.Lock()
+	b.lines, b.part = nil, nil
+	b.mtx.Unlock()
+}
+
+// NewMemoryLogger creates a Logger with lvl as the log level that records
+// messages to the returned LogBuffer rather than printing them. Subloggers
+// record to the same LogBuffer. This is intended for tests that need to
+// assert that something was logged.
+func NewMemoryLogger(lvl slog.Level) (Logger, *LogBuffer) {
+	buf := new(LogBuffer)
+	return NewLogger("TEST", lvl, buf), buf
+}
+
 // NewLoggerMaker creates a new LoggerMaker from the provided io.Writer and
 // debug level string. See SetLevels for details on the debug level string.
 func NewLoggerMaker(writer io.Writer, debugLevel string, utc ...bool) (*LoggerMaker, error) {
-	var opts []slog.BackendOption
-	if len(utc) > 0 && utc[0] {
-		opts = append(opts, inUTC())
-	}
+	return NewLoggerMakerWithTimeFormat(writer, debugLevel, TimeFormatMillis, len(utc) > 0 && utc[0])
+}
+
+// NewLoggerMakerWithTimeFormat is like NewLoggerMaker, but message timestamps
+// of the created loggers are written in the specified TimeFormat.
+func NewLoggerMakerWithTimeFormat(writer io.Writer, debugLevel string, tf TimeFormat, utc bool) (*LoggerMaker, error) {
+	sw := &swapWriter{w: writer}
 	lm := &LoggerMaker{
-		Backend:      slog.NewBackend(writer, opts...),
+		Backend:      newBackend(sw, tf, utc),
 		Levels:       make(map[string]slog.Level),
 		DefaultLevel: DefaultLogLevel,
+		TimeFormat:   tf,
+		writer:       sw,
 	}
 
 	err := lm.SetLevels(debugLevel)
@@ -186,6 +721,18 @@ func NewLoggerMaker(writer io.Writer, debugLevel string, utc ...bool) (*LoggerMa
 	return lm, nil
 }
 
+// SetWriter redirects the output of all loggers created by the LoggerMaker,
+// including existing loggers, to w. This may be used to switch to a new log
+// destination at runtime, e.g. after an external log rotation. File loggers
+// created with FileLogger are not affected. SetWriter is a no-op for a
+// LoggerMaker not created with NewLoggerMaker or NewLoggerMakerWithTimeFormat.
+func (lm *LoggerMaker) SetWriter(w io.Writer) {
+	if lm.writer == nil {
+		return
+	}
+	lm.writer.set(w)
+}
+
 // SetLevelsFromMap sets all logs for certain subsystems with the same name to
 // the corresponding log level in the map.
 func (lm *LoggerMaker) SetLevelsFromMap(lvls map[string]slog.Level) {
@@ -264,12 +811,17 @@ func (lm *LoggerMaker) NewLogger(name string, level ...slog.Level) Logger {
 	}
 	lggr := lm.Backend.Logger(name)
 	lggr.SetLevel(lvl)
+	counts := lm.newLevelCounts()
 	return &logger{
-		Logger:  lggr,
+		Logger:  withCounts(withCaller(lggr, lm.WithCaller), counts),
 		name:    name,
 		level:   lvl,
 		levels:  lm.Levels,
 		backend: lm.Backend,
+		style:   lm.NameStyle,
+		timeFmt: lm.TimeFormat,
+		counts:  counts,
+		caller:  lm.WithCaller,
 	}
 }
 
@@ -280,15 +832,29 @@ func (lm *LoggerMaker) Logger(name string) Logger {
 	lggr := lm.Backend.Logger(name)
 	lvl := lm.bestLevel(name)
 	lggr.SetLevel(lvl)
+	counts := lm.newLevelCounts()
 	return &logger{
-		Logger:  lggr,
+		Logger:  withCounts(withCaller(lggr, lm.WithCaller), counts),
 		name:    name,
 		level:   lvl,
 		levels:  lm.Levels,
 		backend: lm.Backend,
+		style:   lm.NameStyle,
+		timeFmt: lm.TimeFormat,
+		counts:  counts,
+		caller:  lm.WithCaller,
 	}
 }
 
+// newLevelCounts creates level counters for a new logger if CountLevels is
+// set, else nil.
+func (lm *LoggerMaker) newLevelCounts() *levelCounts {
+	if !lm.CountLevels {
+		return nil
+	}
+	return new(levelCounts)
+}
+
 // bestLevel takes a hierarchical list of logger names, least important to most
 // important, and returns the best log level found in the Levels map, else the
 // default.
@@ -302,3 +868,317 @@ func (lm *LoggerMaker) bestLevel(lvls ...string) slog.Level {
 	}
 	return lvl
 }
+
+// ClosableLogger is a Logger that must be closed when no longer needed.
+type ClosableLogger interface {
+	Logger
+	// Close stops the logger, flushing any queued messages.
+	Close()
+}
+
+// logRecord is a formatted log message queued for an asyncLogger.
+type logRecord struct {
+	lggr slog.Logger
+	lvl  slog.Level
+	msg  string
+}
+
+// asyncQueue is the queue and writer goroutine shared by an asyncLogger and
+// its subloggers.
+type asyncQueue struct {
+	mtx     sync.RWMutex
+	closed  bool
+	records chan *logRecord
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+func (q *asyncQueue) run(base slog.Logger) {
+	defer close(q.done)
+	for r := range q.records {
+		if n := q.dropped.Swap(0); n > 0 {
+			base.Warnf("dropped %d log messages", n)
+		}
+		writeRecord(r)
+	}
+	if n := q.dropped.Swap(0); n > 0 {
+		base.Warnf("dropped %d log messages", n)
+	}
+}
+
+func writeRecord(r *logRecord) {
+	switch r.lvl {
+	case LevelTrace:
+		r.lggr.Trace(r.msg)
+	case LevelDebug:
+		r.lggr.Debug(r.msg)
+	case LevelInfo:
+		r.lggr.Info(r.msg)
+	case LevelWarn:
+		r.lggr.Warn(r.msg)
+	case LevelError:
+		r.lggr.Error(r.msg)
+	case LevelCritical:
+		r.lggr.Critical(r.msg)
+	}
+}
+
+// asyncLogger is a Logger that queues messages to be written by a background
+// goroutine so that logging does not block the caller.
+type asyncLogger struct {
+	Logger
+	q *asyncQueue
+}
+
+// AsyncLogger wraps the Logger so that messages are formatted by the caller,
+// but written to the underlying backend by a background goroutine. Up to
+// bufferSize messages may be queued. If the queue is full, messages are
+// dropped, and the number of dropped messages is logged once there is room.
+// Subloggers share the same queue. Close flushes queued messages and stops the
+// goroutine, after which messages are written synchronously.
+func AsyncLogger(base Logger, bufferSize int) ClosableLogger {
+	q := &asyncQueue{
+		records: make(chan *logRecord, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go q.run(base)
+	return &asyncLogger{Logger: base, q: q}
+}
+
+// Dropped is the number of messages dropped since the last "dropped" notice
+// was written.
+func (l *asyncLogger) Dropped() uint64 {
+	return l.q.dropped.Load()
+}
+
+// Close flushes any queued messages and stops the writer goroutine.
+func (l *asyncLogger) Close() {
+	l.q.mtx.Lock()
+	if !l.q.closed {
+		l.q.closed = true
+		close(l.q.records)
+	}
+	l.q.mtx.Unlock()
+	<-l.q.done
+}
+
+func (l *asyncLogger) enqueue(lvl slog.Level, msg string) {
+	r := &logRecord{lggr: l.Logger, lvl: lvl, msg: msg}
+	l.q.mtx.RLock()
+	defer l.q.mtx.RUnlock()
+	if l.q.closed {
+		writeRecord(r)
+		return
+	}
+	select {
+	case l.q.records <- r:
+	default:
+		l.q.dropped.Add(1)
+	}
+}
+
+func (l *asyncLogger) logf(lvl slog.Level, format string, params []any) {
+	if l.Level() <= lvl {
+		l.enqueue(lvl, fmt.Sprintf(format, params...))
+	}
+}
+
+func (l *asyncLogger) log(lvl slog.Level, v []any) {
+	if l.Level() <= lvl {
+		l.enqueue(lvl, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	}
+}
+
+func (l *asyncLogger) Tracef(format string, params ...any)    { l.logf(LevelTrace, format, params) }
+func (l *asyncLogger) Debugf(format string, params ...any)    { l.logf(LevelDebug, format, params) }
+func (l *asyncLogger) Infof(format string, params ...any)     { l.logf(LevelInfo, format, params) }
+func (l *asyncLogger) Warnf(format string, params ...any)     { l.logf(LevelWarn, format, params) }
+func (l *asyncLogger) Errorf(format string, params ...any)    { l.logf(LevelError, format, params) }
+func (l *asyncLogger) Criticalf(format string, params ...any) { l.logf(LevelCritical, format, params) }
+func (l *asyncLogger) Trace(v ...any)                         { l.log(LevelTrace, v) }
+func (l *asyncLogger) Debug(v ...any)                         { l.log(LevelDebug, v) }
+func (l *asyncLogger) Info(v ...any)                          { l.log(LevelInfo, v) }
+func (l *asyncLogger) Warn(v ...any)                          { l.log(LevelWarn, v) }
+func (l *asyncLogger) Error(v ...any)                         { l.log(LevelError, v) }
+func (l *asyncLogger) Critical(v ...any)                      { l.log(LevelCritical, v) }
+
+// Counts returns the level counts of the underlying Logger, if it is a
+// LevelCounter, else nil. Queued messages are counted once written.
+func (l *asyncLogger) Counts() map[slog.Level]uint64 {
+	if lc, ok := l.Logger.(LevelCounter); ok {
+		return lc.Counts()
+	}
+	return nil
+}
+
+// SubLogger creates a sublogger that shares this logger's queue.
+func (l *asyncLogger) SubLogger(name string) Logger {
+	return &asyncLogger{Logger: l.Logger.SubLogger(name), q: l.q}
+}
+
+// FileLogger creates a file logger that shares this logger's queue.
+func (l *asyncLogger) FileLogger(r *rotator.Rotator) Logger {
+	return &asyncLogger{Logger: l.Logger.FileLogger(r), q: l.q}
+}
+
+// With creates a logger with fields that shares this logger's queue.
+func (l *asyncLogger) With(kv ...any) Logger {
+	return &asyncLogger{Logger: l.Logger.With(kv...), q: l.q}
+}
+
+// Meter enforces a time delay on logging. See (*logger).Meter.
+func (l *asyncLogger) Meter(callerID string, delay time.Duration) Logger {
+	if l.Logger.Meter(callerID, delay) == Disabled {
+		return Disabled
+	}
+	return l
+}
+
+// Dedup creates a logger that suppresses duplicate messages before they are
+// queued. See (*logger).Dedup.
+func (l *asyncLogger) Dedup(window time.Duration) Logger {
+	return newDedupLogger(l, window)
+}
+
+// dedupLogger is a Logger that suppresses duplicate messages within a window.
+// See (*logger).Dedup.
+type dedupLogger struct {
+	Logger
+	window time.Duration
+
+	mtx    sync.Mutex
+	recent map[uint64]*dedupEntry // by hash of level and message
+}
+
+// dedupEntry is a message logged within the window, and the number of
+// duplicates suppressed since.
+type dedupEntry struct {
+	lvl     slog.Level
+	msg     string
+	repeats int
+}
+
+func newDedupLogger(lggr Logger, window time.Duration) Logger {
+	if window <= 0 {
+		return lggr
+	}
+	return &dedupLogger{
+		Logger: lggr,
+		window: window,
+		recent: make(map[uint64]*dedupEntry),
+	}
+}
+
+func dedupKey(lvl slog.Level, msg string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte{byte(lvl)})
+	h.Write([]byte(msg))
+	return h.Sum64()
+}
+
+// emit logs the message if it is not a duplicate of one logged within the
+// window.
+func (l *dedupLogger) emit(lvl slog.Level, msg string) {
+	key := dedupKey(lvl, msg)
+	l.mtx.Lock()
+	if e, found := l.recent[key]; found {
+		e.repeats++
+		l.mtx.Unlock()
+		return
+	}
+	l.recent[key] = &dedupEntry{lvl: lvl, msg: msg}
+	l.mtx.Unlock()
+	time.AfterFunc(l.window, func() { l.closeWindow(key) })
+	l.write(lvl, msg)
+}
+
+// closeWindow forgets the message, logging a summary of any duplicates.
+func (l *dedupLogger) closeWindow(key uint64) {
+	l.mtx.Lock()
+	e := l.recent[key]
+	delete(l.recent, key)
+	l.mtx.Unlock()
+	if e != nil && e.repeats > 0 {
+		l.write(e.lvl, fmt.Sprintf("%s (repeated %d times)", e.msg, e.repeats))
+	}
+}
+
+func (l *dedupLogger) write(lvl slog.Level, msg string) {
+	switch lvl {
+	case LevelTrace:
+		l.Logger.Trace(msg)
+	case LevelDebug:
+		l.Logger.Debug(msg)
+	case LevelInfo:
+		l.Logger.Info(msg)
+	case LevelWarn:
+		l.Logger.Warn(msg)
+	case LevelError:
+		l.Logger.Error(msg)
+	default:
+		l.Logger.Critical(msg)
+	}
+}
+
+func (l *dedupLogger) logf(lvl slog.Level, format string, params []any) {
+	if l.Level() <= lvl {
+		l.emit(lvl, fmt.Sprintf(format, params...))
+	}
+}
+
+func (l *dedupLogger) log(lvl slog.Level, v []any) {
+	if l.Level() <= lvl {
+		l.emit(lvl, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	}
+}
+
+func (l *dedupLogger) Tracef(format string, params ...any)    { l.logf(LevelTrace, format, params) }
+func (l *dedupLogger) Debugf(format string, params ...any)    { l.logf(LevelDebug, format, params) }
+func (l *dedupLogger) Infof(format string, params ...any)     { l.logf(LevelInfo, format, params) }
+func (l *dedupLogger) Warnf(format string, params ...any)     { l.logf(LevelWarn, format, params) }
+func (l *dedupLogger) Errorf(format string, params ...any)    { l.logf(LevelError, format, params) }
+func (l *dedupLogger) Criticalf(format string, params ...any) { l.logf(LevelCritical, format, params) }
+func (l *dedupLogger) Trace(v ...any)                         { l.log(LevelTrace, v) }
+func (l *dedupLogger) Debug(v ...any)                         { l.log(LevelDebug, v) }
+func (l *dedupLogger) Info(v ...any)                          { l.log(LevelInfo, v) }
+func (l *dedupLogger) Warn(v ...any)                          { l.log(LevelWarn, v) }
+func (l *dedupLogger) Error(v ...any)                         { l.log(LevelError, v) }
+func (l *dedupLogger) Critical(v ...any)                      { l.log(LevelCritical, v) }
+
+// Counts returns the level counts of the underlying Logger, if it is a
+// LevelCounter, else nil. Suppressed duplicates are not counted.
+func (l *dedupLogger) Counts() map[slog.Level]uint64 {
+	if lc, ok := l.Logger.(LevelCounter); ok {
+		return lc.Counts()
+	}
+	return nil
+}
+
+// SubLogger creates a sublogger with the same window. Subloggers track their
+// own duplicates.
+func (l *dedupLogger) SubLogger(name string) Logger {
+	return newDedupLogger(l.Logger.SubLogger(name), l.window)
+}
+
+// FileLogger creates a file logger with the same window.
+func (l *dedupLogger) FileLogger(r *rotator.Rotator) Logger {
+	return newDedupLogger(l.Logger.FileLogger(r), l.window)
+}
+
+// With creates a logger with fields and the same window.
+func (l *dedupLogger) With(kv ...any) Logger {
+	return newDedupLogger(l.Logger.With(kv...), l.window)
+}
+
+// Meter enforces a time delay on logging. See (*logger).Meter.
+func (l *dedupLogger) Meter(callerID string, delay time.Duration) Logger {
+	if l.Logger.Meter(callerID, delay) == Disabled {
+		return Disabled
+	}
+	return l
+}
+
+// Dedup creates a logger with a different window.
+func (l *dedupLogger) Dedup(window time.Duration) Logger {
+	return newDedupLogger(l.Logger, window)
+}
@@ -0,0 +1,461 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package dex
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+func TestLoggerWith(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewLogger("TEST", LevelTrace, &buf).With("acct", "abc", "market", "dcr_btc")
+
+	log.Infof("hello %s", "world")
+	if line := buf.String(); !strings.Contains(line, "TEST: acct=abc market=dcr_btc: hello world") {
+		t.Fatalf("fields not in formatted output: %q", line)
+	}
+	buf.Reset()
+
+	log.Info("hello", "world")
+	if line := buf.String(); !strings.Contains(line, "TEST: acct=abc market=dcr_btc: hello world") {
+		t.Fatalf("fields not in output: %q", line)
+	}
+	buf.Reset()
+
+	// Subloggers inherit fields, and With appends more.
+	sub := log.SubLogger("SUB").With("order", 1)
+	sub.Debugf("sub")
+	if line := buf.String(); !strings.Contains(line, "TEST[SUB]: acct=abc market=dcr_btc order=1: sub") {
+		t.Fatalf("fields not inherited by sublogger: %q", line)
+	}
+	buf.Reset()
+
+	// A key without a value.
+	log.With("lonely").Warn("missing")
+	if line := buf.String(); !strings.Contains(line, "lonely=MISSING: missing") {
+		t.Fatalf("missing value not indicated: %q", line)
+	}
+	buf.Reset()
+
+	// Meter still works on the derived logger.
+	for i := 0; i < 3; i++ {
+		log.Meter("caller", time.Hour).Info("metered")
+	}
+	if n := strings.Count(buf.String(), "acct=abc market=dcr_btc: metered"); n != 1 {
+		t.Fatalf("expected 1 metered line, got %d", n)
+	}
+}
+
+// gatedWriter is an io.Writer that blocks writes until released.
+type gatedWriter struct {
+	mtx     sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) String() string {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncLogger(t *testing.T) {
+	// Ordering within capacity.
+	var buf bytes.Buffer
+	const n = 16
+	log := AsyncLogger(NewLogger("TEST", LevelTrace, &buf), n)
+	for i := 0; i < n; i++ {
+		log.Infof("msg %d", i)
+	}
+	log.Close()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != n {
+		t.Fatalf("expected %d lines, got %d", n, len(lines))
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, fmt.Sprintf("msg %d", i)) {
+			t.Fatalf("line %d out of order: %q", i, line)
+		}
+	}
+
+	// Logging after Close is synchronous.
+	buf.Reset()
+	log.Info("after close")
+	if !strings.Contains(buf.String(), "after close") {
+		t.Fatalf("message after close not written")
+	}
+
+	// Drop accounting when overfull.
+	w := &gatedWriter{started: make(chan struct{}), release: make(chan struct{})}
+	const bufSize, extra = 4, 3
+	log = AsyncLogger(NewLogger("TEST", LevelTrace, w), bufSize)
+	log.Info("first") // picked up by the writer, which blocks
+	<-w.started
+	sub := log.SubLogger("SUB") // shares the queue
+	for i := 0; i < bufSize+extra; i++ {
+		sub.Infof("queued %d", i)
+	}
+	if dropped := log.(*asyncLogger).Dropped(); dropped != extra {
+		t.Fatalf("expected %d dropped, got %d", extra, dropped)
+	}
+	close(w.release)
+	log.Close()
+	out := w.String()
+	if !strings.Contains(out, fmt.Sprintf("dropped %d log messages", extra)) {
+		t.Fatalf("dropped notice not written: %q", out)
+	}
+	if !strings.Contains(out, fmt.Sprintf("TEST[SUB]: queued %d", bufSize-1)) {
+		t.Fatalf("queued message not written: %q", out)
+	}
+	if strings.Contains(out, fmt.Sprintf("queued %d", bufSize)) {
+		t.Fatalf("dropped message was written: %q", out)
+	}
+}
+
+func TestLogRotatorCustom(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LogRotatorCustom(dir, "test.log", 0, 1); err == nil {
+		t.Fatalf("no error for zero maxKB")
+	}
+	if _, err := LogRotatorCustom(dir, "test.log", 1, 0); err == nil {
+		t.Fatalf("no error for zero maxRolls")
+	}
+
+	r, err := LogRotatorCustom(dir, "test.log", 1, 2)
+	if err != nil {
+		t.Fatalf("LogRotatorCustom error: %v", err)
+	}
+	line := []byte(strings.Repeat("x", 99) + "\n")
+	for i := 0; i < 25; i++ { // 2.5 KB
+		if _, err := r.Write(line); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	rolled, err := filepath.Glob(filepath.Join(dir, "test.log.*"))
+	if err != nil {
+		t.Fatalf("Glob error: %v", err)
+	}
+	if len(rolled) == 0 {
+		t.Fatalf("log file was not rolled")
+	}
+}
+
+func TestMemoryLogger(t *testing.T) {
+	log, buf := NewMemoryLogger(LevelInfo)
+	log.Debug("hidden")
+	log.Warnf("warning %d", 1)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			log.SubLogger("SUB").Infof("concurrent %d", i)
+		}(i)
+	}
+	wg.Wait()
+	log.Meter("caller", time.Hour).Info("metered")
+	log.Meter("caller", time.Hour).Info("metered")
+
+	if buf.Contains("hidden") {
+		t.Fatalf("message below log level was recorded")
+	}
+	if !buf.Contains("warning 1") {
+		t.Fatalf("warning not recorded")
+	}
+	if !buf.Contains("TEST[SUB]: concurrent 7") {
+		t.Fatalf("sublogger message not recorded")
+	}
+	if n := len(buf.Lines()); n != 10 {
+		t.Fatalf("expected 10 lines, got %d", n)
+	}
+	buf.Reset()
+	if len(buf.Lines()) != 0 {
+		t.Fatalf("lines not cleared")
+	}
+}
+
+func TestDedupLogger(t *testing.T) {
+	const window = 100 * time.Millisecond
+	base, buf := NewMemoryLogger(LevelDebug)
+	log := base.Dedup(window)
+
+	for i := 0; i < 5; i++ {
+		log.Errorf("rpc error: %s", "connection refused")
+	}
+	log.Warnf("rpc error: %s", "connection refused") // different level
+	log.Error("other error")
+	log.Trace("hidden")
+
+	lines := buf.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %v", len(lines), lines)
+	}
+	if buf.Contains("repeated") {
+		t.Fatalf("summary logged before the window closed")
+	}
+
+	time.Sleep(window * 2)
+	if !buf.Contains("[ERR] TEST: rpc error: connection refused (repeated 4 times)") {
+		t.Fatalf("no summary of duplicates: %v", buf.Lines())
+	}
+	if n := len(buf.Lines()); n != 4 {
+		t.Fatalf("expected 4 lines after summary, got %d: %v", n, buf.Lines())
+	}
+
+	// After the window, the message is logged again.
+	buf.Reset()
+	log.Errorf("rpc error: %s", "connection refused")
+	if !buf.Contains("rpc error: connection refused") {
+		t.Fatalf("message not logged after the window closed")
+	}
+
+	// A non-positive window does not wrap the logger.
+	if base.Dedup(0) != base {
+		t.Fatalf("zero window wrapped the logger")
+	}
+}
+
+func TestNameStyle(t *testing.T) {
+	for _, tt := range []struct {
+		style NameStyle
+		want  string
+	}{
+		{NameStyleBracket, "A[B][C]: three levels"},
+		{NameStyleDotted, "A.B.C: three levels"},
+	} {
+		var buf bytes.Buffer
+		lm, err := NewLoggerMaker(&buf, "info")
+		if err != nil {
+			t.Fatalf("NewLoggerMaker error: %v", err)
+		}
+		lm.NameStyle = tt.style
+		log := lm.Logger("A").SubLogger("B").With().SubLogger("C")
+		log.Info("three levels")
+		if line := buf.String(); !strings.Contains(line, tt.want) {
+			t.Fatalf("style %d: expected %q in %q", tt.style, tt.want, line)
+		}
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		tf   TimeFormat
+		re   string
+	}{
+		{"millis", TimeFormatMillis, `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3} \[INF\] TEST: hi$`},
+		{"micros", TimeFormatMicros, `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{6} \[INF\] TEST: hi$`},
+		{"rfc3339nano", TimeFormatRFC3339Nano, `^(\S+) \[INF\] TEST: hi$`},
+	} {
+		buf := new(LogBuffer)
+		NewLoggerWithTimeFormat("TEST", LevelInfo, buf, tt.tf, true).Info("hi")
+		lines := buf.Lines()
+		if len(lines) != 1 {
+			t.Fatalf("%s: expected 1 line, got %d", tt.name, len(lines))
+		}
+		m := regexp.MustCompile(tt.re).FindStringSubmatch(lines[0])
+		if m == nil {
+			t.Fatalf("%s: line %q does not match %s", tt.name, lines[0], tt.re)
+		}
+		if tt.tf == TimeFormatRFC3339Nano {
+			ts, err := time.Parse(time.RFC3339Nano, m[1])
+			if err != nil {
+				t.Fatalf("%s: error parsing timestamp: %v", tt.name, err)
+			}
+			if _, offset := ts.Zone(); offset != 0 || !strings.HasSuffix(m[1], "Z") {
+				t.Fatalf("%s: timestamp %q not in UTC", tt.name, m[1])
+			}
+		}
+	}
+
+	// Subloggers of a LoggerMaker use the same format.
+	buf := new(LogBuffer)
+	lm, err := NewLoggerMakerWithTimeFormat(buf, "info", TimeFormatMicros, false)
+	if err != nil {
+		t.Fatalf("NewLoggerMakerWithTimeFormat error: %v", err)
+	}
+	lm.Logger("A").SubLogger("B").Info("hi")
+	re := regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{6} \[INF\] A\[B\]: hi$`)
+	if lines := buf.Lines(); len(lines) != 1 || !re.MatchString(lines[0]) {
+		t.Fatalf("wrong sublogger output %q", lines)
+	}
+}
+
+func TestLoggerMakerSetWriter(t *testing.T) {
+	buf1, buf2 := new(LogBuffer), new(LogBuffer)
+	lm, err := NewLoggerMaker(buf1, "info")
+	if err != nil {
+		t.Fatalf("NewLoggerMaker error: %v", err)
+	}
+	log := lm.Logger("A")
+	subLog := log.SubLogger("B")
+	log.Info("one")
+	subLog.Info("two")
+
+	lm.SetWriter(buf2)
+	log.Info("three")
+	subLog.Info("four")
+	lm.Logger("C").Info("five") // created after the swap
+
+	check := func(buf *LogBuffer, exp ...string) {
+		t.Helper()
+		lines := buf.Lines()
+		if len(lines) != len(exp) {
+			t.Fatalf("expected %d lines, got %d: %q", len(exp), len(lines), lines)
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, exp[i]) {
+				t.Fatalf("line %d: expected %q suffix, got %q", i, exp[i], line)
+			}
+		}
+	}
+	check(buf1, "A: one", "A[B]: two")
+	check(buf2, "A: three", "A[B]: four", "C: five")
+}
+
+func TestLevelCounts(t *testing.T) {
+	buf := new(LogBuffer)
+	lm, err := NewLoggerMaker(buf, "debug")
+	if err != nil {
+		t.Fatalf("NewLoggerMaker error: %v", err)
+	}
+	lm.CountLevels = true
+
+	log := lm.Logger("A")
+	log.Trace("below level, not counted")
+	log.Debug("d")
+	log.Infof("i %d", 1)
+	log.Info("i 2")
+	log.Warnf("w")
+	log.With("k", "v").Error("shared with parent")
+	log.Criticalf("c")
+
+	sub := log.SubLogger("B")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub.Errorf("e %d", i)
+		}()
+	}
+	wg.Wait()
+
+	checkCounts := func(lggr Logger, want map[slog.Level]uint64) {
+		t.Helper()
+		lc, ok := lggr.(LevelCounter)
+		if !ok {
+			t.Fatalf("logger is not a LevelCounter")
+		}
+		counts := lc.Counts()
+		for _, lvl := range []slog.Level{LevelTrace, LevelDebug, LevelInfo, LevelWarn, LevelError, LevelCritical} {
+			if counts[lvl] != want[lvl] {
+				t.Fatalf("wrong %s count: wanted %d, got %d", lvl, want[lvl], counts[lvl])
+			}
+		}
+	}
+	checkCounts(log, map[slog.Level]uint64{LevelDebug: 1, LevelInfo: 2, LevelWarn: 1, LevelError: 1, LevelCritical: 1})
+	checkCounts(sub, map[slog.Level]uint64{LevelError: 10})
+
+	// Not counting by default.
+	lm.CountLevels = false
+	if counts := lm.Logger("C").(LevelCounter).Counts(); counts != nil {
+		t.Fatalf("counts for logger that is not counting: %v", counts)
+	}
+}
+
+func TestColorLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := newColorLogger("TEST", slog.LevelTrace, &buf, true)
+	sub := log.SubLogger("SUB")
+
+	log.Errorf("error message")
+	if !strings.Contains(buf.String(), "\x1b[31m[ERR]\x1b[0m TEST: error message") {
+		t.Fatalf("error not colored: %q", buf.String())
+	}
+	buf.Reset()
+	sub.Warnf("warning message")
+	if !strings.Contains(buf.String(), "\x1b[33m[WRN]\x1b[0m TEST[SUB]: warning message") {
+		t.Fatalf("sublogger warning not colored: %q", buf.String())
+	}
+	buf.Reset()
+	log.Infof("info [message]")
+	if strings.Contains(buf.String(), "\x1b[") || !strings.Contains(buf.String(), "[INF] TEST: info [message]") {
+		t.Fatalf("info message modified: %q", buf.String())
+	}
+
+	buf.Reset()
+	log = newColorLogger("TEST", slog.LevelTrace, &buf, false)
+	log.Errorf("error message")
+	log.SubLogger("SUB").Warnf("warning message")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Fatalf("colored with color disabled: %q", buf.String())
+	}
+}
+
+func TestLoggerWithCaller(t *testing.T) {
+	buf := new(LogBuffer)
+	lm, err := NewLoggerMaker(buf, "info")
+	if err != nil {
+		t.Fatalf("NewLoggerMaker error: %v", err)
+	}
+	lm.WithCaller = true
+	lm.CountLevels = true // more wrapping loggers
+
+	checkCaller := func(line int, msg string) {
+		t.Helper()
+		want := fmt.Sprintf("logging_test.go:%d: %s", line, msg)
+		if !buf.Contains(want) {
+			t.Fatalf("no %q in log lines %q", want, buf.Lines())
+		}
+	}
+
+	log := lm.Logger("A")
+	_, _, line, _ := runtime.Caller(0)
+	log.Infof("formatted %d", 1)
+	checkCaller(line+1, "formatted 1")
+
+	_, _, line, _ = runtime.Caller(0)
+	log.SubLogger("B").Warn("from sublogger")
+	checkCaller(line+1, "from sublogger")
+
+	_, _, line, _ = runtime.Caller(0)
+	log.With("k", "v").Error("with fields")
+	checkCaller(line+1, "k=v: with fields")
+
+	_, _, line, _ = runtime.Caller(0)
+	log.Dedup(time.Minute).Info("deduped")
+	checkCaller(line+1, "deduped")
+
+	// Off by default.
+	buf.Reset()
+	lm.WithCaller = false
+	lm.Logger("C").Info("no caller")
+	if lines := buf.Lines(); len(lines) != 1 || strings.Contains(lines[0], "logging_test.go") {
+		t.Fatalf("unexpected log lines without caller: %q", lines)
+	}
+}
@@ -805,6 +805,13 @@ func TestConnect(t *testing.T) {
 	if connectBack.Time != connect.Time {
 		t.Fatal(connectBack.Time, connect.Time)
 	}
+
+	// The client version is appended only if set.
+	connect.ClientVersion = "1.0.4"
+	b = connect.Serialize()
+	if !bytes.Equal(b, append(exp, "1.0.4"...)) {
+		t.Fatalf("unexpected serialization with client version. got %x", b)
+	}
 }
 
 func TestPenalty(t *testing.T) {
@@ -1152,3 +1159,58 @@ func randomCoin() *Coin {
 		Redeem:  randomBytes(25),
 	}
 }
+
+func TestBondAssetStrength(t *testing.T) {
+	linear := &BondAsset{Amt: 10}
+	scheduled := &BondAsset{Amt: 10, BondTierSchedule: []uint64{10, 25, 50}}
+	for _, tt := range []struct {
+		ba       *BondAsset
+		amt      uint64
+		strength uint32
+	}{
+		{linear, 9, 0},
+		{linear, 10, 1},
+		{linear, 35, 3},
+		{scheduled, 9, 0},
+		{scheduled, 24, 1},
+		{scheduled, 25, 2},
+		{scheduled, 50, 3},
+		{scheduled, 500, 3}, // capped at the schedule
+	} {
+		if strength := tt.ba.Strength(tt.amt); strength != tt.strength {
+			t.Fatalf("wrong strength for %d with schedule %v. wanted %d, got %d", tt.amt, tt.ba.BondTierSchedule, tt.strength, strength)
+		}
+	}
+	for _, tt := range []struct {
+		ba       *BondAsset
+		strength uint64
+		amt      uint64
+	}{
+		{linear, 0, 0},
+		{linear, 4, 40},
+		{scheduled, 1, 10},
+		{scheduled, 3, 50},
+		{scheduled, 5, 75}, // 50 + 25 in a second bond
+		{scheduled, 6, 100},
+	} {
+		if amt := tt.ba.Amount(tt.strength); amt != tt.amt {
+			t.Fatalf("wrong amount for strength %d with schedule %v. wanted %d, got %d", tt.strength, tt.ba.BondTierSchedule, tt.amt, amt)
+		}
+	}
+	if scheduled.MaxStrength() != 3 || linear.MaxStrength() != 0 {
+		t.Fatalf("wrong max strength")
+	}
+}
+
+func TestMatchStatusResultSerialize(t *testing.T) {
+	mid := randomBytes(32)
+	r1 := &MatchStatusResult{MatchID: mid, MakerContract: []byte{1, 2}, TakerContract: []byte{3}}
+	r2 := &MatchStatusResult{MatchID: mid, MakerContract: []byte{1}, TakerContract: []byte{2, 3}}
+	if bytes.Equal(r1.Serialize(), r2.Serialize()) {
+		t.Fatalf("different field splits serialized identically")
+	}
+	// matchid + status + 7 length prefixes + 3 field bytes + active
+	if n := len(r1.Serialize()); n != 32+1+7*4+3+1 {
+		t.Fatalf("wrong serialization length %d", n)
+	}
+}
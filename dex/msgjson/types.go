@@ -99,6 +99,9 @@ const (
 	RPCUpdateRunningBotInvError          // 81
 	RPCMMStatusError                     // 82
 	RPCBridgeError                       // 83
+	ReplayedConnectError                 // 84
+	OutdatedClientError                  // 85
+	TooManyBondsError                    // 86
 )
 
 // Routes are destinations for a "payload" of data. The type of data being
@@ -196,6 +199,10 @@ const (
 	// HealthRoute is the client-originating request-type message requesting the
 	// DEX's health status.
 	HealthRoute = "healthy"
+	// ServerTimeRoute is the client-originating request-type message requesting
+	// the server's signed current time, allowing the client to measure its
+	// clock skew before connecting.
+	ServerTimeRoute = "server_time"
 	// MatchProofRoute is the DEX-originating notification-type message
 	// delivering match cycle results to the client.
 	MatchProofRoute = "match_proof"
@@ -521,7 +528,10 @@ type MatchRequest struct {
 }
 
 // MatchStatusResult is the successful result for the MatchStatusRoute request.
+// The server signs the result so that the client has proof of the reported
+// status.
 type MatchStatusResult struct {
+	Signature
 	MatchID       Bytes `json:"matchid"`
 	Status        uint8 `json:"status"`
 	MakerContract Bytes `json:"makercontract,omitempty"`
@@ -536,6 +546,36 @@ type MatchStatusResult struct {
 	// match status is MakerSwapCast and TakerSwapCast, respectively.
 	MakerTxData Bytes `json:"makertx,omitempty"`
 	TakerTxData Bytes `json:"takertx,omitempty"`
+	// MakerLockTime and TakerLockTime are the refund lock times of the
+	// contracts, in unix seconds, when the contracts are known to the server
+	// and their lock times could be determined. Like the tx data, they are
+	// not signed.
+	MakerLockTime uint64 `json:"makerlocktime,omitempty"`
+	TakerLockTime uint64 `json:"takerlocktime,omitempty"`
+}
+
+var _ Signable = (*MatchStatusResult)(nil)
+
+// Serialize serializes the MatchStatusResult data. The tx data is not
+// included. Each of the optional, variable-length fields is prefixed with its
+// length so that distinct results cannot serialize identically.
+func (r *MatchStatusResult) Serialize() []byte {
+	// MatchStatusResult serialization is matchid (32) + status (1) +
+	// contracts (4 + 97 ish each) + swap and redeem coin IDs (4 + 36 ish
+	// each) + secret (4 + 32) + active (1) = 432
+	s := make([]byte, 0, 432)
+	s = append(s, r.MatchID...)
+	s = append(s, r.Status)
+	for _, b := range [][]byte{r.MakerContract, r.TakerContract, r.MakerSwap,
+		r.TakerSwap, r.MakerRedeem, r.TakerRedeem, r.Secret} {
+		s = append(s, uint32Bytes(uint32(len(b)))...)
+		s = append(s, b...)
+	}
+	var active byte
+	if r.Active {
+		active = 1
+	}
+	return append(s, active)
 }
 
 // OrderStatusRequest details an order for the OrderStatusRoute request. The
@@ -550,6 +590,9 @@ type OrderStatusRequest struct {
 type OrderStatus struct {
 	ID     Bytes  `json:"id"`
 	Status uint16 `json:"status"`
+	// BookedEpochs is the number of whole epochs that the order has spent on
+	// the book. For orders that were never booked, this is zero.
+	BookedEpochs uint64 `json:"bookedEpochs,omitempty"`
 }
 
 // Init is the payload for a client-originating InitRoute request.
@@ -929,21 +972,37 @@ type PreimageResponse struct {
 	Preimage Bytes `json:"pimg"`
 }
 
+// ServerTime is the result for the ServerTimeRoute request.
+type ServerTime struct {
+	Signature
+	Time uint64 `json:"time"` // unix ms
+}
+
+// Serialize serializes the ServerTime data for the signature.
+func (st *ServerTime) Serialize() []byte {
+	return uint64Bytes(st.Time)
+}
+
 // Connect is the payload for a client-originating ConnectRoute request.
 type Connect struct {
 	Signature
 	AccountID  Bytes  `json:"accountid"`
 	APIVersion uint16 `json:"apiver"`
 	Time       uint64 `json:"timestamp"`
+	// ClientVersion is the optional semantic version of the client software,
+	// e.g. 1.0.4 or 1.0.4-pre.
+	ClientVersion string `json:"clientver,omitempty"`
 }
 
 // Serialize serializes the Connect data.
 func (c *Connect) Serialize() []byte {
-	// serialization: account ID (32) + api version (2) + timestamp (8) = 42 bytes
-	s := make([]byte, 0, 42)
+	// serialization: account ID (32) + api version (2) + timestamp (8) +
+	// client version (variable, only if set) = 42+ bytes
+	s := make([]byte, 0, 42+len(c.ClientVersion))
 	s = append(s, c.AccountID...)
 	s = append(s, uint16Bytes(c.APIVersion)...)
-	return append(s, uint64Bytes(c.Time)...)
+	s = append(s, uint64Bytes(c.Time)...)
+	return append(s, c.ClientVersion...)
 }
 
 // Bond is information on a fidelity bond. This is part of the ConnectResult and
@@ -957,6 +1016,32 @@ type Bond struct {
 	Strength uint32 `json:"strength"`
 }
 
+// BondRenewAdvice is a recommendation to post a new bond because active bonds
+// of the asset will soon expire.
+type BondRenewAdvice struct {
+	AssetID  uint32 `json:"assetID"`
+	Strength uint32 `json:"strength"` // total strength of the expiring bonds
+	Expiry   uint64 `json:"expiry"`   // earliest expiry of the expiring bonds
+}
+
+// Forgiveness kinds. See Forgiveness.
+const (
+	ForgivenAccount  = "account"
+	ForgivenPreimage = "preimage"
+	ForgivenMatch    = "match"
+	ForgivenOrder    = "order"
+)
+
+// Forgiveness describes an outcome that was forgiven by the server operator.
+type Forgiveness struct {
+	// Kind is ForgivenAccount if all of the account's violations were
+	// forgiven, otherwise the kind of the forgiven outcome.
+	Kind string `json:"kind"`
+	// ID is the order or match ID of the forgiven outcome. It is empty for
+	// ForgivenAccount.
+	ID Bytes `json:"id,omitempty"`
+}
+
 // ConnectResult is the result for the ConnectRoute request.
 //
 // TODO: Include penalty data as specified in the spec.
@@ -967,6 +1052,17 @@ type ConnectResult struct {
 	Score               int32               `json:"score"`
 	ActiveBonds         []*Bond             `json:"activeBonds"`
 	Reputation          *account.Reputation `json:"reputation"`
+	BondRenewAdvice     []*BondRenewAdvice  `json:"bondRenewAdvice,omitempty"`
+	// RecentForgiveness lists the account's most recently forgiven outcomes,
+	// newest first.
+	RecentForgiveness []*Forgiveness `json:"recentForgiveness,omitempty"`
+	// Truncated indicates that ActiveOrderStatuses was limited by the server.
+	// The remainder may be requested with the OrderStatusRoute. ActiveMatches
+	// is never truncated.
+	Truncated bool `json:"truncated,omitempty"`
+	// TierLimit is the maximum order quantity per epoch, in base asset atoms,
+	// for the user's current tier. Zero means no tier limit.
+	TierLimit uint64 `json:"tierLimit,omitempty"`
 }
 
 // TierChangedNotification is the dex-originating notification sent when the
@@ -1254,6 +1350,50 @@ type BondAsset struct {
 	ID      uint32 `json:"id"`
 	Confs   uint32 `json:"confs"`
 	Amt     uint64 `json:"amount"` // to be implied by bond version?
+	// BondTierSchedule, if set, is the cumulative bond amount required for
+	// each strength, i.e. BondTierSchedule[n-1] is the amount required for a
+	// bond of strength n, allowing non-linear tiers. If empty, each Amt of the
+	// bond is one strength.
+	BondTierSchedule []uint64 `json:"bondTierSchedule,omitempty"`
+}
+
+// Strength is the strength of a single bond of amount amt. With a
+// BondTierSchedule, the strength is the number of schedule amounts that amt
+// meets, so amounts beyond the last schedule amount add no strength. Without
+// a schedule, the strength is linear in Amt.
+func (ba *BondAsset) Strength(amt uint64) uint32 {
+	if len(ba.BondTierSchedule) == 0 {
+		return uint32(amt / ba.Amt)
+	}
+	var strength uint32
+	for _, req := range ba.BondTierSchedule {
+		if amt < req {
+			break
+		}
+		strength++
+	}
+	return strength
+}
+
+// Amount is the total bond amount required for the specified strength. With
+// a BondTierSchedule, no single bond is stronger than MaxStrength, so the
+// amount for a greater strength includes the additional bonds required.
+func (ba *BondAsset) Amount(strength uint64) uint64 {
+	n := uint64(len(ba.BondTierSchedule))
+	if n == 0 {
+		return strength * ba.Amt
+	}
+	amt := (strength / n) * ba.BondTierSchedule[n-1]
+	if rem := strength % n; rem > 0 {
+		amt += ba.BondTierSchedule[rem-1]
+	}
+	return amt
+}
+
+// MaxStrength is the greatest strength of a single bond, or zero if there is
+// no BondTierSchedule limiting it.
+func (ba *BondAsset) MaxStrength() uint64 {
+	return uint64(len(ba.BondTierSchedule))
 }
 
 // ConfigResult is the successful result for the ConfigRoute.
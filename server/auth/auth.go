@@ -8,10 +8,14 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"decred.org/dcrdex/dex"
@@ -26,6 +30,7 @@ import (
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -35,23 +40,115 @@ const (
 
 	maxIDsPerOrderStatusRequest = 10_000
 
-	// freeCancelThreshold is the minimum number of epochs a user should wait before
-	// placing a cancel order, if they want to avoid penalization. It is set to 2,
-	// which means if a user places a cancel order in the same epoch as its limit
-	// order, or the next epoch, the user will be penalized. This value is chosen
-	// because it is the minimum value such that the order remains booked for at
-	// least one full epoch and one full match cycle.
-	freeCancelThreshold = 2
+	// DefaultMatchStatusCacheTTL is the default duration for which a
+	// 'match_status' result is reused for repeated requests.
+	DefaultMatchStatusCacheTTL = 5 * time.Second
+
+	// connectReplayRetention is how long a user's 'connect' times are
+	// remembered for replay protection.
+	connectReplayRetention = time.Hour
+
+	// DefaultFreeCancelEpochGap is the default minimum number of epochs
+	// between a limit order and its cancel order for the cancel to avoid
+	// penalization. It is set to 2, which means if a user places a cancel
+	// order in the same epoch as its limit order, or the next epoch, the user
+	// will be penalized. This value is chosen because it is the minimum value
+	// such that the order remains booked for at least one full epoch and one
+	// full match cycle.
+	DefaultFreeCancelEpochGap = 2
+
+	// DefaultPreimageTimeout is the default time allowed for a user to respond
+	// to a preimage request.
+	DefaultPreimageTimeout = 20 * time.Second
+
+	// DefaultOutcomeRetention is the default number of each class of a
+	// user's outcomes to keep in the DB.
+	DefaultOutcomeRetention = 200
+	// DefaultOutcomePruneInterval is the default interval at which stored
+	// outcomes are pruned.
+	DefaultOutcomePruneInterval = time.Hour
+
+	// currentReputationVersion is the reputation version of accounts whose
+	// outcomes are stored in the reputation points table.
+	currentReputationVersion int16 = 1
 )
 
 var (
 	ErrUserNotConnected = dex.ErrorKind("user not connected")
+	ErrTooManyRequests  = dex.ErrorKind("too many outstanding requests")
+	ErrSignerFailure    = dex.ErrorKind("message signing unavailable")
+	ErrDuplicateRoute   = dex.ErrorKind("duplicate route")
 )
 
+// Errors for rejected 'connect' requests. The errors returned to the client
+// are msgjson.Errors with the corresponding codes.
+var (
+	ErrDrainMode             = dex.ErrorKind("server is draining")
+	ErrConnectRefused        = dex.ErrorKind("connection refused")
+	ErrBadRequest            = dex.ErrorKind("bad request")
+	ErrInvalidAccountID      = dex.ErrorKind("invalid account ID")
+	ErrUnsupportedAPIVersion = dex.ErrorKind("unsupported API version")
+	ErrAccountNotFound       = dex.ErrorKind("account not found")
+	ErrBadSignature          = dex.ErrorKind("bad signature")
+	ErrOutdatedClient        = dex.ErrorKind("outdated client")
+	ErrConnectReplay         = dex.ErrorKind("replayed connect request")
+	ErrNewerConnection       = dex.ErrorKind("account connected on a newer connection")
+	ErrAccountBanned         = dex.ErrorKind("account banned")
+	ErrAccountTempBanned     = dex.ErrorKind("account temporarily banned")
+	ErrInternal              = dex.ErrorKind("internal error")
+)
+
+// connectRejectKinds are the kinds of errors counted as connect rejection
+// reasons in Stats.
+var connectRejectKinds = []dex.ErrorKind{
+	ErrDrainMode, ErrConnectRefused, ErrBadRequest, ErrInvalidAccountID,
+	ErrUnsupportedAPIVersion, ErrAccountNotFound, ErrBadSignature,
+	ErrOutdatedClient, ErrConnectReplay, ErrNewerConnection, ErrAccountBanned,
+	ErrAccountTempBanned, ErrInternal,
+}
+
+// authError is an error of one of the auth error kinds, with the msgjson error
+// code and message for the client.
+type authError struct {
+	kind dex.ErrorKind
+	code int
+	msg  string
+}
+
+func newAuthError(kind dex.ErrorKind, code int, format string, args ...any) error {
+	return &authError{kind: kind, code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Error satisfies the error interface.
+func (e *authError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the error kind, allowing errors.Is to work.
+func (e *authError) Unwrap() error {
+	return e.kind
+}
+
+// toMsgError converts the error to a msgjson.Error for the client. Errors that
+// are not authErrors are reported as internal errors.
+func toMsgError(err error) *msgjson.Error {
+	var authErr *authError
+	if errors.As(err, &authErr) {
+		return msgjson.NewError(authErr.code, "%s", authErr.msg)
+	}
+	return msgjson.NewError(msgjson.RPCInternalError, "internal error")
+}
+
 func unixMsNow() time.Time {
 	return time.Now().Truncate(time.Millisecond).UTC()
 }
 
+// Clock provides the current time. A custom Clock may be provided to the
+// AuthManager to control time-dependent behavior in tests.
+type Clock interface {
+	Now() time.Time
+}
+
 // Storage updates and fetches account-related data from what is presumably a
 // database.
 type Storage interface {
@@ -88,6 +185,15 @@ type Signer interface {
 	PubKey() *secp256k1.PublicKey
 }
 
+// BanPolicy decides if a user should be banned when they connect, in addition
+// to the penalty threshold applied to the user's score. This allows operators
+// to implement custom ban logic, e.g. based on the rate of violations.
+type BanPolicy interface {
+	// ShouldBan is consulted with the user's conduct score and effective tier
+	// when they connect. If true, the user's link is banished.
+	ShouldBan(acctID account.AccountID, score int32, tier int64) bool
+}
+
 // FeeChecker is a function for retrieving the details for a fee payment txn.
 type FeeChecker func(assetID uint32, coinID []byte) (addr string, val uint64, confs int64, err error)
 
@@ -112,12 +218,93 @@ type BondTxParser func(assetID uint32, ver uint16, rawTx []byte) (bondCoinID []b
 // TxDataSource retrieves the raw transaction for a coin ID.
 type TxDataSource func(coinID []byte) (rawTx []byte, err error)
 
+// ContractLockTimeSource retrieves the refund lock time of the swap contract
+// with the given coin ID and contract data.
+type ContractLockTimeSource func(coinID, contract []byte) (time.Time, error)
+
 // A respHandler is the handler for the response to a DEX-originating request. A
 // respHandler has a time associated with it so that old unused handlers can be
 // detected and deleted.
 type respHandler struct {
 	f      func(comms.Link, *msgjson.Message)
-	expire *time.Timer
+	expire *time.Timer // nil if expired by a respSweeper
+	sent   time.Time
+
+	// deadline and expireFunc are used by a respSweeper.
+	deadline   time.Time
+	expireFunc func()
+}
+
+// respSweeper periodically expires the response handlers of clients, as an
+// alternative to a timer for every request. See
+// Config.RespHandlerCleanupInterval.
+type respSweeper struct {
+	mtx     sync.Mutex
+	clients map[*clientInfo]struct{} // with outstanding handlers
+}
+
+func newRespSweeper() *respSweeper {
+	return &respSweeper{clients: make(map[*clientInfo]struct{})}
+}
+
+func (s *respSweeper) add(client *clientInfo) {
+	s.mtx.Lock()
+	s.clients[client] = struct{}{}
+	s.mtx.Unlock()
+}
+
+// sweep removes the expired response handlers of all clients and calls their
+// expire functions.
+func (s *respSweeper) sweep(now time.Time) {
+	s.mtx.Lock()
+	clients := make([]*clientInfo, 0, len(s.clients))
+	for client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.mtx.Unlock()
+
+	for _, client := range clients {
+		expired, remaining := client.expiredHandlers(now)
+		if remaining == 0 {
+			s.mtx.Lock()
+			delete(s.clients, client)
+			s.mtx.Unlock()
+		}
+		for _, expire := range expired {
+			expire()
+		}
+	}
+}
+
+// latencyRing is a fixed-size ring buffer of request-response latencies.
+type latencyRing struct {
+	durs []time.Duration
+	next int  // index of the next write
+	full bool // the buffer has wrapped
+}
+
+func newLatencyRing(size int) *latencyRing {
+	return &latencyRing{durs: make([]time.Duration, size)}
+}
+
+// add records a latency, overwriting the oldest if the buffer is full.
+func (r *latencyRing) add(d time.Duration) {
+	r.durs[r.next] = d
+	r.next++
+	if r.next == len(r.durs) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// list returns the recorded latencies, oldest first.
+func (r *latencyRing) list() []time.Duration {
+	if !r.full {
+		return append([]time.Duration(nil), r.durs[:r.next]...)
+	}
+	durs := make([]time.Duration, 0, len(r.durs))
+	durs = append(durs, r.durs[r.next:]...)
+	return append(durs, r.durs[:r.next]...)
 }
 
 // clientInfo represents a DEX client, including account information and last
@@ -125,12 +312,20 @@ type respHandler struct {
 type clientInfo struct {
 	acct *account.Account
 	conn comms.Link
+	// apiVer is the communications API version negotiated with the client on
+	// connect. Route handlers may use this to branch on protocol changes.
+	apiVer uint16
 
 	mtx          sync.Mutex
 	respHandlers map[uint64]*respHandler
+	maxReqs      int           // max len(respHandlers), 0 is unlimited
+	sweeper      *respSweeper  // nil if handlers expire with timers
+	latencies    *latencyRing  // nil if latency recording is disabled
+	limiter      *rate.Limiter // nil if request rate limiting is disabled
 	tier         int64
 	score        int32
 	bonds        []*db.Bond // only confirmed and active, not pending
+	lastSeen     time.Time  // last route request or response from the client
 }
 
 // not thread-safe
@@ -193,10 +388,17 @@ func (client *clientInfo) rmHandler(id uint64) bool {
 	return found
 }
 
-// logReq associates the specified response handler with the message ID.
-func (client *clientInfo) logReq(id uint64, f func(comms.Link, *msgjson.Message), expireTime time.Duration, expire func()) {
+// logReq associates the specified response handler with the message ID. If
+// the client already has the maximum number of outstanding requests, the
+// handler is not registered and ErrTooManyRequests is returned.
+func (client *clientInfo) logReq(id uint64, f func(comms.Link, *msgjson.Message), expireTime time.Duration, expire func()) error {
 	client.mtx.Lock()
 	defer client.mtx.Unlock()
+	if client.maxReqs > 0 && len(client.respHandlers) >= client.maxReqs {
+		log.Warnf("User %v has %d outstanding requests. Refusing to send request ID %d.",
+			client.acct.ID, len(client.respHandlers), id)
+		return dex.NewError(ErrTooManyRequests, client.acct.ID.String())
+	}
 	doExpire := func() {
 		// Delete the response handler, and call the provided expire function if
 		// (*clientInfo).respHandler has not already retrieved the handler
@@ -205,10 +407,39 @@ func (client *clientInfo) logReq(id uint64, f func(comms.Link, *msgjson.Message)
 			expire()
 		}
 	}
+	now := time.Now()
+	if client.sweeper != nil {
+		client.respHandlers[id] = &respHandler{
+			f:          f,
+			sent:       now,
+			deadline:   now.Add(expireTime),
+			expireFunc: expire,
+		}
+		client.sweeper.add(client)
+		return nil
+	}
 	client.respHandlers[id] = &respHandler{
 		f:      f,
 		expire: time.AfterFunc(expireTime, doExpire),
+		sent:   now,
 	}
+	return nil
+}
+
+// expiredHandlers removes the sweeper-managed response handlers that expired
+// before now, returning their expire functions and the number of remaining
+// handlers.
+func (client *clientInfo) expiredHandlers(now time.Time) (expired []func(), remaining int) {
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	for id, handler := range client.respHandlers {
+		if handler.expire != nil || now.Before(handler.deadline) {
+			continue
+		}
+		delete(client.respHandlers, id)
+		expired = append(expired, handler.expireFunc)
+	}
+	return expired, len(client.respHandlers)
 }
 
 // respHandler extracts the response handler from the respHandlers map. If the
@@ -227,11 +458,36 @@ func (client *clientInfo) respHandler(id uint64) *respHandler {
 	// called, but we found the response handler in the map, clientInfo.expire
 	// is waiting for the lock and will return false, thus preventing the
 	// registered expire func from executing.
-	handler.expire.Stop()
+	if handler.expire != nil {
+		handler.expire.Stop()
+	}
 	delete(client.respHandlers, id)
 	return handler
 }
 
+// recordLatency records the time since the request was sent.
+func (client *clientInfo) recordLatency(sent time.Time) {
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if client.latencies != nil {
+		client.latencies.add(time.Since(sent))
+	}
+}
+
+// markSeen records t as the time of the client's latest activity.
+func (client *clientInfo) markSeen(t time.Time) {
+	client.mtx.Lock()
+	client.lastSeen = t
+	client.mtx.Unlock()
+}
+
+// seen returns the time of the client's latest activity.
+func (client *clientInfo) seen() time.Time {
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	return client.lastSeen
+}
+
 // AuthManager handles authentication-related tasks, including validating client
 // signatures, maintaining association between accounts and `comms.Link`s, and
 // signing messages with the DEX's private key. AuthManager manages requests to
@@ -244,21 +500,138 @@ type AuthManager struct {
 	parseBondTx    BondTxParser
 	checkBond      BondCoinChecker // fidelity bond amount, lockTime, acct, and confs
 	miaUserTimeout time.Duration
+	piTimeout      time.Duration
 	unbookFun      func(account.AccountID)
+	onBondPosted   func(account.AccountID, *db.Bond)
 	route          func(route string, handler comms.MsgHandler)
+	routesMtx      sync.Mutex
+	routes         map[string]struct{} // registered with route
+	clock          Clock               // nil means time.Now
 
 	bondExpiry time.Duration // a bond is expired when time.Until(lockTime) < bondExpiry
-	bondAssets map[uint32]*msgjson.BondAsset
+	// bondRenewWindow is the remaining bond lifetime below which a connecting
+	// user is advised to post a new bond. Zero disables the advice.
+	bondRenewWindow time.Duration
+	// maxBondLockTime is the maximum time from now until a new bond's lock
+	// time. Zero is unlimited.
+	maxBondLockTime time.Duration
+	// bondConfGrace is how long after submission a bond that is awaiting
+	// confirmations counts toward the user's tier on connect.
+	bondConfGrace time.Duration
+
+	bondAssetMtx sync.RWMutex
+	bondAssets   map[uint32]*msgjson.BondAsset
+	bondAssetIDs map[string]uint32 // by config name
 
 	freeCancels      bool
+	freeCancelGap    int32         // cancels with at least this epoch gap are free
+	freeCancelWindow time.Duration // cancels of younger orders are free
 	penaltyThreshold int32
+	penaltyPerTier   int32 // penaltyThreshold increase per bond tier, positive
 	cancelThresh     float64
+	newAcctGrace     int   // match outcomes before penalties apply to a new account
+	matchSample      int16 // match outcomes considered in the score
+	pimgSample       int16 // preimage outcomes considered in the score
+	maxScore         int32 // scores saturate at maxScore and minScore
+	minScore         int32
+
+	// outcomeRetention is the number of each class of a user's outcomes kept
+	// in the DB, and outcomePruneInterval is how often they are pruned.
+	outcomeRetention     int
+	outcomePruneInterval time.Duration
+
+	valueWeightedScoring bool
+	mktValueMtx          sync.Mutex
+	mktValues            map[[2]uint32]*matchValues // recent match values by [base, quote]
+
+	// apiVers are the supported communications API versions. If empty, the
+	// client's requested version is not checked.
+	apiVers []uint16
+	// minClientVer is the minimum client software version. If nil, the
+	// client's version is not checked.
+	minClientVer *dex.Semver
+	// maxOutstandingReqs is the maximum number of unanswered requests for a
+	// client. 0 means unlimited.
+	maxOutstandingReqs int
+	// respSweeper expires response handlers every respSweepInterval. It is nil
+	// if each request has its own expiration timer.
+	respSweeper       *respSweeper
+	respSweepInterval time.Duration
+
+	// banPolicy is an optional custom ban policy consulted on connect.
+	banPolicy BanPolicy
+
+	// connectFilter is an optional filter of client IP addresses consulted
+	// on connect.
+	connectFilter func(ip dex.IPKey) error
+
+	// connTagger optionally provides a custom ID for a client's connection,
+	// set on connect.
+	connTagger func(conn comms.Link) string
+
+	// connectSkew is the maximum amount a 'connect' request's time may
+	// precede the user's latest 'connect'. Zero disables replay protection.
+	connectSkew      time.Duration
+	connectStampsMtx sync.Mutex
+	connectStamps    map[account.AccountID]*connectStamps
+
+	// tempBans are the times until which accounts' 'connect' requests are
+	// refused. See TempBan.
+	tempBanMtx sync.Mutex
+	tempBans   map[account.AccountID]time.Time
+
+	// minTradingTier is the minimum effective tier required to place orders.
+	minTradingTier int64
+
+	// tierLimits is the max order quantity per epoch by effective tier.
+	tierLimits map[int64]uint64
+	// exemptAccts are accounts whose penalties never take them below tier 1.
+	exemptAccts map[account.AccountID]bool
+
+	// maxOrderStatusBatch is the maximum number of orders in an
+	// 'order_status' request.
+	maxOrderStatusBatch int
+
+	// maxConnectOrders is the maximum number of active orders included in a
+	// 'connect' response. Zero means no limit.
+	maxConnectOrders int
+
+	// maxBonds is the maximum number of active bonds an account may have.
+	// Zero means no limit.
+	maxBonds int
+
+	// reqRate and reqBurst configure each connected account's request rate
+	// limiter. A zero reqRate disables limiting.
+	reqRate  rate.Limit
+	reqBurst int
+
+	// matchStatusCache stores recent 'match_status' results so that repeated
+	// requests within matchStatusTTL do not hit the DB and tx data sources.
+	matchStatusTTL   time.Duration
+	matchStatusMtx   sync.Mutex
+	matchStatusCache map[matchStatusKey]*cachedMatchStatus
+	// matchStatusGen is incremented with every invalidation so that a result
+	// read from the DB before an invalidation is not cached after it.
+	matchStatusGen uint64
+
+	// latencyHistory is the number of request latencies recorded per client.
+	latencyHistory int
+
+	// draining is set to 1 when new connections should be refused, such as
+	// prior to shutdown. Accessed atomically.
+	draining uint32
+	// safeMode is set to 1 when the order placement routes should refuse new
+	// orders. Accessed atomically.
+	safeMode uint32
+
+	// connects counts 'connect' requests by outcome.
+	connects connectCounters
 
 	// latencyQ is a queue for fee coin waiters to deal with latency.
 	latencyQ *wait.TickerQueue
 
 	bondWaiterMtx sync.Mutex
-	bondWaiterIdx map[string]struct{}
+	bondWaiterIdx map[string]*pendingBond
 
 	connMtx   sync.RWMutex
 	users     map[account.AccountID]*clientInfo
@@ -269,8 +642,14 @@ type AuthManager struct {
 	matchOutcomes  map[account.AccountID]*latestOutcomes[*db.MatchResult]
 	preimgOutcomes map[account.AccountID]*latestOutcomes[*db.PreimageOutcome]
 	orderOutcomes  map[account.AccountID]*latestOutcomes[*db.OrderOutcome] // cancel/complete, was in clientInfo.recentOrders
+	// outcomePruneUsers are the users that had outcomes stored while not
+	// connected, which are pruned by the outcome pruner.
+	outcomePruneUsers map[account.AccountID]struct{}
 
+	txDataSrcMtx  sync.RWMutex
 	txDataSources map[uint32]TxDataSource
+	// lockTimeSources is also protected by txDataSrcMtx.
+	lockTimeSources map[uint32]ContractLockTimeSource
 
 	prepaidBondMtx sync.Mutex
 }
@@ -295,6 +674,17 @@ const (
 	DefaultPenaltyThreshold = 20
 )
 
+// Value-weighted scoring. A match penalty is scaled by the match value relative
+// to the market's typical match value, bounded by minValueWeight and
+// maxValueWeight.
+const (
+	minValueWeight = 0.5
+	maxValueWeight = 2.0
+	// matchValueWindow is the number of a market's most recently stored match
+	// values that are averaged for the market's typical match value.
+	matchValueWindow = 100
+)
+
 type Outcome = db.Outcome
 
 var outcomeScores = map[Outcome]int32{
@@ -343,12 +733,28 @@ type Config struct {
 
 	// TxDataSources are sources of tx data for a coin ID.
 	TxDataSources map[uint32]TxDataSource
+	// ContractLockTimeSources are sources of swap contract lock times, which
+	// are included in 'match_status' responses. Optional.
+	ContractLockTimeSources map[uint32]ContractLockTimeSource
 
 	// UserUnbooker is a function for unbooking all of a user's orders.
 	UserUnbooker func(account.AccountID)
+	// OnBondPosted is an optional function called after a bond, including a
+	// pre-paid bond, is recorded for an account and the user's tier is
+	// recomputed. It is called synchronously, so it should not block.
+	OnBondPosted func(acctID account.AccountID, bond *db.Bond)
 	// MiaUserTimeout is how long after a user disconnects until UserUnbooker is
 	// called for that user.
 	MiaUserTimeout time.Duration
+	// PreimageTimeout is how long a user has to respond to a preimage request
+	// before the order is revoked and a preimage miss is scored. Users on slow
+	// connections may be unfairly penalized if it is too short. This is
+	// independent of MiaUserTimeout. A user that disconnects keeps their booked
+	// orders for MiaUserTimeout, but any preimage requests for their epoch
+	// orders are still missed if they do not reconnect and respond within
+	// PreimageTimeout, which is typically much shorter. If zero,
+	// DefaultPreimageTimeout is used.
+	PreimageTimeout time.Duration
 
 	CancelThreshold float64
 	FreeCancels     bool
@@ -356,10 +762,233 @@ type Config struct {
 	// PenaltyThreshold defines the score deficit at which a user's bond is
 	// revoked.
 	PenaltyThreshold uint32
+	// PenaltyThresholdPerTier is added to the PenaltyThreshold for each tier
+	// of the user's bonds, so that users with more bonded have a larger
+	// tolerance. The effective threshold is PenaltyThreshold +
+	// PenaltyThresholdPerTier * bond tier. Zero disables scaling.
+	PenaltyThresholdPerTier int32
+	// NewAccountGraceMatches is the number of match outcomes that must be
+	// recorded for a connected user before conduct violations reduce their
+	// tier, so that a new account is not penalized for a single early miss.
+	// The user's score is still reported and recorded. Zero disables the
+	// grace period.
+	NewAccountGraceMatches int
+	// ScoringSampleSize is the number of a user's most recent match outcomes,
+	// and of their most recent preimage outcomes, that are considered in
+	// their score. A larger sample smooths out occasional failures, while a
+	// smaller one forgives past failures sooner. If zero, the last
+	// ScoringMatchLimit match outcomes and the last 40 preimage outcomes are
+	// used.
+	ScoringSampleSize int
+
+	// OutcomeRetention is the number of a user's most recent outcomes of each
+	// class (preimage, match, and order) that are kept in the DB. Older
+	// outcomes of connected users are pruned as new outcomes are recorded,
+	// while those of users that were offline are pruned periodically. The
+	// retention is never less than the number of outcomes used for scoring.
+	// If zero, DefaultOutcomeRetention is used.
+	OutcomeRetention int
+	// OutcomePruneInterval is the interval at which the outcomes of users
+	// that were offline are pruned. If zero, DefaultOutcomePruneInterval is
+	// used. If negative, they are not pruned.
+	OutcomePruneInterval time.Duration
+	// MaxScore and MinScore bound a user's score. The sum of the user's
+	// outcome scores saturates at these bounds rather than overflowing, and
+	// the clamped score is compared to the penalty threshold. If zero, the
+	// bounds are the limits of an int32, which realistic scores never reach.
+	MaxScore int32
+	MinScore int32
+
+	// SupportedAPIVers are the communications API versions that a client may
+	// request in their 'connect' request. If empty, any version is accepted.
+	SupportedAPIVers []uint16
+
+	// MinClientVersion is the minimum client software version that may
+	// connect. Clients that report an older version in their 'connect'
+	// request, or that do not report a version, are refused. If nil, any
+	// client version is accepted.
+	MinClientVersion *dex.Semver
+
+	// MaxOutstandingRequests is the maximum number of requests to a client
+	// that may be awaiting a response. Requests beyond this limit are refused
+	// until responses are received or the pending requests expire. The default
+	// of 0 means unlimited.
+	MaxOutstandingRequests int
+
+	// RespHandlerCleanupInterval is how often expired response handlers for
+	// requests to clients are swept. A positive interval replaces the timer
+	// for each request with a periodic sweep, so a handler and its expire
+	// function may be delayed by up to one interval. The default of 0 uses a
+	// timer for each request, expiring handlers immediately.
+	RespHandlerCleanupInterval time.Duration
+
+	// LatencyHistorySize is the number of most recent request-response
+	// latencies to record for each connected client. See RequestLatencies. The
+	// default of 0 disables latency recording.
+	LatencyHistorySize int
+
+	// BanPolicy is an optional policy consulted when a user connects to decide
+	// if they should be banned. If nil, no additional bans are applied.
+	BanPolicy BanPolicy
+
+	// MatchStatusCacheTTL is how long a 'match_status' result is reused for
+	// repeated requests for the same match. If zero,
+	// DefaultMatchStatusCacheTTL is used. A negative value disables caching.
+	MatchStatusCacheTTL time.Duration
+
+	// MaxOrderStatusBatch is the maximum number of orders that may be
+	// requested in a single 'order_status' request. If zero, a default limit
+	// of 10,000 is used.
+	MaxOrderStatusBatch int
+
+	// MaxConnectActiveOrders limits the number of active orders included in a
+	// 'connect' response. If the list is truncated, the response's Truncated
+	// flag is set, and the client should request the rest with 'order_status'.
+	// Active matches are never truncated, since the client revokes any of its
+	// matches that are missing from the response. Zero means no limit.
+	MaxConnectActiveOrders int
+
+	// MaxBondsPerAccount is the maximum number of active bonds that an
+	// account may have. A 'postbond' request for a new bond that would
+	// exceed the limit is rejected with a TooManyBondsError. Zero means no
+	// limit.
+	MaxBondsPerAccount int
+
+	// RequestsPerSecond is the sustained rate of requests that a connected
+	// account may make to routes registered with Route. Requests in excess
+	// of the rate, after RequestBurst, are rejected with a
+	// TooManyRequestsError. Zero disables request rate limiting.
+	RequestsPerSecond float64
+
+	// RequestBurst is the number of requests an account may make at once
+	// when limited by RequestsPerSecond. If not positive, a burst of 1 is
+	// used.
+	RequestBurst int
+
+	// MinTradingTier is the minimum effective tier a user must have to place
+	// new orders. See CanTrade. Users below this tier may still connect to
+	// complete their existing swaps. If zero, a minimum tier of 1 is used.
+	MinTradingTier int64
+
+	// TierLimits maps an effective tier to the maximum order quantity, in
+	// base asset atoms, that a user with that tier may place per epoch. The
+	// limit for the user's tier is included in the 'connect' response so that
+	// clients can self-enforce it, and markets enforce it with TierLimit when
+	// accepting new orders. Tiers without an entry are not limited.
+	TierLimits map[int64]uint64
+
+	// ExemptAccounts are trusted accounts, e.g. house liquidity bots, that
+	// are not penalized below tier 1. Their scores are still tracked and
+	// logged, and they still need a bonded tier of at least 1.
+	ExemptAccounts map[account.AccountID]bool
+
+	// ConnectTimeSkew enables replay protection for 'connect' requests. A
+	// connect is rejected if its time was already used by one of the user's
+	// connects, or precedes the user's latest connect by more than
+	// ConnectTimeSkew. Zero disables replay protection.
+	ConnectTimeSkew time.Duration
+
+	// ConnectFilter is an optional function that is called with the IP
+	// address of the client at the start of a 'connect' request. If it
+	// returns a non-nil error, the connect request is refused. This may be
+	// used to enforce jurisdictional restrictions, e.g. with a GeoIP lookup.
+	ConnectFilter func(ip dex.IPKey) error
+
+	// ConnectionTagger is an optional function that is called with an
+	// authenticated client's connection on connect. The returned string is
+	// set as the connection's custom ID, e.g. to record which frontend or
+	// region the user connected through. See ClientCustomID.
+	ConnectionTagger func(conn comms.Link) string
+
+	// Clock is an optional source of the current time. If nil, the system
+	// clock is used.
+	Clock Clock
+
+	// FreeCancelEpochGap is the minimum number of epochs between a limit
+	// order and its cancel order for the cancel to be free, i.e. not counted
+	// against the user's cancellation ratio. A cancel at exactly this gap is
+	// free. If zero, DefaultFreeCancelEpochGap is used.
+	FreeCancelEpochGap int32
+
+	// FreeCancelWindow makes the cancel of an order placed less than
+	// FreeCancelWindow before the cancel executed free, i.e. not counted
+	// against the user's cancellation ratio, regardless of the epoch gap.
+	// This forgives quick corrections of mistaken orders. Zero disables the
+	// window.
+	FreeCancelWindow time.Duration
+
+	// ValueWeightedScoring scales each match penalty by the match value
+	// relative to the typical match value for the market, so that failing a
+	// large swap counts more than failing a small one. The typical match value
+	// is the average of the market's most recently stored match values. The
+	// scale is bounded between 0.5 and 2.
+	ValueWeightedScoring bool
+
+	// BondRenewWindow is the remaining bond lifetime below which a user is
+	// advised to post a new bond in the 'connect' response. The remaining
+	// lifetime is the time until the bond expires, which is BondExpiry before
+	// its lock time. Zero disables the advice.
+	BondRenewWindow time.Duration
+
+	// MaxBondLockTime is the maximum time from now until the lock time of a
+	// posted bond. Bonds locked for longer are rejected to protect users from
+	// accidentally locking their funds for an unreasonable duration. Zero
+	// means unlimited.
+	MaxBondLockTime time.Duration
+
+	// BondConfGrace is how long after a bond is posted that it counts toward
+	// the user's tier on connect while it is still awaiting the required
+	// confirmations. This prevents a user from abruptly dropping a tier
+	// because of normal confirmation delays. Zero means pending bonds never
+	// count.
+	BondConfGrace time.Duration
+}
+
+// ValidateBondAssets checks that each bond asset has a positive bond amount
+// and a nonzero number of required confirmations, that any BondTierSchedule is
+// positive and strictly increasing, that the asset ID of a known asset symbol
+// is correct, and that no asset ID is configured under more than one name.
+func ValidateBondAssets(m map[string]*msgjson.BondAsset) error {
+	names := make(map[uint32]string, len(m))
+	for name, ba := range m {
+		if ba == nil {
+			return fmt.Errorf("bond asset %q has no configuration", name)
+		}
+		if ba.Amt == 0 {
+			return fmt.Errorf("bond asset %q has a zero bond amount", name)
+		}
+		if ba.Confs == 0 {
+			return fmt.Errorf("bond asset %q requires zero confirmations", name)
+		}
+		var prevAmt uint64
+		for i, amt := range ba.BondTierSchedule {
+			if amt <= prevAmt {
+				return fmt.Errorf("bond asset %q tier schedule amount %d for strength %d is not more than %d for the previous strength",
+					name, amt, i+1, prevAmt)
+			}
+			prevAmt = amt
+		}
+		if assetID, found := dex.BipSymbolID(name); found && assetID != ba.ID {
+			return fmt.Errorf("bond asset %q has asset ID %d, expected %d", name, ba.ID, assetID)
+		}
+		if other, found := names[ba.ID]; found {
+			return fmt.Errorf("bond assets %q and %q have the same asset ID %d", other, name, ba.ID)
+		}
+		names[ba.ID] = name
+	}
+	return nil
 }
 
-// NewAuthManager is the constructor for an AuthManager.
-func NewAuthManager(cfg *Config) *AuthManager {
+// NewAuthManager is the constructor for an AuthManager. The BondAssets are
+// checked with ValidateBondAssets.
+func NewAuthManager(cfg *Config) (*AuthManager, error) {
+	if err := ValidateBondAssets(cfg.BondAssets); err != nil {
+		return nil, err
+	}
+	piTimeout := cfg.PreimageTimeout
+	if piTimeout <= 0 {
+		piTimeout = DefaultPreimageTimeout
+	}
 	// A penalty threshold of 0 is not sensible, so have a default.
 	penaltyThreshold := int32(cfg.PenaltyThreshold)
 	if penaltyThreshold <= 0 {
@@ -369,43 +998,155 @@ func NewAuthManager(cfg *Config) *AuthManager {
 	if penaltyThreshold > 0 {
 		penaltyThreshold *= -1
 	}
+	penaltyPerTier := cfg.PenaltyThresholdPerTier
+	if penaltyPerTier < 0 {
+		penaltyPerTier = 0
+	}
+	maxOrderStatusBatch := cfg.MaxOrderStatusBatch
+	if maxOrderStatusBatch <= 0 {
+		maxOrderStatusBatch = maxIDsPerOrderStatusRequest
+	}
+	freeCancelGap := cfg.FreeCancelEpochGap
+	if freeCancelGap <= 0 {
+		freeCancelGap = DefaultFreeCancelEpochGap
+	}
+	minTradingTier := cfg.MinTradingTier
+	if minTradingTier <= 0 {
+		minTradingTier = 1
+	}
+	matchSample, pimgSample := int16(ScoringMatchLimit), int16(scoringOrderLimit)
+	if n := cfg.ScoringSampleSize; n > 0 {
+		matchSample = int16(min(n, math.MaxInt16))
+		pimgSample = matchSample
+	}
+	outcomeRetention := cfg.OutcomeRetention
+	if outcomeRetention <= 0 {
+		outcomeRetention = DefaultOutcomeRetention
+	}
+	outcomeRetention = max(outcomeRetention, int(matchSample), int(pimgSample), cancelThreshWindow)
+	outcomePruneInterval := cfg.OutcomePruneInterval
+	if outcomePruneInterval == 0 {
+		outcomePruneInterval = DefaultOutcomePruneInterval
+	}
+	maxScore, minScore := cfg.MaxScore, cfg.MinScore
+	if maxScore == 0 {
+		maxScore = math.MaxInt32
+	}
+	if minScore == 0 {
+		minScore = math.MinInt32
+	}
+	matchStatusTTL := cfg.MatchStatusCacheTTL
+	if matchStatusTTL == 0 {
+		matchStatusTTL = DefaultMatchStatusCacheTTL
+	}
 	// Re-key the maps for efficiency in AuthManager methods.
 	bondAssets := make(map[uint32]*msgjson.BondAsset, len(cfg.BondAssets))
-	for _, asset := range cfg.BondAssets {
-		bondAssets[asset.ID] = asset
+	bondAssetIDs := make(map[string]uint32, len(cfg.BondAssets))
+	for name, asset := range cfg.BondAssets {
+		// Copy so that SetBondConfs does not modify the caller's BondAsset.
+		ba := *asset
+		bondAssets[asset.ID] = &ba
+		bondAssetIDs[name] = asset.ID
 	}
 
 	auth := &AuthManager{
-		storage:          cfg.Storage,
-		signer:           cfg.Signer,
-		bondAssets:       bondAssets,
-		bondExpiry:       time.Duration(cfg.BondExpiry) * time.Second,
-		parseBondTx:      cfg.BondTxParser, // e.g. dcr's ParseBondTx
-		checkBond:        cfg.BondChecker,  // e.g. dcr's BondCoin
-		miaUserTimeout:   cfg.MiaUserTimeout,
-		unbookFun:        cfg.UserUnbooker,
-		route:            cfg.Route,
-		freeCancels:      cfg.FreeCancels,
-		penaltyThreshold: penaltyThreshold,
-		cancelThresh:     cfg.CancelThreshold,
-		latencyQ:         wait.NewTickerQueue(recheckInterval),
-		users:            make(map[account.AccountID]*clientInfo),
-		conns:            make(map[uint64]*clientInfo),
-		unbookers:        make(map[account.AccountID]*time.Timer),
-		bondWaiterIdx:    make(map[string]struct{}),
-		matchOutcomes:    make(map[account.AccountID]*latestOutcomes[*db.MatchResult]),
-		preimgOutcomes:   make(map[account.AccountID]*latestOutcomes[*db.PreimageOutcome]),
-		orderOutcomes:    make(map[account.AccountID]*latestOutcomes[*db.OrderOutcome]),
-		txDataSources:    cfg.TxDataSources,
+		storage:              cfg.Storage,
+		signer:               cfg.Signer,
+		bondAssets:           bondAssets,
+		bondExpiry:           time.Duration(cfg.BondExpiry) * time.Second,
+		bondRenewWindow:      cfg.BondRenewWindow,
+		maxBondLockTime:      cfg.MaxBondLockTime,
+		bondConfGrace:        cfg.BondConfGrace,
+		parseBondTx:          cfg.BondTxParser, // e.g. dcr's ParseBondTx
+		checkBond:            cfg.BondChecker,  // e.g. dcr's BondCoin
+		miaUserTimeout:       cfg.MiaUserTimeout,
+		piTimeout:            piTimeout,
+		unbookFun:            cfg.UserUnbooker,
+		onBondPosted:         cfg.OnBondPosted,
+		route:                cfg.Route,
+		routes:               make(map[string]struct{}),
+		freeCancels:          cfg.FreeCancels,
+		freeCancelGap:        freeCancelGap,
+		freeCancelWindow:     cfg.FreeCancelWindow,
+		valueWeightedScoring: cfg.ValueWeightedScoring,
+		mktValues:            make(map[[2]uint32]*matchValues),
+		penaltyThreshold:     penaltyThreshold,
+		penaltyPerTier:       penaltyPerTier,
+		newAcctGrace:         cfg.NewAccountGraceMatches,
+		matchSample:          matchSample,
+		pimgSample:           pimgSample,
+		outcomeRetention:     outcomeRetention,
+		outcomePruneInterval: outcomePruneInterval,
+		maxScore:             maxScore,
+		minScore:             minScore,
+		cancelThresh:         cfg.CancelThreshold,
+		latencyQ:             wait.NewTickerQueue(recheckInterval),
+		users:                make(map[account.AccountID]*clientInfo),
+		conns:                make(map[uint64]*clientInfo),
+		unbookers:            make(map[account.AccountID]*time.Timer),
+		bondWaiterIdx:        make(map[string]*pendingBond),
+		matchOutcomes:        make(map[account.AccountID]*latestOutcomes[*db.MatchResult]),
+		preimgOutcomes:       make(map[account.AccountID]*latestOutcomes[*db.PreimageOutcome]),
+		orderOutcomes:        make(map[account.AccountID]*latestOutcomes[*db.OrderOutcome]),
+		outcomePruneUsers:    make(map[account.AccountID]struct{}),
+		connects:             newConnectCounters(),
+		txDataSources:        make(map[uint32]TxDataSource, len(cfg.TxDataSources)),
+		apiVers:              cfg.SupportedAPIVers,
+		minClientVer:         cfg.MinClientVersion,
+		maxOutstandingReqs:   cfg.MaxOutstandingRequests,
+		respSweepInterval:    cfg.RespHandlerCleanupInterval,
+		latencyHistory:       cfg.LatencyHistorySize,
+		banPolicy:            cfg.BanPolicy,
+		connectFilter:        cfg.ConnectFilter,
+		connTagger:           cfg.ConnectionTagger,
+		clock:                cfg.Clock,
+		matchStatusTTL:       matchStatusTTL,
+		maxOrderStatusBatch:  maxOrderStatusBatch,
+		maxConnectOrders:     cfg.MaxConnectActiveOrders,
+		maxBonds:             cfg.MaxBondsPerAccount,
+		reqRate:              rate.Limit(cfg.RequestsPerSecond),
+		reqBurst:             cfg.RequestBurst,
+		minTradingTier:       minTradingTier,
+		tierLimits:           maps.Clone(cfg.TierLimits),
+		exemptAccts:          maps.Clone(cfg.ExemptAccounts),
+		matchStatusCache:     make(map[matchStatusKey]*cachedMatchStatus),
+		bondAssetIDs:         bondAssetIDs,
+		connectSkew:          cfg.ConnectTimeSkew,
+		connectStamps:        make(map[account.AccountID]*connectStamps),
+		tempBans:             make(map[account.AccountID]time.Time),
+	}
+
+	maps.Copy(auth.txDataSources, cfg.TxDataSources)
+	auth.lockTimeSources = maps.Clone(cfg.ContractLockTimeSources)
+	if auth.reqBurst <= 0 {
+		auth.reqBurst = 1
+	}
+	if auth.respSweepInterval > 0 {
+		auth.respSweeper = newRespSweeper()
 	}
 
 	// Unauthenticated
-	cfg.Route(msgjson.ConnectRoute, auth.handleConnect)
-	cfg.Route(msgjson.PostBondRoute, auth.handlePostBond)
-	cfg.Route(msgjson.PreValidateBondRoute, auth.handlePreValidateBond)
-	cfg.Route(msgjson.MatchStatusRoute, auth.handleMatchStatus)
-	cfg.Route(msgjson.OrderStatusRoute, auth.handleOrderStatus)
-	return auth
+	for route, handler := range map[string]comms.MsgHandler{
+		msgjson.ConnectRoute:         auth.handleConnect,
+		msgjson.PostBondRoute:        auth.handlePostBond,
+		msgjson.PreValidateBondRoute: auth.handlePreValidateBond,
+		msgjson.MatchStatusRoute:     auth.handleMatchStatus,
+		msgjson.OrderStatusRoute:     auth.handleOrderStatus,
+		msgjson.ServerTimeRoute:      auth.handleServerTime,
+	} {
+		if err := auth.registerRoute(route, handler); err != nil {
+			return nil, err
+		}
+	}
+	return auth, nil
+}
+
+// now is the current time according to the AuthManager's Clock.
+func (auth *AuthManager) now() time.Time {
+	if auth.clock == nil {
+		return time.Now()
+	}
+	return auth.clock.Now()
 }
 
 func (auth *AuthManager) unbookUserOrders(user account.AccountID) {
@@ -431,17 +1172,163 @@ func (auth *AuthManager) ExpectUsers(users map[account.AccountID]struct{}, withi
 	}
 }
 
+// EnterDrainMode causes new 'connect' requests to be refused, while already
+// authenticated users may continue to use the DEX to settle their swaps.
+func (auth *AuthManager) EnterDrainMode() {
+	if atomic.CompareAndSwapUint32(&auth.draining, 0, 1) {
+		log.Infof("Entering drain mode. New connections will be refused.")
+	}
+}
+
+// ExitDrainMode resumes accepting new 'connect' requests.
+func (auth *AuthManager) ExitDrainMode() {
+	if atomic.CompareAndSwapUint32(&auth.draining, 1, 0) {
+		log.Infof("Exiting drain mode. New connections will be accepted.")
+	}
+}
+
+// Draining is true if the AuthManager is in drain mode.
+func (auth *AuthManager) Draining() bool {
+	return atomic.LoadUint32(&auth.draining) == 1
+}
+
+// orderPlacementRoutes are the routes that are refused in safe mode. Cancel
+// orders are still accepted so that users can pull their booked orders.
+var orderPlacementRoutes = map[string]bool{
+	msgjson.LimitRoute:  true,
+	msgjson.MarketRoute: true,
+}
+
+// SetSafeMode enables or disables safe mode. In safe mode, new orders are
+// refused server-wide, e.g. while a pricing bug is investigated. Unlike drain
+// mode, new connections are accepted, and all other routes, including
+// cancels and swap settlement, continue to work.
+func (auth *AuthManager) SetSafeMode(on bool) {
+	var v uint32
+	if on {
+		v = 1
+	}
+	if atomic.SwapUint32(&auth.safeMode, v) == v {
+		return
+	}
+	if on {
+		log.Warnf("Entering safe mode. New orders will be refused.")
+	} else {
+		log.Infof("Exiting safe mode. New orders will be accepted.")
+	}
+}
+
+// SafeMode is true if the AuthManager is in safe mode.
+func (auth *AuthManager) SafeMode() bool {
+	return atomic.LoadUint32(&auth.safeMode) == 1
+}
+
+// bondAsset returns the current parameters for a bond asset.
+func (auth *AuthManager) bondAsset(assetID uint32) (*msgjson.BondAsset, bool) {
+	auth.bondAssetMtx.RLock()
+	defer auth.bondAssetMtx.RUnlock()
+	ba, ok := auth.bondAssets[assetID]
+	return ba, ok
+}
+
+// SetBondConfs sets the number of confirmations required for a bond of the
+// named asset to be accepted. Bonds already waiting for confirmations are not
+// affected.
+func (auth *AuthManager) SetBondConfs(assetName string, confs uint32) error {
+	auth.bondAssetMtx.Lock()
+	defer auth.bondAssetMtx.Unlock()
+	assetID, found := auth.bondAssetIDs[assetName]
+	if !found {
+		return fmt.Errorf("unknown bond asset %q", assetName)
+	}
+	// Replace rather than modify the BondAsset, which may be in use.
+	ba := *auth.bondAssets[assetID]
+	log.Infof("Changing required %s bond confirmations from %d to %d", assetName, ba.Confs, confs)
+	ba.Confs = confs
+	auth.bondAssets[assetID] = &ba
+	return nil
+}
+
+// ConfigSnapshot returns the effective configuration of the AuthManager for
+// diagnostics, including any changes made at runtime, e.g. with SetBondConfs.
+// Keys are the camel-cased names of the corresponding Config fields, and the
+// values are JSON-friendly. Funcs and interfaces such as the BanPolicy are only
+// reported as set or not.
+func (auth *AuthManager) ConfigSnapshot() map[string]any {
+	auth.bondAssetMtx.RLock()
+	bondAssets := make(map[string]msgjson.BondAsset, len(auth.bondAssetIDs))
+	for name, assetID := range auth.bondAssetIDs {
+		bondAssets[name] = *auth.bondAssets[assetID]
+	}
+	auth.bondAssetMtx.RUnlock()
+
+	var minClientVer string
+	if auth.minClientVer != nil {
+		minClientVer = auth.minClientVer.String()
+	}
+
+	return map[string]any{
+		"bondExpiry":              auth.bondExpiry.String(),
+		"bondRenewWindow":         auth.bondRenewWindow.String(),
+		"maxBondLockTime":         auth.maxBondLockTime.String(),
+		"bondConfGrace":           auth.bondConfGrace.String(),
+		"bondAssets":              bondAssets,
+		"miaUserTimeout":          auth.miaUserTimeout.String(),
+		"preimageTimeout":         auth.piTimeout.String(),
+		"penaltyThreshold":        -auth.penaltyThreshold, // positive, as configured
+		"penaltyThresholdPerTier": auth.penaltyPerTier,
+		"cancelThreshold":         auth.cancelThresh,
+		"freeCancels":             auth.freeCancels,
+		"freeCancelEpochGap":      auth.freeCancelGap,
+		"freeCancelWindow":        auth.freeCancelWindow.String(),
+		"valueWeightedScoring":    auth.valueWeightedScoring,
+		"newAccountGraceMatches":  auth.newAcctGrace,
+		"scoringSampleSize":       auth.matchSample,
+		"outcomeRetention":        auth.outcomeRetention,
+		"outcomePruneInterval":    auth.outcomePruneInterval.String(),
+		"maxScore":                auth.maxScore,
+		"minScore":                auth.minScore,
+		"minTradingTier":          auth.minTradingTier,
+		"tierLimits":              maps.Clone(auth.tierLimits),
+		"exemptAccounts":          len(auth.exemptAccts),
+		"supportedAPIVers":        append([]uint16{}, auth.apiVers...),
+		"minClientVersion":        minClientVer,
+		"maxOutstandingRequests":  auth.maxOutstandingReqs,
+		"respSweepInterval":       auth.respSweepInterval.String(),
+		"connectTimeSkew":         auth.connectSkew.String(),
+		"maxOrderStatusBatch":     auth.maxOrderStatusBatch,
+		"maxConnectActiveOrders":  auth.maxConnectOrders,
+		"maxBondsPerAccount":      auth.maxBonds,
+		"requestsPerSecond":       float64(auth.reqRate),
+		"requestBurst":            auth.reqBurst,
+		"matchStatusCacheTTL":     auth.matchStatusTTL.String(),
+		"latencyHistorySize":      auth.latencyHistory,
+		"banPolicy":               auth.banPolicy != nil,
+		"connectFilter":           auth.connectFilter != nil,
+		"connectionTagger":        auth.connTagger != nil,
+		"draining":                auth.Draining(),
+	}
+}
+
 // GraceLimit returns the number of initial orders allowed for a new user before
 // the cancellation rate threshold is enforced.
 func (auth *AuthManager) GraceLimit() int {
+	return GraceLimitForThreshold(auth.cancelThresh)
+}
+
+// GraceLimitForThreshold returns the number of initial orders allowed for a new
+// user before the cancellation rate threshold, thresh, is enforced.
+func GraceLimitForThreshold(thresh float64) int {
 	// Grace period if: total/(1+total) <= thresh OR total <= thresh/(1-thresh).
-	return int(math.Round(1e8*auth.cancelThresh/(1-auth.cancelThresh))) / 1e8
+	return int(math.Round(1e8*thresh/(1-thresh))) / 1e8
 }
 
 // RecordCancel records a user's executed cancel order, including the canceled
-// order ID, and the time when the cancel was executed.
-func (auth *AuthManager) RecordCancel(user account.AccountID, oid, target order.OrderID, epochGap int32, t time.Time) {
-	score := auth.recordOrderDone(user, oid, &target, epochGap, t.UnixMilli())
+// order ID, the time when the canceled order was placed, and the time when the
+// cancel was executed.
+func (auth *AuthManager) RecordCancel(user account.AccountID, oid, target order.OrderID, epochGap int32, placed, t time.Time) {
+	penalized := auth.cancelPenalized(epochGap) && !auth.inFreeCancelWindow(placed, t)
+	score := auth.recordOrderDone(user, oid, &target, penalized, t.UnixMilli())
 
 	rep, tierChanged, scoreChanged := auth.computeUserReputation(user, score)
 	effectiveTier := rep.EffectiveTier()
@@ -464,7 +1351,7 @@ func (auth *AuthManager) RecordCancel(user account.AccountID, oid, target order.
 // a swap involving the order was successfully completed and the order is no
 // longer on the books if it ever was.
 func (auth *AuthManager) RecordCompletedOrder(user account.AccountID, oid order.OrderID, t time.Time) {
-	score := auth.recordOrderDone(user, oid, nil, db.EpochGapNA, t.UnixMilli())
+	score := auth.recordOrderDone(user, oid, nil, false, t.UnixMilli())
 	rep, tierChanged, scoreChanged := auth.computeUserReputation(user, score) // may raise tier
 	if tierChanged {
 		log.Tracef("RecordCompletedOrder: tier changed for user %v strikes %d, bond tier %v => trading tier %v",
@@ -475,14 +1362,30 @@ func (auth *AuthManager) RecordCompletedOrder(user account.AccountID, oid order.
 	}
 }
 
+// cancelPenalized checks whether a cancel order with the given epoch gap counts
+// against the user's cancellation ratio. Cancels with an epoch gap of at least
+// the configured free cancel gap are free. An unknown epoch gap
+// (db.EpochGapNA) is not penalized.
+func (auth *AuthManager) cancelPenalized(epochGap int32) bool {
+	return epochGap >= 0 && epochGap < auth.freeCancelGap
+}
+
+// inFreeCancelWindow checks whether an order placed at the given time and
+// canceled at t was canceled within the configured FreeCancelWindow. A zero
+// placement time is never in the window.
+func (auth *AuthManager) inFreeCancelWindow(placed, t time.Time) bool {
+	return auth.freeCancelWindow > 0 && !placed.IsZero() && t.Sub(placed) < auth.freeCancelWindow
+}
+
 // recordOrderDone records that an order has finished processing. This can be a
 // cancel order, which matched and unbooked another order, or a trade order that
 // completed the swap negotiation. Note that in the case of a cancel, oid refers
 // to the ID of the cancel order itself, while target is non-nil for cancel
-// orders. The user's new score is returned, which can be used to compute the
-// user's tier with computeUserTier.
-func (auth *AuthManager) recordOrderDone(user account.AccountID, oid order.OrderID, target *order.OrderID, epochGap int32, tMS int64) (score int32) {
-	canceled := target != nil && epochGap >= 0 && epochGap < freeCancelThreshold
+// orders. penalized indicates whether a cancel counts against the user's
+// cancellation ratio. The user's new score is returned, which can be used to
+// compute the user's tier with computeUserTier.
+func (auth *AuthManager) recordOrderDone(user account.AccountID, oid order.OrderID, target *order.OrderID, penalized bool, tMS int64) (score int32) {
+	canceled := target != nil && penalized
 	o, err := auth.storage.AddOrderOutcome(auth.ctx, user, oid, canceled)
 	if err != nil {
 		log.Errorf("Error storing order outcome for order %s, user %s: %v", oid, user, err)
@@ -501,6 +1404,7 @@ func (auth *AuthManager) recordOrderDone(user account.AccountID, oid order.Order
 			oid, user, tMS, target)
 		return
 	}
+	auth.outcomePruneUsers[user] = struct{}{}
 	auth.violationMtx.Unlock()
 
 	// The user is currently not connected and authenticated. When the user logs
@@ -519,6 +1423,9 @@ func (auth *AuthManager) recordOrderDone(user account.AccountID, oid order.Order
 // dex.Connector interface.
 func (auth *AuthManager) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 	auth.ctx = ctx
+	if err := auth.loadMatchValues(ctx); err != nil {
+		return nil, err
+	}
 	auth.wg.Add(1)
 	go func() {
 		defer auth.wg.Done()
@@ -529,6 +1436,9 @@ func (auth *AuthManager) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 			select {
 			case <-t.C:
 				auth.checkBonds()
+				auth.pruneMatchStatusCache()
+				auth.pruneConnectStamps()
+				auth.pruneTempBans()
 			case <-ctx.Done():
 				return
 			}
@@ -541,6 +1451,31 @@ func (auth *AuthManager) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 		auth.latencyQ.Run(ctx)
 	}()
 
+	if auth.respSweeper != nil {
+		auth.wg.Add(1)
+		go func() {
+			defer auth.wg.Done()
+			auth.sweepRespHandlers(ctx)
+		}()
+	}
+
+	if auth.outcomePruneInterval > 0 {
+		auth.wg.Add(1)
+		go func() {
+			defer auth.wg.Done()
+			t := time.NewTicker(auth.outcomePruneInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					auth.pruneOutcomes(ctx)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
 	auth.wg.Add(1)
 	go func() {
 		defer auth.wg.Done()
@@ -556,11 +1491,72 @@ func (auth *AuthManager) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 	return &auth.wg, nil
 }
 
+// sweepRespHandlers expires response handlers every respSweepInterval until the
+// context is canceled.
+func (auth *AuthManager) sweepRespHandlers(ctx context.Context) {
+	t := time.NewTicker(auth.respSweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			auth.respSweeper.sweep(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pruneOutcomes deletes the stored outcomes of the users that had outcomes
+// recorded while they were not connected, keeping the latest outcomeRetention
+// outcomes of each class.
+func (auth *AuthManager) pruneOutcomes(ctx context.Context) {
+	auth.violationMtx.Lock()
+	users := make([]account.AccountID, 0, len(auth.outcomePruneUsers))
+	for user := range auth.outcomePruneUsers {
+		users = append(users, user)
+	}
+	auth.outcomePruneUsers = make(map[account.AccountID]struct{})
+	auth.violationMtx.Unlock()
+
+	n := auth.outcomeRetention
+	for _, user := range users {
+		pimgs, matches, ords, err := auth.storage.GetUserReputationData(ctx, user, n, n, n)
+		if err != nil {
+			log.Errorf("Error loading outcomes to prune for user %v: %v", user, err)
+			continue
+		}
+		prune := func(class db.OutcomeClass, kept int, oldestKept int64) {
+			if kept < n {
+				return // nothing older
+			}
+			if err := auth.storage.PruneOutcomes(ctx, user, class, oldestKept-1); err != nil {
+				log.Errorf("Error pruning outcomes of class %v for user %v: %v", class, user, err)
+			}
+		}
+		prune(db.OutcomeClassPreimage, len(pimgs), oldestOutcomeID(pimgs))
+		prune(db.OutcomeClassMatch, len(matches), oldestOutcomeID(matches))
+		prune(db.OutcomeClassOrder, len(ords), oldestOutcomeID(ords))
+	}
+	if len(users) > 0 {
+		log.Debugf("Pruned outcomes of %d users", len(users))
+	}
+}
+
+// oldestOutcomeID is the lowest DB ID of the outcomes.
+func oldestOutcomeID[T db.Outcomer](outcomes []T) int64 {
+	var oldest int64 = math.MaxInt64
+	for _, o := range outcomes {
+		oldest = min(oldest, o.ID())
+	}
+	return oldest
+}
+
 // Route wraps the comms.Route function, storing the response handler with the
 // associated clientInfo, and sending the message on the current comms.Link for
-// the client.
+// the client. If the route is already registered, the error is logged and the
+// existing handler is kept.
 func (auth *AuthManager) Route(route string, handler func(account.AccountID, *msgjson.Message) *msgjson.Error) {
-	auth.route(route, func(conn comms.Link, msg *msgjson.Message) *msgjson.Error {
+	err := auth.registerRoute(route, func(conn comms.Link, msg *msgjson.Message) *msgjson.Error {
 		client := auth.conn(conn)
 		if client == nil {
 			return &msgjson.Error{
@@ -568,12 +1564,52 @@ func (auth *AuthManager) Route(route string, handler func(account.AccountID, *ms
 				Message: "cannot use route '" + route + "' on an unauthorized connection",
 			}
 		}
+		client.markSeen(auth.now())
+		if client.limiter != nil && !client.limiter.Allow() {
+			log.Debugf("Rate limiting '%s' request for user %v", route, client.acct.ID)
+			return msgjson.NewError(msgjson.TooManyRequestsError, "too many requests to %s", route)
+		}
+		if orderPlacementRoutes[route] && auth.SafeMode() {
+			return msgjson.NewError(msgjson.TryAgainLaterError,
+				"the server is in safe mode and not accepting new orders. swaps will continue to settle")
+		}
 		msgErr := handler(client.acct.ID, msg)
 		if msgErr != nil {
 			log.Debugf("Handling of '%s' request for user %v failed: %v", route, client.acct.ID, msgErr)
 		}
 		return msgErr
 	})
+	if err != nil {
+		log.Errorf("Route: %v", err)
+	}
+}
+
+// registerRoute registers the handler with the route function provided to the
+// constructor, recording the route name for RegisteredRoutes. An error
+// wrapping ErrDuplicateRoute is returned if the route is already registered,
+// in which case the existing handler is not replaced.
+func (auth *AuthManager) registerRoute(route string, handler comms.MsgHandler) error {
+	auth.routesMtx.Lock()
+	defer auth.routesMtx.Unlock()
+	if _, found := auth.routes[route]; found {
+		return fmt.Errorf("%w: %q", ErrDuplicateRoute, route)
+	}
+	auth.routes[route] = struct{}{}
+	auth.route(route, handler)
+	return nil
+}
+
+// RegisteredRoutes returns the sorted names of the routes registered by the
+// AuthManager, including those registered via Route.
+func (auth *AuthManager) RegisteredRoutes() []string {
+	auth.routesMtx.Lock()
+	defer auth.routesMtx.Unlock()
+	routes := make([]string, 0, len(auth.routes))
+	for route := range auth.routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	return routes
 }
 
 // Message signing and signature verification.
@@ -603,17 +1639,30 @@ func (auth *AuthManager) Auth(user account.AccountID, msg, sig []byte) error {
 
 // SignMsg signs the message with the DEX private key, returning the DER encoded
 // signature. SHA256 is used to hash the message before signing it.
-func (auth *AuthManager) SignMsg(msg []byte) []byte {
+func (auth *AuthManager) SignMsg(msg []byte) ([]byte, error) {
+	if auth.signer == nil {
+		return nil, dex.NewError(ErrSignerFailure, "no signer configured")
+	}
 	hash := sha256.Sum256(msg)
-	return auth.signer.Sign(hash[:]).Serialize()
+	sig := auth.signer.Sign(hash[:])
+	if sig == nil {
+		return nil, dex.NewError(ErrSignerFailure, "no signature produced")
+	}
+	return sig.Serialize(), nil
 }
 
-// Sign signs the msgjson.Signables with the DEX private key.
-func (auth *AuthManager) Sign(signables ...msgjson.Signable) {
+// Sign signs the msgjson.Signables with the DEX private key. An error is
+// returned if signing is unavailable, in which case the signatures of any
+// remaining signables are not set.
+func (auth *AuthManager) Sign(signables ...msgjson.Signable) error {
 	for _, signable := range signables {
-		sig := auth.SignMsg(signable.Serialize())
+		sig, err := auth.SignMsg(signable.Serialize())
+		if err != nil {
+			return err
+		}
 		signable.SetSig(sig)
 	}
+	return nil
 }
 
 // Response and notification (non-request) messages
@@ -640,6 +1689,28 @@ func (auth *AuthManager) Send(user account.AccountID, msg *msgjson.Message) erro
 	return err
 }
 
+// Broadcast sends the non-Request-type msgjson.Message to every connected
+// user, returning the number of users to which the message was successfully
+// sent. Like Send, a client whose link fails is removed.
+func (auth *AuthManager) Broadcast(msg *msgjson.Message) (sent int) {
+	auth.connMtx.RLock()
+	clients := make([]*clientInfo, 0, len(auth.users))
+	for _, client := range auth.users {
+		clients = append(clients, client)
+	}
+	auth.connMtx.RUnlock()
+
+	for _, client := range clients {
+		if err := client.conn.Send(msg); err != nil {
+			log.Debugf("error broadcasting to user %v: %v", client.acct.ID, err)
+			auth.removeClient(client)
+			continue
+		}
+		sent++
+	}
+	return
+}
+
 // Notify sends a message to a client. The message should be a notification.
 // See msgjson.NewNotification.
 func (auth *AuthManager) Notify(acctID account.AccountID, msg *msgjson.Message) {
@@ -663,7 +1734,9 @@ func (auth *AuthManager) request(user account.AccountID, msg *msgjson.Message, f
 		return dex.NewError(ErrUserNotConnected, user.String())
 	}
 	// log.Tracef("Registering '%s' request ID %d for user %v (auth clientInfo)", msg.Route, msg.ID, user)
-	client.logReq(msg.ID, f, expireTimeout, expire)
+	if err := client.logReq(msg.ID, f, expireTimeout, expire); err != nil {
+		return err
+	}
 	// auth.handleResponse checks clientInfo map and the found client's request
 	// handler map, where the expire function should be found for msg.ID.
 	err := client.conn.Request(msg, auth.handleResponse, expireTimeout, func() {})
@@ -698,36 +1771,205 @@ func (auth *AuthManager) RequestWithTimeout(user account.AccountID, msg *msgjson
 	return auth.request(user, msg, f, expireTimeout, expire)
 }
 
+// RequestLatencies returns the recorded times between sending a request to
+// the user and receiving their response, oldest first. Only the most recent
+// Config.LatencyHistorySize latencies are kept. Nil is returned if the user is
+// not connected or latency recording is disabled.
+func (auth *AuthManager) RequestLatencies(acctID account.AccountID) []time.Duration {
+	client := auth.user(acctID)
+	if client == nil {
+		return nil
+	}
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if client.latencies == nil {
+		return nil
+	}
+	return client.latencies.list()
+}
+
 func (auth *AuthManager) integrateOutcomes(
 	matchOutcomes *latestOutcomes[*db.MatchResult],
 	preimgOutcomes *latestOutcomes[*db.PreimageOutcome],
 	orderOutcomes *latestOutcomes[*db.OrderOutcome],
 ) (score, successCount, piMissCount int32) {
 
+	// Sum in an int64 so that the total cannot overflow before clamping.
+	var total int64
 	if matchOutcomes != nil {
 		matchCounts := matchOutcomes.binViolations()
-		for v, count := range matchCounts {
-			score += outcomeScores[v] * int32(count)
+		if auth.valueWeightedScoring {
+			total += auth.weightedMatchScore(matchOutcomes)
+		} else {
+			for v, count := range matchCounts {
+				total += int64(outcomeScores[v]) * int64(count)
+			}
 		}
 		successCount = int32(matchCounts[db.OutcomeSwapSuccess])
 	}
 	if preimgOutcomes != nil {
 		counts := preimgOutcomes.binViolations()
 		piMissCount = int32(counts[db.OutcomePreimageMiss])
-		score += outcomeScores[db.OutcomePreimageMiss] * piMissCount
-	}
-	if !auth.freeCancels {
-		counts := orderOutcomes.binViolations()
-		successes, cancels := int32(counts[db.OutcomeOrderComplete]), int32(counts[db.OutcomeOrderCanceled])
-		totalOrds := int(successes + cancels)
-		if totalOrds > auth.GraceLimit() {
-			cancelRate := float64(cancels) / float64(totalOrds)
-			if cancelRate > auth.cancelThresh {
-				score += outcomeScores[db.OutcomeOrderCanceled]
+		total += int64(outcomeScores[db.OutcomePreimageMiss]) * int64(piMissCount)
+	}
+	if auth.excessiveCancels(orderOutcomes) {
+		total += int64(outcomeScores[db.OutcomeOrderCanceled])
+	}
+	return auth.clampScore(total), successCount, piMissCount
+}
+
+// clampScore bounds the score by the configured minimum and maximum scores.
+func (auth *AuthManager) clampScore(score int64) int32 {
+	return int32(max(min(score, int64(auth.maxScore)), int64(auth.minScore)))
+}
+
+// excessiveCancels checks if the user's cancellation rate exceeds the cancel
+// threshold, after the grace period. This is always false with free cancels.
+func (auth *AuthManager) excessiveCancels(orderOutcomes *latestOutcomes[*db.OrderOutcome]) bool {
+	if auth.freeCancels {
+		return false
+	}
+	counts := orderOutcomes.binViolations()
+	successes, cancels := int32(counts[db.OutcomeOrderComplete]), int32(counts[db.OutcomeOrderCanceled])
+	totalOrds := int(successes + cancels)
+	if totalOrds <= auth.GraceLimit() {
+		return false
+	}
+	cancelRate := float64(cancels) / float64(totalOrds)
+	return cancelRate > auth.cancelThresh
+}
+
+// outcomeBreakdown is the contribution of each outcome category to the score
+// computed by integrateOutcomes, keyed by the outcome's description.
+// Categories that do not contribute are omitted. With value-weighted scoring,
+// the contributions are rounded separately, so their sum may differ slightly
+// from the total score.
+func (auth *AuthManager) outcomeBreakdown(
+	matchOutcomes *latestOutcomes[*db.MatchResult],
+	preimgOutcomes *latestOutcomes[*db.PreimageOutcome],
+	orderOutcomes *latestOutcomes[*db.OrderOutcome],
+) map[string]int32 {
+
+	breakdown := make(map[string]int32)
+	if matchOutcomes != nil {
+		matchOutcomes.mtx.Lock()
+		scores := make(map[Outcome]float64)
+		for _, m := range matchOutcomes.outcomes {
+			s := float64(outcomeScores[m.MatchOutcome])
+			if s < 0 && auth.valueWeightedScoring {
+				s *= auth.valueWeight(m)
+			}
+			scores[m.MatchOutcome] += s
+		}
+		matchOutcomes.mtx.Unlock()
+		for v, s := range scores {
+			if s := int32(math.Round(s)); s != 0 {
+				breakdown[v.String()] = s
 			}
 		}
 	}
-	return
+	if preimgOutcomes != nil {
+		counts := preimgOutcomes.binViolations()
+		if piMissCount := int32(counts[db.OutcomePreimageMiss]); piMissCount > 0 {
+			breakdown[db.OutcomePreimageMiss.String()] = outcomeScores[db.OutcomePreimageMiss] * piMissCount
+		}
+	}
+	if auth.excessiveCancels(orderOutcomes) {
+		breakdown[db.OutcomeOrderCanceled.String()] = outcomeScores[db.OutcomeOrderCanceled]
+	}
+	return breakdown
+}
+
+// weightedMatchScore sums the scores of the match outcomes, scaling each
+// penalty by the match's valueWeight.
+func (auth *AuthManager) weightedMatchScore(matchOutcomes *latestOutcomes[*db.MatchResult]) int64 {
+	matchOutcomes.mtx.Lock()
+	defer matchOutcomes.mtx.Unlock()
+	var score float64
+	for _, m := range matchOutcomes.outcomes {
+		s := float64(outcomeScores[m.MatchOutcome])
+		if s < 0 {
+			s *= auth.valueWeight(m)
+		}
+		score += s
+	}
+	return int64(math.Round(score))
+}
+
+// valueWeight is the penalty scale for the match outcome, which is the match
+// value relative to the typical value for the market, bounded by
+// minValueWeight and maxValueWeight. If either value is unknown, the weight
+// is 1.
+func (auth *AuthManager) valueWeight(m *db.MatchResult) float64 {
+	if m.Value == 0 {
+		return 1
+	}
+	typical := auth.typicalMatchValue(m.Base, m.Quote)
+	if typical == 0 {
+		return 1
+	}
+	return math.Min(math.Max(float64(m.Value)/typical, minValueWeight), maxValueWeight)
+}
+
+// matchValues is a window of a market's most recently stored match values.
+type matchValues struct {
+	values []uint64
+	sum    float64
+}
+
+func (mv *matchValues) add(value uint64) {
+	mv.values = append(mv.values, value)
+	mv.sum += float64(value)
+	if len(mv.values) > matchValueWindow {
+		mv.sum -= float64(mv.values[0])
+		mv.values = mv.values[1:]
+	}
+}
+
+// typicalMatchValue is the average of the market's most recently stored match
+// values, or zero if none are known.
+func (auth *AuthManager) typicalMatchValue(base, quote uint32) float64 {
+	auth.mktValueMtx.Lock()
+	defer auth.mktValueMtx.Unlock()
+	mv := auth.mktValues[[2]uint32{base, quote}]
+	if mv == nil || len(mv.values) == 0 {
+		return 0
+	}
+	return mv.sum / float64(len(mv.values))
+}
+
+// recordMatchValue adds a stored match value to the market's window of recent
+// match values.
+func (auth *AuthManager) recordMatchValue(base, quote uint32, value uint64) {
+	if !auth.valueWeightedScoring || value == 0 {
+		return
+	}
+	mkt := [2]uint32{base, quote}
+	auth.mktValueMtx.Lock()
+	defer auth.mktValueMtx.Unlock()
+	mv, found := auth.mktValues[mkt]
+	if !found {
+		mv = new(matchValues)
+		auth.mktValues[mkt] = mv
+	}
+	mv.add(value)
+}
+
+// loadMatchValues loads the most recently stored match values for each market
+// from the DB, so that the typical match values depend only on the stored
+// outcomes, and not on how long the server has been running.
+func (auth *AuthManager) loadMatchValues(ctx context.Context) error {
+	if !auth.valueWeightedScoring {
+		return nil
+	}
+	matches, err := auth.storage.RecentMatchValues(ctx, matchValueWindow)
+	if err != nil {
+		return fmt.Errorf("error loading recent match values: %w", err)
+	}
+	for _, m := range matches {
+		auth.recordMatchValue(m.Base, m.Quote, m.Value)
+	}
+	return nil
 }
 
 // userScore computes an authenticated user's score from their recent order and
@@ -762,24 +2004,39 @@ func (auth *AuthManager) UserScore(user account.AccountID) (score int32, err err
 // UserReputation calculates some quantities related to the user's reputation.
 // UserReputation satisfies market.AuthManager.
 func (auth *AuthManager) UserReputation(user account.AccountID) (tier int64, score, maxScore int32, err error) {
-	maxScore = ScoringMatchLimit
+	maxScore = int32(auth.matchSample)
 	score, err = auth.UserScore(user)
 	if err != nil {
 		return
 	}
 	r, _, _ := auth.computeUserReputation(user, score)
 	if r != nil {
-		return r.EffectiveTier(), r.Score, ScoringMatchLimit, nil
+		return r.EffectiveTier(), r.Score, maxScore, nil
 
 	}
 	return
 }
 
-// userReputation computes the breakdown of a user's tier and score.
+// TierForBonds returns the bonded tier provided by the bonds at the given
+// time. As on connect, a bond only counts if it is still active at that time,
+// i.e. its LockTime is at least BondExpiry after at. Conduct penalties are not
+// considered.
+func (auth *AuthManager) TierForBonds(bonds []*db.Bond, at time.Time) (bondTier int64) {
+	lockTimeThresh := at.Add(auth.bondExpiry)
+	for _, bond := range bonds {
+		if time.Unix(bond.LockTime, 0).Before(lockTimeThresh) {
+			continue // expired
+		}
+		bondTier += int64(bond.Strength)
+	}
+	return bondTier
+}
+
+// userReputation computes the breakdown of a user's tier and score.
 func (auth *AuthManager) userReputation(bondTier int64, score int32) *account.Reputation {
 	var penalties int32
 	if score < 0 {
-		penalties = score / auth.penaltyThreshold
+		penalties = score / auth.effectivePenaltyThreshold(bondTier)
 	}
 	return &account.Reputation{
 		BondedTier: bondTier,
@@ -788,6 +2045,40 @@ func (auth *AuthManager) userReputation(bondTier int64, score int32) *account.Re
 	}
 }
 
+// inNewAccountGrace checks if the user is connected with fewer than the
+// NewAccountGraceMatches match outcomes recorded, in which case penalties do
+// not reduce their tier. violationMtx must not be held.
+func (auth *AuthManager) inNewAccountGrace(user account.AccountID) bool {
+	if auth.newAcctGrace <= 0 {
+		return false
+	}
+	auth.violationMtx.Lock()
+	matches, found := auth.matchOutcomes[user]
+	auth.violationMtx.Unlock()
+	return found && matches.len() < auth.newAcctGrace
+}
+
+// applyExemption limits the penalties of an exempt user so that their
+// effective tier is at least 1 if they have a bonded tier of at least 1.
+func (auth *AuthManager) applyExemption(user account.AccountID, rep *account.Reputation) {
+	if !auth.exemptAccts[user] || rep.BondedTier < 1 || rep.EffectiveTier() >= 1 {
+		return
+	}
+	maxPenalties := uint16(min(rep.BondedTier-1, math.MaxUint16))
+	log.Debugf("Exempt user %v with score %d limited to %d penalties instead of %d",
+		user, rep.Score, maxPenalties, rep.Penalties)
+	rep.Penalties = maxPenalties
+}
+
+// effectivePenaltyThreshold is the (negative) penalty threshold for a user
+// with the given bond tier, scaled by the penaltyPerTier.
+func (auth *AuthManager) effectivePenaltyThreshold(bondTier int64) int32 {
+	if bondTier <= 0 {
+		return auth.penaltyThreshold
+	}
+	return auth.penaltyThreshold - auth.penaltyPerTier*int32(bondTier)
+}
+
 // tier computes a user's tier from their conduct score and bond tier.
 func (auth *AuthManager) tier(bondTier int64, score int32) int64 {
 	return auth.userReputation(bondTier, score).EffectiveTier()
@@ -802,21 +2093,25 @@ func (auth *AuthManager) computeUserReputation(user account.AccountID, score int
 	client := auth.user(user)
 	if client == nil {
 		// Offline. Load active bonds and legacyFeePaid flag from DB.
-		lockTimeThresh := time.Now().Add(auth.bondExpiry)
-		_, bonds := auth.storage.Account(user, lockTimeThresh)
-		var bondTier int64
-		for _, bond := range bonds {
-			bondTier += int64(bond.Strength)
-		}
-		return auth.userReputation(bondTier, score), false, false
+		now := auth.now()
+		_, bonds := auth.storage.Account(user, now.Add(auth.bondExpiry))
+		r = auth.userReputation(auth.TierForBonds(bonds, now), score)
+		auth.applyExemption(user, r)
+		return r, false, false
 	}
 
+	grace := auth.inNewAccountGrace(user)
+
 	client.mtx.Lock()
 	defer client.mtx.Unlock()
 	wasTier := client.tier
 	wasScore := client.score
 	bondTier := client.bondTier()
 	r = auth.userReputation(bondTier, score)
+	if grace {
+		r.Penalties = 0
+	}
+	auth.applyExemption(user, r)
 	client.tier = r.EffectiveTier()
 	client.score = score
 	scoreChanged = wasScore != score
@@ -839,12 +2134,15 @@ func (auth *AuthManager) ComputeUserReputation(user account.AccountID) *account.
 	return r
 }
 
-func (auth *AuthManager) registerMatchOutcome(user account.AccountID, outcome Outcome, mmid db.MarketMatchID) (score int32) {
-	o, err := auth.storage.AddMatchOutcome(auth.ctx, user, mmid.MatchID, outcome)
+func (auth *AuthManager) registerMatchOutcome(user account.AccountID, outcome Outcome, mmid db.MarketMatchID, value uint64) (score int32) {
+	auth.invalidateMatchStatus(mmid.MatchID)
+
+	o, err := auth.storage.AddMatchOutcome(auth.ctx, user, mmid, value, outcome)
 	if err != nil {
 		log.Errorf("Error storing match outcome %s for user %s: %w", user, mmid.MatchID, err)
 		return
 	}
+	auth.recordMatchValue(mmid.Base, mmid.Quote, value)
 
 	auth.violationMtx.Lock()
 	if matchOutcomes, found := auth.matchOutcomes[user]; found {
@@ -858,6 +2156,7 @@ func (auth *AuthManager) registerMatchOutcome(user account.AccountID, outcome Ou
 		auth.violationMtx.Unlock()
 		return
 	}
+	auth.outcomePruneUsers[user] = struct{}{}
 	auth.violationMtx.Unlock()
 
 	// The user is currently not connected and authenticated. When the user logs
@@ -876,7 +2175,7 @@ func (auth *AuthManager) registerMatchOutcome(user account.AccountID, outcome Ou
 // TODO: provide lots instead of value, or convert to lots somehow. But, Swapper
 // has no clue about lot size, and neither does DB!
 func (auth *AuthManager) SwapSuccess(user account.AccountID, mmid db.MarketMatchID, value uint64, redeemTime time.Time) {
-	score := auth.registerMatchOutcome(user, db.OutcomeSwapSuccess, mmid)
+	score := auth.registerMatchOutcome(user, db.OutcomeSwapSuccess, mmid, value)
 	rep, tierChanged, scoreChanged := auth.computeUserReputation(user, score) // may raise tier
 	effectiveTier := rep.EffectiveTier()
 	log.Debugf("Match success for user %v: strikes %d, bond tier %v => tier %v",
@@ -899,7 +2198,7 @@ func (auth *AuthManager) SwapSuccess(user account.AccountID, mmid db.MarketMatch
 // TODO: provide lots instead of value, or convert to lots somehow. But, Swapper
 // has no clue about lot size, and neither does DB!
 func (auth *AuthManager) Inaction(user account.AccountID, outcome Outcome, mmid db.MarketMatchID, matchValue uint64, refTime time.Time, oid order.OrderID) {
-	score := auth.registerMatchOutcome(user, outcome, mmid)
+	score := auth.registerMatchOutcome(user, outcome, mmid, matchValue)
 
 	// Recompute tier.
 	rep, tierChanged, scoreChanged := auth.computeUserReputation(user, score)
@@ -939,6 +2238,7 @@ func (auth *AuthManager) registerPreimageOutcome(user account.AccountID, miss bo
 		auth.violationMtx.Unlock()
 		return
 	}
+	auth.outcomePruneUsers[user] = struct{}{}
 	auth.violationMtx.Unlock()
 
 	// The user is currently not connected and authenticated. When the user logs
@@ -953,6 +2253,19 @@ func (auth *AuthManager) registerPreimageOutcome(user account.AccountID, miss bo
 	return
 }
 
+// PreimageTimeout is how long a user has to respond to a preimage request.
+func (auth *AuthManager) PreimageTimeout() time.Duration {
+	return auth.piTimeout
+}
+
+// PreimageOnTime checks if a preimage received at the received time in
+// response to a request sent at the requested time is within the
+// PreimageTimeout. A preimage that is not on time should be scored with
+// MissedPreimage, even if it is otherwise valid.
+func (auth *AuthManager) PreimageOnTime(requested, received time.Time) bool {
+	return !received.Before(requested) && received.Sub(requested) <= auth.piTimeout
+}
+
 // PreimageSuccess registers an accepted preimage for the user.
 func (auth *AuthManager) PreimageSuccess(user account.AccountID, epochEnd time.Time, oid order.OrderID) {
 	score := auth.registerPreimageOutcome(user, false, oid, epochEnd)
@@ -999,13 +2312,18 @@ func (auth *AuthManager) Penalize(user account.AccountID, lastRule account.Rule,
 	details = fmt.Sprintf("%s\nLast Broken Rule Details: %s\n%s", details, lastRule.Description(), extraDetails)
 	penalty := &msgjson.Penalty{
 		Rule:    lastRule,
-		Time:    uint64(time.Now().UnixMilli()),
+		Time:    uint64(auth.now().UnixMilli()),
 		Details: details,
 	}
 	penaltyNote := &msgjson.PenaltyNote{
 		Penalty: penalty,
 	}
-	penaltyNote.Sig = auth.SignMsg(penaltyNote.Serialize())
+	sig, err := auth.SignMsg(penaltyNote.Serialize())
+	if err != nil {
+		log.Errorf("error signing penalty notification: %v", err)
+		return
+	}
+	penaltyNote.Sig = sig
 	note, err := msgjson.NewNotification(msgjson.PenaltyRoute, penaltyNote)
 	if err != nil {
 		log.Errorf("error creating penalty notification: %w", err)
@@ -1034,6 +2352,44 @@ func (auth *AuthManager) AcctStatus(user account.AccountID) (connected bool, tie
 	return
 }
 
+// CanTrade indicates if the user is connected and their effective tier is at
+// least the configured minimum trading tier. Order submission routes should
+// consult CanTrade, but users that cannot trade may still connect to settle
+// their active swaps.
+func (auth *AuthManager) CanTrade(user account.AccountID) bool {
+	client := auth.user(user)
+	if client == nil {
+		return false
+	}
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	return client.tier >= auth.minTradingTier
+}
+
+// TierLimit is the maximum order quantity per epoch for the connected user,
+// according to their effective tier and Config.TierLimits. Zero means the
+// user's orders are not limited by tier, or the user is not connected.
+func (auth *AuthManager) TierLimit(user account.AccountID) uint64 {
+	client := auth.user(user)
+	if client == nil {
+		return 0
+	}
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	return auth.tierLimits[client.tier]
+}
+
+// ClientCustomID returns the custom ID of the user's current connection, as
+// set by the Config.ConnectionTagger on connect. An empty string is returned
+// if the user is not connected or the connection was not tagged.
+func (auth *AuthManager) ClientCustomID(acctID account.AccountID) string {
+	client := auth.user(acctID)
+	if client == nil {
+		return ""
+	}
+	return client.conn.CustomID()
+}
+
 func (auth *AuthManager) reRepUser(user account.AccountID) (*account.Reputation, error) {
 	// Reload outcomes from DB. NOTE: This does not use loadUserScore because we
 	// also need to update the matchOutcomes map if the user is online.
@@ -1073,6 +2429,16 @@ func (auth *AuthManager) ForgiveMatchFail(user account.AccountID, mid order.Matc
 	if err != nil {
 		return
 	}
+	if forgiven {
+		// Forgive the failure in the user's reputation outcomes too.
+		var outcomeForgiven bool
+		if outcomeForgiven, err = auth.storage.ForgiveMatchOutcome(auth.ctx, user, mid); err != nil {
+			return
+		}
+		if !outcomeForgiven {
+			log.Debugf("No failed outcome for match %v to forgive for user %v", mid, user)
+		}
+	}
 	rep, err := auth.reRepUser(user)
 	if err != nil {
 		return
@@ -1083,6 +2449,24 @@ func (auth *AuthManager) ForgiveMatchFail(user account.AccountID, mid order.Matc
 	return
 }
 
+// ForgivePreimageMisses forgives the user's n most recent preimage misses so
+// that they no longer count against the user's score. If the user is online,
+// their score and tier are updated.
+func (auth *AuthManager) ForgivePreimageMisses(ctx context.Context, user account.AccountID, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid number of preimage misses to forgive: %d", n)
+	}
+	forgiven, err := auth.storage.ForgivePreimageMisses(ctx, user, n)
+	if err != nil {
+		return err
+	}
+	log.Infof("Forgave %d preimage misses for user %v", forgiven, user)
+	if _, err := auth.reRepUser(user); err != nil {
+		return fmt.Errorf("error updating user reputation after forgiveness: %w", err)
+	}
+	return nil
+}
+
 // CreatePrepaidBonds generates pre-paid bonds.
 func (auth *AuthManager) CreatePrepaidBonds(n int, strength uint32, durSecs int64) ([][]byte, error) {
 	coinIDs := make([][]byte, n)
@@ -1090,7 +2474,7 @@ func (auth *AuthManager) CreatePrepaidBonds(n int, strength uint32, durSecs int6
 	for i := 0; i < n; i++ {
 		coinIDs[i] = encode.RandomBytes(prepaidBondIDLength)
 	}
-	lockTime := time.Now().Add(auth.bondExpiry).Add(time.Duration(durSecs) * time.Second)
+	lockTime := auth.now().Add(auth.bondExpiry).Add(time.Duration(durSecs) * time.Second)
 	if err := auth.storage.StorePrepaidBonds(coinIDs, strength, lockTime.Unix()); err != nil {
 		return nil, err
 	}
@@ -1123,7 +2507,10 @@ func (auth *AuthManager) sendTierChanged(acctID account.AccountID, rep *account.
 		Reputation: rep,
 		Reason:     reason,
 	}
-	auth.Sign(tierChangedNtfn)
+	if err := auth.Sign(tierChangedNtfn); err != nil {
+		log.Errorf("Error signing tier changed notification for account %v: %v", acctID, err)
+		return
+	}
 	resp, err := msgjson.NewNotification(msgjson.TierChangeRoute, tierChangedNtfn)
 	if err != nil {
 		log.Error("TierChangeRoute encoding error: %v", err)
@@ -1140,7 +2527,10 @@ func (auth *AuthManager) sendScoreChanged(acctID account.AccountID, rep *account
 	note := &msgjson.ScoreChangedNotification{
 		Reputation: *rep,
 	}
-	auth.Sign(note)
+	if err := auth.Sign(note); err != nil {
+		log.Errorf("Error signing score changed notification for account %v: %v", acctID, err)
+		return
+	}
 	resp, err := msgjson.NewNotification(msgjson.ScoreChangeRoute, note)
 	if err != nil {
 		log.Error("TierChangeRoute encoding error: %v", err)
@@ -1164,7 +2554,10 @@ func (auth *AuthManager) sendBondExpired(acctID account.AccountID, bond *db.Bond
 		Tier:       effectiveTier,
 		Reputation: rep,
 	}
-	auth.Sign(bondExpNtfn)
+	if err := auth.Sign(bondExpNtfn); err != nil {
+		log.Errorf("Error signing bond expired notification for account %v: %v", acctID, err)
+		return
+	}
 	resp, err := msgjson.NewNotification(msgjson.BondExpiredRoute, bondExpNtfn)
 	if err != nil {
 		log.Error("BondExpiredRoute encoding error: %v", err)
@@ -1179,7 +2572,7 @@ func (auth *AuthManager) sendBondExpired(acctID account.AccountID, bond *db.Bond
 // checkBonds checks all connected users' bonds expiry and recomputes user tier
 // on change. This should be run on a ticker.
 func (auth *AuthManager) checkBonds() {
-	lockTimeThresh := time.Now().Add(auth.bondExpiry).Unix()
+	lockTimeThresh := auth.now().Add(auth.bondExpiry).Unix()
 
 	checkClientBonds := func(client *clientInfo) ([]*db.Bond, *account.Reputation) {
 		client.mtx.Lock()
@@ -1247,24 +2640,51 @@ func (auth *AuthManager) addBond(user account.AccountID, bond *db.Bond) *account
 	auth.violationMtx.Lock()
 	score := auth.userScore(user)
 	auth.violationMtx.Unlock()
+	grace := auth.inNewAccountGrace(user)
 
 	client.mtx.Lock()
 	defer client.mtx.Unlock()
 
 	bondTier := client.addBond(bond)
 	rep := auth.userReputation(bondTier, score)
+	if grace {
+		rep.Penalties = 0
+	}
+	auth.applyExemption(user, rep)
 	client.tier = rep.EffectiveTier()
 	client.score = score
 
 	return rep
 }
 
+// bondPosted calls the OnBondPosted callback, if set.
+func (auth *AuthManager) bondPosted(user account.AccountID, bond *db.Bond) {
+	if auth.onBondPosted != nil {
+		auth.onBondPosted(user, bond)
+	}
+}
+
 // addClient adds the client to the users and conns maps, and stops any unbook
-// timers started when they last disconnected.
-func (auth *AuthManager) addClient(client *clientInfo) {
+// timers started when they last disconnected. If the user is already
+// registered on a newer link, the client is not added, its link is
+// disconnected, and false is returned.
+func (auth *AuthManager) addClient(client *clientInfo) bool {
 	auth.connMtx.Lock()
 	defer auth.connMtx.Unlock()
 	user := client.acct.ID
+	connID := client.conn.ID()
+
+	// Concurrent connects for the same account may finish in any order. Link
+	// IDs increase with each new connection, so the link with the larger ID
+	// is the newer one and supersedes the other.
+	oldClient := auth.users[user]
+	if oldClient != nil && oldClient.conn.ID() > connID {
+		log.Warnf("User %v authorized from %v (id %d) after authorizing on newer connection from %v (id %d). Disconnecting the older one.",
+			user, client.conn.Addr(), connID, oldClient.conn.Addr(), oldClient.conn.ID())
+		client.conn.Disconnect()
+		return false
+	}
+
 	if unbookTimer, found := auth.unbookers[user]; found {
 		if unbookTimer.Stop() {
 			log.Debugf("Stopped unbook timer for user %v", user)
@@ -1272,10 +2692,7 @@ func (auth *AuthManager) addClient(client *clientInfo) {
 		delete(auth.unbookers, user)
 	}
 
-	oldClient := auth.users[user]
 	auth.users[user] = client
-
-	connID := client.conn.ID()
 	auth.conns[connID] = client
 
 	// Now that the new conn ID is registered, disconnect any existing old link
@@ -1283,7 +2700,7 @@ func (auth *AuthManager) addClient(client *clientInfo) {
 	if oldClient != nil {
 		oldConnID := oldClient.conn.ID()
 		if oldConnID == connID {
-			return // reused conn, just update maps
+			return true // reused conn, just update maps
 		}
 		log.Warnf("User %v reauthorized from %v (id %d) with an existing connection from %v (id %d). Disconnecting the old one.",
 			user, client.conn.Addr(), connID, oldClient.conn.Addr(), oldConnID)
@@ -1299,6 +2716,21 @@ func (auth *AuthManager) addClient(client *clientInfo) {
 		log.Debugf("Link down: id=%d, ip=%s.", client.conn.ID(), client.conn.Addr())
 		auth.removeClient(client) // must stop if connID already removed
 	}()
+	return true
+}
+
+// Disconnect closes the connection of the specified user and removes the
+// client, as if the user had disconnected. Unlike Banish, the user is not
+// quarantined and may reconnect immediately. The return value indicates if the
+// user was connected.
+func (auth *AuthManager) Disconnect(acctID account.AccountID) bool {
+	client := auth.user(acctID)
+	if client == nil {
+		return false
+	}
+	log.Infof("Disconnecting user %v at %v", acctID, client.conn.Addr())
+	auth.removeClient(client)
+	return true
 }
 
 // removeClient removes the client from the users and conns map, and sets a
@@ -1351,6 +2783,25 @@ func matchStatusToOutcome(s order.MatchStatus) Outcome {
 	}
 }
 
+// ReputationUpgradeStatus surveys the reputation versions of all accounts,
+// returning the number of accounts still pending an upgrade to the current
+// reputation version, and the number already upgraded. Accounts are upgraded
+// when the user next connects.
+func (auth *AuthManager) ReputationUpgradeStatus(ctx context.Context) (pending, done int, err error) {
+	counts, err := auth.storage.CountUsersByReputationVersion(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error counting users by reputation version: %w", err)
+	}
+	for ver, n := range counts {
+		if ver < currentReputationVersion {
+			pending += n
+		} else {
+			done += n
+		}
+	}
+	return pending, done, nil
+}
+
 // loadUserOutcomes returns user's latest match and preimage outcomes from order
 // and swap data retrieved from the DB.
 func (auth *AuthManager) loadUserOutcomes(user account.AccountID) (pimgs *latestOutcomes[*db.PreimageOutcome], matches *latestOutcomes[*db.MatchResult], ords *latestOutcomes[*db.OrderOutcome], err error) {
@@ -1361,7 +2812,7 @@ func (auth *AuthManager) loadUserOutcomes(user account.AccountID) (pimgs *latest
 	switch repVer {
 	case 0:
 		return auth.upgradeUserOutcomesV0(user)
-	case 1:
+	case currentReputationVersion:
 		return auth.loadUserOutcomesV1(user)
 	default:
 		return nil, nil, nil, fmt.Errorf("unknown user reputation version %d", repVer)
@@ -1371,7 +2822,7 @@ func (auth *AuthManager) loadUserOutcomes(user account.AccountID) (pimgs *latest
 func (auth *AuthManager) upgradeUserOutcomesV0(user account.AccountID) (*latestOutcomes[*db.PreimageOutcome], *latestOutcomes[*db.MatchResult], *latestOutcomes[*db.OrderOutcome], error) {
 	// Load the N most recent matches resulting in success or an at-fault match
 	// revocation for the user.
-	matchOutcomes, err := auth.storage.CompletedAndAtFaultMatchStats(user, ScoringMatchLimit)
+	matchOutcomes, err := auth.storage.CompletedAndAtFaultMatchStats(user, int(auth.matchSample))
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("CompletedAndAtFaultMatchStats: %w", err)
 	}
@@ -1381,11 +2832,14 @@ func (auth *AuthManager) upgradeUserOutcomesV0(user account.AccountID) (*latestO
 		matches = append(matches, &db.MatchResult{
 			MatchID:      m.ID,
 			MatchOutcome: legacyMatchOutcomeToOutcome(m),
+			Value:        m.Value,
+			Base:         m.Base,
+			Quote:        m.Quote,
 		})
 	}
 
 	// Load the count of preimage misses in the N most recently placed orders.
-	piOutcomes, err := auth.storage.PreimageStats(user, scoringOrderLimit)
+	piOutcomes, err := auth.storage.PreimageStats(user, int(auth.pimgSample))
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("PreimageStats: %w", err)
 	}
@@ -1409,7 +2863,7 @@ func (auth *AuthManager) upgradeUserOutcomesV0(user account.AccountID) (*latestO
 		return nil, nil, nil, err
 	}
 
-	ords := assembleCanceledOrders(oids, compTimes, cancels)
+	ords := auth.assembleCanceledOrders(oids, compTimes, cancels)
 
 	pimgs, matches, ords, err = auth.storage.UpgradeUserReputationV1(auth.ctx, user, pimgs, matches, ords)
 	if err != nil {
@@ -1417,14 +2871,17 @@ func (auth *AuthManager) upgradeUserOutcomesV0(user account.AccountID) (*latestO
 	}
 
 	log.Infof("User %s reputation upgraded to version 1", user)
+	for _, m := range matches {
+		auth.recordMatchValue(m.Base, m.Quote, m.Value)
+	}
 
-	return newLatestOutcomes(pimgs, scoringOrderLimit),
-		newLatestOutcomes(matches, ScoringMatchLimit),
+	return newLatestOutcomes(pimgs, auth.pimgSample),
+		newLatestOutcomes(matches, auth.matchSample),
 		newLatestOutcomes(ords, cancelThreshWindow),
 		nil
 }
 
-func assembleCanceledOrders(oids /* completed */ []order.OrderID, compTimes []int64, cancels []*db.CancelRecord) []*db.OrderOutcome {
+func (auth *AuthManager) assembleCanceledOrders(oids /* completed */ []order.OrderID, compTimes []int64, cancels []*db.CancelRecord) []*db.OrderOutcome {
 	type stampedOrderOutcome struct {
 		Outcome *db.OrderOutcome
 		Stamp   int64
@@ -1437,10 +2894,14 @@ func assembleCanceledOrders(oids /* completed */ []order.OrderID, compTimes []in
 		})
 	}
 	for _, o := range cancels {
+		var placed time.Time // zero if unknown
+		if o.TargetTime > 0 {
+			placed = time.UnixMilli(o.TargetTime)
+		}
 		stampedOrds = append(stampedOrds, &stampedOrderOutcome{
 			Outcome: &db.OrderOutcome{
 				OrderID:  o.ID,
-				Canceled: o.EpochGap >= 0 && o.EpochGap < freeCancelThreshold,
+				Canceled: auth.cancelPenalized(o.EpochGap) && !auth.inFreeCancelWindow(placed, time.UnixMilli(o.MatchTime)),
 			},
 			Stamp: o.MatchTime,
 		})
@@ -1459,12 +2920,12 @@ func assembleCanceledOrders(oids /* completed */ []order.OrderID, compTimes []in
 }
 
 func (auth *AuthManager) loadUserOutcomesV1(user account.AccountID) (*latestOutcomes[*db.PreimageOutcome], *latestOutcomes[*db.MatchResult], *latestOutcomes[*db.OrderOutcome], error) {
-	pimgs, matches, ords, err := auth.storage.GetUserReputationData(auth.ctx, user, scoringOrderLimit, ScoringMatchLimit, cancelThreshWindow)
+	pimgs, matches, ords, err := auth.storage.GetUserReputationData(auth.ctx, user, int(auth.pimgSample), int(auth.matchSample), cancelThreshWindow)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("error loading v1 user reputation data for user %s: %w", user, err)
 	}
-	return newLatestOutcomes(pimgs, scoringOrderLimit),
-		newLatestOutcomes(matches, ScoringMatchLimit),
+	return newLatestOutcomes(pimgs, auth.pimgSample),
+		newLatestOutcomes(matches, auth.matchSample),
 		newLatestOutcomes(ords, cancelThreshWindow), nil
 }
 
@@ -1522,44 +2983,393 @@ func (auth *AuthManager) UserMatchFails(user account.AccountID, n int) ([]*Match
 	return fails, nil
 }
 
+// ReputationOutcome is a JSON-friendly reputation outcome. ID is the match ID
+// for match outcomes, and the order ID otherwise.
+type ReputationOutcome struct {
+	ID      dex.Bytes `json:"id"`
+	Outcome string    `json:"outcome"`
+}
+
+// ReputationExport is the full reputation state of a user. See
+// ExportReputation.
+type ReputationExport struct {
+	AccountID account.AccountID `json:"accountID"`
+	account.Reputation
+	// Tier is the effective tier, accounting for penalties.
+	Tier      int64                `json:"tier"`
+	Preimages []*ReputationOutcome `json:"preimages"`
+	Matches   []*ReputationOutcome `json:"matches"`
+	Orders    []*ReputationOutcome `json:"orders"`
+	Bonds     []*msgjson.Bond      `json:"bonds"`
+}
+
+// reputationOutcomes converts the outcomes to ReputationOutcomes.
+func reputationOutcomes[T db.Outcomer](outcomes []T, id func(T) []byte) []*ReputationOutcome {
+	ros := make([]*ReputationOutcome, 0, len(outcomes))
+	for _, o := range outcomes {
+		ros = append(ros, &ReputationOutcome{
+			ID:      id(o),
+			Outcome: o.Outcome().String(),
+		})
+	}
+	return ros
+}
+
+// ExportReputation dumps the user's reputation data from the DB, along with
+// their active bonds, and the score and tier computed from them. This is
+// intended for support and compliance requests. The score is computed from
+// the exported outcomes, and may briefly differ from a connected user's
+// current score.
+func (auth *AuthManager) ExportReputation(ctx context.Context, acctID account.AccountID) (*ReputationExport, error) {
+	now := auth.now()
+	acct, bonds := auth.storage.Account(acctID, now.Add(auth.bondExpiry))
+	if acct == nil {
+		return nil, fmt.Errorf("unknown account %v", acctID)
+	}
+	pimgs, matches, ords, err := auth.storage.GetUserReputationData(ctx, acctID, int(auth.pimgSample), int(auth.matchSample), cancelThreshWindow)
+	if err != nil {
+		return nil, fmt.Errorf("error loading reputation data for user %s: %w", acctID, err)
+	}
+
+	score, _, _ := auth.integrateOutcomes(newLatestOutcomes(matches, auth.matchSample),
+		newLatestOutcomes(pimgs, auth.pimgSample), newLatestOutcomes(ords, cancelThreshWindow))
+	rep := auth.userReputation(auth.TierForBonds(bonds, now), score)
+
+	msgBonds := make([]*msgjson.Bond, 0, len(bonds))
+	for _, bond := range bonds {
+		msgBonds = append(msgBonds, &msgjson.Bond{
+			Version:  bond.Version,
+			Amount:   uint64(bond.Amount),
+			Expiry:   uint64(time.Unix(bond.LockTime, 0).Add(-auth.bondExpiry).Unix()),
+			CoinID:   bond.CoinID,
+			AssetID:  bond.AssetID,
+			Strength: bond.Strength,
+		})
+	}
+
+	return &ReputationExport{
+		AccountID:  acctID,
+		Reputation: *rep,
+		Tier:       rep.EffectiveTier(),
+		Preimages:  reputationOutcomes(pimgs, func(p *db.PreimageOutcome) []byte { return p.OrderID[:] }),
+		Matches:    reputationOutcomes(matches, func(m *db.MatchResult) []byte { return m.MatchID[:] }),
+		Orders:     reputationOutcomes(ords, func(o *db.OrderOutcome) []byte { return o.OrderID[:] }),
+		Bonds:      msgBonds,
+	}, nil
+}
+
 // loadUserScore computes the user's current score from order and swap data
 // retrieved from the DB. Use this instead of userScore if the user is offline.
+// At trace level, each outcome category's contribution is logged.
 func (auth *AuthManager) loadUserScore(user account.AccountID) (int32, error) {
-	latestPreimageResults, latestMatches, latestFinished, err := auth.loadUserOutcomes(user)
+	score, breakdown, err := auth.loadUserScoreBreakdown(user)
 	if err != nil {
 		return 0, err
 	}
-
-	score, _, _ := auth.integrateOutcomes(latestMatches, latestPreimageResults, latestFinished)
+	if log.Level() <= dex.LevelTrace { // don't sort unless needed
+		logScoreBreakdown(user, score, breakdown)
+	}
 	return score, nil
 }
 
+// logScoreBreakdown logs the contribution of each outcome category to the
+// user's score, in category order, with the running total.
+func logScoreBreakdown(user account.AccountID, score int32, breakdown map[string]int32) {
+	categories := make([]string, 0, len(breakdown))
+	for category := range breakdown {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	var running int32
+	for _, category := range categories {
+		contrib := breakdown[category]
+		running += contrib
+		log.Tracef("score user=%v outcome=%q contribution=%d running=%d", user, category, contrib, running)
+	}
+	log.Tracef("score user=%v total=%d", user, score)
+}
+
+// loadUserScoreBreakdown computes the user's score from history in DB, along
+// with the contribution of each outcome category to the score. See
+// outcomeBreakdown.
+func (auth *AuthManager) loadUserScoreBreakdown(user account.AccountID) (total int32, breakdown map[string]int32, err error) {
+	latestPreimageResults, latestMatches, latestFinished, err := auth.loadUserOutcomes(user)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	total, _, _ = auth.integrateOutcomes(latestMatches, latestPreimageResults, latestFinished)
+	breakdown = auth.outcomeBreakdown(latestMatches, latestPreimageResults, latestFinished)
+	return total, breakdown, nil
+}
+
+// connectStamps are the times of a user's recent 'connect' requests.
+type connectStamps struct {
+	latest  uint64          // msgjson.Connect.Time, milliseconds
+	seen    map[uint64]bool // all times within connectSkew of latest
+	claimed time.Time       // local time of the last claim
+}
+
+// claimConnectStamp checks that a 'connect' request with the given time is not
+// a replay of a previous request, and records the time so that it cannot be
+// used again. The check and the record are atomic, so concurrent replays of a
+// request cannot both pass. The time is claimed even if the connect fails
+// later, since a replay must not succeed where the original failed.
+func (auth *AuthManager) claimConnectStamp(user account.AccountID, stamp uint64) error {
+	if auth.connectSkew <= 0 {
+		return nil
+	}
+	skew := uint64(auth.connectSkew.Milliseconds())
+	auth.connectStampsMtx.Lock()
+	defer auth.connectStampsMtx.Unlock()
+	stamps, found := auth.connectStamps[user]
+	if !found {
+		stamps = &connectStamps{seen: make(map[uint64]bool)}
+		auth.connectStamps[user] = stamps
+	}
+	switch {
+	case stamps.seen[stamp]:
+		return newAuthError(ErrConnectReplay, msgjson.ReplayedConnectError, "connect request time %d was already used", stamp)
+	case stamp+skew < stamps.latest:
+		return newAuthError(ErrConnectReplay, msgjson.ReplayedConnectError, "connect request time %d is older than last connect time %d",
+			stamp, stamps.latest)
+	}
+	stamps.seen[stamp] = true
+	stamps.claimed = auth.now()
+	if stamp > stamps.latest {
+		stamps.latest = stamp
+		// Times that are too old are rejected without the set.
+		for t := range stamps.seen {
+			if t+skew < stamp {
+				delete(stamps.seen, t)
+			}
+		}
+	}
+	return nil
+}
+
+// pruneConnectStamps forgets the connect times of users that have not
+// connected within connectReplayRetention. This should be run on a ticker.
+func (auth *AuthManager) pruneConnectStamps() {
+	auth.connectStampsMtx.Lock()
+	defer auth.connectStampsMtx.Unlock()
+	for user, stamps := range auth.connectStamps {
+		if auth.now().Sub(stamps.claimed) > connectReplayRetention {
+			delete(auth.connectStamps, user)
+		}
+	}
+}
+
+// TempBan refuses 'connect' requests for the account until the specified
+// time, after which the account may connect normally. Unlike Banish, the ban
+// is by account rather than by IP, and it expires. If the user is connected,
+// they are disconnected. A later TempBan replaces an earlier one, and a time
+// that has already passed lifts the ban.
+func (auth *AuthManager) TempBan(user account.AccountID, until time.Time) {
+	banned := until.After(auth.now())
+	auth.tempBanMtx.Lock()
+	if banned {
+		auth.tempBans[user] = until
+	} else {
+		delete(auth.tempBans, user)
+	}
+	auth.tempBanMtx.Unlock()
+	if !banned {
+		log.Infof("Lifted temporary ban of account %v", user)
+		return
+	}
+	log.Infof("Temporarily banned account %v until %v", user, until)
+	auth.Disconnect(user)
+}
+
+// checkTempBan returns an error stating the unban time if the account is
+// temporarily banned. An expired ban is removed.
+func (auth *AuthManager) checkTempBan(user account.AccountID) error {
+	auth.tempBanMtx.Lock()
+	defer auth.tempBanMtx.Unlock()
+	until, found := auth.tempBans[user]
+	if !found {
+		return nil
+	}
+	if !auth.now().Before(until) {
+		delete(auth.tempBans, user)
+		return nil
+	}
+	return newAuthError(ErrAccountTempBanned, msgjson.AccountClosedError,
+		"account is banned until %s", until.UTC().Format(time.RFC3339))
+}
+
+// pruneTempBans removes expired temporary bans. This should be run on a
+// ticker.
+func (auth *AuthManager) pruneTempBans() {
+	auth.tempBanMtx.Lock()
+	defer auth.tempBanMtx.Unlock()
+	now := auth.now()
+	for user, until := range auth.tempBans {
+		if !now.Before(until) {
+			delete(auth.tempBans, user)
+		}
+	}
+}
+
+// apiVersionSupported checks if the requested communications API version is
+// supported. If no supported versions are configured, any version is allowed.
+func (auth *AuthManager) apiVersionSupported(ver uint16) bool {
+	if len(auth.apiVers) == 0 {
+		return true
+	}
+	for _, v := range auth.apiVers {
+		if v == ver {
+			return true
+		}
+	}
+	return false
+}
+
+// checkClientVersion checks that the client version reported in a 'connect'
+// request is at least the configured minimum client version. Pre-release and
+// build metadata are ignored.
+func (auth *AuthManager) checkClientVersion(clientVer string) error {
+	minVer := auth.minClientVer
+	if minVer == nil {
+		return nil
+	}
+	if clientVer == "" {
+		return newAuthError(ErrOutdatedClient, msgjson.OutdatedClientError,
+			"client version not reported, minimum client version is %s", minVer)
+	}
+	coreVer := clientVer
+	if i := strings.IndexAny(clientVer, "-+"); i >= 0 {
+		coreVer = clientVer[:i] // strip pre-release and build metadata
+	}
+	ver, err := dex.SemverFromString(coreVer)
+	if err != nil {
+		return newAuthError(ErrOutdatedClient, msgjson.OutdatedClientError, "invalid client version %q: %v", clientVer, err)
+	}
+	if semverLess(*ver, *minVer) {
+		return newAuthError(ErrOutdatedClient, msgjson.OutdatedClientError,
+			"client version %s is no longer supported, please upgrade to version %s or later", clientVer, minVer)
+	}
+	return nil
+}
+
+// semverLess checks if version a precedes version b.
+func semverLess(a, b dex.Semver) bool {
+	if a.Major != b.Major {
+		return a.Major < b.Major
+	}
+	if a.Minor != b.Minor {
+		return a.Minor < b.Minor
+	}
+	return a.Patch < b.Patch
+}
+
+// connectCounters are counters of 'connect' requests. The rejected map is
+// populated with a counter for each of the connectRejectKinds on creation and
+// is not modified after, so it may be read without locking.
+type connectCounters struct {
+	total     atomic.Uint64
+	succeeded atomic.Uint64
+	rejected  map[dex.ErrorKind]*atomic.Uint64
+}
+
+func newConnectCounters() connectCounters {
+	rejected := make(map[dex.ErrorKind]*atomic.Uint64, len(connectRejectKinds))
+	for _, kind := range connectRejectKinds {
+		rejected[kind] = new(atomic.Uint64)
+	}
+	return connectCounters{rejected: rejected}
+}
+
+// record counts a connect request that failed with err, or succeeded if err
+// is nil. Errors without one of the connectRejectKinds are counted as
+// ErrInternal.
+func (c *connectCounters) record(err error) {
+	c.total.Add(1)
+	if err == nil {
+		c.succeeded.Add(1)
+		return
+	}
+	kind := ErrInternal
+	var authErr *authError
+	if errors.As(err, &authErr) && c.rejected[authErr.kind] != nil {
+		kind = authErr.kind
+	}
+	c.rejected[kind].Add(1)
+}
+
+// Stats is a snapshot of the AuthManager's counters.
+type Stats struct {
+	// Connects is the number of 'connect' requests handled.
+	Connects uint64 `json:"connects"`
+	// ConnectsSucceeded is the number of 'connect' requests that
+	// authenticated the user.
+	ConnectsSucceeded uint64 `json:"connectsSucceeded"`
+	// ConnectsRejected is the number of rejected 'connect' requests by
+	// reason, e.g. "bad signature". Reasons with no rejections are omitted.
+	ConnectsRejected map[string]uint64 `json:"connectsRejected"`
+}
+
+// Stats returns a snapshot of the AuthManager's counters. A spike in rejected
+// connects, e.g. many bad signatures, may indicate an attack.
+func (auth *AuthManager) Stats() *Stats {
+	s := &Stats{
+		Connects:          auth.connects.total.Load(),
+		ConnectsSucceeded: auth.connects.succeeded.Load(),
+		ConnectsRejected:  make(map[string]uint64),
+	}
+	for kind, n := range auth.connects.rejected {
+		if v := n.Load(); v > 0 {
+			s.ConnectsRejected[string(kind)] = v
+		}
+	}
+	return s
+}
+
 // handleConnect is the handler for the 'connect' route. The user is authorized,
 // a response is issued, and a clientInfo is created or updated.
 func (auth *AuthManager) handleConnect(conn comms.Link, msg *msgjson.Message) *msgjson.Error {
+	err := auth.connect(conn, msg)
+	auth.connects.record(err)
+	if err != nil {
+		return toMsgError(err)
+	}
+	return nil
+}
+
+// connect authenticates the user and sends the 'connect' response. If the
+// request is rejected, the error wraps one of the auth error kinds, e.g.
+// ErrAccountNotFound.
+func (auth *AuthManager) connect(conn comms.Link, msg *msgjson.Message) error {
+	if auth.Draining() {
+		return newAuthError(ErrDrainMode, msgjson.TryAgainLaterError,
+			"server is draining, new connections are not accepted")
+	}
+	if auth.connectFilter != nil {
+		if err := auth.connectFilter(conn.IP()); err != nil {
+			log.Debugf("Connect from %v refused by filter: %v", conn.Addr(), err)
+			return newAuthError(ErrConnectRefused, msgjson.UnauthorizedConnection, "connection refused: %v", err)
+		}
+	}
 	connect := new(msgjson.Connect)
 	err := msg.Unmarshal(&connect)
 	if err != nil || connect == nil {
-		return &msgjson.Error{
-			Code:    msgjson.RPCParseError,
-			Message: "error parsing connect request",
-		}
+		return newAuthError(ErrBadRequest, msgjson.RPCParseError, "error parsing connect request")
 	}
 	if len(connect.AccountID) != account.HashSize {
-		return &msgjson.Error{
-			Code:    msgjson.AuthenticationError,
-			Message: "authentication error. invalid account ID",
-		}
+		return newAuthError(ErrInvalidAccountID, msgjson.AuthenticationError, "authentication error. invalid account ID")
+	}
+	if !auth.apiVersionSupported(connect.APIVersion) {
+		return newAuthError(ErrUnsupportedAPIVersion, msgjson.RPCVersionUnsupported,
+			"unsupported API version %d, supported versions: %v", connect.APIVersion, auth.apiVers)
 	}
 	var user account.AccountID
 	copy(user[:], connect.AccountID[:])
-	lockTimeThresh := time.Now().Add(auth.bondExpiry).Truncate(time.Second)
+	lockTimeThresh := auth.now().Add(auth.bondExpiry).Truncate(time.Second)
 	acctInfo, bonds := auth.storage.Account(user, lockTimeThresh)
 	if acctInfo == nil {
-		return &msgjson.Error{
-			Code:    msgjson.AccountNotFoundError,
-			Message: "no account found for account ID: " + connect.AccountID.String(),
-		}
+		return newAuthError(ErrAccountNotFound, msgjson.AccountNotFoundError,
+			"no account found for account ID: %s", connect.AccountID)
 	}
 
 	// Tier 0 accounts may connect to complete swaps, etc. but not place new
@@ -1569,15 +3379,32 @@ func (auth *AuthManager) handleConnect(conn comms.Link, msg *msgjson.Message) *m
 	sigMsg := connect.Serialize()
 	err = checkSigS256(sigMsg, connect.SigBytes(), acctInfo.PubKey)
 	if err != nil {
-		return &msgjson.Error{
-			Code:    msgjson.SignatureError,
-			Message: "signature error: " + err.Error(),
-		}
+		return newAuthError(ErrBadSignature, msgjson.SignatureError, "signature error: %v", err)
+	}
+
+	// Only reveal the ban to the account holder.
+	if err := auth.checkTempBan(user); err != nil {
+		log.Debugf("Rejecting connect from %v for account %v: %v", conn.Addr(), user, err)
+		return err
+	}
+
+	if err := auth.checkClientVersion(connect.ClientVersion); err != nil {
+		log.Debugf("Rejecting connect from %v for account %v: %v", conn.Addr(), user, err)
+		return err
+	}
+
+	if err := auth.claimConnectStamp(user, connect.Time); err != nil {
+		log.Warnf("Rejecting connect from %v for account %v: %v", conn.Addr(), user, err)
+		return err
 	}
 
 	// Check to see if there is already an existing client for this account.
 	respHandlers := make(map[uint64]*respHandler)
 	oldClient := auth.user(acctInfo.ID)
+	if oldClient != nil && oldClient.conn.ID() > conn.ID() {
+		return newAuthError(ErrNewerConnection, msgjson.UnauthorizedConnection,
+			"account is connected on a newer connection")
+	}
 	if oldClient != nil {
 		oldClient.mtx.Lock()
 		respHandlers = oldClient.respHandlers
@@ -1588,10 +3415,7 @@ func (auth *AuthManager) handleConnect(conn comms.Link, msg *msgjson.Message) *m
 	latestPreimageResults, latestMatches, latestFinished, err := auth.loadUserOutcomes(user)
 	if err != nil {
 		log.Errorf("Failed to compute user %v score: %v", user, err)
-		return &msgjson.Error{
-			Code:    msgjson.RPCInternalError,
-			Message: "DB error",
-		}
+		return newAuthError(ErrInternal, msgjson.RPCInternalError, "DB error")
 	}
 	score, successCount, piMissCount := auth.integrateOutcomes(latestMatches, latestPreimageResults, latestFinished)
 
@@ -1609,20 +3433,31 @@ func (auth *AuthManager) handleConnect(conn comms.Link, msg *msgjson.Message) *m
 	auth.orderOutcomes[user] = latestFinished
 	auth.violationMtx.Unlock()
 
+	if auth.connTagger != nil {
+		conn.SetCustomID(auth.connTagger(conn))
+	}
+
 	client := &clientInfo{
 		acct:         acctInfo,
 		conn:         conn,
+		apiVer:       connect.APIVersion,
 		respHandlers: respHandlers,
+		maxReqs:      auth.maxOutstandingReqs,
+		sweeper:      auth.respSweeper,
+		lastSeen:     auth.now(),
+	}
+	if auth.latencyHistory > 0 {
+		client.latencies = newLatencyRing(auth.latencyHistory)
+	}
+	if auth.reqRate > 0 {
+		client.limiter = rate.NewLimiter(auth.reqRate, auth.reqBurst)
 	}
 
 	// Get the list of active orders for this user.
 	activeOrderStatuses, err := auth.storage.ActiveUserOrderStatuses(user)
 	if err != nil {
 		log.Errorf("ActiveUserOrderStatuses(%v): %v", user, err)
-		return &msgjson.Error{
-			Code:    msgjson.RPCInternalError,
-			Message: "DB error",
-		}
+		return newAuthError(ErrInternal, msgjson.RPCInternalError, "DB error")
 	}
 
 	msgOrderStatuses := make([]*msgjson.OrderStatus, 0, len(activeOrderStatuses))
@@ -1637,10 +3472,7 @@ func (auth *AuthManager) handleConnect(conn comms.Link, msg *msgjson.Message) *m
 	matches, err := auth.storage.AllActiveUserMatches(user)
 	if err != nil {
 		log.Errorf("AllActiveUserMatches(%v): %v", user, err)
-		return &msgjson.Error{
-			Code:    msgjson.RPCInternalError,
-			Message: "DB error",
-		}
+		return newAuthError(ErrInternal, msgjson.RPCInternalError, "DB error")
 	}
 
 	// There may be as many as 2*len(matches) match messages if the user matched
@@ -1688,7 +3520,15 @@ func (auth *AuthManager) handleConnect(conn comms.Link, msg *msgjson.Message) *m
 		msgMatchForSide(match, order.Taker)
 	}
 
-	conn.Authorized()
+	// Cap the active orders so that a user with very many does not produce an
+	// enormous response. The client requests any missing orders with
+	// 'order_status'. The matches are not capped, since the client treats a
+	// match missing from the response as revoked.
+	var truncated bool
+	if limit := auth.maxConnectOrders; limit > 0 && len(msgOrderStatuses) > limit {
+		msgOrderStatuses = msgOrderStatuses[:limit]
+		truncated = true
+	}
 
 	// Prepare bond info for response.
 	var bondTier int64
@@ -1714,15 +3554,33 @@ func (auth *AuthManager) handleConnect(conn comms.Link, msg *msgjson.Message) *m
 		})
 		activeBonds = append(activeBonds, bond)
 	}
+	if auth.bondConfGrace > 0 {
+		bondTier += auth.pendingBondTier(user, activeBonds)
+	}
 
 	// Ensure tier and filtered bonds agree.
 	rep := auth.userReputation(bondTier, score)
+	if auth.inNewAccountGrace(user) {
+		rep.Penalties = 0
+	}
+	auth.applyExemption(user, rep)
 	client.tier = rep.EffectiveTier()
 	client.score = score
 	client.bonds = activeBonds
 
+	if auth.banPolicy != nil && auth.banPolicy.ShouldBan(user, score, client.tier) {
+		log.Infof("Banning account %v from %v by ban policy. tier = %v, score = %v",
+			user, conn.Addr(), client.tier, score)
+		conn.Banish()
+		return newAuthError(ErrAccountBanned, msgjson.AccountClosedError, "account banned")
+	}
+
 	// Sign and send the connect response.
-	sig := auth.SignMsg(sigMsg)
+	sig, err := auth.SignMsg(sigMsg)
+	if err != nil {
+		log.Errorf("handleConnect signing error: %v", err)
+		return newAuthError(ErrInternal, msgjson.RPCInternalError, "internal error")
+	}
 	resp := &msgjson.ConnectResult{
 		Sig:                 sig,
 		ActiveOrderStatuses: msgOrderStatuses,
@@ -1730,30 +3588,96 @@ func (auth *AuthManager) handleConnect(conn comms.Link, msg *msgjson.Message) *m
 		Score:               score,
 		ActiveBonds:         msgBonds,
 		Reputation:          rep,
+		BondRenewAdvice:     auth.bondRenewAdvice(msgBonds),
+		RecentForgiveness:   auth.recentForgiveness(user),
+		Truncated:           truncated,
+		TierLimit:           auth.tierLimits[rep.EffectiveTier()],
 	}
 	respMsg, err := msgjson.NewResponse(msg.ID, resp, nil)
 	if err != nil {
 		log.Errorf("handleConnect prepare response error: %v", err)
-		return &msgjson.Error{
-			Code:    msgjson.RPCInternalError,
-			Message: "internal error",
-		}
+		return newAuthError(ErrInternal, msgjson.RPCInternalError, "internal error")
+	}
+
+	// Decide which link wins before responding, so that a link superseded by
+	// a concurrent connect on a newer link is not told it succeeded.
+	if !auth.addClient(client) {
+		return newAuthError(ErrNewerConnection, msgjson.UnauthorizedConnection,
+			"account is connected on a newer connection")
 	}
 
+	conn.Authorized()
 	err = conn.Send(respMsg)
 	if err != nil {
 		log.Error("Failed to send connect response: " + err.Error())
+		auth.removeClient(client)
 		return nil
 	}
 
 	log.Infof("Authenticated account %v from %v with %d active orders, %d active matches, tier = %v, "+
 		"bond tier = %v, score = %v",
 		user, conn.Addr(), len(msgOrderStatuses), len(msgMatches), client.tier, bondTier, score)
-	auth.addClient(client)
 
 	return nil
 }
 
+// bondRenewAdvice lists, by asset, the active bonds that will expire within
+// the bondRenewWindow.
+func (auth *AuthManager) bondRenewAdvice(bonds []*msgjson.Bond) []*msgjson.BondRenewAdvice {
+	if auth.bondRenewWindow <= 0 {
+		return nil
+	}
+	renewThresh := uint64(auth.now().Add(auth.bondRenewWindow).Unix())
+	var advice []*msgjson.BondRenewAdvice
+	byAsset := make(map[uint32]*msgjson.BondRenewAdvice)
+	for _, bond := range bonds {
+		if bond.Expiry >= renewThresh {
+			continue
+		}
+		a, found := byAsset[bond.AssetID]
+		if !found {
+			a = &msgjson.BondRenewAdvice{AssetID: bond.AssetID, Expiry: bond.Expiry}
+			byAsset[bond.AssetID] = a
+			advice = append(advice, a)
+		}
+		a.Strength += bond.Strength
+		if bond.Expiry < a.Expiry {
+			a.Expiry = bond.Expiry
+		}
+	}
+	return advice
+}
+
+// recentForgivenessLimit is the maximum number of forgiven outcomes reported
+// in the connect response.
+const recentForgivenessLimit = 10
+
+// recentForgiveness lists the user's most recently forgiven outcomes. Errors
+// are logged, but do not prevent the user from connecting.
+func (auth *AuthManager) recentForgiveness(user account.AccountID) []*msgjson.Forgiveness {
+	forgiven, err := auth.storage.RecentForgiveness(auth.ctx, user, recentForgivenessLimit)
+	if err != nil {
+		log.Errorf("Error loading forgiven outcomes for user %v: %v", user, err)
+		return nil
+	}
+	var msgForgiven []*msgjson.Forgiveness
+	for _, f := range forgiven {
+		fg := &msgjson.Forgiveness{ID: f.Link[:]}
+		switch f.Class {
+		case db.OutcomeClassPreimage:
+			fg.Kind = msgjson.ForgivenPreimage
+		case db.OutcomeClassMatch:
+			fg.Kind = msgjson.ForgivenMatch
+		case db.OutcomeClassOrder:
+			fg.Kind = msgjson.ForgivenOrder
+		default:
+			fg.Kind, fg.ID = msgjson.ForgivenAccount, nil
+		}
+		msgForgiven = append(msgForgiven, fg)
+	}
+	return msgForgiven
+}
+
 func (auth *AuthManager) loadRecentFinishedOrders(aid account.AccountID, N int) (*latestOutcomes[*db.OrderOutcome], error) {
 	// Load the N latest successfully completed orders for the user.
 	oids, compTimes, err := auth.storage.CompletedUserOrders(aid, N)
@@ -1768,7 +3692,42 @@ func (auth *AuthManager) loadRecentFinishedOrders(aid account.AccountID, N int)
 	}
 
 	// Create the sorted list with capacity.
-	return newLatestOutcomes(assembleCanceledOrders(oids, compTimes, cancels), cancelThreshWindow), nil
+	return newLatestOutcomes(auth.assembleCanceledOrders(oids, compTimes, cancels), cancelThreshWindow), nil
+}
+
+// CancelRatio computes the user's cancellation ratio from their most recent
+// completed orders and executed cancel orders in storage. As with the user's
+// score, cancel orders with an epoch gap of at least the FreeCancelEpochGap, or
+// that target an order placed within the FreeCancelWindow, are free, and are
+// counted as completions rather than cancels. The ratio is
+// cancels / (completions + cancels), or zero if there are neither.
+func (auth *AuthManager) CancelRatio(acctID account.AccountID) (ratio float64, completions, cancels int, err error) {
+	ords, err := auth.loadRecentFinishedOrders(acctID, cancelThreshWindow)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("error loading recent orders for user %v: %w", acctID, err)
+	}
+	counts := ords.binViolations()
+	completions, cancels = int(counts[db.OutcomeOrderComplete]), int(counts[db.OutcomeOrderCanceled])
+	if total := completions + cancels; total > 0 {
+		ratio = float64(cancels) / float64(total)
+	}
+	return ratio, completions, cancels, nil
+}
+
+// IdleClients returns the IDs of connected accounts that have not sent a route
+// request or response within the threshold duration. Operators may use this to
+// disconnect zombie connections that have not yet signaled Done.
+func (auth *AuthManager) IdleClients(threshold time.Duration) []account.AccountID {
+	cutoff := auth.now().Add(-threshold)
+	auth.connMtx.RLock()
+	defer auth.connMtx.RUnlock()
+	var idle []account.AccountID
+	for acctID, client := range auth.users {
+		if client.seen().Before(cutoff) {
+			idle = append(idle, acctID)
+		}
+	}
+	return idle
 }
 
 // handleResponse handles all responses for AuthManager registered routes,
@@ -1780,6 +3739,7 @@ func (auth *AuthManager) handleResponse(conn comms.Link, msg *msgjson.Message) {
 		log.Errorf("response from unknown connection")
 		return
 	}
+	client.markSeen(auth.now())
 	handler := client.respHandler(msg.ID)
 	if handler == nil {
 		log.Debugf("(*AuthManager).handleResponse: unknown msg ID %d", msg.ID)
@@ -1797,6 +3757,7 @@ func (auth *AuthManager) handleResponse(conn comms.Link, msg *msgjson.Message) {
 		}
 		return
 	}
+	client.recordLatency(handler.sent)
 	handler.f(conn, msg)
 }
 
@@ -1823,15 +3784,165 @@ func (mm *marketMatches) idList() []order.MatchID {
 	return ids
 }
 
+// matchStatusKey is the key for a cached 'match_status' result. The result
+// depends on the user's side of the match, so the user is part of the key.
+type matchStatusKey struct {
+	user account.AccountID
+	mid  order.MatchID
+}
+
+// cachedMatchStatus is a 'match_status' result with its expiration time.
+type cachedMatchStatus struct {
+	result *msgjson.MatchStatusResult
+	expire time.Time
+}
+
+// cachedMatchStatus retrieves an unexpired 'match_status' result from the
+// cache.
+func (auth *AuthManager) cachedMatchStatus(user account.AccountID, mid order.MatchID) *msgjson.MatchStatusResult {
+	if auth.matchStatusTTL < 0 {
+		return nil
+	}
+	auth.matchStatusMtx.Lock()
+	defer auth.matchStatusMtx.Unlock()
+	cached, found := auth.matchStatusCache[matchStatusKey{user, mid}]
+	if !found || auth.now().After(cached.expire) {
+		return nil
+	}
+	return cached.result
+}
+
+// matchStatusGeneration is the current cache generation, which should be
+// retrieved before loading match statuses that will be cached.
+func (auth *AuthManager) matchStatusGeneration() uint64 {
+	auth.matchStatusMtx.Lock()
+	defer auth.matchStatusMtx.Unlock()
+	return auth.matchStatusGen
+}
+
+// cacheMatchStatus stores the 'match_status' result for the user. The result
+// is not stored if the cache was invalidated since generation gen, since the
+// result may already be stale.
+func (auth *AuthManager) cacheMatchStatus(user account.AccountID, mid order.MatchID, result *msgjson.MatchStatusResult, gen uint64) {
+	if auth.matchStatusTTL < 0 {
+		return
+	}
+	auth.matchStatusMtx.Lock()
+	if gen != auth.matchStatusGen {
+		auth.matchStatusMtx.Unlock()
+		return
+	}
+	auth.matchStatusCache[matchStatusKey{user, mid}] = &cachedMatchStatus{
+		result: result,
+		expire: auth.now().Add(auth.matchStatusTTL),
+	}
+	auth.matchStatusMtx.Unlock()
+}
+
+// invalidateMatchStatus removes any cached 'match_status' results for the
+// match, for both parties. This should be used when the match status changes.
+func (auth *AuthManager) invalidateMatchStatus(mid order.MatchID) {
+	auth.matchStatusMtx.Lock()
+	defer auth.matchStatusMtx.Unlock()
+	auth.matchStatusGen++
+	for k := range auth.matchStatusCache {
+		if k.mid == mid {
+			delete(auth.matchStatusCache, k)
+		}
+	}
+}
+
+// MatchStatusChanged should be called when the stored status of a match
+// changes, such as when a swap or redeem is recorded, so that stale
+// 'match_status' results are not served from the cache.
+func (auth *AuthManager) MatchStatusChanged(mid order.MatchID) {
+	auth.invalidateMatchStatus(mid)
+}
+
+// pruneMatchStatusCache removes expired 'match_status' results from the cache.
+// This should be run on a ticker.
+func (auth *AuthManager) pruneMatchStatusCache() {
+	now := auth.now()
+	auth.matchStatusMtx.Lock()
+	defer auth.matchStatusMtx.Unlock()
+	for k, cached := range auth.matchStatusCache {
+		if now.After(cached.expire) {
+			delete(auth.matchStatusCache, k)
+		}
+	}
+}
+
+// SetTxDataSource registers the source of tx data for the asset, replacing any
+// existing source. This may be used to swap asset backends, e.g. after an RPC
+// node failover, without restarting.
+func (auth *AuthManager) SetTxDataSource(assetID uint32, src TxDataSource) {
+	auth.txDataSrcMtx.Lock()
+	auth.txDataSources[assetID] = src
+	auth.txDataSrcMtx.Unlock()
+}
+
+// RemoveTxDataSource unregisters the source of tx data for the asset.
+func (auth *AuthManager) RemoveTxDataSource(assetID uint32) {
+	auth.txDataSrcMtx.Lock()
+	delete(auth.txDataSources, assetID)
+	auth.txDataSrcMtx.Unlock()
+}
+
 // getTxData gets the tx data for the coin ID.
 func (auth *AuthManager) getTxData(assetID uint32, coinID []byte) ([]byte, error) {
+	auth.txDataSrcMtx.RLock()
 	txDataSrc, found := auth.txDataSources[assetID]
+	auth.txDataSrcMtx.RUnlock()
 	if !found {
 		return nil, fmt.Errorf("no tx data source for asset ID %d", assetID)
 	}
 	return txDataSrc(coinID)
 }
 
+// contractLockTime gets the lock time of the swap contract in unix seconds. If
+// there is no source for the asset, or the lock time cannot be determined,
+// zero is returned.
+func (auth *AuthManager) contractLockTime(assetID uint32, coinID, contract []byte) uint64 {
+	if len(coinID) == 0 || len(contract) == 0 {
+		return 0
+	}
+	auth.txDataSrcMtx.RLock()
+	src, found := auth.lockTimeSources[assetID]
+	auth.txDataSrcMtx.RUnlock()
+	if !found {
+		return 0
+	}
+	lockTime, err := src(coinID, contract)
+	if err != nil {
+		log.Debugf("Unable to get lock time of %s contract %s: %v", dex.BipIDSymbol(assetID),
+			coinIDString(assetID, coinID), err)
+		return 0
+	}
+	return uint64(lockTime.Unix())
+}
+
+// handleServerTime handles requests to the 'server_time' route. The response
+// is the server's current time signed with the DEX private key. No account is
+// required, so clients may use this to detect clock skew prior to 'connect'.
+func (auth *AuthManager) handleServerTime(conn comms.Link, msg *msgjson.Message) *msgjson.Error {
+	st := &msgjson.ServerTime{
+		Time: uint64(unixMsNow().UnixMilli()),
+	}
+	if err := auth.Sign(st); err != nil {
+		log.Errorf("error signing server_time response: %v", err)
+		return msgjson.NewError(msgjson.RPCInternalError, "signing error")
+	}
+	resp, err := msgjson.NewResponse(msg.ID, st, nil)
+	if err != nil {
+		log.Errorf("error encoding server_time response: %v", err)
+		return msgjson.NewError(msgjson.RPCInternalError, "encoding error")
+	}
+	if err = conn.Send(resp); err != nil {
+		log.Debugf("error sending server_time response: %v", err)
+	}
+	return nil
+}
+
 // handleMatchStatus handles requests to the 'match_status' route.
 func (auth *AuthManager) handleMatchStatus(conn comms.Link, msg *msgjson.Message) *msgjson.Error {
 	client := auth.conn(conn)
@@ -1849,6 +3960,8 @@ func (auth *AuthManager) handleMatchStatus(conn comms.Link, msg *msgjson.Message
 
 	mkts := make(map[string]*marketMatches)
 	var count int
+	var cachedResults []*msgjson.MatchStatusResult
+	cachedIDs := make(map[order.MatchID]bool)
 	for _, req := range matchReqs {
 		mkt, err := dex.MarketName(req.Base, req.Quote)
 		if err != nil {
@@ -1857,6 +3970,16 @@ func (auth *AuthManager) handleMatchStatus(conn comms.Link, msg *msgjson.Message
 		if len(req.MatchID) != order.MatchIDSize {
 			return msgjson.NewError(msgjson.InvalidRequestError, "match ID is wrong length: %s", req.MatchID)
 		}
+		var matchID order.MatchID
+		copy(matchID[:], req.MatchID)
+		if cachedIDs[matchID] {
+			continue
+		}
+		if result := auth.cachedMatchStatus(client.acct.ID, matchID); result != nil {
+			cachedIDs[matchID] = true
+			cachedResults = append(cachedResults, result)
+			continue
+		}
 		mktMatches, found := mkts[mkt]
 		if !found {
 			mktMatches = &marketMatches{
@@ -1866,14 +3989,14 @@ func (auth *AuthManager) handleMatchStatus(conn comms.Link, msg *msgjson.Message
 			}
 			mkts[mkt] = mktMatches
 		}
-		var matchID order.MatchID
-		copy(matchID[:], req.MatchID)
 		if mktMatches.add(matchID) {
 			count++
 		}
 	}
 
-	results := make([]*msgjson.MatchStatusResult, 0, count) // should be non-nil even for count==0
+	results := make([]*msgjson.MatchStatusResult, 0, count+len(cachedResults)) // should be non-nil even for count==0
+	results = append(results, cachedResults...)
+	cacheGen := auth.matchStatusGeneration()
 	for _, mm := range mkts {
 		statuses, err := auth.storage.MatchStatuses(client.acct.ID, mm.base, mm.quote, mm.idList())
 		// no results is not an error
@@ -1884,13 +4007,13 @@ func (auth *AuthManager) handleMatchStatus(conn comms.Link, msg *msgjson.Message
 		}
 		for _, status := range statuses {
 			var makerTxData, takerTxData []byte
-			var assetID uint32
+			makerAssetID, takerAssetID := mm.base, mm.quote
+			if status.TakerSell {
+				makerAssetID, takerAssetID = mm.quote, mm.base
+			}
 			switch {
 			case status.IsTaker && status.Status == order.MakerSwapCast:
-				assetID = mm.base
-				if status.TakerSell {
-					assetID = mm.quote
-				}
+				assetID := makerAssetID
 				makerTxData, err = auth.getTxData(assetID, status.MakerSwap)
 				if err != nil {
 					log.Errorf("failed to get maker tx data for %s %s: %v", dex.BipIDSymbol(assetID),
@@ -1898,10 +4021,7 @@ func (auth *AuthManager) handleMatchStatus(conn comms.Link, msg *msgjson.Message
 					return msgjson.NewError(msgjson.RPCInternalError, "blockchain retrieval error")
 				}
 			case status.IsMaker && status.Status == order.TakerSwapCast:
-				assetID = mm.quote
-				if status.TakerSell {
-					assetID = mm.base
-				}
+				assetID := takerAssetID
 				takerTxData, err = auth.getTxData(assetID, status.TakerSwap)
 				if err != nil {
 					log.Errorf("failed to get taker tx data for %s %s: %v", dex.BipIDSymbol(assetID),
@@ -1910,7 +4030,7 @@ func (auth *AuthManager) handleMatchStatus(conn comms.Link, msg *msgjson.Message
 				}
 			}
 
-			results = append(results, &msgjson.MatchStatusResult{
+			result := &msgjson.MatchStatusResult{
 				MatchID:       status.ID.Bytes(),
 				Status:        uint8(status.Status),
 				MakerContract: status.MakerContract,
@@ -1923,7 +4043,15 @@ func (auth *AuthManager) handleMatchStatus(conn comms.Link, msg *msgjson.Message
 				Active:        status.Active,
 				MakerTxData:   makerTxData,
 				TakerTxData:   takerTxData,
-			})
+				MakerLockTime: auth.contractLockTime(makerAssetID, status.MakerSwap, status.MakerContract),
+				TakerLockTime: auth.contractLockTime(takerAssetID, status.TakerSwap, status.TakerContract),
+			}
+			if err := auth.Sign(result); err != nil {
+				log.Errorf("Error signing match status for match %v: %v", status.ID, err)
+				return msgjson.NewError(msgjson.RPCInternalError, "internal error")
+			}
+			auth.cacheMatchStatus(client.acct.ID, status.ID, result, cacheGen)
+			results = append(results, result)
 		}
 	}
 
@@ -1992,9 +4120,19 @@ func (auth *AuthManager) handleOrderStatus(conn comms.Link, msg *msgjson.Message
 	if len(orderReqs) == 0 { // includes null and [] Payload
 		return msgjson.NewError(msgjson.InvalidRequestError, "no order id provided")
 	}
-	if len(orderReqs) > maxIDsPerOrderStatusRequest {
+	if len(orderReqs) > auth.maxOrderStatusBatch {
 		return msgjson.NewError(msgjson.InvalidRequestError, "cannot request statuses for more than %v orders",
-			maxIDsPerOrderStatusRequest)
+			auth.maxOrderStatusBatch)
+	}
+
+	// Validate all requests before processing any of them.
+	for i, req := range orderReqs {
+		if req == nil {
+			return msgjson.NewError(msgjson.InvalidRequestError, "order status request %d is null", i)
+		}
+		if len(req.OrderID) != order.OrderIDSize {
+			return msgjson.NewError(msgjson.InvalidRequestError, "order ID at index %d is wrong length: %s", i, req.OrderID)
+		}
 	}
 
 	mkts := make(map[string]*marketOrders)
@@ -2004,9 +4142,6 @@ func (auth *AuthManager) handleOrderStatus(conn comms.Link, msg *msgjson.Message
 		if err != nil {
 			return msgjson.NewError(msgjson.InvalidRequestError, "market with base=%d, quote=%d is not known", req.Base, req.Quote)
 		}
-		if len(req.OrderID) != order.OrderIDSize {
-			return msgjson.NewError(msgjson.InvalidRequestError, "order ID is wrong length: %s", req.OrderID)
-		}
 		mktOrders, found := mkts[mkt]
 		if !found {
 			mktOrders = &marketOrders{
@@ -2032,10 +4167,12 @@ func (auth *AuthManager) handleOrderStatus(conn comms.Link, msg *msgjson.Message
 				client.acct.ID, mm.base, mm.quote, mm.orderIDs, err)
 			return msgjson.NewError(msgjson.RPCInternalError, "DB error")
 		}
+		now := auth.now()
 		for _, orderStatus := range orderStatuses {
 			results = append(results, &msgjson.OrderStatus{
-				ID:     orderStatus.ID.Bytes(),
-				Status: uint16(orderStatus.Status),
+				ID:           orderStatus.ID.Bytes(),
+				Status:       uint16(orderStatus.Status),
+				BookedEpochs: orderStatus.BookedEpochs(now),
 			})
 		}
 	}
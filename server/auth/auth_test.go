@@ -9,9 +9,14 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -45,6 +50,7 @@ type ratioData struct {
 	oidsCanceled   []order.OrderID
 	timesCanceled  []int64
 	epochGaps      []int32
+	targetTimes    []int64 // optional
 }
 
 // TStorage satisfies the Storage interface
@@ -62,6 +68,17 @@ type TStorage struct {
 	regAsset            uint32
 	bonds               []*db.Bond
 	ratio               ratioData
+	orderStatusCalls    int
+	matchValues         []*db.MatchResult
+	repVersions         map[account.AccountID]int16
+	repVersionsErr      error
+	matchFailForgiven   bool
+	forgiven            []*db.ForgivenOutcome
+	forgivenErr         error
+	repPimgs            []*db.PreimageOutcome
+	repMatches          []*db.MatchResult
+	repOrds             []*db.OrderOutcome
+	pruned              map[db.OutcomeClass]int64
 }
 
 func (s *TStorage) AccountInfo(account.AccountID) (*db.Account, error) {
@@ -84,18 +101,25 @@ func (s *TStorage) StorePrepaidBonds(coinIDs [][]byte, strength uint32, lockTime
 	return nil
 }
 func (s *TStorage) CompletedAndAtFaultMatchStats(aid account.AccountID, lastN int) ([]*db.MatchOutcome, error) {
+	if n := len(s.userMatchOutcomes); lastN > 0 && n > lastN {
+		return s.userMatchOutcomes[n-lastN:], nil
+	}
 	return s.userMatchOutcomes, nil
 }
 func (s *TStorage) UserMatchFails(aid account.AccountID, lastN int) ([]*db.MatchFail, error) {
 	return nil, nil
 }
 func (s *TStorage) PreimageStats(user account.AccountID, lastN int) ([]*db.PreimageResult, error) {
+	if n := len(s.userPreimageResults); lastN > 0 && n > lastN {
+		return s.userPreimageResults[n-lastN:], nil
+	}
 	return s.userPreimageResults, nil
 }
 func (s *TStorage) ForgiveMatchFail(mid order.MatchID) (bool, error) {
-	return false, nil
+	return s.matchFailForgiven, nil
 }
 func (s *TStorage) UserOrderStatuses(aid account.AccountID, base, quote uint32, oids []order.OrderID) ([]*db.OrderStatus, error) {
+	s.orderStatusCalls++
 	return s.orderStatuses, nil
 }
 func (s *TStorage) ActiveUserOrderStatuses(aid account.AccountID) ([]*db.OrderStatus, error) {
@@ -132,20 +156,23 @@ func (s *TStorage) ExecutedCancelsForUser(aid account.AccountID, _ int) (cancels
 			MatchTime: s.ratio.timesCanceled[i],
 			EpochGap:  s.ratio.epochGaps[i],
 		})
+		if s.ratio.targetTimes != nil {
+			cancels[i].TargetTime = s.ratio.targetTimes[i]
+		}
 	}
 	return cancels, nil
 }
 
 func (s *TStorage) GetUserReputationData(ctx context.Context, user account.AccountID, pimgSz, matchSz, orderSz int) ([]*db.PreimageOutcome, []*db.MatchResult, []*db.OrderOutcome, error) {
-	return nil, nil, nil, nil
+	return s.repPimgs, s.repMatches, s.repOrds, nil
 }
 
 func (s *TStorage) AddPreimageOutcome(ctx context.Context, user account.AccountID, oid order.OrderID, miss bool) (*db.PreimageOutcome, error) {
-	return nil, nil
+	return &db.PreimageOutcome{DBID: nextDBID(), OrderID: oid, Miss: miss}, nil
 }
 
-func (s *TStorage) AddMatchOutcome(ctx context.Context, user account.AccountID, mid order.MatchID, outcome Outcome) (*db.MatchResult, error) {
-	return nil, nil
+func (s *TStorage) AddMatchOutcome(ctx context.Context, user account.AccountID, mmid db.MarketMatchID, value uint64, outcome Outcome) (*db.MatchResult, error) {
+	return &db.MatchResult{DBID: nextDBID(), MatchID: mmid.MatchID, MatchOutcome: outcome, Value: value, Base: mmid.Base, Quote: mmid.Quote}, nil
 }
 
 var dbIDCounter int64
@@ -159,6 +186,9 @@ func (s *TStorage) AddOrderOutcome(ctx context.Context, user account.AccountID,
 }
 
 func (s *TStorage) PruneOutcomes(ctx context.Context, user account.AccountID, outcomeClass db.OutcomeClass, fromDBID int64) error {
+	if s.pruned != nil {
+		s.pruned[outcomeClass] = fromDBID
+	}
 	return nil
 }
 
@@ -166,6 +196,17 @@ func (s *TStorage) GetUserReputationVersion(ctx context.Context, user account.Ac
 	return 0, nil
 }
 
+func (s *TStorage) CountUsersByReputationVersion(ctx context.Context) (map[int16]int, error) {
+	if s.repVersionsErr != nil {
+		return nil, s.repVersionsErr
+	}
+	counts := make(map[int16]int)
+	for _, ver := range s.repVersions {
+		counts[ver]++
+	}
+	return counts, nil
+}
+
 func (s *TStorage) UpgradeUserReputationV1(
 	ctx context.Context, user account.AccountID, pimgs []*db.PreimageOutcome, matches []*db.MatchResult, ords []*db.OrderOutcome, /* Without DB IDs */
 ) ([]*db.PreimageOutcome, []*db.MatchResult, []*db.OrderOutcome, error) /* With DB IDs */ {
@@ -173,9 +214,47 @@ func (s *TStorage) UpgradeUserReputationV1(
 }
 
 func (s *TStorage) ForgiveUser(ctx context.Context, user account.AccountID) error {
+	s.forgiven = append(s.forgiven, &db.ForgivenOutcome{DBID: nextDBID()})
 	return nil
 }
 
+func (s *TStorage) RecentMatchValues(ctx context.Context, n int) ([]*db.MatchResult, error) {
+	return s.matchValues, nil
+}
+
+func (s *TStorage) RecentForgiveness(ctx context.Context, user account.AccountID, n int) ([]*db.ForgivenOutcome, error) {
+	if s.forgivenErr != nil {
+		return nil, s.forgivenErr
+	}
+	var forgiven []*db.ForgivenOutcome
+	for i := len(s.forgiven) - 1; i >= 0 && len(forgiven) < n; i-- {
+		forgiven = append(forgiven, s.forgiven[i])
+	}
+	return forgiven, nil
+}
+
+func (s *TStorage) ForgivePreimageMisses(ctx context.Context, user account.AccountID, n int) (int64, error) {
+	var forgiven int64
+	for i := len(s.userPreimageResults) - 1; i >= 0 && forgiven < int64(n); i-- {
+		if r := s.userPreimageResults[i]; r.Miss {
+			r.Miss = false
+			forgiven++
+		}
+	}
+	return forgiven, nil
+}
+
+func (s *TStorage) ForgiveMatchOutcome(ctx context.Context, user account.AccountID, mid order.MatchID) (bool, error) {
+	for _, r := range s.repMatches {
+		if r.MatchID == mid && r.MatchOutcome > db.OutcomeSwapSuccess {
+			r.MatchOutcome = db.OutcomeForgiven
+			s.forgiven = append(s.forgiven, &db.ForgivenOutcome{DBID: r.DBID, Class: db.OutcomeClassMatch, Link: mid})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // TSigner satisfies the Signer interface
 type TSigner struct {
 	sig *ecdsa.Signature
@@ -201,10 +280,12 @@ type TRPCClient struct {
 	sendRawErr error
 	requestErr error
 	banished   bool
+	sendMtx    sync.Mutex
 	sends      []*msgjson.Message
 	reqs       []*tReq
 	on         uint32
 	closed     chan struct{}
+	customID   string
 }
 
 func (c *TRPCClient) ID() uint64    { return c.id }
@@ -212,6 +293,8 @@ func (c *TRPCClient) IP() dex.IPKey { return c.ip }
 func (c *TRPCClient) Addr() string  { return c.addr }
 func (c *TRPCClient) Authorized()   {}
 func (c *TRPCClient) Send(msg *msgjson.Message) error {
+	c.sendMtx.Lock()
+	defer c.sendMtx.Unlock()
 	c.sends = append(c.sends, msg)
 	return c.sendErr
 }
@@ -223,7 +306,9 @@ func (c *TRPCClient) SendRaw(b []byte) error {
 	if err != nil {
 		return err
 	}
+	c.sendMtx.Lock()
 	c.sends = append(c.sends, msg)
+	c.sendMtx.Unlock()
 	return nil
 }
 func (c *TRPCClient) SendError(id uint64, msg *msgjson.Error) {
@@ -257,6 +342,8 @@ func (c *TRPCClient) getReq() *tReq {
 	return req
 }
 func (c *TRPCClient) getSend() *msgjson.Message {
+	c.sendMtx.Lock()
+	defer c.sendMtx.Unlock()
 	if len(c.sends) == 0 {
 		return nil
 	}
@@ -265,11 +352,26 @@ func (c *TRPCClient) getSend() *msgjson.Message {
 	return msg
 }
 
+// getNtfn removes and returns the first sent notification with the route.
+func (c *TRPCClient) getNtfn(route string) *msgjson.Message {
+	c.sendMtx.Lock()
+	defer c.sendMtx.Unlock()
+	for i, msg := range c.sends {
+		if msg.Type == msgjson.Notification && msg.Route == route {
+			c.sends = append(c.sends[:i], c.sends[i+1:]...)
+			return msg
+		}
+	}
+	return nil
+}
+
 func (c *TRPCClient) CustomID() string {
-	return ""
+	return c.customID
 }
 
-func (c *TRPCClient) SetCustomID(string) {}
+func (c *TRPCClient) SetCustomID(id string) {
+	c.customID = id
+}
 
 var tClientID uint64
 
@@ -461,7 +563,7 @@ func TestMain(m *testing.M) {
 		// secp256k1.PrivKeyFromBytes
 		dexKey, _ := secp256k1.ParsePubKey(tDexPubKeyBytes)
 		signer := &TSigner{pubkey: dexKey}
-		authMgr := NewAuthManager(&Config{
+		authMgr, err := NewAuthManager(&Config{
 			Storage:    storage,
 			Signer:     signer,
 			BondExpiry: 86400,
@@ -482,6 +584,10 @@ func TestMain(m *testing.M) {
 				tRoutes[route] = handler
 			},
 		})
+		if err != nil {
+			fmt.Printf("NewAuthManager error: %v\n", err)
+			return 1
+		}
 		cm := dex.NewConnectionMaster(authMgr)
 		cm.Connect(ctx)
 		defer cm.Disconnect()
@@ -565,12 +671,15 @@ func TestGraceLimit(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			got := GraceLimitForThreshold(tt.thresh)
+			if got != tt.wantLimit {
+				t.Errorf("incorrect grace limit. got %d, want %d", got, tt.wantLimit)
+			}
 			auth := &AuthManager{
 				cancelThresh: tt.thresh,
 			}
-			got := auth.GraceLimit()
-			if got != tt.wantLimit {
-				t.Errorf("incorrect grace limit. got %d, want %d", got, tt.wantLimit)
+			if got = auth.GraceLimit(); got != tt.wantLimit {
+				t.Errorf("incorrect AuthManager grace limit. got %d, want %d", got, tt.wantLimit)
 			}
 		})
 	}
@@ -743,6 +852,134 @@ func TestAuthManager_loadUserScore(t *testing.T) {
 	}
 }
 
+func TestScoringSampleSize(t *testing.T) {
+	wantScore := setViolations()
+	defer clearViolations()
+	user := tNewUser(t)
+
+	// The most recent outcomes are successes.
+	const sample = 3
+	for i := 0; i < sample; i++ {
+		rig.storage.userMatchOutcomes = append(rig.storage.userMatchOutcomes,
+			newMatchOutcome(order.MatchComplete, randomMatchID(), false, 7, nextTime()))
+	}
+	wantScore += sample * matchCompletedScore
+	score, err := rig.mgr.loadUserScore(user.acctID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != wantScore {
+		t.Fatalf("wrong score with default sample. got %d, want %d", score, wantScore)
+	}
+
+	defer func(matchSample, pimgSample int16) {
+		rig.mgr.matchSample, rig.mgr.pimgSample = matchSample, pimgSample
+	}(rig.mgr.matchSample, rig.mgr.pimgSample)
+	rig.mgr.matchSample, rig.mgr.pimgSample = sample, sample
+
+	// Only the recent successes count. The preimage miss is also too old.
+	score, err = rig.mgr.loadUserScore(user.acctID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != sample*matchCompletedScore {
+		t.Fatalf("wrong score with small sample. got %d, want %d", score, sample*matchCompletedScore)
+	}
+	if _, _, maxScore, _ := rig.mgr.UserReputation(user.acctID); maxScore != sample {
+		t.Fatalf("wrong max score %d", maxScore)
+	}
+}
+
+func TestLoadUserScoreBreakdown(t *testing.T) {
+	wantScore := setViolations()
+	defer clearViolations()
+	user := tNewUser(t)
+
+	score, breakdown, err := rig.mgr.loadUserScoreBreakdown(user.acctID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != wantScore {
+		t.Fatalf("wrong score. got %d, want %d", score, wantScore)
+	}
+
+	wantBreakdown := map[string]int32{
+		db.OutcomeSwapSuccess.String():     4 * matchCompletedScore,
+		db.OutcomePreimageMiss.String():    preimageMissScore,
+		db.OutcomeNoSwapAsMaker.String():   2 * noSwapAsMakerScore,
+		db.OutcomeNoSwapAsTaker.String():   noSwapAsTakerScore,
+		db.OutcomeNoRedeemAsMaker.String(): noRedeemAsMakerScore,
+		db.OutcomeNoRedeemAsTaker.String(): noRedeemAsTakerScore,
+	}
+	if len(breakdown) != len(wantBreakdown) {
+		t.Fatalf("wrong number of categories. got %v, want %v", breakdown, wantBreakdown)
+	}
+	var sum int32
+	for category, want := range wantBreakdown {
+		if got := breakdown[category]; got != want {
+			t.Errorf("wrong %q contribution. got %d, want %d", category, got, want)
+		}
+		sum += breakdown[category]
+	}
+	if sum != score {
+		t.Fatalf("breakdown sums to %d, score is %d", sum, score)
+	}
+}
+
+func TestLoadUserScoreTrace(t *testing.T) {
+	wantScore := setViolations()
+	defer clearViolations()
+	user := tNewUser(t)
+
+	logger, buf := dex.NewMemoryLogger(dex.LevelTrace)
+	UseLogger(logger)
+	defer UseLogger(dex.StdOutLogger("AUTH_TEST", dex.LevelTrace))
+
+	score, err := rig.mgr.loadUserScore(user.acctID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != wantScore {
+		t.Fatalf("wrong score. got %d, want %d", score, wantScore)
+	}
+
+	// Categories are logged in sorted order with the running total.
+	breakdown := map[string]int32{
+		db.OutcomeSwapSuccess.String():     4 * matchCompletedScore,
+		db.OutcomePreimageMiss.String():    preimageMissScore,
+		db.OutcomeNoSwapAsMaker.String():   2 * noSwapAsMakerScore,
+		db.OutcomeNoSwapAsTaker.String():   noSwapAsTakerScore,
+		db.OutcomeNoRedeemAsMaker.String(): noRedeemAsMakerScore,
+		db.OutcomeNoRedeemAsTaker.String(): noRedeemAsTakerScore,
+	}
+	categories := make([]string, 0, len(breakdown))
+	for category := range breakdown {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	var running int32
+	for _, category := range categories {
+		running += breakdown[category]
+		line := fmt.Sprintf("score user=%v outcome=%q contribution=%d running=%d", user.acctID, category, breakdown[category], running)
+		if !buf.Contains(line) {
+			t.Fatalf("missing trace line %q in %v", line, buf.Lines())
+		}
+	}
+	if line := fmt.Sprintf("score user=%v total=%d", user.acctID, score); !buf.Contains(line) {
+		t.Fatalf("missing trace line %q", line)
+	}
+
+	// Nothing is logged above trace level.
+	logger, buf = dex.NewMemoryLogger(dex.LevelDebug)
+	UseLogger(logger)
+	if _, err := rig.mgr.loadUserScore(user.acctID); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Contains("score user=") {
+		t.Fatalf("score traced at debug level")
+	}
+}
+
 func TestConnect(t *testing.T) {
 	user := tNewUser(t)
 	rig.signer.sig = user.randomSignature()
@@ -1090,6 +1327,115 @@ func TestAccountErrors(t *testing.T) {
 
 }
 
+func TestNewAccountGrace(t *testing.T) {
+	initPenaltyThresh := rig.mgr.penaltyThreshold
+	rig.mgr.penaltyThreshold = -1
+	rig.mgr.newAcctGrace = 3
+	rig.storage.setBondTier(1)
+	rig.storage.userPreimageResults = nil
+	defer func() {
+		rig.mgr.penaltyThreshold = initPenaltyThresh
+		rig.mgr.newAcctGrace = 0
+		rig.storage.bonds = nil
+		clearViolations()
+	}()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	reconnect := func() *clientInfo {
+		t.Helper()
+		if client := rig.mgr.user(user.acctID); client != nil {
+			rig.mgr.removeClient(client)
+			user.conn = tNewRPCClient() // disconnect necessitates new conn ID
+		}
+		connectUser(t, user)
+		client := rig.mgr.user(user.acctID)
+		if client == nil {
+			t.Fatalf("client not found")
+		}
+		return client
+	}
+
+	// A new user with one violation is not penalized.
+	rig.storage.userMatchOutcomes = []*db.MatchOutcome{
+		newMatchOutcome(order.NewlyMatched, randomMatchID(), true, 7, nextTime()), // noSwapAsMaker
+	}
+	if client := reconnect(); client.tier != 1 || client.score >= 0 {
+		t.Fatalf("new user penalized. tier = %d, score = %d", client.tier, client.score)
+	}
+
+	// Past the grace count, the violation is penalized.
+	rig.storage.userMatchOutcomes = append(rig.storage.userMatchOutcomes,
+		newMatchOutcome(order.MatchComplete, randomMatchID(), false, 7, nextTime()),
+		newMatchOutcome(order.MatchComplete, randomMatchID(), false, 7, nextTime()))
+	if client := reconnect(); client.tier > 0 {
+		t.Fatalf("user past grace count not penalized. tier = %d, score = %d", client.tier, client.score)
+	}
+
+	// Without a grace period, the new user is penalized.
+	rig.mgr.newAcctGrace = 0
+	rig.storage.userMatchOutcomes = rig.storage.userMatchOutcomes[:1]
+	if client := reconnect(); client.tier > 0 {
+		t.Fatalf("user not penalized without grace period. tier = %d", client.tier)
+	}
+}
+
+func TestExemptAccounts(t *testing.T) {
+	initPenaltyThresh := rig.mgr.penaltyThreshold
+	rig.mgr.penaltyThreshold = -1
+	rig.storage.setBondTier(1)
+	rig.storage.userPreimageResults = nil
+	rig.storage.userMatchOutcomes = []*db.MatchOutcome{
+		newMatchOutcome(order.NewlyMatched, randomMatchID(), true, 7, nextTime()), // noSwapAsMaker
+	}
+	defer func() {
+		rig.mgr.penaltyThreshold = initPenaltyThresh
+		rig.mgr.exemptAccts = nil
+		rig.storage.bonds = nil
+		clearViolations()
+	}()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	reconnect := func() *clientInfo {
+		t.Helper()
+		if client := rig.mgr.user(user.acctID); client != nil {
+			rig.mgr.removeClient(client)
+			user.conn = tNewRPCClient() // disconnect necessitates new conn ID
+		}
+		connectUser(t, user)
+		client := rig.mgr.user(user.acctID)
+		if client == nil {
+			t.Fatalf("client not found")
+		}
+		return client
+	}
+
+	// Not exempt. The violation is penalized.
+	if client := reconnect(); client.tier > 0 {
+		t.Fatalf("non-exempt user not penalized. tier = %d, score = %d", client.tier, client.score)
+	}
+
+	// Exempt. Still tier 1, but the score is recorded.
+	rig.mgr.exemptAccts = map[account.AccountID]bool{user.acctID: true}
+	client := reconnect()
+	if client.tier != 1 {
+		t.Fatalf("exempt user penalized. tier = %d, score = %d", client.tier, client.score)
+	}
+	if client.score != noSwapAsMakerScore {
+		t.Fatalf("wrong score %d for exempt user, expected %d", client.score, noSwapAsMakerScore)
+	}
+	if rep := rig.mgr.ComputeUserReputation(user.acctID); rep.EffectiveTier() != 1 || rep.Score != noSwapAsMakerScore {
+		t.Fatalf("wrong reputation for exempt user: %+v", rep)
+	}
+
+	// Exemption does not grant a tier without bonds.
+	rig.storage.bonds = nil
+	if client := reconnect(); client.tier > 0 {
+		t.Fatalf("exempt user without bonds has tier %d", client.tier)
+	}
+}
+
 func TestRoute(t *testing.T) {
 	user := tNewUser(t)
 	rig.signer.sig = user.randomSignature()
@@ -1125,118 +1471,566 @@ func TestRoute(t *testing.T) {
 	}
 }
 
-func TestAuth(t *testing.T) {
-	user := tNewUser(t)
-	rig.signer.sig = user.randomSignature()
-	connectUser(t, user)
-
-	msgBytes := randBytes(50)
-	sigBytes := signMsg(user.privKey, msgBytes)
-	err := rig.mgr.Auth(user.acctID, msgBytes, sigBytes)
-	if err != nil {
-		t.Fatalf("unexpected auth error: %v", err)
+func TestRegisteredRoutes(t *testing.T) {
+	routes := rig.mgr.RegisteredRoutes()
+	if !sort.StringsAreSorted(routes) {
+		t.Fatalf("routes not sorted: %v", routes)
+	}
+	has := func(route string) bool {
+		i := sort.SearchStrings(routes, route)
+		return i < len(routes) && routes[i] == route
+	}
+	for _, route := range []string{msgjson.ConnectRoute, msgjson.PostBondRoute,
+		msgjson.PreValidateBondRoute, msgjson.MatchStatusRoute, msgjson.OrderStatusRoute} {
+		if !has(route) {
+			t.Fatalf("route %q not registered", route)
+		}
 	}
 
-	foreigner := tNewUser(t)
-	sigBytes = signMsg(user.privKey, msgBytes)
-	err = rig.mgr.Auth(foreigner.acctID, msgBytes, sigBytes)
-	if err == nil {
-		t.Fatalf("no auth error for foreigner")
+	// Routes added with Route are included.
+	rig.mgr.Route("registeredroute", func(account.AccountID, *msgjson.Message) *msgjson.Error { return nil })
+	routes = rig.mgr.RegisteredRoutes()
+	if !has("registeredroute") {
+		t.Fatalf("route added with Route not listed: %v", routes)
 	}
+}
 
-	msgBytes = randBytes(50)
-	err = rig.mgr.Auth(user.acctID, msgBytes, sigBytes)
-	if err == nil {
-		t.Fatalf("no error for wrong message")
+func TestDuplicateRoute(t *testing.T) {
+	var first, second bool
+	err := rig.mgr.registerRoute("duproute", func(comms.Link, *msgjson.Message) *msgjson.Error {
+		first = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("registerRoute error: %v", err)
+	}
+	err = rig.mgr.registerRoute("duproute", func(comms.Link, *msgjson.Message) *msgjson.Error {
+		second = true
+		return nil
+	})
+	if !errors.Is(err, ErrDuplicateRoute) {
+		t.Fatalf("expected ErrDuplicateRoute, got %v", err)
+	}
+	// Route logs the conflict and does not replace the handler either.
+	rig.mgr.Route("duproute", func(account.AccountID, *msgjson.Message) *msgjson.Error {
+		second = true
+		return nil
+	})
+	tRoutes["duproute"](nil, nil)
+	if !first || second {
+		t.Fatalf("original handler was replaced")
 	}
 }
 
-func TestSign(t *testing.T) {
-	sig1 := tNewUser(t).randomSignature()
-	sig1Bytes := sig1.Serialize()
-	rig.signer.sig = sig1
-	s := &tSignable{b: randBytes(25)}
-	rig.mgr.Sign(s)
-	if !bytes.Equal(sig1Bytes, s.SigBytes()) {
-		t.Fatalf("incorrect signature. expected %x, got %x", sig1.Serialize(), s.SigBytes())
+func TestDrainMode(t *testing.T) {
+	connected := tNewUser(t)
+	rig.signer.sig = connected.randomSignature()
+	connectUser(t, connected)
+
+	rig.mgr.EnterDrainMode()
+	defer rig.mgr.ExitDrainMode()
+
+	// A new connection is refused.
+	newcomer := tNewUser(t)
+	rpcErr := rig.mgr.handleConnect(newcomer.conn, queueUser(t, newcomer))
+	makeEnsureErr(t)(rpcErr, "drain mode connect", msgjson.TryAgainLaterError)
+	if rig.mgr.user(newcomer.acctID) != nil {
+		t.Fatalf("user registered in drain mode")
 	}
 
-	// Try two at a time
-	s2 := &tSignable{b: randBytes(25)}
-	rig.mgr.Sign(s, s2)
+	// The connected user can still use routes.
+	var routed bool
+	rig.mgr.Route("drainroute", func(id account.AccountID, msg *msgjson.Message) *msgjson.Error {
+		routed = id == connected.acctID
+		return nil
+	})
+	if rpcErr := tRoutes["drainroute"](connected.conn, nil); rpcErr != nil {
+		t.Fatalf("route error for connected user in drain mode: %s", rpcErr.Message)
+	}
+	if !routed {
+		t.Fatalf("route not handled for connected user")
+	}
+
+	// Exiting drain mode allows new connections.
+	rig.mgr.ExitDrainMode()
+	connectUser(t, newcomer)
+	if rig.mgr.user(newcomer.acctID) == nil {
+		t.Fatalf("user not registered after exiting drain mode")
+	}
 }
 
-func TestSend(t *testing.T) {
+func TestSafeMode(t *testing.T) {
 	user := tNewUser(t)
 	rig.signer.sig = user.randomSignature()
 	connectUser(t, user)
-	foreigner := tNewUser(t)
 
-	type tA struct {
-		A int
+	handled := make(map[string]bool)
+	for _, route := range []string{msgjson.LimitRoute, msgjson.MarketRoute, msgjson.CancelRoute} {
+		route := route
+		rig.mgr.Route(route, func(account.AccountID, *msgjson.Message) *msgjson.Error {
+			handled[route] = true
+			return nil
+		})
 	}
-	payload := &tA{A: 5}
-	resp, _ := msgjson.NewResponse(comms.NextID(), payload, nil)
-	payload = &tA{A: 10}
-	req, _ := msgjson.NewRequest(comms.NextID(), "testroute", payload)
 
-	// Send a message to a foreigner
-	rig.mgr.Send(foreigner.acctID, resp)
-	if foreigner.conn.getSend() != nil {
-		t.Fatalf("message magically got through to foreigner")
+	rig.mgr.SetSafeMode(true)
+	defer rig.mgr.SetSafeMode(false)
+	if !rig.mgr.SafeMode() {
+		t.Fatalf("not in safe mode")
 	}
-	if user.conn.getSend() != nil {
-		t.Fatalf("foreigner message sent to authed user")
+
+	// New orders are refused.
+	for _, route := range []string{msgjson.LimitRoute, msgjson.MarketRoute} {
+		rpcErr := tRoutes[route](user.conn, nil)
+		makeEnsureErr(t)(rpcErr, route+" in safe mode", msgjson.TryAgainLaterError)
+		if handled[route] {
+			t.Fatalf("%s handled in safe mode", route)
+		}
 	}
 
-	// Now send to the user
-	rig.mgr.Send(user.acctID, resp)
-	msg := user.conn.getSend()
-	if msg == nil {
-		t.Fatalf("no message for authed user")
+	// Cancels and status requests still work.
+	if rpcErr := tRoutes[msgjson.CancelRoute](user.conn, nil); rpcErr != nil || !handled[msgjson.CancelRoute] {
+		t.Fatalf("cancel not handled in safe mode: %v", rpcErr)
 	}
-	tr := new(tA)
-	r, _ := msg.Response()
-	err := json.Unmarshal(r.Result, tr)
-	if err != nil {
-		t.Fatalf("unmarshal error: %v", err)
+	rig.storage.orderStatuses = []*db.OrderStatus{{}}
+	defer func() { rig.storage.orderStatuses = nil }()
+	req, _ := msgjson.NewRequest(comms.NextID(), msgjson.OrderStatusRoute,
+		[]msgjson.OrderStatusRequest{{OrderID: encode.RandomBytes(order.OrderIDSize)}})
+	if rpcErr := tRoutes[msgjson.OrderStatusRoute](user.conn, req); rpcErr != nil {
+		t.Fatalf("order status error in safe mode: %v", rpcErr)
 	}
-	if tr.A != 5 {
-		t.Fatalf("expected A = 5, got A = %d", tr.A)
+	if user.conn.getSend() == nil {
+		t.Fatalf("no order status response in safe mode")
 	}
 
-	// Send a request to a foreigner
-	rig.mgr.Request(foreigner.acctID, req, func(comms.Link, *msgjson.Message) {})
-	if foreigner.conn.getReq() != nil {
-		t.Fatalf("request magically got through to foreigner")
+	// Orders are accepted again after exiting safe mode.
+	rig.mgr.SetSafeMode(false)
+	if rpcErr := tRoutes[msgjson.LimitRoute](user.conn, nil); rpcErr != nil || !handled[msgjson.LimitRoute] {
+		t.Fatalf("limit order not handled after exiting safe mode: %v", rpcErr)
 	}
-	if user.conn.getReq() != nil {
-		t.Fatalf("foreigner request sent to authed user")
+}
+
+func TestConnectErrorKinds(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+
+	ensureKind := func(err error, tag string, kind error, code int) {
+		t.Helper()
+		if !errors.Is(err, kind) {
+			t.Fatalf("%s: expected error %q, got %v", tag, kind, err)
+		}
+		if msgErr := toMsgError(err); msgErr.Code != code {
+			t.Fatalf("%s: wrong msgjson error code. expected %d, got %d", tag, code, msgErr.Code)
+		}
 	}
 
-	// Send a request to an authed user.
-	rig.mgr.Request(user.acctID, req, func(comms.Link, *msgjson.Message) {})
-	treq := user.conn.getReq()
-	if treq == nil {
-		t.Fatalf("no request for user")
+	// Account not found.
+	msg := queueUser(t, user)
+	rig.storage.acct = nil
+	ensureKind(rig.mgr.connect(user.conn, msg), "no account", ErrAccountNotFound, msgjson.AccountNotFoundError)
+
+	// Bad signature.
+	rig.storage.acct = &account.Account{ID: user.acctID, PubKey: user.privKey.PubKey()}
+	connect := tNewConnect(user)
+	connect.SetSig(signMsg(user.privKey, []byte("wrong")))
+	msg, _ = msgjson.NewRequest(comms.NextID(), msgjson.ConnectRoute, connect)
+	err := rig.mgr.connect(user.conn, msg)
+	ensureKind(err, "bad signature", ErrBadSignature, msgjson.SignatureError)
+	if errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("bad signature error matches ErrAccountNotFound")
 	}
 
-	tr = new(tA)
-	err = json.Unmarshal(treq.msg.Payload, tr)
-	if err != nil {
-		t.Fatalf("request unmarshal error: %v", err)
+	// Drain mode.
+	rig.mgr.EnterDrainMode()
+	err = rig.mgr.connect(user.conn, queueUser(t, user))
+	rig.mgr.ExitDrainMode()
+	ensureKind(err, "drain mode", ErrDrainMode, msgjson.TryAgainLaterError)
+
+	// Outdated client.
+	minVer := dex.NewSemver(1, 0, 0)
+	rig.mgr.minClientVer = &minVer
+	err = rig.mgr.connect(user.conn, queueUser(t, user))
+	rig.mgr.minClientVer = nil
+	ensureKind(err, "outdated client", ErrOutdatedClient, msgjson.OutdatedClientError)
+
+	if err := rig.mgr.connect(user.conn, queueUser(t, user)); err != nil {
+		t.Fatalf("connect error: %v", err)
 	}
-	if tr.A != 10 {
-		t.Fatalf("expected A = 10, got A = %d", tr.A)
+
+	// Other errors are internal errors for the client.
+	if msgErr := toMsgError(errors.New("test error")); msgErr.Code != msgjson.RPCInternalError {
+		t.Fatalf("wrong code for unknown error %d", msgErr.Code)
 	}
 }
 
-func TestConnectErrors(t *testing.T) {
+func TestCanTrade(t *testing.T) {
+	rig.mgr.minTradingTier = 3
+	defer func() { rig.mgr.minTradingTier = 1 }()
+	defer func() { rig.storage.bonds = nil }()
+
+	// Not connected.
 	user := tNewUser(t)
-	rig.storage.acct = nil
-	rig.signer.sig = user.randomSignature()
+	if rig.mgr.CanTrade(user.acctID) {
+		t.Fatalf("disconnected user can trade")
+	}
 
-	ensureErr := makeEnsureErr(t)
+	// Connected below the minimum trading tier.
+	rig.storage.setBondTier(2)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	if rig.mgr.user(user.acctID) == nil {
+		t.Fatalf("user below trading tier not allowed to connect")
+	}
+	if rig.mgr.CanTrade(user.acctID) {
+		t.Fatalf("user below trading tier can trade")
+	}
+
+	// Connected at the minimum trading tier.
+	user = tNewUser(t)
+	rig.storage.setBondTier(3)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	if !rig.mgr.CanTrade(user.acctID) {
+		t.Fatalf("user at trading tier cannot trade")
+	}
+}
+
+func TestSetBondConfs(t *testing.T) {
+	user := tNewUser(t)
+	acctPubKey := user.privKey.PubKey().SerializeCompressed()
+	const bondConfs = 5
+	rig.mgr.checkBond = func(ctx context.Context, assetID uint32, ver uint16, coinID []byte) (amt, lockTime, confs int64, acct account.AccountID, err error) {
+		return int64(tRegFee * 10), time.Now().Add(48 * time.Hour).Unix(), bondConfs, user.acctID, nil
+	}
+	defer func() { rig.mgr.checkBond = nil }()
+	defer rig.mgr.SetBondConfs("dcr", uint32(tBondConfs))
+
+	postBond := func() {
+		t.Helper()
+		pb := &msgjson.PostBond{
+			AcctPubKey: acctPubKey,
+			AssetID:    42,
+			CoinID:     encode.RandomBytes(36),
+		}
+		pb.SetSig(signMsg(user.privKey, pb.Serialize()))
+		msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.PostBondRoute, pb)
+		if rpcErr := rig.mgr.handlePostBond(user.conn, msg); rpcErr != nil {
+			t.Fatalf("handlePostBond error: %v", rpcErr)
+		}
+	}
+
+	if err := rig.mgr.SetBondConfs("btc", 10); err == nil {
+		t.Fatalf("no error for unknown bond asset")
+	}
+
+	// Enough confirmations. The bond is accepted right away.
+	if err := rig.mgr.SetBondConfs("dcr", bondConfs); err != nil {
+		t.Fatalf("SetBondConfs error: %v", err)
+	}
+	postBond()
+	if user.conn.getSend() == nil {
+		t.Fatalf("no postbond response for confirmed bond")
+	}
+
+	// Raise the required confirmations. The same bond is now unconfirmed.
+	if err := rig.mgr.SetBondConfs("dcr", bondConfs+1); err != nil {
+		t.Fatalf("SetBondConfs error: %v", err)
+	}
+	if ba, _ := rig.mgr.bondAsset(42); ba.Confs != bondConfs+1 {
+		t.Fatalf("bond confs not updated")
+	}
+	postBond()
+	if user.conn.getSend() != nil {
+		t.Fatalf("postbond response for unconfirmed bond")
+	}
+}
+
+func TestMaxBondLockTime(t *testing.T) {
+	user := tNewUser(t)
+	acctPubKey := user.privKey.PubKey().SerializeCompressed()
+	lockTime := time.Now().Add(30 * 24 * time.Hour).Unix()
+	rig.mgr.checkBond = func(ctx context.Context, assetID uint32, ver uint16, coinID []byte) (amt, lt, confs int64, acct account.AccountID, err error) {
+		return int64(tRegFee * 10), lockTime, tBondConfs, user.acctID, nil
+	}
+	defer func() { rig.mgr.checkBond, rig.mgr.maxBondLockTime = nil, 0 }()
+
+	postBond := func() *msgjson.Error {
+		pb := &msgjson.PostBond{
+			AcctPubKey: acctPubKey,
+			AssetID:    42,
+			CoinID:     encode.RandomBytes(36),
+		}
+		pb.SetSig(signMsg(user.privKey, pb.Serialize()))
+		msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.PostBondRoute, pb)
+		return rig.mgr.handlePostBond(user.conn, msg)
+	}
+
+	// No limit.
+	if rpcErr := postBond(); rpcErr != nil {
+		t.Fatalf("handlePostBond error with no max lock time: %v", rpcErr)
+	}
+	user.conn.getSend()
+
+	// Lock time within the limit.
+	rig.mgr.maxBondLockTime = 60 * 24 * time.Hour
+	if rpcErr := postBond(); rpcErr != nil {
+		t.Fatalf("handlePostBond error for lock time within limit: %v", rpcErr)
+	}
+	user.conn.getSend()
+
+	// Lock time too far in the future.
+	rig.mgr.maxBondLockTime = 7 * 24 * time.Hour
+	rpcErr := postBond()
+	makeEnsureErr(t)(rpcErr, "excessive lock time", msgjson.BondError)
+	if !strings.Contains(rpcErr.Message, "excessive lock time") {
+		t.Fatalf("wrong error message: %q", rpcErr.Message)
+	}
+}
+
+func TestBondTierSchedule(t *testing.T) {
+	user := tNewUser(t)
+	acctPubKey := user.privKey.PubKey().SerializeCompressed()
+	lockTime := time.Now().Add(30 * 24 * time.Hour).Unix()
+	var bondAmt int64
+	rig.mgr.checkBond = func(ctx context.Context, assetID uint32, ver uint16, coinID []byte) (amt, lt, confs int64, acct account.AccountID, err error) {
+		return bondAmt, lockTime, tBondConfs, user.acctID, nil
+	}
+	bondAsset, _ := rig.mgr.bondAsset(42)
+	rig.mgr.bondAssetMtx.Lock()
+	bondAsset.BondTierSchedule = []uint64{tRegFee, tRegFee * 3, tRegFee * 6}
+	rig.mgr.bondAssetMtx.Unlock()
+	defer func() {
+		rig.mgr.checkBond = nil
+		rig.mgr.bondAssetMtx.Lock()
+		bondAsset.BondTierSchedule = nil
+		rig.mgr.bondAssetMtx.Unlock()
+	}()
+
+	postBond := func(amt uint64) (uint32, *msgjson.Error) {
+		t.Helper()
+		bondAmt = int64(amt)
+		pb := &msgjson.PostBond{
+			AcctPubKey: acctPubKey,
+			AssetID:    42,
+			CoinID:     encode.RandomBytes(36),
+		}
+		pb.SetSig(signMsg(user.privKey, pb.Serialize()))
+		msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.PostBondRoute, pb)
+		if rpcErr := rig.mgr.handlePostBond(user.conn, msg); rpcErr != nil {
+			return 0, rpcErr
+		}
+		resp, _ := user.conn.getSend().Response()
+		res := new(msgjson.PostBondResult)
+		if err := json.Unmarshal(resp.Result, res); err != nil {
+			t.Fatalf("error unmarshaling postbond result: %v", err)
+		}
+		return res.Strength, nil
+	}
+
+	for _, tt := range []struct {
+		amt          uint64
+		wantStrength uint32
+	}{
+		{tRegFee, 1},
+		{tRegFee * 4, 2},  // linear would be 4
+		{tRegFee * 6, 3},  // exactly the last tier
+		{tRegFee * 10, 3}, // no strength beyond the schedule
+	} {
+		strength, rpcErr := postBond(tt.amt)
+		if rpcErr != nil {
+			t.Fatalf("handlePostBond error for amount %d: %v", tt.amt, rpcErr)
+		}
+		if strength != tt.wantStrength {
+			t.Fatalf("wrong strength for amount %d. wanted %d, got %d", tt.amt, tt.wantStrength, strength)
+		}
+	}
+
+	// Less than the first tier is insufficient.
+	_, rpcErr := postBond(tRegFee - 1)
+	makeEnsureErr(t)(rpcErr, "insufficient bond", msgjson.BondError)
+	if !strings.Contains(rpcErr.Message, "insufficient bond amount") {
+		t.Fatalf("wrong error message: %q", rpcErr.Message)
+	}
+
+	// Linear without a schedule.
+	if strength := (&msgjson.BondAsset{Amt: tRegFee}).Strength(tRegFee * 4); strength != 4 {
+		t.Fatalf("wrong linear strength %d", strength)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	msgBytes := randBytes(50)
+	sigBytes := signMsg(user.privKey, msgBytes)
+	err := rig.mgr.Auth(user.acctID, msgBytes, sigBytes)
+	if err != nil {
+		t.Fatalf("unexpected auth error: %v", err)
+	}
+
+	foreigner := tNewUser(t)
+	sigBytes = signMsg(user.privKey, msgBytes)
+	err = rig.mgr.Auth(foreigner.acctID, msgBytes, sigBytes)
+	if err == nil {
+		t.Fatalf("no auth error for foreigner")
+	}
+
+	msgBytes = randBytes(50)
+	err = rig.mgr.Auth(user.acctID, msgBytes, sigBytes)
+	if err == nil {
+		t.Fatalf("no error for wrong message")
+	}
+}
+
+func TestSign(t *testing.T) {
+	sig1 := tNewUser(t).randomSignature()
+	sig1Bytes := sig1.Serialize()
+	rig.signer.sig = sig1
+	s := &tSignable{b: randBytes(25)}
+	if err := rig.mgr.Sign(s); err != nil {
+		t.Fatalf("Sign error: %v", err)
+	}
+	if !bytes.Equal(sig1Bytes, s.SigBytes()) {
+		t.Fatalf("incorrect signature. expected %x, got %x", sig1.Serialize(), s.SigBytes())
+	}
+
+	// Try two at a time
+	s2 := &tSignable{b: randBytes(25)}
+	if err := rig.mgr.Sign(s, s2); err != nil {
+		t.Fatalf("Sign error: %v", err)
+	}
+
+	// A nil signature is an error, not a panic.
+	rig.signer.sig = nil
+	s3 := &tSignable{b: randBytes(25)}
+	if err := rig.mgr.Sign(s3); !errors.Is(err, ErrSignerFailure) {
+		t.Fatalf("expected ErrSignerFailure for nil signature, got %v", err)
+	}
+	if len(s3.SigBytes()) != 0 {
+		t.Fatalf("signature set for failed signing")
+	}
+	rig.signer.sig = sig1
+
+	// No signer.
+	authMgr := &AuthManager{}
+	if err := authMgr.Sign(s3); !errors.Is(err, ErrSignerFailure) {
+		t.Fatalf("expected ErrSignerFailure for nil signer, got %v", err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	foreigner := tNewUser(t)
+
+	type tA struct {
+		A int
+	}
+	payload := &tA{A: 5}
+	resp, _ := msgjson.NewResponse(comms.NextID(), payload, nil)
+	payload = &tA{A: 10}
+	req, _ := msgjson.NewRequest(comms.NextID(), "testroute", payload)
+
+	// Send a message to a foreigner
+	rig.mgr.Send(foreigner.acctID, resp)
+	if foreigner.conn.getSend() != nil {
+		t.Fatalf("message magically got through to foreigner")
+	}
+	if user.conn.getSend() != nil {
+		t.Fatalf("foreigner message sent to authed user")
+	}
+
+	// Now send to the user
+	rig.mgr.Send(user.acctID, resp)
+	msg := user.conn.getSend()
+	if msg == nil {
+		t.Fatalf("no message for authed user")
+	}
+	tr := new(tA)
+	r, _ := msg.Response()
+	err := json.Unmarshal(r.Result, tr)
+	if err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if tr.A != 5 {
+		t.Fatalf("expected A = 5, got A = %d", tr.A)
+	}
+
+	// Send a request to a foreigner
+	rig.mgr.Request(foreigner.acctID, req, func(comms.Link, *msgjson.Message) {})
+	if foreigner.conn.getReq() != nil {
+		t.Fatalf("request magically got through to foreigner")
+	}
+	if user.conn.getReq() != nil {
+		t.Fatalf("foreigner request sent to authed user")
+	}
+
+	// Send a request to an authed user.
+	rig.mgr.Request(user.acctID, req, func(comms.Link, *msgjson.Message) {})
+	treq := user.conn.getReq()
+	if treq == nil {
+		t.Fatalf("no request for user")
+	}
+
+	tr = new(tA)
+	err = json.Unmarshal(treq.msg.Payload, tr)
+	if err != nil {
+		t.Fatalf("request unmarshal error: %v", err)
+	}
+	if tr.A != 10 {
+		t.Fatalf("expected A = 10, got A = %d", tr.A)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	users := make([]*tUser, 0, 3)
+	for i := 0; i < 3; i++ {
+		user := tNewUser(t)
+		rig.signer.sig = user.randomSignature()
+		connectUser(t, user)
+		users = append(users, user)
+	}
+
+	msg, _ := msgjson.NewNotification("maintenance", "server going down")
+	// Other tests may leave connected users, so only require that at least our
+	// users got the message.
+	if sent := rig.mgr.Broadcast(msg); sent < len(users) {
+		t.Fatalf("expected at least %d sends, got %d", len(users), sent)
+	}
+	for i, user := range users {
+		var found bool
+		for _, m := range user.conn.sends {
+			if m == msg {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("user %d did not receive the broadcast", i)
+		}
+	}
+
+	// A send error removes the client and is not counted.
+	failer := users[0]
+	failer.conn.sendErr = fmt.Errorf("test error")
+	before := rig.mgr.Broadcast(msg)
+	if rig.mgr.user(failer.acctID) != nil {
+		t.Fatalf("failed client not removed")
+	}
+	if after := rig.mgr.Broadcast(msg); after != before {
+		t.Fatalf("expected %d sends after removal, got %d", before, after)
+	}
+}
+
+func TestConnectErrors(t *testing.T) {
+	user := tNewUser(t)
+	rig.storage.acct = nil
+	rig.signer.sig = user.randomSignature()
+
+	ensureErr := makeEnsureErr(t)
 
 	// Test an invalid json payload
 	msg, err := msgjson.NewRequest(comms.NextID(), "testreq", nil)
@@ -1304,271 +2098,2570 @@ func TestConnectErrors(t *testing.T) {
 	}
 }
 
-func TestHandleResponse(t *testing.T) {
+func TestConnectAPIVersion(t *testing.T) {
+	rig.mgr.apiVers = []uint16{1, 2}
+	defer func() { rig.mgr.apiVers = nil }()
+
 	user := tNewUser(t)
 	rig.signer.sig = user.randomSignature()
-	connectUser(t, user)
-	foreigner := tNewUser(t)
-	unknownResponse, err := msgjson.NewResponse(comms.NextID(), 10, nil)
-	if err != nil {
-		t.Fatalf("error encoding unknown response: %v", err)
-	}
-
-	// test foreigner. Really just want to make sure that this returns before
-	// trying to run a nil handler function, which would panic.
-	rig.mgr.handleResponse(foreigner.conn, unknownResponse)
+	rig.storage.acct = &account.Account{ID: user.acctID, PubKey: user.privKey.PubKey()}
 
-	// test for a missing handler
-	rig.mgr.handleResponse(user.conn, unknownResponse)
-	m := user.conn.getSend()
-	if m == nil {
-		t.Fatalf("no error sent for unknown response")
+	newConnectMsg := func(apiVer uint16) *msgjson.Message {
+		connect := tNewConnect(user)
+		connect.APIVersion = apiVer
+		connect.SetSig(signMsg(user.privKey, connect.Serialize()))
+		msg, err := msgjson.NewRequest(comms.NextID(), msgjson.ConnectRoute, connect)
+		if err != nil {
+			t.Fatalf("NewRequest error: %v", err)
+		}
+		return msg
 	}
-	resp, _ := m.Response()
-	if resp.Error == nil {
-		t.Fatalf("error not set in response for unknown response")
+
+	// unsupported version
+	rpcErr := rig.mgr.handleConnect(user.conn, newConnectMsg(0))
+	makeEnsureErr(t)(rpcErr, "unsupported API version", msgjson.RPCVersionUnsupported)
+	if !strings.Contains(rpcErr.Message, "[1 2]") {
+		t.Fatalf("supported versions not listed in error message: %q", rpcErr.Message)
 	}
-	if resp.Error.Code != msgjson.UnknownResponseID {
-		t.Fatalf("wrong error code for unknown response. expected %d, got %d",
-			msgjson.UnknownResponseID, resp.Error.Code)
+	if rig.mgr.user(user.acctID) != nil {
+		t.Fatalf("user registered with unsupported API version")
 	}
 
-	// Check that expired response handlers are removed from the map.
+	// supported version
+	rpcErr = rig.mgr.handleConnect(user.conn, newConnectMsg(2))
+	if rpcErr != nil {
+		t.Fatalf("error for supported API version: %s", rpcErr.Message)
+	}
+	if user.conn.getSend() == nil {
+		t.Fatalf("no response to clear")
+	}
 	client := rig.mgr.user(user.acctID)
 	if client == nil {
-		t.Fatalf("client not found")
-	}
-
-	newID := comms.NextID()
-	client.logReq(newID, func(comms.Link, *msgjson.Message) {},
-		0, func() { t.Log("expired (ok)") })
-	// Wait until response handler expires.
-	if waitFor(func() bool {
-		client.mtx.Lock()
-		defer client.mtx.Unlock()
-		return len(client.respHandlers) == 0
-	}, 10*time.Second) {
-		t.Fatalf("expected 0 response handlers, found %d", len(client.respHandlers))
+		t.Fatalf("user not registered with supported API version")
 	}
-	client.mtx.Lock()
-	if client.respHandlers[newID] != nil {
-		t.Fatalf("response handler should have been expired")
+	if client.apiVer != 2 {
+		t.Fatalf("wrong negotiated API version. wanted 2, got %d", client.apiVer)
 	}
-	client.mtx.Unlock()
+}
 
-	// After logging a new request, there should still be exactly one response handler
-	// present. A short sleep is added to give a chance for clean-up running in a
-	// separate go-routine to finish before we continue asserting on the result.
-	newID = comms.NextID()
-	client.logReq(newID, func(comms.Link, *msgjson.Message) {}, time.Hour, noop)
-	time.Sleep(time.Millisecond)
-	client.mtx.Lock()
-	if len(client.respHandlers) != 1 {
-		t.Fatalf("expected 1 response handler, found %d", len(client.respHandlers))
-	}
-	if client.respHandlers[newID] == nil {
-		t.Fatalf("wrong response handler left after cleanup cycle")
-	}
-	client.mtx.Unlock()
+type tBanPolicy struct {
+	ban  bool
+	tier int64
 }
 
-func TestAuthManager_RecordCancel_RecordCompletedOrder(t *testing.T) {
-	user := tNewUser(t)
-	rig.signer.sig = user.randomSignature()
-	connectUser(t, user)
+func (p *tBanPolicy) ShouldBan(_ account.AccountID, _ int32, tier int64) bool {
+	p.tier = tier
+	return p.ban
+}
 
+func TestBanPolicy(t *testing.T) {
+	policy := &tBanPolicy{ban: true}
+	rig.mgr.banPolicy = policy
+	defer func() { rig.mgr.banPolicy = nil }()
+
+	rig.storage.setBondTier(1)
+	defer func() { rig.storage.bonds = nil }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	rpcErr := rig.mgr.handleConnect(user.conn, queueUser(t, user))
+	makeEnsureErr(t)(rpcErr, "ban policy", msgjson.AccountClosedError)
+	if !user.conn.banished {
+		t.Fatalf("link not banished")
+	}
+	if rig.mgr.user(user.acctID) != nil {
+		t.Fatalf("banned user registered")
+	}
+	if policy.tier != 1 {
+		t.Fatalf("wrong tier provided to ban policy. wanted 1, got %d", policy.tier)
+	}
+
+	// Not banned.
+	policy.ban = false
+	user = tNewUser(t)
+	connectUser(t, user)
+	if user.conn.banished {
+		t.Fatalf("link banished")
+	}
+	if rig.mgr.user(user.acctID) == nil {
+		t.Fatalf("user not registered")
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	user := tNewUser(t)
+	if rig.mgr.Disconnect(user.acctID) {
+		t.Fatalf("Disconnect reported success for an offline user")
+	}
+	connectUser(t, user)
+	if rig.mgr.user(user.acctID) == nil {
+		t.Fatalf("user not connected")
+	}
+	if !rig.mgr.Disconnect(user.acctID) {
+		t.Fatalf("Disconnect did not find the connected user")
+	}
+	if rig.mgr.user(user.acctID) != nil {
+		t.Fatalf("user still connected after Disconnect")
+	}
+	select {
+	case <-user.conn.Done():
+	default:
+		t.Fatalf("link not disconnected")
+	}
+	if user.conn.banished {
+		t.Fatalf("user banished by Disconnect")
+	}
+	if rig.mgr.Disconnect(user.acctID) {
+		t.Fatalf("second Disconnect reported success")
+	}
+}
+
+func TestConnectionTagger(t *testing.T) {
+	rig.mgr.connTagger = func(conn comms.Link) string {
+		return "frontend-" + conn.Addr()
+	}
+	defer func() { rig.mgr.connTagger = nil }()
+
+	user := tNewUser(t)
+	if id := rig.mgr.ClientCustomID(user.acctID); id != "" {
+		t.Fatalf("custom ID %q for disconnected user", id)
+	}
+	user.conn.addr = "10.1.2.3:7232"
+	connectUser(t, user)
+	if id, exp := rig.mgr.ClientCustomID(user.acctID), "frontend-"+user.conn.addr; id != exp {
+		t.Fatalf("wrong custom ID %q, expected %q", id, exp)
+	}
+}
+
+func TestConnectFilter(t *testing.T) {
+	bannedIP := dex.NewIPKey("10.0.0.1")
+	var filtered []dex.IPKey
+	rig.mgr.connectFilter = func(ip dex.IPKey) error {
+		filtered = append(filtered, ip)
+		if ip == bannedIP {
+			return errors.New("disallowed region")
+		}
+		return nil
+	}
+	defer func() { rig.mgr.connectFilter = nil }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	rig.storage.acct = &account.Account{ID: user.acctID, PubKey: user.privKey.PubKey()}
+
+	// A bad signature would be a SignatureError if the filter was not
+	// checked first.
+	connect := tNewConnect(user)
+	connect.SetSig([]byte{0x09, 0x08})
+	msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.ConnectRoute, connect)
+	user.conn.ip = bannedIP
+	rpcErr := rig.mgr.handleConnect(user.conn, msg)
+	makeEnsureErr(t)(rpcErr, "filtered IP", msgjson.UnauthorizedConnection)
+	if !strings.Contains(rpcErr.Message, "disallowed region") {
+		t.Fatalf("filter error not in message: %q", rpcErr.Message)
+	}
+	if rig.mgr.user(user.acctID) != nil {
+		t.Fatalf("filtered user registered")
+	}
+
+	// Allowed IP.
+	user.conn.ip = dex.NewIPKey("123.123.123.123")
+	connectUser(t, user)
+	if rig.mgr.user(user.acctID) == nil {
+		t.Fatalf("user not registered")
+	}
+	if len(filtered) != 2 || filtered[0] != bannedIP || filtered[1] != user.conn.ip {
+		t.Fatalf("wrong IPs passed to filter: %v", filtered)
+	}
+}
+
+func TestBondRenewAdvice(t *testing.T) {
+	rig.mgr.bondRenewWindow = 2 * time.Hour
+	defer func() {
+		rig.mgr.bondRenewWindow = 0
+		rig.storage.bonds = nil
+	}()
+
+	now := time.Now()
+	expiringAt := func(expiry time.Time) int64 {
+		return expiry.Add(rig.mgr.bondExpiry).Unix()
+	}
+	rig.storage.bonds = []*db.Bond{
+		{AssetID: 42, Strength: 1, LockTime: expiringAt(now.Add(time.Hour))},
+		{AssetID: 42, Strength: 2, LockTime: expiringAt(now.Add(90 * time.Minute))},
+		{AssetID: 42, Strength: 4, LockTime: expiringAt(now.Add(24 * time.Hour))},
+		{AssetID: 0, Strength: 8, LockTime: expiringAt(now.Add(30 * 24 * time.Hour))},
+	}
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	result := extractConnectResult(t, connectUser(t, user))
+	if len(result.BondRenewAdvice) != 1 {
+		t.Fatalf("expected 1 bond renew advice, got %d", len(result.BondRenewAdvice))
+	}
+	advice := result.BondRenewAdvice[0]
+	if advice.AssetID != 42 || advice.Strength != 3 {
+		t.Fatalf("wrong advice. wanted asset 42 strength 3, got asset %d strength %d",
+			advice.AssetID, advice.Strength)
+	}
+	if wantExpiry := uint64(now.Add(time.Hour).Unix()); advice.Expiry != wantExpiry {
+		t.Fatalf("wrong advice expiry. wanted %d, got %d", wantExpiry, advice.Expiry)
+	}
+
+	// No advice if no bonds are expiring soon.
+	rig.storage.bonds = rig.storage.bonds[2:]
+	user = tNewUser(t)
+	result = extractConnectResult(t, connectUser(t, user))
+	if len(result.BondRenewAdvice) != 0 {
+		t.Fatalf("unexpected bond renew advice: %v", result.BondRenewAdvice)
+	}
+}
+
+func TestConnectReplay(t *testing.T) {
+	rig.mgr.connectSkew = time.Minute
+	defer func() { rig.mgr.connectSkew = 0 }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	rig.storage.acct = &account.Account{ID: user.acctID, PubKey: user.privKey.PubKey()}
+
+	ensureErr := makeEnsureErr(t)
+	connectAt := func(stamp uint64) *msgjson.Error {
+		connect := tNewConnect(user)
+		connect.Time = stamp
+		connect.SetSig(signMsg(user.privKey, connect.Serialize()))
+		msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.ConnectRoute, connect)
+		rpcErr := rig.mgr.handleConnect(user.conn, msg)
+		if rpcErr == nil && user.conn.getSend() == nil {
+			t.Fatalf("no connect response")
+		}
+		return rpcErr
+	}
+
+	now := uint64(time.Now().UnixMilli())
+	if rpcErr := connectAt(now); rpcErr != nil {
+		t.Fatalf("connect error: %v", rpcErr)
+	}
+
+	// Duplicate timestamp.
+	ensureErr(connectAt(now), "duplicate connect time", msgjson.ReplayedConnectError)
+
+	// Stale timestamp.
+	stale := now - uint64(2*time.Minute/time.Millisecond)
+	ensureErr(connectAt(stale), "stale connect time", msgjson.ReplayedConnectError)
+
+	// Slightly older, but within the skew, is allowed.
+	if rpcErr := connectAt(now - 1000); rpcErr != nil {
+		t.Fatalf("connect error within skew: %v", rpcErr)
+	}
+
+	// Newer is allowed.
+	if rpcErr := connectAt(now + 1000); rpcErr != nil {
+		t.Fatalf("connect error for newer time: %v", rpcErr)
+	}
+
+	// Earlier accepted times within the skew can't be replayed.
+	ensureErr(connectAt(now), "replayed connect time", msgjson.ReplayedConnectError)
+	ensureErr(connectAt(now-1000), "replayed older connect time", msgjson.ReplayedConnectError)
+
+	// Only one of concurrent replays of a new time passes.
+	stamp := now + 2000
+	var wg sync.WaitGroup
+	var passed atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rig.mgr.claimConnectStamp(user.acctID, stamp) == nil {
+				passed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := passed.Load(); n != 1 {
+		t.Fatalf("%d concurrent replays passed", n)
+	}
+
+	// Times too old to pass are not kept.
+	if err := rig.mgr.claimConnectStamp(user.acctID, now+uint64(2*time.Minute/time.Millisecond)); err != nil {
+		t.Fatalf("claimConnectStamp error: %v", err)
+	}
+	rig.mgr.connectStampsMtx.Lock()
+	_, found := rig.mgr.connectStamps[user.acctID].seen[now-1000]
+	rig.mgr.connectStampsMtx.Unlock()
+	if found {
+		t.Fatalf("connect time outside of the skew still recorded")
+	}
+}
+
+func TestRespHandlerCleanupInterval(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	rig.mgr.respSweepInterval = interval
+	rig.mgr.respSweeper = newRespSweeper()
+	defer func() {
+		rig.mgr.respSweepInterval = 0
+		rig.mgr.respSweeper = nil
+	}()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	client := rig.mgr.user(user.acctID)
+	if client == nil {
+		t.Fatalf("client not found")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rig.mgr.sweepRespHandlers(ctx)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	expired := make(chan struct{})
+	expiredID := comms.NextID()
+	client.logReq(expiredID, func(comms.Link, *msgjson.Message) {}, 0, func() { close(expired) })
+	liveID := comms.NextID()
+	client.logReq(liveID, func(comms.Link, *msgjson.Message) {}, time.Hour, noop)
+
+	select {
+	case <-expired:
+	case <-time.After(20 * interval):
+		t.Fatalf("response handler not expired promptly")
+	}
+
+	client.mtx.Lock()
+	if client.respHandlers[expiredID] != nil {
+		client.mtx.Unlock()
+		t.Fatalf("expired response handler not removed")
+	}
+	if len(client.respHandlers) != 1 {
+		client.mtx.Unlock()
+		t.Fatalf("expected 1 response handler, found %d", len(client.respHandlers))
+	}
+	client.mtx.Unlock()
+
+	// The live handler is still available for a response.
+	if client.respHandler(liveID) == nil {
+		t.Fatalf("live response handler not found")
+	}
+
+	// A client with no outstanding handlers is no longer swept.
+	rig.mgr.respSweeper.sweep(time.Now())
+	rig.mgr.respSweeper.mtx.Lock()
+	_, found := rig.mgr.respSweeper.clients[client]
+	rig.mgr.respSweeper.mtx.Unlock()
+	if found {
+		t.Fatalf("client without response handlers still swept")
+	}
+}
+
+func TestHandleResponse(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	foreigner := tNewUser(t)
+	unknownResponse, err := msgjson.NewResponse(comms.NextID(), 10, nil)
+	if err != nil {
+		t.Fatalf("error encoding unknown response: %v", err)
+	}
+
+	// test foreigner. Really just want to make sure that this returns before
+	// trying to run a nil handler function, which would panic.
+	rig.mgr.handleResponse(foreigner.conn, unknownResponse)
+
+	// test for a missing handler
+	rig.mgr.handleResponse(user.conn, unknownResponse)
+	m := user.conn.getSend()
+	if m == nil {
+		t.Fatalf("no error sent for unknown response")
+	}
+	resp, _ := m.Response()
+	if resp.Error == nil {
+		t.Fatalf("error not set in response for unknown response")
+	}
+	if resp.Error.Code != msgjson.UnknownResponseID {
+		t.Fatalf("wrong error code for unknown response. expected %d, got %d",
+			msgjson.UnknownResponseID, resp.Error.Code)
+	}
+
+	// Check that expired response handlers are removed from the map.
 	client := rig.mgr.user(user.acctID)
 	if client == nil {
 		t.Fatalf("client not found")
 	}
 
-	newOrderID := func() (oid order.OrderID) {
-		rand.Read(oid[:])
-		return
+	newID := comms.NextID()
+	client.logReq(newID, func(comms.Link, *msgjson.Message) {},
+		0, func() { t.Log("expired (ok)") })
+	// Wait until response handler expires.
+	if waitFor(func() bool {
+		client.mtx.Lock()
+		defer client.mtx.Unlock()
+		return len(client.respHandlers) == 0
+	}, 10*time.Second) {
+		t.Fatalf("expected 0 response handlers, found %d", len(client.respHandlers))
+	}
+	client.mtx.Lock()
+	if client.respHandlers[newID] != nil {
+		t.Fatalf("response handler should have been expired")
+	}
+	client.mtx.Unlock()
+
+	// After logging a new request, there should still be exactly one response handler
+	// present. A short sleep is added to give a chance for clean-up running in a
+	// separate go-routine to finish before we continue asserting on the result.
+	newID = comms.NextID()
+	client.logReq(newID, func(comms.Link, *msgjson.Message) {}, time.Hour, noop)
+	time.Sleep(time.Millisecond)
+	client.mtx.Lock()
+	if len(client.respHandlers) != 1 {
+		t.Fatalf("expected 1 response handler, found %d", len(client.respHandlers))
+	}
+	if client.respHandlers[newID] == nil {
+		t.Fatalf("wrong response handler left after cleanup cycle")
+	}
+	client.mtx.Unlock()
+}
+
+func TestMaxOutstandingRequests(t *testing.T) {
+	const maxReqs = 5
+	rig.mgr.maxOutstandingReqs = maxReqs
+	defer func() { rig.mgr.maxOutstandingReqs = 0 }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	newReq := func() *msgjson.Message {
+		msg, _ := msgjson.NewRequest(comms.NextID(), "request", "data")
+		return msg
+	}
+
+	// Flood the client with requests that are never answered.
+	for i := 0; i < maxReqs; i++ {
+		if err := rig.mgr.Request(user.acctID, newReq(), func(comms.Link, *msgjson.Message) {}); err != nil {
+			t.Fatalf("error for request %d: %v", i, err)
+		}
+	}
+	err := rig.mgr.Request(user.acctID, newReq(), func(comms.Link, *msgjson.Message) {})
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected ErrTooManyRequests, got %v", err)
+	}
+	if len(user.conn.reqs) != maxReqs {
+		t.Fatalf("expected %d requests sent, got %d", maxReqs, len(user.conn.reqs))
+	}
+	// The user should not be disconnected.
+	client := rig.mgr.user(user.acctID)
+	if client == nil {
+		t.Fatalf("user removed after refused request")
+	}
+
+	// Responding to one frees up a slot.
+	req := user.conn.getReq()
+	resp, _ := msgjson.NewResponse(req.msg.ID, nil, nil)
+	rig.mgr.handleResponse(user.conn, resp)
+	if err := rig.mgr.Request(user.acctID, newReq(), func(comms.Link, *msgjson.Message) {}); err != nil {
+		t.Fatalf("error for request after response: %v", err)
+	}
+	client.mtx.Lock()
+	nHandlers := len(client.respHandlers)
+	client.mtx.Unlock()
+	if nHandlers != maxReqs {
+		t.Fatalf("expected %d response handlers, found %d", maxReqs, nHandlers)
+	}
+}
+
+func TestRequestLatencies(t *testing.T) {
+	const histSize = 3
+	rig.mgr.latencyHistory = histSize
+	defer func() { rig.mgr.latencyHistory = 0 }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	if lats := rig.mgr.RequestLatencies(user.acctID); len(lats) != 0 {
+		t.Fatalf("expected no latencies, got %d", len(lats))
+	}
+
+	respond := func() {
+		t.Helper()
+		msg, _ := msgjson.NewRequest(comms.NextID(), "request", "data")
+		if err := rig.mgr.Request(user.acctID, msg, func(comms.Link, *msgjson.Message) {}); err != nil {
+			t.Fatalf("Request error: %v", err)
+		}
+		req := user.conn.getReq()
+		resp, _ := msgjson.NewResponse(req.msg.ID, nil, nil)
+		rig.mgr.handleResponse(user.conn, resp)
+	}
+
+	respond()
+	if lats := rig.mgr.RequestLatencies(user.acctID); len(lats) != 1 {
+		t.Fatalf("expected 1 latency, got %d", len(lats))
+	}
+
+	// The ring buffer is bounded.
+	for i := 0; i < 2*histSize; i++ {
+		respond()
+	}
+	if lats := rig.mgr.RequestLatencies(user.acctID); len(lats) != histSize {
+		t.Fatalf("expected %d latencies, got %d", histSize, len(lats))
+	}
+
+	// Unknown user.
+	if lats := rig.mgr.RequestLatencies(newAccountID()); lats != nil {
+		t.Fatalf("expected nil latencies for unknown user")
+	}
+}
+
+func TestLatencyRing(t *testing.T) {
+	r := newLatencyRing(3)
+	for i := 1; i <= 5; i++ {
+		r.add(time.Duration(i))
+	}
+	lats := r.list()
+	want := []time.Duration{3, 4, 5}
+	if len(lats) != len(want) {
+		t.Fatalf("wrong number of latencies. wanted %d, got %d", len(want), len(lats))
+	}
+	for i := range want {
+		if lats[i] != want[i] {
+			t.Fatalf("wrong latency at index %d. wanted %d, got %d", i, want[i], lats[i])
+		}
+	}
+}
+
+func TestValueWeightedScoring(t *testing.T) {
+	const base, quote = 42, 0
+	const typical = 1e8
+	result := func(outcome Outcome, value uint64) *db.MatchResult {
+		return &db.MatchResult{
+			DBID:         nextDBID(),
+			MatchID:      randomMatchID(),
+			MatchOutcome: outcome,
+			Value:        value,
+			Base:         base,
+			Quote:        quote,
+		}
+	}
+	matches := newLatestOutcomes([]*db.MatchResult{
+		result(db.OutcomeSwapSuccess, typical*10),     // +1, successes are not weighted
+		result(db.OutcomeNoSwapAsTaker, typical*10),   // -11 * 2 (capped)
+		result(db.OutcomeNoRedeemAsMaker, typical/10), // -7 * 0.5 (floored)
+		result(db.OutcomeNoSwapAsMaker, typical),      // -4 * 1
+		result(db.OutcomeNoRedeemAsTaker, 0),          // -1, unknown value
+	}, ScoringMatchLimit)
+	pimgs := newLatestOutcomes([]*db.PreimageOutcome{}, scoringOrderLimit)
+	ords := newLatestOutcomes([]*db.OrderOutcome{}, cancelThreshWindow)
+
+	setTypical := func(values ...uint64) {
+		rig.mgr.mktValueMtx.Lock()
+		delete(rig.mgr.mktValues, [2]uint32{base, quote})
+		rig.mgr.mktValueMtx.Unlock()
+		for _, v := range values {
+			rig.mgr.recordMatchValue(base, quote, v)
+		}
+	}
+
+	unweighted, successes, _ := rig.mgr.integrateOutcomes(matches, pimgs, ords)
+	if unweighted != 1-11-7-4-1 {
+		t.Fatalf("wrong unweighted score. wanted %d, got %d", 1-11-7-4-1, unweighted)
+	}
+
+	rig.mgr.valueWeightedScoring = true
+	defer func() {
+		setTypical()
+		rig.mgr.valueWeightedScoring = false
+	}()
+	setTypical(typical)
+	weighted, weightedSuccesses, _ := rig.mgr.integrateOutcomes(matches, pimgs, ords)
+	if expScore := int32(-30); weighted != expScore { // 1 - 22 - 3.5 - 4 - 1 = -29.5, rounded
+		t.Fatalf("wrong weighted score. wanted %d, got %d", expScore, weighted)
+	}
+	if successes != weightedSuccesses {
+		t.Fatalf("success count changed with weighting: %d != %d", successes, weightedSuccesses)
+	}
+
+	// Without a typical value for the market, the scores are the same.
+	setTypical()
+	if score, _, _ := rig.mgr.integrateOutcomes(matches, pimgs, ords); score != unweighted {
+		t.Fatalf("wrong score without a typical value. wanted %d, got %d", unweighted, score)
+	}
+
+	// The typical value is the average of the recent match values, and only
+	// the most recent matchValueWindow values are kept.
+	setTypical(typical*10, typical, typical*3)
+	if avg := rig.mgr.typicalMatchValue(base, quote); avg != typical*14/3 {
+		t.Fatalf("wrong typical value. wanted %f, got %f", typical*14/3., avg)
+	}
+	for range matchValueWindow {
+		rig.mgr.recordMatchValue(base, quote, typical)
+	}
+	if avg := rig.mgr.typicalMatchValue(base, quote); avg != typical {
+		t.Fatalf("old values not dropped from the typical value. wanted %f, got %f", typical, avg)
+	}
+
+	// On startup, the typical values are loaded from the stored match values,
+	// so the same history gives the same scores regardless of uptime.
+	setTypical()
+	rig.storage.matchValues = []*db.MatchResult{
+		{Value: typical, Base: base, Quote: quote},
+		{Value: typical * 3, Base: base, Quote: quote},
+	}
+	defer func() { rig.storage.matchValues = nil }()
+	if err := rig.mgr.loadMatchValues(context.Background()); err != nil {
+		t.Fatalf("loadMatchValues error: %v", err)
+	}
+	if avg := rig.mgr.typicalMatchValue(base, quote); avg != typical*2 {
+		t.Fatalf("wrong loaded typical value. wanted %f, got %f", typical*2., avg)
+	}
+	// Loading a user's outcomes does not change it.
+	rig.storage.repMatches = []*db.MatchResult{result(db.OutcomeSwapSuccess, typical*10)}
+	defer func() { rig.storage.repMatches = nil }()
+	if _, _, _, err := rig.mgr.loadUserOutcomesV1(newAccountID()); err != nil {
+		t.Fatalf("loadUserOutcomesV1 error: %v", err)
+	}
+	if avg := rig.mgr.typicalMatchValue(base, quote); avg != typical*2 {
+		t.Fatalf("typical value changed by loading outcomes. wanted %f, got %f", typical*2., avg)
+	}
+}
+
+func TestCancelRatio(t *testing.T) {
+	defer func() { rig.storage.ratio = ratioData{} }()
+
+	user := tNewUser(t)
+	rig.storage.ratio = ratioData{
+		oidsCompleted:  []order.OrderID{{0x1}, {0x2}, {0x3}},
+		timesCompleted: []int64{1000, 2000, 3000},
+		oidsCancels:    []order.OrderID{{0x4}, {0x5}, {0x6}},
+		oidsCanceled:   []order.OrderID{{0x7}, {0x8}, {0x9}},
+		timesCanceled:  []int64{1500, 2500, 3500},
+		// The cancel at the default free epoch gap is free.
+		epochGaps: []int32{0, 1, DefaultFreeCancelEpochGap},
+	}
+
+	ratio, completions, cancels, err := rig.mgr.CancelRatio(user.acctID)
+	if err != nil {
+		t.Fatalf("CancelRatio error: %v", err)
+	}
+	if completions != 4 || cancels != 2 {
+		t.Fatalf("wrong counts. wanted 4 completions, 2 cancels, got %d, %d", completions, cancels)
+	}
+	if ratio != 2.0/6 {
+		t.Fatalf("wrong ratio. wanted %f, got %f", 2.0/6, ratio)
+	}
+
+	// No orders.
+	rig.storage.ratio = ratioData{}
+	ratio, completions, cancels, err = rig.mgr.CancelRatio(user.acctID)
+	if err != nil {
+		t.Fatalf("CancelRatio error: %v", err)
+	}
+	if ratio != 0 || completions != 0 || cancels != 0 {
+		t.Fatalf("expected zeros, got %f, %d, %d", ratio, completions, cancels)
+	}
+}
+
+func TestFreeCancelEpochGap(t *testing.T) {
+	rig.mgr.freeCancelGap = 3
+	defer func() {
+		rig.mgr.freeCancelGap = DefaultFreeCancelEpochGap
+		rig.storage.ratio = ratioData{}
+	}()
+
+	for _, tt := range []struct {
+		gap       int32
+		penalized bool
+	}{
+		{db.EpochGapNA, false},
+		{0, true},
+		{2, true},  // just below the configured gap
+		{3, false}, // at the configured gap
+		{4, false},
+	} {
+		if penalized := rig.mgr.cancelPenalized(tt.gap); penalized != tt.penalized {
+			t.Fatalf("epoch gap %d: wanted penalized = %t, got %t", tt.gap, tt.penalized, penalized)
+		}
+	}
+
+	// The cancel ratio honors the configured gap.
+	user := tNewUser(t)
+	rig.storage.ratio = ratioData{
+		oidsCancels:   []order.OrderID{{0x1}, {0x2}},
+		oidsCanceled:  []order.OrderID{{0x3}, {0x4}},
+		timesCanceled: []int64{1000, 2000},
+		epochGaps:     []int32{2, 3},
+	}
+	_, completions, cancels, err := rig.mgr.CancelRatio(user.acctID)
+	if err != nil {
+		t.Fatalf("CancelRatio error: %v", err)
+	}
+	if completions != 1 || cancels != 1 {
+		t.Fatalf("wrong counts. wanted 1 completion, 1 cancel, got %d, %d", completions, cancels)
+	}
+
+	// RecordCancel honors the configured gap.
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	rig.mgr.RecordCancel(user.acctID, order.OrderID{0x5}, order.OrderID{0x6}, 2, time.Time{}, time.Now())
+	rig.mgr.RecordCancel(user.acctID, order.OrderID{0x7}, order.OrderID{0x8}, 3, time.Time{}, time.Now())
+	rig.mgr.violationMtx.Lock()
+	outcomes := rig.mgr.orderOutcomes[user.acctID].outcomes
+	rig.mgr.violationMtx.Unlock()
+	n := len(outcomes)
+	if n < 2 || !outcomes[n-2].Canceled || outcomes[n-1].Canceled {
+		t.Fatalf("wrong cancel outcomes recorded")
+	}
+}
+
+func TestFreeCancelWindow(t *testing.T) {
+	const window = time.Minute
+	rig.mgr.freeCancelWindow = window
+	defer func() {
+		rig.mgr.freeCancelWindow = 0
+		rig.storage.ratio = ratioData{}
+	}()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	lastCanceled := func() bool {
+		t.Helper()
+		rig.mgr.violationMtx.Lock()
+		defer rig.mgr.violationMtx.Unlock()
+		outcomes := rig.mgr.orderOutcomes[user.acctID].outcomes
+		if len(outcomes) == 0 {
+			t.Fatalf("no order outcomes recorded")
+		}
+		return outcomes[len(outcomes)-1].Canceled
+	}
+
+	// A penalized epoch gap is forgiven for an order canceled inside the
+	// window.
+	now := time.Now()
+	rig.mgr.RecordCancel(user.acctID, randomOrderID(), randomOrderID(), 0, now.Add(-window/2), now)
+	if lastCanceled() {
+		t.Fatalf("cancel inside the free cancel window was penalized")
+	}
+
+	// Outside the window, the epoch gap decides.
+	rig.mgr.RecordCancel(user.acctID, randomOrderID(), randomOrderID(), 0, now.Add(-window*2), now)
+	if !lastCanceled() {
+		t.Fatalf("cancel outside the free cancel window was not penalized")
+	}
+
+	// An unknown placement time is never in the window.
+	rig.mgr.RecordCancel(user.acctID, randomOrderID(), randomOrderID(), 0, time.Time{}, now)
+	if !lastCanceled() {
+		t.Fatalf("cancel with unknown placement time was not penalized")
+	}
+
+	// The cancel ratio, which is also used to upgrade v0 reputations, honors
+	// the window using the stored placement times of the targeted orders.
+	execTime := now.UnixMilli()
+	rig.storage.ratio = ratioData{
+		oidsCancels:   []order.OrderID{{0x1}, {0x2}, {0x3}},
+		oidsCanceled:  []order.OrderID{{0x4}, {0x5}, {0x6}},
+		timesCanceled: []int64{execTime, execTime, execTime},
+		epochGaps:     []int32{0, 0, 0},
+		targetTimes:   []int64{now.Add(-window / 2).UnixMilli(), now.Add(-window * 2).UnixMilli(), 0},
+	}
+	_, completions, cancels, err := rig.mgr.CancelRatio(user.acctID)
+	if err != nil {
+		t.Fatalf("CancelRatio error: %v", err)
+	}
+	if completions != 1 || cancels != 2 {
+		t.Fatalf("wrong counts. wanted 1 completion, 2 cancels, got %d, %d", completions, cancels)
+	}
+
+	// A zero window disables it.
+	rig.mgr.freeCancelWindow = 0
+	rig.mgr.RecordCancel(user.acctID, randomOrderID(), randomOrderID(), 0, now.Add(-window/2), now)
+	if !lastCanceled() {
+		t.Fatalf("cancel not penalized with the free cancel window disabled")
+	}
+	_, completions, cancels, err = rig.mgr.CancelRatio(user.acctID)
+	if err != nil {
+		t.Fatalf("CancelRatio error: %v", err)
+	}
+	if completions != 0 || cancels != 3 {
+		t.Fatalf("wrong counts with the window disabled. wanted 0 completions, 3 cancels, got %d, %d", completions, cancels)
+	}
+}
+
+func TestAuthManager_RecordCancel_RecordCompletedOrder(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	client := rig.mgr.user(user.acctID)
+	if client == nil {
+		t.Fatalf("client not found")
+	}
+
+	newOrderID := func() (oid order.OrderID) {
+		rand.Read(oid[:])
+		return
+	}
+
+	orderOutcomes := rig.mgr.orderOutcomes[user.acctID]
+
+	oid := newOrderID()
+	tCompleted := unixMsNow()
+	rig.mgr.RecordCompletedOrder(user.acctID, oid, tCompleted)
+
+	counts := func(os *latestOutcomes[*db.OrderOutcome]) (total, cancels int) {
+		m := os.binViolations()
+		successes, cancels := int(m[db.OutcomeOrderComplete]), int(m[db.OutcomeOrderCanceled])
+		return successes + cancels, cancels
+	}
+	total, cancels := counts(orderOutcomes)
+	if total != 1 {
+		t.Errorf("got %d total orders, expected %d", total, 1)
+	}
+	if cancels != 0 {
+		t.Errorf("got %d cancels, expected %d", cancels, 0)
+	}
+
+	checkOrd := func(ord *db.OrderOutcome, oid order.OrderID, cancel bool, timestamp int64) {
+		if ord.OrderID != oid {
+			t.Errorf("completed order id mismatch. got %v, expected %v",
+				ord.OrderID, oid)
+		}
+		if ord.Canceled != cancel {
+			t.Errorf("order marked as cancel=%v, expected %v", ord.Canceled, cancel)
+		}
+	}
+
+	ord := orderOutcomes.outcomes[0]
+	checkOrd(ord, oid, false, tCompleted.UnixMilli())
+
+	// another
+	oid = newOrderID()
+	tCompleted = tCompleted.Add(time.Millisecond) // newer
+	rig.mgr.RecordCompletedOrder(user.acctID, oid, tCompleted)
+
+	total, cancels = counts(orderOutcomes)
+	if total != 2 {
+		t.Errorf("got %d total orders, expected %d", total, 2)
+	}
+	if cancels != 0 {
+		t.Errorf("got %d cancels, expected %d", cancels, 0)
+	}
+
+	ord = orderOutcomes.outcomes[1]
+	checkOrd(ord, oid, false, tCompleted.UnixMilli())
+
+	// now a cancel
+	coid := newOrderID()
+	tCompleted = tCompleted.Add(time.Millisecond) // newer
+	rig.mgr.RecordCancel(user.acctID, coid, oid, 1, time.Time{}, tCompleted)
+
+	total, cancels = counts(orderOutcomes)
+	if total != 3 {
+		t.Errorf("got %d total orders, expected %d", total, 3)
+	}
+	if cancels != 1 {
+		t.Errorf("got %d cancels, expected %d", cancels, 1)
+	}
+
+	ord = orderOutcomes.outcomes[2]
+	checkOrd(ord, coid, true, tCompleted.UnixMilli())
+}
+
+func TestForgivePreimageMisses(t *testing.T) {
+	rig.storage.userPreimageResults = []*db.PreimageResult{
+		newPreimageResult(true, nextTime()),
+		newPreimageResult(false, nextTime()),
+		newPreimageResult(true, nextTime()),
+		newPreimageResult(true, nextTime()),
+	}
+	defer func() { rig.storage.userPreimageResults = nil }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	client := rig.mgr.user(user.acctID)
+	if client == nil {
+		t.Fatalf("client not found")
+	}
+	getScore := func() int32 {
+		client.mtx.Lock()
+		defer client.mtx.Unlock()
+		return client.score
+	}
+
+	if err := rig.mgr.ForgivePreimageMisses(context.Background(), user.acctID, 0); err == nil {
+		t.Fatalf("no error for forgiving zero preimage misses")
+	}
+
+	scoreBefore := getScore()
+	if err := rig.mgr.ForgivePreimageMisses(context.Background(), user.acctID, 2); err != nil {
+		t.Fatalf("ForgivePreimageMisses error: %v", err)
+	}
+	if scoreAfter := getScore(); scoreAfter != scoreBefore-2*preimageMissScore {
+		t.Fatalf("wrong score after forgiveness. wanted %d, got %d", scoreBefore-2*preimageMissScore, scoreAfter)
+	}
+	// The score change is sent asynchronously. Don't leave it signing while
+	// the next test sets the signer.
+	if waitFor(func() bool { return user.conn.getNtfn(msgjson.ScoreChangeRoute) != nil }, time.Second) {
+		t.Fatalf("no score change notification")
+	}
+	if !rig.storage.userPreimageResults[0].Miss {
+		t.Fatalf("oldest preimage miss was forgiven")
+	}
+}
+
+func TestConnectRecentForgiveness(t *testing.T) {
+	defer func() {
+		rig.storage.forgiven = nil
+		rig.storage.forgivenErr = nil
+		rig.storage.matchFailForgiven = false
+		rig.storage.repMatches = nil
+	}()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	if res := extractConnectResult(t, connectUser(t, user)); len(res.RecentForgiveness) != 0 {
+		t.Fatalf("unexpected forgiveness for new user: %v", res.RecentForgiveness)
+	}
+
+	if err := rig.mgr.ForgiveUser(user.acctID); err != nil {
+		t.Fatalf("ForgiveUser error: %v", err)
+	}
+	rig.storage.matchFailForgiven = true
+	mid := randomMatchID()
+	failure := &db.MatchResult{DBID: nextDBID(), MatchID: mid, MatchOutcome: db.OutcomeNoSwapAsMaker}
+	rig.storage.repMatches = []*db.MatchResult{failure}
+	if forgiven, _, err := rig.mgr.ForgiveMatchFail(user.acctID, mid); err != nil || !forgiven {
+		t.Fatalf("ForgiveMatchFail: forgiven = %t, err = %v", forgiven, err)
+	}
+	// The existing failure is forgiven rather than adding a new outcome.
+	if len(rig.storage.repMatches) != 1 || failure.MatchOutcome != db.OutcomeForgiven {
+		t.Fatalf("match failure not forgiven in place: %v", failure.MatchOutcome)
+	}
+
+	res := extractConnectResult(t, connectUser(t, user))
+	if len(res.RecentForgiveness) != 2 {
+		t.Fatalf("expected 2 forgiven outcomes, got %d", len(res.RecentForgiveness))
+	}
+	if fg := res.RecentForgiveness[0]; fg.Kind != msgjson.ForgivenMatch || !bytes.Equal(fg.ID, mid[:]) {
+		t.Fatalf("wrong match forgiveness %+v", fg)
+	}
+	if fg := res.RecentForgiveness[1]; fg.Kind != msgjson.ForgivenAccount || len(fg.ID) != 0 {
+		t.Fatalf("wrong account forgiveness %+v", fg)
+	}
+
+	// A storage error does not prevent connecting.
+	rig.storage.forgivenErr = errors.New("test error")
+	if res := extractConnectResult(t, connectUser(t, user)); len(res.RecentForgiveness) != 0 {
+		t.Fatalf("unexpected forgiveness with storage error")
+	}
+}
+
+func TestConnectActiveOrdersLimit(t *testing.T) {
+	const limit = 5
+	rig.mgr.maxConnectOrders = limit
+	defer func() {
+		rig.mgr.maxConnectOrders = 0
+		rig.storage.matches = nil
+		rig.storage.orderStatuses = nil
+	}()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+
+	// Matches are never capped, since the client would revoke any that are
+	// missing.
+	for i := 0; i < limit*4; i++ {
+		matchData, _ := userMatchData(user.acctID)
+		rig.storage.matches = append(rig.storage.matches, matchData)
+	}
+	res := extractConnectResult(t, connectUser(t, user))
+	if res.Truncated {
+		t.Fatalf("truncation indicated for matches")
+	}
+	if len(res.ActiveMatches) != limit*4 {
+		t.Fatalf("expected %d matches, got %d", limit*4, len(res.ActiveMatches))
+	}
+
+	// Many orders are capped.
+	rig.storage.matches = nil
+	for i := 0; i < limit+1; i++ {
+		rig.storage.orderStatuses = append(rig.storage.orderStatuses, &db.OrderStatus{
+			ID:     randomOrderID(),
+			Status: order.OrderStatusBooked,
+		})
+	}
+	res = extractConnectResult(t, connectUser(t, user))
+	if !res.Truncated || len(res.ActiveOrderStatuses) != limit {
+		t.Fatalf("expected %d truncated orders, got %d, truncated = %t",
+			limit, len(res.ActiveOrderStatuses), res.Truncated)
+	}
+
+	// No limit.
+	rig.mgr.maxConnectOrders = 0
+	res = extractConnectResult(t, connectUser(t, user))
+	if res.Truncated || len(res.ActiveOrderStatuses) != limit+1 {
+		t.Fatalf("expected %d untruncated orders, got %d, truncated = %t",
+			limit+1, len(res.ActiveOrderStatuses), res.Truncated)
+	}
+}
+
+func TestMatchStatusSigned(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	priv, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	rig.mgr.signer = &tPrivKeySigner{priv}
+	defer func() {
+		rig.mgr.signer = rig.signer
+		rig.storage.matchStatuses = nil
+	}()
+
+	mid := randomMatchID()
+	rig.storage.matchStatuses = []*db.MatchStatus{{
+		ID:          mid,
+		Status:      order.MakerRedeemed,
+		IsMaker:     true,
+		MakerSwap:   encode.RandomBytes(36),
+		TakerSwap:   encode.RandomBytes(36),
+		MakerRedeem: encode.RandomBytes(36),
+		Secret:      encode.RandomBytes(32),
+		Active:      true,
+	}}
+
+	req, _ := msgjson.NewRequest(1, msgjson.MatchStatusRoute, []msgjson.MatchRequest{{MatchID: mid[:]}})
+	if msgErr := rig.mgr.handleMatchStatus(user.conn, req); msgErr != nil {
+		t.Fatalf("handleMatchStatus error: %v", msgErr)
+	}
+	resp := user.conn.getSend()
+	if resp == nil {
+		t.Fatalf("no response sent")
+	}
+	var statuses []*msgjson.MatchStatusResult
+	if err := resp.UnmarshalResult(&statuses); err != nil {
+		t.Fatalf("UnmarshalResult error: %v", err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(statuses))
+	}
+	status := statuses[0]
+
+	verify := func() bool {
+		sig, err := ecdsa.ParseDERSignature(status.Sig)
+		if err != nil {
+			t.Fatalf("error parsing signature: %v", err)
+		}
+		hash := sha256.Sum256(status.Serialize())
+		return sig.Verify(hash[:], priv.PubKey())
+	}
+	if !verify() {
+		t.Fatalf("match status signature not valid")
+	}
+
+	// A modified status does not validate.
+	status.Status = uint8(order.MatchComplete)
+	if verify() {
+		t.Fatalf("signature valid for a modified status")
+	}
+}
+
+func TestMatchStatusLockTimes(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	makerLockTime := time.Now().Add(20 * time.Hour).Truncate(time.Second)
+	takerLockTime := time.Now().Add(8 * time.Hour).Truncate(time.Second)
+	makerContract, takerContract := encode.RandomBytes(50), encode.RandomBytes(50)
+	rig.mgr.lockTimeSources = map[uint32]ContractLockTimeSource{
+		// Taker sells base (42), maker sells quote (0).
+		0: func(coinID, contract []byte) (time.Time, error) {
+			if !bytes.Equal(contract, makerContract) {
+				return time.Time{}, errors.New("wrong maker contract")
+			}
+			return makerLockTime, nil
+		},
+		42: func(coinID, contract []byte) (time.Time, error) {
+			if !bytes.Equal(contract, takerContract) {
+				return time.Time{}, errors.New("wrong taker contract")
+			}
+			return takerLockTime, nil
+		},
+	}
+	rig.mgr.txDataSources[42] = func([]byte) ([]byte, error) { return []byte{0x01}, nil }
+	defer func() {
+		rig.mgr.lockTimeSources = nil
+		delete(rig.mgr.txDataSources, 42)
+		rig.storage.matchStatuses = nil
+	}()
+
+	rig.storage.matchStatuses = []*db.MatchStatus{{
+		ID:            randomMatchID(),
+		Status:        order.TakerSwapCast,
+		IsMaker:       true,
+		TakerSell:     true,
+		MakerContract: makerContract,
+		TakerContract: takerContract,
+		MakerSwap:     encode.RandomBytes(36),
+		TakerSwap:     encode.RandomBytes(36),
+		Active:        true,
+	}}
+
+	req, _ := msgjson.NewRequest(1, msgjson.MatchStatusRoute, []msgjson.MatchRequest{{
+		Base: 42, Quote: 0, MatchID: encode.RandomBytes(32),
+	}})
+	if msgErr := rig.mgr.handleMatchStatus(user.conn, req); msgErr != nil {
+		t.Fatalf("handleMatchStatus error: %v", msgErr)
+	}
+	resp := user.conn.getSend()
+	if resp == nil {
+		t.Fatalf("no response sent")
+	}
+	var statuses []*msgjson.MatchStatusResult
+	if err := resp.UnmarshalResult(&statuses); err != nil {
+		t.Fatalf("UnmarshalResult error: %v", err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(statuses))
+	}
+	status := statuses[0]
+	if status.MakerLockTime != uint64(makerLockTime.Unix()) {
+		t.Fatalf("wrong maker lock time %d, expected %d", status.MakerLockTime, makerLockTime.Unix())
+	}
+	if status.TakerLockTime != uint64(takerLockTime.Unix()) {
+		t.Fatalf("wrong taker lock time %d, expected %d", status.TakerLockTime, takerLockTime.Unix())
+	}
+}
+
+func TestMatchStatus(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	rig.storage.matchStatuses = []*db.MatchStatus{{
+		Status:    order.MakerSwapCast,
+		IsTaker:   true,
+		MakerSwap: []byte{0x01},
+	}}
+
+	tTxData := encode.RandomBytes(5)
+	rig.mgr.txDataSources[0] = func([]byte) ([]byte, error) {
+		return tTxData, nil
+	}
+
+	reqPayload := []msgjson.MatchRequest{{MatchID: encode.RandomBytes(32)}}
+
+	req, _ := msgjson.NewRequest(1, msgjson.MatchStatusRoute, reqPayload)
+
+	getStatus := func() *msgjson.MatchStatusResult {
+		msgErr := rig.mgr.handleMatchStatus(user.conn, req)
+		if msgErr != nil {
+			t.Fatalf("handleMatchStatus error: %v", msgErr)
+		}
+
+		resp := user.conn.getSend()
+		if resp == nil {
+			t.Fatalf("no matches sent")
+		}
+
+		statuses := []msgjson.MatchStatusResult{}
+		err := resp.UnmarshalResult(&statuses)
+		if err != nil {
+			t.Fatalf("UnmarshalResult error: %v", err)
+		}
+		if len(statuses) != 1 {
+			t.Fatalf("expected 1 match, got %d", len(statuses))
+		}
+		return &statuses[0]
+	}
+
+	// As taker in MakerSwapCast, we expect tx data.
+	status := getStatus()
+	if !bytes.Equal(status.MakerTxData, tTxData) {
+		t.Fatalf("wrong maker tx data. exected %x, got %s", tTxData, status.MakerTxData)
+	}
+
+	// As maker, we don't expect any tx data.
+	rig.storage.matchStatuses[0].IsTaker = false
+	rig.storage.matchStatuses[0].IsMaker = true
+	if len(getStatus().TakerTxData) != 0 {
+		t.Fatalf("got tx data as maker in MakerSwapCast")
+	}
+
+	// As maker in TakerSwapCast, we do expect tx data.
+	rig.storage.matchStatuses[0].Status = order.TakerSwapCast
+	rig.storage.matchStatuses[0].TakerSwap = []byte{0x01}
+	txData := getStatus().TakerTxData
+	if !bytes.Equal(txData, tTxData) {
+		t.Fatalf("wrong taker tx data. exected %x, got %s", tTxData, txData)
+	}
+
+	reqPayload[0].MatchID = []byte{}
+	req, _ = msgjson.NewRequest(1, msgjson.MatchStatusRoute, reqPayload)
+	msgErr := rig.mgr.handleMatchStatus(user.conn, req)
+	if msgErr == nil {
+		t.Fatalf("no error for bad match ID")
+	}
+}
+
+func TestMatchStatusCache(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	mid := randomMatchID()
+	rig.storage.matchStatuses = []*db.MatchStatus{{
+		ID:        mid,
+		Status:    order.MakerSwapCast,
+		IsTaker:   true,
+		MakerSwap: []byte{0x01},
+	}}
+	defer func() { rig.storage.matchStatuses = nil }()
+
+	var txDataCalls int
+	rig.mgr.txDataSources[0] = func([]byte) ([]byte, error) {
+		txDataCalls++
+		return encode.RandomBytes(5), nil
+	}
+
+	req, _ := msgjson.NewRequest(1, msgjson.MatchStatusRoute, []msgjson.MatchRequest{{MatchID: mid[:]}})
+	getStatus := func() {
+		t.Helper()
+		if msgErr := rig.mgr.handleMatchStatus(user.conn, req); msgErr != nil {
+			t.Fatalf("handleMatchStatus error: %v", msgErr)
+		}
+		resp := user.conn.getSend()
+		if resp == nil {
+			t.Fatalf("no matches sent")
+		}
+		var statuses []msgjson.MatchStatusResult
+		if err := resp.UnmarshalResult(&statuses); err != nil {
+			t.Fatalf("UnmarshalResult error: %v", err)
+		}
+		if len(statuses) != 1 {
+			t.Fatalf("expected 1 match, got %d", len(statuses))
+		}
+	}
+
+	getStatus()
+	getStatus()
+	if txDataCalls != 1 {
+		t.Fatalf("expected 1 tx data request, got %d", txDataCalls)
+	}
+
+	// A status change forces a new lookup.
+	rig.mgr.MatchStatusChanged(mid)
+	getStatus()
+	if txDataCalls != 2 {
+		t.Fatalf("expected 2 tx data requests after invalidation, got %d", txDataCalls)
+	}
+
+	// A result loaded before an invalidation is not cached.
+	gen := rig.mgr.matchStatusGeneration()
+	rig.mgr.MatchStatusChanged(mid)
+	rig.mgr.cacheMatchStatus(user.acctID, mid, &msgjson.MatchStatusResult{}, gen)
+	if rig.mgr.cachedMatchStatus(user.acctID, mid) != nil {
+		t.Fatalf("stale match status cached")
+	}
+	getStatus()
+	if txDataCalls != 3 {
+		t.Fatalf("expected 3 tx data requests after stale result, got %d", txDataCalls)
+	}
+
+	// Expired entries are not used, and are pruned.
+	rig.mgr.matchStatusMtx.Lock()
+	rig.mgr.matchStatusCache[matchStatusKey{user.acctID, mid}].expire = time.Now().Add(-time.Second)
+	rig.mgr.matchStatusMtx.Unlock()
+	rig.mgr.pruneMatchStatusCache()
+	rig.mgr.matchStatusMtx.Lock()
+	_, found := rig.mgr.matchStatusCache[matchStatusKey{user.acctID, mid}]
+	rig.mgr.matchStatusMtx.Unlock()
+	if found {
+		t.Fatalf("expired match status not pruned")
+	}
+	getStatus()
+	if txDataCalls != 4 {
+		t.Fatalf("expected 4 tx data requests after expiry, got %d", txDataCalls)
+	}
+}
+
+func TestSetTxDataSource(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	mid := randomMatchID()
+	rig.storage.matchStatuses = []*db.MatchStatus{{
+		ID:        mid,
+		Status:    order.MakerSwapCast,
+		IsTaker:   true,
+		MakerSwap: []byte{0x01},
+	}}
+	defer func() { rig.storage.matchStatuses = nil }()
+
+	req, _ := msgjson.NewRequest(1, msgjson.MatchStatusRoute, []msgjson.MatchRequest{{MatchID: mid[:]}})
+	getTxData := func() []byte {
+		t.Helper()
+		rig.mgr.invalidateMatchStatus(mid)
+		if msgErr := rig.mgr.handleMatchStatus(user.conn, req); msgErr != nil {
+			t.Fatalf("handleMatchStatus error: %v", msgErr)
+		}
+		resp := user.conn.getSend()
+		if resp == nil {
+			t.Fatalf("no matches sent")
+		}
+		var statuses []msgjson.MatchStatusResult
+		if err := resp.UnmarshalResult(&statuses); err != nil {
+			t.Fatalf("UnmarshalResult error: %v", err)
+		}
+		if len(statuses) != 1 {
+			t.Fatalf("expected 1 match, got %d", len(statuses))
+		}
+		return statuses[0].MakerTxData
+	}
+
+	txData1, txData2 := encode.RandomBytes(5), encode.RandomBytes(5)
+	rig.mgr.SetTxDataSource(0, func([]byte) ([]byte, error) { return txData1, nil })
+	if txData := getTxData(); !bytes.Equal(txData, txData1) {
+		t.Fatalf("wrong tx data. expected %x, got %x", txData1, txData)
+	}
+
+	// Swap the source.
+	rig.mgr.SetTxDataSource(0, func([]byte) ([]byte, error) { return txData2, nil })
+	if txData := getTxData(); !bytes.Equal(txData, txData2) {
+		t.Fatalf("wrong tx data after swapping source. expected %x, got %x", txData2, txData)
+	}
+
+	// Without a source, the request fails.
+	rig.mgr.RemoveTxDataSource(0)
+	rig.mgr.invalidateMatchStatus(mid)
+	if msgErr := rig.mgr.handleMatchStatus(user.conn, req); msgErr == nil {
+		t.Fatalf("no error without a tx data source")
+	}
+}
+
+type tClock struct {
+	mtx sync.Mutex
+	now time.Time
+}
+
+func (c *tClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *tClock) advance(d time.Duration) {
+	c.mtx.Lock()
+	c.now = c.now.Add(d)
+	c.mtx.Unlock()
+}
+
+func TestClock(t *testing.T) {
+	clock := &tClock{now: time.Now()}
+	rig.mgr.clock = clock
+	defer func() { rig.mgr.clock = nil }()
+
+	if now := rig.mgr.now(); !now.Equal(clock.now) {
+		t.Fatalf("wrong time. wanted %v, got %v", clock.now, now)
+	}
+
+	// Cached match status expiry.
+	user, mid := newAccountID(), randomMatchID()
+	rig.mgr.cacheMatchStatus(user, mid, &msgjson.MatchStatusResult{}, rig.mgr.matchStatusGeneration())
+	if rig.mgr.cachedMatchStatus(user, mid) == nil {
+		t.Fatalf("match status not cached")
+	}
+	clock.advance(rig.mgr.matchStatusTTL + time.Millisecond)
+	if rig.mgr.cachedMatchStatus(user, mid) != nil {
+		t.Fatalf("expired match status returned")
+	}
+
+	// Connect time retention.
+	rig.mgr.connectSkew = time.Minute
+	defer func() { rig.mgr.connectSkew = 0 }()
+	if err := rig.mgr.claimConnectStamp(user, 1); err != nil {
+		t.Fatalf("claimConnectStamp error: %v", err)
+	}
+	clock.advance(connectReplayRetention)
+	rig.mgr.pruneConnectStamps()
+	rig.mgr.connectStampsMtx.Lock()
+	_, found := rig.mgr.connectStamps[user]
+	rig.mgr.connectStampsMtx.Unlock()
+	if !found {
+		t.Fatalf("connect times pruned too soon")
+	}
+	clock.advance(time.Millisecond)
+	rig.mgr.pruneConnectStamps()
+	rig.mgr.connectStampsMtx.Lock()
+	_, found = rig.mgr.connectStamps[user]
+	rig.mgr.connectStampsMtx.Unlock()
+	if found {
+		t.Fatalf("connect times not pruned")
+	}
+}
+
+func TestConcurrentConnect(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		user := tNewUser(t)
+		rig.signer.sig = user.randomSignature()
+		oldConn, newConn := tNewRPCClient(), tNewRPCClient()
+		oldConnect, newConnect := queueUser(t, user), queueUser(t, user)
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		errs := make([]error, 2)
+		for i, c := range []struct {
+			conn *TRPCClient
+			msg  *msgjson.Message
+		}{{oldConn, oldConnect}, {newConn, newConnect}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				errs[i] = rig.mgr.connect(c.conn, c.msg)
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		// The newer link always succeeds. If the older link lost the race, it
+		// is told so rather than getting a success response.
+		if errs[1] != nil {
+			t.Fatalf("connect error on newer link: %v", errs[1])
+		}
+		if errs[0] != nil {
+			if !errors.Is(errs[0], ErrNewerConnection) {
+				t.Fatalf("wrong error on older link: %v", errs[0])
+			}
+			if resp := oldConn.getSend(); resp != nil {
+				t.Fatalf("older link got a connect response")
+			}
+		}
+
+		client := rig.mgr.user(user.acctID)
+		if client == nil {
+			t.Fatalf("no client registered")
+		}
+		if client.conn.ID() != newConn.ID() {
+			t.Fatalf("expected newest conn ID %d, got %d", newConn.ID(), client.conn.ID())
+		}
+		var live int
+		rig.mgr.connMtx.RLock()
+		for _, c := range rig.mgr.conns {
+			if c.acct.ID == user.acctID {
+				live++
+			}
+		}
+		rig.mgr.connMtx.RUnlock()
+		if live != 1 {
+			t.Fatalf("expected 1 live client, found %d", live)
+		}
+		if rig.mgr.conn(oldConn) != nil {
+			t.Fatalf("older link still registered")
+		}
+		rig.mgr.removeClient(client)
+	}
+}
+
+func TestPenaltyThresholdPerTier(t *testing.T) {
+	score := setViolations()
+	defer clearViolations()
+	if score > -3 {
+		t.Fatalf("test score of %d is not low enough, revise the test", score)
+	}
+
+	origBonds, initPenaltyThresh := rig.storage.bonds, rig.mgr.penaltyThreshold
+	defer func() {
+		rig.storage.bonds = origBonds
+		rig.mgr.penaltyThreshold, rig.mgr.penaltyPerTier = initPenaltyThresh, 0
+	}()
+
+	connectTier := func(bondTier uint32) int64 {
+		t.Helper()
+		rig.storage.setBondTier(bondTier)
+		user := tNewUser(t)
+		rig.signer.sig = user.randomSignature()
+		connectUser(t, user)
+		client := rig.mgr.user(user.acctID)
+		if client == nil {
+			t.Fatalf("client not found")
+		}
+		defer rig.mgr.removeClient(client)
+		return client.tier
+	}
+
+	for _, tt := range []struct {
+		name      string
+		bondTier  uint32
+		threshold int32
+		perTier   int32
+		wantTier0 bool
+	}{
+		{"tier 1, unscaled", 1, score, 0, true},
+		{"tier 1, scaled", 1, score, 1, false},
+		{"tier 3, unscaled", 3, score / 3, 0, true},
+		{"tier 3, scaled", 3, score / 3, -score / 3, false},
+	} {
+		rig.mgr.penaltyThreshold, rig.mgr.penaltyPerTier = tt.threshold, tt.perTier
+		wantThresh := tt.threshold - tt.perTier*int32(tt.bondTier)
+		if thresh := rig.mgr.effectivePenaltyThreshold(int64(tt.bondTier)); thresh != wantThresh {
+			t.Fatalf("%s: wrong effective threshold. wanted %d, got %d", tt.name, wantThresh, thresh)
+		}
+		if tier := connectTier(tt.bondTier); (tier < 1) != tt.wantTier0 {
+			t.Fatalf("%s: wanted tier 0 = %t, got tier %d", tt.name, tt.wantTier0, tier)
+		}
+	}
+}
+
+func TestIdleClients(t *testing.T) {
+	clock := &tClock{now: time.Now()}
+	rig.mgr.clock = clock
+	defer func() { rig.mgr.clock = nil }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	defer rig.mgr.removeClient(rig.mgr.user(user.acctID))
+
+	isIdle := func(threshold time.Duration) bool {
+		for _, acctID := range rig.mgr.IdleClients(threshold) {
+			if acctID == user.acctID {
+				return true
+			}
+		}
+		return false
+	}
+
+	const threshold = time.Minute
+	if isIdle(threshold) {
+		t.Fatalf("newly connected client reported idle")
+	}
+	clock.advance(threshold + time.Second)
+	if !isIdle(threshold) {
+		t.Fatalf("client not reported idle after threshold")
+	}
+
+	// Activity resets the idle timer.
+	rig.mgr.user(user.acctID).markSeen(rig.mgr.now())
+	if isIdle(threshold) {
+		t.Fatalf("active client reported idle")
+	}
+	clock.advance(threshold / 2)
+	if isIdle(threshold) {
+		t.Fatalf("client reported idle before threshold")
+	}
+	clock.advance(threshold)
+	if !isIdle(threshold) {
+		t.Fatalf("client not reported idle after second threshold")
+	}
+}
+
+type tPrivKeySigner struct {
+	privKey *secp256k1.PrivateKey
+}
+
+func (s *tPrivKeySigner) Sign(hash []byte) *ecdsa.Signature { return ecdsa.Sign(s.privKey, hash) }
+func (s *tPrivKeySigner) PubKey() *secp256k1.PublicKey      { return s.privKey.PubKey() }
+
+func TestServerTime(t *testing.T) {
+	privKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+	signer := &tPrivKeySigner{privKey}
+	origSigner := rig.mgr.signer
+	rig.mgr.signer = signer
+	defer func() { rig.mgr.signer = origSigner }()
+
+	// No account required.
+	conn := tNewRPCClient()
+	req, _ := msgjson.NewRequest(1, msgjson.ServerTimeRoute, nil)
+	if msgErr := rig.mgr.handleServerTime(conn, req); msgErr != nil {
+		t.Fatalf("handleServerTime error: %v", msgErr)
+	}
+	resp := conn.getSend()
+	if resp == nil {
+		t.Fatalf("no server_time response sent")
+	}
+	st := new(msgjson.ServerTime)
+	if err := resp.UnmarshalResult(st); err != nil {
+		t.Fatalf("UnmarshalResult error: %v", err)
+	}
+
+	if d := time.Since(time.UnixMilli(int64(st.Time))); d < 0 || d > time.Second {
+		t.Fatalf("server time %d not within a second of now (diff %v)", st.Time, d)
+	}
+
+	sig, err := ecdsa.ParseDERSignature(st.Sig)
+	if err != nil {
+		t.Fatalf("error parsing signature: %v", err)
+	}
+	hash := sha256.Sum256(st.Serialize())
+	if !sig.Verify(hash[:], signer.PubKey()) {
+		t.Fatalf("server_time signature verification failed")
+	}
+}
+
+func TestOrderStatus(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	rig.storage.orderStatuses = []*db.OrderStatus{{}}
+
+	reqPayload := []msgjson.OrderStatusRequest{
+		{
+			OrderID: encode.RandomBytes(order.OrderIDSize),
+		},
+	}
+
+	req, _ := msgjson.NewRequest(1, msgjson.OrderStatusRoute, reqPayload)
+
+	msgErr := rig.mgr.handleOrderStatus(user.conn, req)
+	if msgErr != nil {
+		t.Fatalf("handleOrderStatus error: %v", msgErr)
+	}
+
+	resp := user.conn.getSend()
+	if resp == nil {
+		t.Fatalf("no orders sent")
+	}
+
+	var statuses []*msgjson.OrderStatus
+	err := resp.UnmarshalResult(&statuses)
+	if err != nil {
+		t.Fatalf("UnmarshalResult error: %v", err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("expected 1 order, got %d", len(statuses))
+	}
+
+	reqPayload[0].OrderID = []byte{}
+	req, _ = msgjson.NewRequest(1, msgjson.OrderStatusRoute, reqPayload)
+	msgErr = rig.mgr.handleOrderStatus(user.conn, req)
+	if msgErr == nil {
+		t.Fatalf("no error for bad order ID")
+	}
+}
+
+func TestOrderStatusBatchLimits(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	const maxBatch = 3
+	rig.mgr.maxOrderStatusBatch = maxBatch
+	defer func() { rig.mgr.maxOrderStatusBatch = maxIDsPerOrderStatusRequest }()
+
+	ensureErr := makeEnsureErr(t)
+	newReq := func(n int) []msgjson.OrderStatusRequest {
+		reqs := make([]msgjson.OrderStatusRequest, n)
+		for i := range reqs {
+			reqs[i] = msgjson.OrderStatusRequest{
+				Base:    uint32(i), // different markets
+				Quote:   42,
+				OrderID: encode.RandomBytes(order.OrderIDSize),
+			}
+		}
+		return reqs
+	}
+
+	// Oversized batch.
+	req, _ := msgjson.NewRequest(1, msgjson.OrderStatusRoute, newReq(maxBatch+1))
+	ensureErr(rig.mgr.handleOrderStatus(user.conn, req), "oversized batch", msgjson.InvalidRequestError)
+
+	// One bad ID rejects the whole batch without querying storage.
+	rig.storage.orderStatusCalls = 0
+	reqs := newReq(maxBatch)
+	reqs[maxBatch-1].OrderID = encode.RandomBytes(order.OrderIDSize - 1)
+	req, _ = msgjson.NewRequest(1, msgjson.OrderStatusRoute, reqs)
+	ensureErr(rig.mgr.handleOrderStatus(user.conn, req), "bad order ID", msgjson.InvalidRequestError)
+	if rig.storage.orderStatusCalls != 0 {
+		t.Fatalf("storage queried for a batch with a bad order ID")
+	}
+
+	// A full batch is ok.
+	req, _ = msgjson.NewRequest(1, msgjson.OrderStatusRoute, newReq(maxBatch))
+	if msgErr := rig.mgr.handleOrderStatus(user.conn, req); msgErr != nil {
+		t.Fatalf("handleOrderStatus error: %v", msgErr)
+	}
+	if user.conn.getSend() == nil {
+		t.Fatalf("no response sent")
+	}
+}
+
+func TestOrderStatusBookedEpochs(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+	defer func() { rig.storage.orderStatuses = nil }()
+
+	const epochDur = 10_000 // ms
+	const epochIdx = 1000
+	bookTime := int64(epochIdx+1) * epochDur // end of the matching epoch
+
+	// Booked for 4 epochs, then executed mid-epoch.
+	executedOid := randomOrderID()
+	// Matched in its epoch without ever being booked.
+	unbookedOid := randomOrderID()
+	// Still booked, measured up to the manager's clock.
+	bookedOid := randomOrderID()
+	rig.mgr.clock = &tClock{now: time.UnixMilli(bookTime + 7*epochDur)}
+	defer func() { rig.mgr.clock = nil }()
+	rig.storage.orderStatuses = []*db.OrderStatus{{
+		ID:         executedOid,
+		Status:     order.OrderStatusExecuted,
+		EpochIdx:   epochIdx,
+		EpochDur:   epochDur,
+		UnbookTime: bookTime + 4*epochDur + epochDur/2,
+	}, {
+		ID:       unbookedOid,
+		Status:   order.OrderStatusExecuted,
+		EpochIdx: epochIdx,
+		EpochDur: epochDur,
+	}, {
+		ID:       bookedOid,
+		Status:   order.OrderStatusBooked,
+		EpochIdx: epochIdx,
+		EpochDur: epochDur,
+	}}
+
+	reqPayload := []msgjson.OrderStatusRequest{
+		{OrderID: executedOid[:]},
+		{OrderID: unbookedOid[:]},
+		{OrderID: bookedOid[:]},
+	}
+	req, _ := msgjson.NewRequest(1, msgjson.OrderStatusRoute, reqPayload)
+	if msgErr := rig.mgr.handleOrderStatus(user.conn, req); msgErr != nil {
+		t.Fatalf("handleOrderStatus error: %v", msgErr)
+	}
+	resp := user.conn.getSend()
+	if resp == nil {
+		t.Fatalf("no response sent")
+	}
+	var statuses []*msgjson.OrderStatus
+	if err := resp.UnmarshalResult(&statuses); err != nil {
+		t.Fatalf("UnmarshalResult error: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 statuses, got %d", len(statuses))
+	}
+	for _, status := range statuses {
+		var expEpochs uint64
+		switch {
+		case bytes.Equal(status.ID, executedOid[:]):
+			expEpochs = 4
+		case bytes.Equal(status.ID, bookedOid[:]):
+			expEpochs = 7
+		}
+		if status.BookedEpochs != expEpochs {
+			t.Fatalf("wrong booked epochs for order %s. expected %d, got %d",
+				status.ID, expEpochs, status.BookedEpochs)
+		}
+	}
+}
+
+func Test_checkSigS256(t *testing.T) {
+	sig := []byte{0x30, 0, 0x02, 0x01, 9, 0x2, 0x01, 10}
+	ecdsa.ParseDERSignature(sig) // panic on line 132: sigStr[2] != 0x02 after trimming to sigStr[:(1+2)]
+
+	sig = []byte{0x30, 1, 0x02, 0x01, 9, 0x2, 0x01, 10}
+	ecdsa.ParseDERSignature(sig) // panic on line 139: rLen := int(sigStr[index]) with index=3 and len = 3
+}
+
+func TestReputationUpgradeStatus(t *testing.T) {
+	defer func() { rig.storage.repVersions, rig.storage.repVersionsErr = nil, nil }()
+
+	pending, done, err := rig.mgr.ReputationUpgradeStatus(context.Background())
+	if err != nil {
+		t.Fatalf("ReputationUpgradeStatus error: %v", err)
+	}
+	if pending != 0 || done != 0 {
+		t.Fatalf("expected no accounts, got %d pending, %d done", pending, done)
+	}
+
+	rig.storage.repVersions = make(map[account.AccountID]int16)
+	for i := 0; i < 5; i++ {
+		var ver int16 // v0, legacy
+		if i < 3 {
+			ver = currentReputationVersion
+		}
+		rig.storage.repVersions[tNewUser(t).acctID] = ver
+	}
+	pending, done, err = rig.mgr.ReputationUpgradeStatus(context.Background())
+	if err != nil {
+		t.Fatalf("ReputationUpgradeStatus error: %v", err)
+	}
+	if pending != 2 || done != 3 {
+		t.Fatalf("expected 2 pending, 3 done, got %d pending, %d done", pending, done)
+	}
+
+	rig.storage.repVersionsErr = errors.New("test error")
+	if _, _, err = rig.mgr.ReputationUpgradeStatus(context.Background()); err == nil {
+		t.Fatalf("no error for storage error")
+	}
+}
+
+func TestMinClientVersion(t *testing.T) {
+	minVer := dex.NewSemver(1, 0, 4)
+	rig.mgr.minClientVer = &minVer
+	defer func() { rig.mgr.minClientVer = nil }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	rig.storage.acct = &account.Account{ID: user.acctID, PubKey: user.privKey.PubKey()}
+
+	connectWithVersion := func(clientVer string) *msgjson.Error {
+		connect := tNewConnect(user)
+		connect.ClientVersion = clientVer
+		connect.SetSig(signMsg(user.privKey, connect.Serialize()))
+		msg, err := msgjson.NewRequest(comms.NextID(), msgjson.ConnectRoute, connect)
+		if err != nil {
+			t.Fatalf("NewRequest error: %v", err)
+		}
+		rpcErr := rig.mgr.handleConnect(user.conn, msg)
+		if rpcErr == nil && user.conn.getSend() == nil {
+			t.Fatalf("no connect response")
+		}
+		return rpcErr
+	}
+
+	ensureErr := makeEnsureErr(t)
+	ensureErr(connectWithVersion("1.0.3"), "old client version", msgjson.OutdatedClientError)
+	ensureErr(connectWithVersion("0.6.5+release"), "old major version", msgjson.OutdatedClientError)
+	ensureErr(connectWithVersion(""), "no client version", msgjson.OutdatedClientError)
+	ensureErr(connectWithVersion("banana"), "invalid client version", msgjson.OutdatedClientError)
+	if rig.mgr.user(user.acctID) != nil {
+		t.Fatalf("user registered with outdated client version")
+	}
+
+	for _, ver := range []string{"1.0.4", "1.0.5-pre", "1.1.0", "2.0.0+release.local"} {
+		if rpcErr := connectWithVersion(ver); rpcErr != nil {
+			t.Fatalf("error for allowed client version %s: %s", ver, rpcErr.Message)
+		}
+	}
+
+	// A bad signature is reported before the client version.
+	connect := tNewConnect(user)
+	connect.ClientVersion = "1.0.3"
+	connect.SetSig(signMsg(user.privKey, []byte("wrong")))
+	msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.ConnectRoute, connect)
+	ensureErr(rig.mgr.handleConnect(user.conn, msg), "bad signature", msgjson.SignatureError)
+}
+
+func TestConfigSnapshot(t *testing.T) {
+	snap := rig.mgr.ConfigSnapshot()
+	for key, want := range map[string]any{
+		"bondExpiry":         "24h0m0s",
+		"miaUserTimeout":     "1m30s",
+		"cancelThreshold":    0.9,
+		"penaltyThreshold":   int32(DefaultPenaltyThreshold),
+		"freeCancelEpochGap": int32(DefaultFreeCancelEpochGap),
+		"minClientVersion":   "",
+		"banPolicy":          false,
+		"draining":           false,
+	} {
+		if got, found := snap[key]; !found {
+			t.Fatalf("snapshot missing key %q", key)
+		} else if got != want {
+			t.Fatalf("wrong %s: wanted %v (%T), got %v (%T)", key, want, want, got, got)
+		}
+	}
+	bondAssets := snap["bondAssets"].(map[string]msgjson.BondAsset)
+	if ba, found := bondAssets["dcr"]; !found || ba.ID != 42 || ba.Confs != uint32(tBondConfs) {
+		t.Fatalf("wrong bond assets %+v", bondAssets)
+	}
+	if _, err := json.Marshal(snap); err != nil {
+		t.Fatalf("error encoding snapshot: %v", err)
+	}
+
+	// Runtime changes are reflected.
+	if err := rig.mgr.SetBondConfs("dcr", uint32(tBondConfs+1)); err != nil {
+		t.Fatalf("SetBondConfs error: %v", err)
+	}
+	defer rig.mgr.SetBondConfs("dcr", uint32(tBondConfs))
+	bondAssets = rig.mgr.ConfigSnapshot()["bondAssets"].(map[string]msgjson.BondAsset)
+	if bondAssets["dcr"].Confs != uint32(tBondConfs+1) {
+		t.Fatalf("bond confs change not reflected, got %d", bondAssets["dcr"].Confs)
 	}
+}
 
-	orderOutcomes := rig.mgr.orderOutcomes[user.acctID]
+func TestOnBondPosted(t *testing.T) {
+	user := tNewUser(t)
+	acctPubKey := user.privKey.PubKey().SerializeCompressed()
+	const bondStrength = 3
+	rig.mgr.checkBond = func(ctx context.Context, assetID uint32, ver uint16, coinID []byte) (amt, lockTime, confs int64, acct account.AccountID, err error) {
+		return int64(tRegFee * 10 * bondStrength), time.Now().Add(48 * time.Hour).Unix(), tBondConfs, user.acctID, nil
+	}
+	type postedBond struct {
+		acctID account.AccountID
+		bond   *db.Bond
+	}
+	var posted []postedBond
+	rig.mgr.onBondPosted = func(acctID account.AccountID, bond *db.Bond) {
+		posted = append(posted, postedBond{acctID, bond})
+	}
+	defer func() { rig.mgr.checkBond, rig.mgr.onBondPosted = nil, nil }()
 
-	oid := newOrderID()
-	tCompleted := unixMsNow()
-	rig.mgr.RecordCompletedOrder(user.acctID, oid, tCompleted)
+	pb := &msgjson.PostBond{
+		AcctPubKey: acctPubKey,
+		AssetID:    42,
+		CoinID:     encode.RandomBytes(36),
+	}
+	pb.SetSig(signMsg(user.privKey, pb.Serialize()))
+	msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.PostBondRoute, pb)
+	if rpcErr := rig.mgr.handlePostBond(user.conn, msg); rpcErr != nil {
+		t.Fatalf("handlePostBond error: %v", rpcErr)
+	}
+	if user.conn.getSend() == nil {
+		t.Fatalf("no postbond response")
+	}
+	if len(posted) != 1 {
+		t.Fatalf("expected 1 bond posted callback, got %d", len(posted))
+	}
+	if posted[0].acctID != user.acctID {
+		t.Fatalf("wrong account %v, wanted %v", posted[0].acctID, user.acctID)
+	}
+	if posted[0].bond.Strength != bondStrength {
+		t.Fatalf("wrong bond strength %d, wanted %d", posted[0].bond.Strength, bondStrength)
+	}
 
-	counts := func(os *latestOutcomes[*db.OrderOutcome]) (total, cancels int) {
-		m := os.binViolations()
-		successes, cancels := int(m[db.OutcomeOrderComplete]), int(m[db.OutcomeOrderCanceled])
-		return successes + cancels, cancels
+	// A nil callback is a no-op.
+	rig.mgr.onBondPosted = nil
+	pb.CoinID = encode.RandomBytes(36)
+	pb.SetSig(signMsg(user.privKey, pb.Serialize()))
+	msg, _ = msgjson.NewRequest(comms.NextID(), msgjson.PostBondRoute, pb)
+	if rpcErr := rig.mgr.handlePostBond(user.conn, msg); rpcErr != nil {
+		t.Fatalf("handlePostBond error: %v", rpcErr)
 	}
-	total, cancels := counts(orderOutcomes)
-	if total != 1 {
-		t.Errorf("got %d total orders, expected %d", total, 1)
+}
+
+func TestTierForBonds(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	bondExpiry := rig.mgr.bondExpiry
+	bond := func(strength uint32, lockTime time.Time) *db.Bond {
+		return &db.Bond{Strength: strength, LockTime: lockTime.Unix()}
 	}
-	if cancels != 0 {
-		t.Errorf("got %d cancels, expected %d", cancels, 0)
+	bonds := []*db.Bond{
+		bond(1, now.Add(bondExpiry+time.Hour)),   // active
+		bond(2, now.Add(bondExpiry)),             // active, just
+		bond(4, now.Add(bondExpiry-time.Second)), // expired, but not locktime yet
+		bond(8, now.Add(-time.Hour)),             // expired and refundable
 	}
 
-	checkOrd := func(ord *db.OrderOutcome, oid order.OrderID, cancel bool, timestamp int64) {
-		if ord.OrderID != oid {
-			t.Errorf("completed order id mismatch. got %v, expected %v",
-				ord.OrderID, oid)
-		}
-		if ord.Canceled != cancel {
-			t.Errorf("order marked as cancel=%v, expected %v", ord.Canceled, cancel)
+	for _, tt := range []struct {
+		name string
+		at   time.Time
+		tier int64
+	}{
+		{"now", now, 3},
+		{"earlier", now.Add(-time.Hour), 7},
+		{"much earlier", now.Add(-bondExpiry - 2*time.Hour), 15},
+		{"later", now.Add(time.Minute), 1},
+		{"much later", now.Add(2 * time.Hour), 0},
+	} {
+		if tier := rig.mgr.TierForBonds(bonds, tt.at); tier != tt.tier {
+			t.Fatalf("%s: wanted tier %d, got %d", tt.name, tt.tier, tier)
 		}
 	}
 
-	ord := orderOutcomes.outcomes[0]
-	checkOrd(ord, oid, false, tCompleted.UnixMilli())
+	if tier := rig.mgr.TierForBonds(nil, now); tier != 0 {
+		t.Fatalf("wanted tier 0 for no bonds, got %d", tier)
+	}
+}
 
-	// another
-	oid = newOrderID()
-	tCompleted = tCompleted.Add(time.Millisecond) // newer
-	rig.mgr.RecordCompletedOrder(user.acctID, oid, tCompleted)
+func TestMaxBondsPerAccount(t *testing.T) {
+	user := tNewUser(t)
+	acctPubKey := user.privKey.PubKey().SerializeCompressed()
+	lockTime := time.Now().Add(48 * time.Hour).Unix()
+	rig.mgr.checkBond = func(context.Context, uint32, uint16, []byte) (int64, int64, int64, account.AccountID, error) {
+		return int64(tRegFee * 10), lockTime, tBondConfs, user.acctID, nil
+	}
+	const maxBonds = 3
+	rig.mgr.maxBonds = maxBonds
+	defer func() {
+		rig.mgr.checkBond = nil
+		rig.mgr.maxBonds = 0
+		rig.storage.bonds = nil
+	}()
+	rig.storage.bonds = nil
+
+	postBond := func(coinID []byte) *msgjson.Error {
+		pb := &msgjson.PostBond{
+			AcctPubKey: acctPubKey,
+			AssetID:    42,
+			CoinID:     coinID,
+		}
+		pb.SetSig(signMsg(user.privKey, pb.Serialize()))
+		msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.PostBondRoute, pb)
+		return rig.mgr.handlePostBond(user.conn, msg)
+	}
 
-	total, cancels = counts(orderOutcomes)
-	if total != 2 {
-		t.Errorf("got %d total orders, expected %d", total, 2)
+	// Post bonds up to the limit. TStorage does not store bonds, so add them
+	// to the account's active bonds as they are accepted.
+	for i := 0; i < maxBonds; i++ {
+		coinID := encode.RandomBytes(36)
+		if rpcErr := postBond(coinID); rpcErr != nil {
+			t.Fatalf("handlePostBond error for bond %d: %v", i, rpcErr)
+		}
+		if user.conn.getSend() == nil {
+			t.Fatalf("no postbond response for bond %d", i)
+		}
+		rig.storage.bonds = append(rig.storage.bonds, &db.Bond{
+			AssetID:  42,
+			CoinID:   coinID,
+			Strength: 1,
+			LockTime: lockTime,
+		})
 	}
-	if cancels != 0 {
-		t.Errorf("got %d cancels, expected %d", cancels, 0)
+
+	// The next one is rejected.
+	rpcErr := postBond(encode.RandomBytes(36))
+	if rpcErr == nil || rpcErr.Code != msgjson.TooManyBondsError {
+		t.Fatalf("expected a TooManyBondsError, got %v", rpcErr)
 	}
 
-	ord = orderOutcomes.outcomes[1]
-	checkOrd(ord, oid, false, tCompleted.UnixMilli())
+	// Re-posting a known bond is still ok.
+	if rpcErr := postBond(rig.storage.bonds[0].CoinID); rpcErr != nil {
+		t.Fatalf("handlePostBond error for a known bond: %v", rpcErr)
+	}
 
-	// now a cancel
-	coid := newOrderID()
-	tCompleted = tCompleted.Add(time.Millisecond) // newer
-	rig.mgr.RecordCancel(user.acctID, coid, oid, 1, tCompleted)
+	// No limit.
+	rig.mgr.maxBonds = 0
+	if rpcErr := postBond(encode.RandomBytes(36)); rpcErr != nil {
+		t.Fatalf("handlePostBond error with no limit: %v", rpcErr)
+	}
+}
 
-	total, cancels = counts(orderOutcomes)
-	if total != 3 {
-		t.Errorf("got %d total orders, expected %d", total, 3)
+func TestPreimageTimeout(t *testing.T) {
+	if timeout := rig.mgr.PreimageTimeout(); timeout != DefaultPreimageTimeout {
+		t.Fatalf("wrong default preimage timeout %v", timeout)
 	}
-	if cancels != 1 {
-		t.Errorf("got %d cancels, expected %d", cancels, 1)
+	authMgr, err := NewAuthManager(&Config{
+		Storage:         rig.storage,
+		Signer:          rig.signer,
+		PreimageTimeout: 5 * time.Second,
+		Route:           func(string, comms.MsgHandler) {},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthManager error: %v", err)
+	}
+	if timeout := authMgr.PreimageTimeout(); timeout != 5*time.Second {
+		t.Fatalf("wrong preimage timeout %v", timeout)
 	}
 
-	ord = orderOutcomes.outcomes[2]
-	checkOrd(ord, coid, true, tCompleted.UnixMilli())
+	requested := time.Now()
+	for _, tt := range []struct {
+		name     string
+		received time.Time
+		onTime   bool
+	}{
+		{"immediate", requested, true},
+		{"just within window", requested.Add(5*time.Second - time.Millisecond), true},
+		{"at deadline", requested.Add(5 * time.Second), true},
+		{"late", requested.Add(5*time.Second + time.Millisecond), false},
+		{"not received", time.Time{}, false},
+	} {
+		if onTime := authMgr.PreimageOnTime(requested, tt.received); onTime != tt.onTime {
+			t.Fatalf("%s: wanted on time = %t, got %t", tt.name, tt.onTime, onTime)
+		}
+	}
 }
 
-func TestMatchStatus(t *testing.T) {
+func TestRequestRateLimit(t *testing.T) {
+	const burst = 3
+	rig.mgr.reqRate, rig.mgr.reqBurst = 0.001, burst
+	defer func() {
+		rig.mgr.reqRate, rig.mgr.reqBurst = 0, 1
+	}()
+
 	user := tNewUser(t)
-	rig.signer.sig = user.randomSignature()
 	connectUser(t, user)
 
-	rig.storage.matchStatuses = []*db.MatchStatus{{
-		Status:    order.MakerSwapCast,
-		IsTaker:   true,
-		MakerSwap: []byte{0x01},
-	}}
+	var handled int
+	rig.mgr.Route("limitedroute", func(account.AccountID, *msgjson.Message) *msgjson.Error {
+		handled++
+		return nil
+	})
+	f := tRoutes["limitedroute"]
+	ensureErr := makeEnsureErr(t)
+	for i := 0; i < burst*3; i++ {
+		rpcErr := f(user.conn, nil)
+		if i < burst {
+			if rpcErr != nil {
+				t.Fatalf("request %d rejected within burst: %s", i, rpcErr.Message)
+			}
+			continue
+		}
+		ensureErr(rpcErr, "over limit", msgjson.TooManyRequestsError)
+	}
+	if handled != burst {
+		t.Fatalf("expected %d handled requests, got %d", burst, handled)
+	}
 
-	tTxData := encode.RandomBytes(5)
-	rig.mgr.txDataSources[0] = func([]byte) ([]byte, error) {
-		return tTxData, nil
+	// Users connected with limiting disabled are not throttled.
+	rig.mgr.reqRate = 0
+	free := tNewUser(t)
+	connectUser(t, free)
+	for i := 0; i < burst*3; i++ {
+		if rpcErr := f(free.conn, nil); rpcErr != nil {
+			t.Fatalf("unexpected error with limiting disabled: %s", rpcErr.Message)
+		}
 	}
+}
 
-	reqPayload := []msgjson.MatchRequest{{MatchID: encode.RandomBytes(32)}}
+func TestExportReputation(t *testing.T) {
+	user := tNewUser(t)
+	rig.storage.acct = &account.Account{ID: user.acctID, PubKey: user.privKey.PubKey()}
+	lockTime := time.Now().Add(rig.mgr.bondExpiry * 2).Unix()
+	rig.storage.bonds = []*db.Bond{{AssetID: 42, CoinID: []byte{0x01}, Amount: int64(tRegFee * 10), Strength: 2, LockTime: lockTime}}
+	oid, mid := randomOrderID(), randomMatchID()
+	rig.storage.repPimgs = []*db.PreimageOutcome{{DBID: 1, OrderID: oid, Miss: true}}
+	rig.storage.repMatches = []*db.MatchResult{
+		{DBID: 2, MatchID: mid, MatchOutcome: db.OutcomeSwapSuccess},
+		{DBID: 3, MatchID: randomMatchID(), MatchOutcome: db.OutcomeNoSwapAsTaker},
+	}
+	rig.storage.repOrds = []*db.OrderOutcome{{DBID: 4, OrderID: oid, Canceled: true}}
+	defer func() {
+		rig.storage.acct, rig.storage.bonds = nil, nil
+		rig.storage.repPimgs, rig.storage.repMatches, rig.storage.repOrds = nil, nil, nil
+	}()
+
+	export, err := rig.mgr.ExportReputation(context.Background(), user.acctID)
+	if err != nil {
+		t.Fatalf("ExportReputation error: %v", err)
+	}
 
-	req, _ := msgjson.NewRequest(1, msgjson.MatchStatusRoute, reqPayload)
+	wantScore := int32(preimageMissScore + matchCompletedScore + noSwapAsTakerScore)
+	if export.Score != wantScore {
+		t.Fatalf("wrong score. got %d, want %d", export.Score, wantScore)
+	}
+	if export.BondedTier != 2 || export.Tier != 2 {
+		t.Fatalf("wrong tiers. bonded %d, effective %d", export.BondedTier, export.Tier)
+	}
+	if export.AccountID != user.acctID {
+		t.Fatalf("wrong account ID")
+	}
+	if len(export.Preimages) != 1 || !bytes.Equal(export.Preimages[0].ID, oid[:]) ||
+		export.Preimages[0].Outcome != db.OutcomePreimageMiss.String() {
+		t.Fatalf("wrong preimage outcomes %+v", export.Preimages)
+	}
+	if len(export.Matches) != 2 || !bytes.Equal(export.Matches[0].ID, mid[:]) ||
+		export.Matches[1].Outcome != db.OutcomeNoSwapAsTaker.String() {
+		t.Fatalf("wrong match outcomes %+v", export.Matches)
+	}
+	if len(export.Orders) != 1 || export.Orders[0].Outcome != db.OutcomeOrderCanceled.String() {
+		t.Fatalf("wrong order outcomes %+v", export.Orders)
+	}
+	if len(export.Bonds) != 1 || export.Bonds[0].Strength != 2 || export.Bonds[0].AssetID != 42 {
+		t.Fatalf("wrong bonds %+v", export.Bonds)
+	}
 
-	getStatus := func() *msgjson.MatchStatusResult {
-		msgErr := rig.mgr.handleMatchStatus(user.conn, req)
-		if msgErr != nil {
-			t.Fatalf("handleMatchStatus error: %v", msgErr)
+	b, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("error marshalling export: %v", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("error unmarshalling export: %v", err)
+	}
+	for _, k := range []string{"accountID", "score", "bondedTier", "penalties", "tier", "preimages", "matches", "orders", "bonds"} {
+		if _, found := m[k]; !found {
+			t.Fatalf("export JSON missing %q: %s", k, b)
 		}
+	}
 
-		resp := user.conn.getSend()
-		if resp == nil {
-			t.Fatalf("no matches sent")
+	// Unknown accounts are an error.
+	rig.storage.acct = nil
+	if _, err := rig.mgr.ExportReputation(context.Background(), user.acctID); err == nil {
+		t.Fatalf("no error for unknown account")
+	}
+}
+
+func TestConnectTierLimit(t *testing.T) {
+	rig.mgr.tierLimits = map[int64]uint64{1: 5e8, 2: 2e9}
+	defer func() {
+		rig.mgr.tierLimits = nil
+		rig.storage.bonds = nil
+	}()
+
+	user := tNewUser(t)
+	for _, tt := range []struct {
+		tier     uint32
+		expLimit uint64
+	}{
+		{1, 5e8},
+		{2, 2e9},
+		{3, 0}, // not limited
+	} {
+		rig.storage.setBondTier(tt.tier)
+		rig.signer.sig = user.randomSignature()
+		res := extractConnectResult(t, connectUser(t, user))
+		if res.TierLimit != tt.expLimit {
+			t.Fatalf("tier %d: expected connect limit %d, got %d", tt.tier, tt.expLimit, res.TierLimit)
+		}
+		if limit := rig.mgr.TierLimit(user.acctID); limit != tt.expLimit {
+			t.Fatalf("tier %d: expected TierLimit %d, got %d", tt.tier, tt.expLimit, limit)
 		}
+	}
 
-		statuses := []msgjson.MatchStatusResult{}
-		err := resp.UnmarshalResult(&statuses)
-		if err != nil {
-			t.Fatalf("UnmarshalResult error: %v", err)
+	if limit := rig.mgr.TierLimit(tNewUser(t).acctID); limit != 0 {
+		t.Fatalf("limit %d for unconnected user", limit)
+	}
+}
+
+func TestValidateBondAssets(t *testing.T) {
+	validAssets := func() map[string]*msgjson.BondAsset {
+		return map[string]*msgjson.BondAsset{
+			"dcr": {ID: 42, Confs: 2, Amt: 1e8},
+			"btc": {ID: 0, Confs: 1, Amt: 1e6},
 		}
-		if len(statuses) != 1 {
-			t.Fatalf("expected 1 match, got %d", len(statuses))
+	}
+	if err := ValidateBondAssets(validAssets()); err != nil {
+		t.Fatalf("unexpected error for valid assets: %v", err)
+	}
+	if err := ValidateBondAssets(nil); err != nil {
+		t.Fatalf("unexpected error for no assets: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		mod    func(map[string]*msgjson.BondAsset)
+		expErr string
+	}{{
+		name:   "nil asset",
+		mod:    func(m map[string]*msgjson.BondAsset) { m["ltc"] = nil },
+		expErr: `"ltc"`,
+	}, {
+		name:   "zero amount",
+		mod:    func(m map[string]*msgjson.BondAsset) { m["dcr"].Amt = 0 },
+		expErr: `"dcr" has a zero bond amount`,
+	}, {
+		name:   "zero confs",
+		mod:    func(m map[string]*msgjson.BondAsset) { m["btc"].Confs = 0 },
+		expErr: `"btc" requires zero confirmations`,
+	}, {
+		name:   "wrong ID",
+		mod:    func(m map[string]*msgjson.BondAsset) { m["btc"].ID = 2 },
+		expErr: `"btc" has asset ID 2, expected 0`,
+	}, {
+		name:   "duplicate ID",
+		mod:    func(m map[string]*msgjson.BondAsset) { m["mydcr"] = &msgjson.BondAsset{ID: 42, Confs: 1, Amt: 1} },
+		expErr: "same asset ID 42",
+	}, {
+		name:   "zero tier schedule amount",
+		mod:    func(m map[string]*msgjson.BondAsset) { m["dcr"].BondTierSchedule = []uint64{0, 1e8} },
+		expErr: "amount 0 for strength 1",
+	}, {
+		name:   "decreasing tier schedule",
+		mod:    func(m map[string]*msgjson.BondAsset) { m["dcr"].BondTierSchedule = []uint64{1e8, 3e8, 2e8} },
+		expErr: "amount 200000000 for strength 3",
+	}} {
+		assets := validAssets()
+		tt.mod(assets)
+		err := ValidateBondAssets(assets)
+		if err == nil {
+			t.Fatalf("%s: no error", tt.name)
+		}
+		if !strings.Contains(err.Error(), tt.expErr) {
+			t.Fatalf("%s: expected error containing %q, got %q", tt.name, tt.expErr, err)
 		}
-		return &statuses[0]
 	}
 
-	// As taker in MakerSwapCast, we expect tx data.
-	status := getStatus()
-	if !bytes.Equal(status.MakerTxData, tTxData) {
-		t.Fatalf("wrong maker tx data. exected %x, got %s", tTxData, status.MakerTxData)
+	if _, err := NewAuthManager(&Config{
+		Storage:    rig.storage,
+		Signer:     rig.signer,
+		BondAssets: map[string]*msgjson.BondAsset{"dcr": {ID: 42, Confs: 1}},
+		Route:      func(string, comms.MsgHandler) {},
+	}); err == nil {
+		t.Fatalf("no NewAuthManager error for invalid bond assets")
 	}
+}
 
-	// As maker, we don't expect any tx data.
-	rig.storage.matchStatuses[0].IsTaker = false
-	rig.storage.matchStatuses[0].IsMaker = true
-	if len(getStatus().TakerTxData) != 0 {
-		t.Fatalf("got tx data as maker in MakerSwapCast")
+func TestScoreClamping(t *testing.T) {
+	const n = 10_000
+	newMatches := func(outcome Outcome) *latestOutcomes[*db.MatchResult] {
+		matches := make([]*db.MatchResult, 0, n)
+		for i := 0; i < n; i++ {
+			matches = append(matches, &db.MatchResult{DBID: int64(i + 1), MatchID: randomMatchID(), MatchOutcome: outcome})
+		}
+		return newLatestOutcomes(matches, n)
 	}
+	successes := newMatches(db.OutcomeSwapSuccess)
+	failures := newMatches(db.OutcomeNoRedeemAsTaker)
+	ords := newLatestOutcomes([]*db.OrderOutcome{}, cancelThreshWindow)
 
-	// As maker in TakerSwapCast, we do expect tx data.
-	rig.storage.matchStatuses[0].Status = order.TakerSwapCast
-	rig.storage.matchStatuses[0].TakerSwap = []byte{0x01}
-	txData := getStatus().TakerTxData
-	if !bytes.Equal(txData, tTxData) {
-		t.Fatalf("wrong taker tx data. exected %x, got %s", tTxData, txData)
+	// The default bounds do not affect realistic scores.
+	score, _, _ := rig.mgr.integrateOutcomes(successes, nil, ords)
+	if score != n*matchCompletedScore {
+		t.Fatalf("wrong unclamped score %d", score)
+	}
+	score, _, _ = rig.mgr.integrateOutcomes(failures, nil, ords)
+	if score != n*noRedeemAsTakerScore {
+		t.Fatalf("wrong unclamped score %d", score)
 	}
 
-	reqPayload[0].MatchID = []byte{}
-	req, _ = msgjson.NewRequest(1, msgjson.MatchStatusRoute, reqPayload)
-	msgErr := rig.mgr.handleMatchStatus(user.conn, req)
-	if msgErr == nil {
-		t.Fatalf("no error for bad match ID")
+	defer func(maxScore, minScore int32) {
+		rig.mgr.maxScore, rig.mgr.minScore = maxScore, minScore
+	}(rig.mgr.maxScore, rig.mgr.minScore)
+	rig.mgr.maxScore, rig.mgr.minScore = 100, -50
+
+	if score, _, _ = rig.mgr.integrateOutcomes(successes, nil, ords); score != 100 {
+		t.Fatalf("score %d not clamped to the max score", score)
+	}
+	if score, _, _ = rig.mgr.integrateOutcomes(failures, nil, ords); score != -50 {
+		t.Fatalf("score %d not clamped to the min score", score)
+	}
+
+	// Sums beyond the range of an int32 saturate rather than overflow.
+	rig.mgr.maxScore, rig.mgr.minScore = math.MaxInt32, math.MinInt32
+	if score := rig.mgr.clampScore(math.MaxInt32 + 1000); score != math.MaxInt32 {
+		t.Fatalf("overflowing score not saturated: %d", score)
+	}
+	if score := rig.mgr.clampScore(math.MinInt32 - 1000); score != math.MinInt32 {
+		t.Fatalf("underflowing score not saturated: %d", score)
 	}
 }
 
-func TestOrderStatus(t *testing.T) {
+func TestBondConfGrace(t *testing.T) {
+	const grace = time.Minute * 10
+	rig.mgr.bondConfGrace = grace
+	rig.storage.setBondTier(1)
+	defer func() {
+		rig.mgr.bondConfGrace = 0
+		rig.storage.bonds = nil
+	}()
+
 	user := tNewUser(t)
-	rig.signer.sig = user.randomSignature()
-	connectUser(t, user)
+	pendingBond := &db.Bond{
+		AssetID:  42,
+		CoinID:   encode.RandomBytes(36),
+		Strength: 2,
+		LockTime: time.Now().Add(rig.mgr.bondExpiry * 2).Unix(),
+	}
+	key := bondKey(pendingBond.AssetID, pendingBond.CoinID)
+	if !rig.mgr.registerBondWaiter(key, user.acctID, pendingBond) {
+		t.Fatalf("failed to register bond waiter")
+	}
+	defer rig.mgr.removeBondWaiter(key)
 
-	rig.storage.orderStatuses = []*db.OrderStatus{{}}
+	connectTier := func() int64 {
+		t.Helper()
+		rig.signer.sig = user.randomSignature()
+		return extractConnectResult(t, connectUser(t, user)).Reputation.BondedTier
+	}
 
-	reqPayload := []msgjson.OrderStatusRequest{
-		{
-			OrderID: encode.RandomBytes(order.OrderIDSize),
-		},
+	// Inside the grace window, the pending bond counts.
+	if tier := connectTier(); tier != 3 {
+		t.Fatalf("expected bonded tier 3 with pending bond in grace window, got %d", tier)
 	}
 
-	req, _ := msgjson.NewRequest(1, msgjson.OrderStatusRoute, reqPayload)
+	// Outside the grace window, it does not.
+	rig.mgr.bondWaiterMtx.Lock()
+	rig.mgr.bondWaiterIdx[key].submitted = time.Now().Add(-grace * 2)
+	rig.mgr.bondWaiterMtx.Unlock()
+	if tier := connectTier(); tier != 1 {
+		t.Fatalf("expected bonded tier 1 with pending bond outside grace window, got %d", tier)
+	}
 
-	msgErr := rig.mgr.handleOrderStatus(user.conn, req)
-	if msgErr != nil {
-		t.Fatalf("handleOrderStatus error: %v", msgErr)
+	// No grace, pending bonds never count.
+	rig.mgr.bondConfGrace = 0
+	rig.mgr.bondWaiterMtx.Lock()
+	rig.mgr.bondWaiterIdx[key].submitted = time.Now()
+	rig.mgr.bondWaiterMtx.Unlock()
+	if tier := connectTier(); tier != 1 {
+		t.Fatalf("expected bonded tier 1 with no grace, got %d", tier)
 	}
+}
 
-	resp := user.conn.getSend()
-	if resp == nil {
-		t.Fatalf("no orders sent")
+func TestOutcomePruner(t *testing.T) {
+	user := tNewUser(t) // never connected
+	defer func(retention int) { rig.mgr.outcomeRetention = retention }(rig.mgr.outcomeRetention)
+	rig.mgr.outcomeRetention = 3
+	rig.storage.pruned = make(map[db.OutcomeClass]int64)
+	defer func() {
+		rig.storage.pruned = nil
+		rig.storage.repPimgs, rig.storage.repMatches, rig.storage.repOrds = nil, nil, nil
+	}()
+
+	// Storing an outcome for an offline user queues them for pruning.
+	rig.mgr.registerPreimageOutcome(user.acctID, true, randomOrderID(), time.Now())
+	rig.mgr.violationMtx.Lock()
+	_, queued := rig.mgr.outcomePruneUsers[user.acctID]
+	rig.mgr.violationMtx.Unlock()
+	if !queued {
+		t.Fatalf("offline user not queued for outcome pruning")
 	}
 
-	var statuses []*msgjson.OrderStatus
-	err := resp.UnmarshalResult(&statuses)
-	if err != nil {
-		t.Fatalf("UnmarshalResult error: %v", err)
+	rig.storage.repPimgs = []*db.PreimageOutcome{
+		{DBID: 7, OrderID: randomOrderID()},
+		{DBID: 6, OrderID: randomOrderID()},
+		{DBID: 5, OrderID: randomOrderID()},
 	}
-	if len(statuses) != 1 {
-		t.Fatalf("expected 1 order, got %d", len(statuses))
+	rig.storage.repMatches = []*db.MatchResult{
+		{DBID: 9, MatchID: randomMatchID()},
+		{DBID: 8, MatchID: randomMatchID()},
 	}
+	rig.mgr.pruneOutcomes(context.Background())
 
-	reqPayload[0].OrderID = []byte{}
-	req, _ = msgjson.NewRequest(1, msgjson.OrderStatusRoute, reqPayload)
-	msgErr = rig.mgr.handleOrderStatus(user.conn, req)
-	if msgErr == nil {
-		t.Fatalf("no error for bad order ID")
+	// Only the preimage outcomes are at the retention limit.
+	if fromDBID, found := rig.storage.pruned[db.OutcomeClassPreimage]; !found || fromDBID != 4 {
+		t.Fatalf("wrong preimage pruning. found = %t, fromDBID = %d", found, fromDBID)
+	}
+	if _, found := rig.storage.pruned[db.OutcomeClassMatch]; found {
+		t.Fatalf("match outcomes pruned below retention limit")
+	}
+	if _, found := rig.storage.pruned[db.OutcomeClassOrder]; found {
+		t.Fatalf("order outcomes pruned with none stored")
+	}
+
+	// The queue is drained.
+	clear(rig.storage.pruned)
+	rig.mgr.pruneOutcomes(context.Background())
+	if len(rig.storage.pruned) != 0 {
+		t.Fatalf("outcomes pruned again without new outcomes")
 	}
 }
 
-func Test_checkSigS256(t *testing.T) {
-	sig := []byte{0x30, 0, 0x02, 0x01, 9, 0x2, 0x01, 10}
-	ecdsa.ParseDERSignature(sig) // panic on line 132: sigStr[2] != 0x02 after trimming to sigStr[:(1+2)]
+func TestConnectStats(t *testing.T) {
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	defer func() { rig.storage.acct = nil }()
+
+	// The rig's AuthManager is shared, so compare against a starting snapshot.
+	before := rig.mgr.Stats()
+
+	// Two bad signatures.
+	for i := 0; i < 2; i++ {
+		rig.storage.acct = &account.Account{ID: user.acctID, PubKey: user.privKey.PubKey()}
+		connect := tNewConnect(user)
+		connect.SetSig(signMsg(user.privKey, []byte("wrong")))
+		msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.ConnectRoute, connect)
+		if rig.mgr.handleConnect(user.conn, msg) == nil {
+			t.Fatalf("no error for bad signature")
+		}
+	}
 
-	sig = []byte{0x30, 1, 0x02, 0x01, 9, 0x2, 0x01, 10}
-	ecdsa.ParseDERSignature(sig) // panic on line 139: rLen := int(sigStr[index]) with index=3 and len = 3
+	// Account not found.
+	msg := queueUser(t, user)
+	rig.storage.acct = nil
+	if rig.mgr.handleConnect(user.conn, msg) == nil {
+		t.Fatalf("no error for unknown account")
+	}
+
+	// Drain mode.
+	rig.mgr.EnterDrainMode()
+	rpcErr := rig.mgr.handleConnect(user.conn, queueUser(t, user))
+	rig.mgr.ExitDrainMode()
+	if rpcErr == nil {
+		t.Fatalf("no error in drain mode")
+	}
+
+	// Success.
+	connectUser(t, user)
+
+	after := rig.mgr.Stats()
+	if n := after.Connects - before.Connects; n != 5 {
+		t.Fatalf("expected 5 connects, got %d", n)
+	}
+	if n := after.ConnectsSucceeded - before.ConnectsSucceeded; n != 1 {
+		t.Fatalf("expected 1 successful connect, got %d", n)
+	}
+	for kind, want := range map[dex.ErrorKind]uint64{
+		ErrBadSignature:    2,
+		ErrAccountNotFound: 1,
+		ErrDrainMode:       1,
+		ErrOutdatedClient:  0,
+	} {
+		reason := string(kind)
+		if n := after.ConnectsRejected[reason] - before.ConnectsRejected[reason]; n != want {
+			t.Fatalf("expected %d connects rejected with %q, got %d", want, reason, n)
+		}
+	}
+}
+
+func TestTempBan(t *testing.T) {
+	clock := &tClock{now: time.Now()}
+	rig.mgr.clock = clock
+	defer func() { rig.mgr.clock = nil }()
+
+	user := tNewUser(t)
+	rig.signer.sig = user.randomSignature()
+	connectUser(t, user)
+
+	// Banning a connected user disconnects them.
+	until := clock.Now().Add(time.Hour)
+	rig.mgr.TempBan(user.acctID, until)
+	if rig.mgr.user(user.acctID) != nil {
+		t.Fatalf("temporarily banned user still connected")
+	}
+
+	// The ban is not revealed to an unauthenticated request.
+	connect := tNewConnect(user)
+	connect.SetSig(signMsg(user.privKey, []byte("wrong")))
+	msg, _ := msgjson.NewRequest(comms.NextID(), msgjson.ConnectRoute, connect)
+	if err := rig.mgr.connect(user.conn, msg); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("expected ErrBadSignature for a banned account, got %v", err)
+	}
+
+	// Connects are refused before the ban expires.
+	err := rig.mgr.connect(user.conn, queueUser(t, user))
+	if !errors.Is(err, ErrAccountTempBanned) {
+		t.Fatalf("expected ErrAccountTempBanned, got %v", err)
+	}
+	rpcErr := rig.mgr.handleConnect(user.conn, queueUser(t, user))
+	makeEnsureErr(t)(rpcErr, "temp banned connect", msgjson.AccountClosedError)
+	if unban := until.UTC().Format(time.RFC3339); !strings.Contains(rpcErr.Message, unban) {
+		t.Fatalf("error message %q does not state the unban time %s", rpcErr.Message, unban)
+	}
+
+	// Connects succeed after the ban expires, and the ban is removed.
+	clock.advance(time.Hour)
+	connectUser(t, user)
+	if rig.mgr.user(user.acctID) == nil {
+		t.Fatalf("user not connected after ban expired")
+	}
+	rig.mgr.tempBanMtx.Lock()
+	_, found := rig.mgr.tempBans[user.acctID]
+	rig.mgr.tempBanMtx.Unlock()
+	if found {
+		t.Fatalf("expired ban not removed")
+	}
+
+	// A ban in the past lifts an existing ban.
+	other := tNewUser(t)
+	rig.mgr.TempBan(other.acctID, clock.Now().Add(time.Hour))
+	rig.mgr.TempBan(other.acctID, clock.Now().Add(-time.Second))
+	if err := rig.mgr.checkTempBan(other.acctID); err != nil {
+		t.Fatalf("lifted ban still in effect: %v", err)
+	}
+
+	// Expired bans are pruned.
+	rig.mgr.TempBan(other.acctID, clock.Now().Add(time.Minute))
+	rig.mgr.pruneTempBans()
+	if err := rig.mgr.checkTempBan(other.acctID); err == nil {
+		t.Fatalf("unexpired ban pruned")
+	}
+	clock.advance(time.Minute)
+	rig.mgr.pruneTempBans()
+	rig.mgr.tempBanMtx.Lock()
+	n := len(rig.mgr.tempBans)
+	rig.mgr.tempBanMtx.Unlock()
+	if n != 0 {
+		t.Fatalf("%d expired bans not pruned", n)
+	}
 }
@@ -9,6 +9,10 @@ import (
 	"decred.org/dcrdex/server/db"
 )
 
+// latestOutcomes is an in-memory window of a user's most recent outcomes.
+// Outcomes are written through to storage before they are added, so every
+// outcome in the window has a DB ID and nothing needs to be flushed on
+// shutdown.
 type latestOutcomes[T db.Outcomer] struct {
 	mtx      sync.Mutex
 	cap      int16
@@ -45,6 +49,12 @@ func (la *latestOutcomes[T]) add(o T) (popped int64) {
 	return
 }
 
+func (la *latestOutcomes[T]) len() int {
+	la.mtx.Lock()
+	defer la.mtx.Unlock()
+	return len(la.outcomes)
+}
+
 func (la *latestOutcomes[T]) binViolations() map[Outcome]int64 {
 	la.mtx.Lock()
 	defer la.mtx.Unlock()
@@ -6,6 +6,7 @@ package auth
 import (
 	"bytes"
 	"context"
+	"slices"
 	"time"
 
 	"decred.org/dcrdex/dex"
@@ -30,22 +31,76 @@ func bondKey(assetID uint32, coinID []byte) string {
 	return string(append(encode.Uint32Bytes(assetID), coinID...))
 }
 
-func (auth *AuthManager) registerBondWaiter(key string) bool {
+// pendingBond is a posted bond that is awaiting the required confirmations.
+type pendingBond struct {
+	acctID    account.AccountID
+	bond      *db.Bond
+	submitted time.Time
+}
+
+func (auth *AuthManager) registerBondWaiter(key string, acctID account.AccountID, bond *db.Bond) bool {
 	auth.bondWaiterMtx.Lock()
 	defer auth.bondWaiterMtx.Unlock()
 	if _, found := auth.bondWaiterIdx[key]; found {
 		return false
 	}
-	auth.bondWaiterIdx[key] = struct{}{}
+	auth.bondWaiterIdx[key] = &pendingBond{
+		acctID:    acctID,
+		bond:      bond,
+		submitted: auth.now(),
+	}
 	return true
 }
 
+// pendingBondTier is the tier from the user's bonds that are awaiting
+// confirmations and were submitted within bondConfGrace. Bonds that are
+// already in active are not counted.
+func (auth *AuthManager) pendingBondTier(acctID account.AccountID, active []*db.Bond) (tier int64) {
+	now := auth.now()
+	lockTimeThresh := now.Add(auth.bondExpiry).Unix()
+	auth.bondWaiterMtx.Lock()
+	defer auth.bondWaiterMtx.Unlock()
+	for _, pb := range auth.bondWaiterIdx {
+		if pb.acctID != acctID || now.Sub(pb.submitted) > auth.bondConfGrace ||
+			pb.bond.LockTime < lockTimeThresh {
+			continue
+		}
+		isActive := func(bond *db.Bond) bool {
+			return bond.AssetID == pb.bond.AssetID && bytes.Equal(bond.CoinID, pb.bond.CoinID)
+		}
+		if slices.ContainsFunc(active, isActive) {
+			continue
+		}
+		log.Debugf("Counting unconfirmed bond %s toward tier for user %v",
+			coinIDString(pb.bond.AssetID, pb.bond.CoinID), acctID)
+		tier += int64(pb.bond.Strength)
+	}
+	return tier
+}
+
 func (auth *AuthManager) removeBondWaiter(key string) {
 	auth.bondWaiterMtx.Lock()
 	delete(auth.bondWaiterIdx, key)
 	auth.bondWaiterMtx.Unlock()
 }
 
+// checkBondLockTime checks that a new bond's lock time is at least bondExpiry
+// from now, and no more than maxBondLockTime from now if that limit is set.
+func (auth *AuthManager) checkBondLockTime(lockTime int64) *msgjson.Error {
+	now := auth.now()
+	// A bond's lockTime must be after bondExpiry from now.
+	lockTimeThresh := now.Add(auth.bondExpiry)
+	if lockTime < lockTimeThresh.Unix() {
+		return msgjson.NewError(msgjson.BondError, "insufficient lock time %d, needed at least %d", lockTime, lockTimeThresh.Unix())
+	}
+	if auth.maxBondLockTime > 0 {
+		if maxLockTime := now.Add(auth.maxBondLockTime).Unix(); lockTime > maxLockTime {
+			return msgjson.NewError(msgjson.BondError, "excessive lock time %d, must be no later than %d", lockTime, maxLockTime)
+		}
+	}
+	return nil
+}
+
 // handlePreValidateBond handles the 'prevalidatebond' request.
 //
 // The request payload includes the user's account public key and the serialized
@@ -66,7 +121,7 @@ func (auth *AuthManager) handlePreValidateBond(conn comms.Link, msg *msgjson.Mes
 	}
 
 	assetID := preBond.AssetID
-	bondAsset, ok := auth.bondAssets[assetID]
+	bondAsset, ok := auth.bondAsset(assetID)
 	if !ok {
 		return msgjson.NewError(msgjson.BondError, "%s does not support bonds", dex.BipIDSymbol(assetID))
 	}
@@ -88,20 +143,17 @@ func (auth *AuthManager) handlePreValidateBond(conn comms.Link, msg *msgjson.Mes
 		}
 	}
 
-	// A bond's lockTime must be after bondExpiry from now.
-	lockTimeThresh := time.Now().Add(auth.bondExpiry)
-
 	// Decode raw tx, check fee output (0) and account commitment output (1).
 	bondCoinID, amt, lockTime, commitAcct, err :=
 		auth.parseBondTx(assetID, preBond.Version, preBond.RawTx /*, postBond.Data*/)
 	if err != nil {
 		return msgjson.NewError(msgjson.BondError, "invalid bond transaction: %v", err)
 	}
-	if amt < int64(bondAsset.Amt) {
-		return msgjson.NewError(msgjson.BondError, "insufficient bond amount %d, needed %d", amt, bondAsset.Amt)
+	if minAmt := bondAsset.Amount(1); amt < int64(minAmt) {
+		return msgjson.NewError(msgjson.BondError, "insufficient bond amount %d, needed %d", amt, minAmt)
 	}
-	if lockTime < lockTimeThresh.Unix() {
-		return msgjson.NewError(msgjson.BondError, "insufficient lock time %d, needed at least %d", lockTime, lockTimeThresh.Unix())
+	if msgErr := auth.checkBondLockTime(lockTime); msgErr != nil {
+		return msgErr
 	}
 
 	// Must be equal to account ID computed from pubkey in the PayFee message.
@@ -121,7 +173,12 @@ func (auth *AuthManager) handlePreValidateBond(conn comms.Link, msg *msgjson.Mes
 		Amount:    uint64(amt),
 		Expiry:    uint64(expireTime.Unix()),
 	}
-	preBondRes.SetSig(auth.SignMsg(append(preBondRes.Serialize(), preBond.RawTx...)))
+	sig, err := auth.SignMsg(append(preBondRes.Serialize(), preBond.RawTx...))
+	if err != nil {
+		log.Errorf("Error signing prevalidatebond result: %v", err)
+		return msgjson.NewError(msgjson.RPCInternalError, "internal signing error")
+	}
+	preBondRes.SetSig(sig)
 
 	resp, err := msgjson.NewResponse(msg.ID, preBondRes, nil)
 	if err != nil { // shouldn't be possible
@@ -153,7 +210,7 @@ func (auth *AuthManager) handlePostBond(conn comms.Link, msg *msgjson.Message) *
 	}
 
 	assetID := postBond.AssetID
-	bondAsset, ok := auth.bondAssets[assetID]
+	bondAsset, ok := auth.bondAsset(assetID)
 	if !ok && assetID != account.PrepaidBondID {
 		return msgjson.NewError(msgjson.BondError, "%s does not support bonds", dex.BipIDSymbol(assetID))
 	}
@@ -180,7 +237,7 @@ func (auth *AuthManager) handlePostBond(conn comms.Link, msg *msgjson.Message) *
 	}
 
 	// A bond's lockTime must be after bondExpiry from now.
-	lockTimeThresh := time.Now().Add(auth.bondExpiry)
+	lockTimeThresh := auth.now().Add(auth.bondExpiry)
 
 	bondVer, bondCoinID := postBond.Version, postBond.CoinID
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
@@ -189,11 +246,11 @@ func (auth *AuthManager) handlePostBond(conn comms.Link, msg *msgjson.Message) *
 	if err != nil {
 		return msgjson.NewError(msgjson.BondError, "invalid bond transaction: %v", err)
 	}
-	if amt < int64(bondAsset.Amt) {
-		return msgjson.NewError(msgjson.BondError, "insufficient bond amount %d, needed %d", amt, bondAsset.Amt)
+	if minAmt := bondAsset.Amount(1); amt < int64(minAmt) {
+		return msgjson.NewError(msgjson.BondError, "insufficient bond amount %d, needed %d", amt, minAmt)
 	}
-	if lockTime < lockTimeThresh.Unix() {
-		return msgjson.NewError(msgjson.BondError, "insufficient lock time %d, needed at least %d", lockTime, lockTimeThresh.Unix())
+	if msgErr := auth.checkBondLockTime(lockTime); msgErr != nil {
+		return msgErr
 	}
 
 	// Must be equal to account ID computed from pubkey in the PayFee message.
@@ -201,7 +258,7 @@ func (auth *AuthManager) handlePostBond(conn comms.Link, msg *msgjson.Message) *
 		return msgjson.NewError(msgjson.BondError, "invalid bond transaction - account commitment does not match pubkey")
 	}
 
-	strength := uint32(uint64(amt) / bondAsset.Amt)
+	strength := bondAsset.Strength(uint64(amt))
 
 	// All good. The client gets a PostBondResult (no error) unless the confirms
 	// check has an unexpected error or times out.
@@ -215,7 +272,10 @@ func (auth *AuthManager) handlePostBond(conn comms.Link, msg *msgjson.Message) *
 		BondID:     bondCoinID,
 		Reputation: auth.ComputeUserReputation(acctID),
 	}
-	auth.Sign(postBondRes)
+	if err := auth.Sign(postBondRes); err != nil {
+		log.Errorf("Error signing postbond result: %v", err)
+		return msgjson.NewError(msgjson.RPCInternalError, "internal signing error")
+	}
 
 	sendResp := func() *msgjson.Error {
 		resp, err := msgjson.NewResponse(msg.ID, postBondRes, nil)
@@ -249,6 +309,10 @@ func (auth *AuthManager) handlePostBond(conn comms.Link, msg *msgjson.Message) *
 		}
 	}
 
+	if auth.maxBonds > 0 && len(bonds) >= auth.maxBonds {
+		return msgjson.NewError(msgjson.TooManyBondsError, "account already has the maximum of %d active bonds", auth.maxBonds)
+	}
+
 	dbBond := &db.Bond{
 		Version:  postBond.Version,
 		AssetID:  assetID,
@@ -261,7 +325,7 @@ func (auth *AuthManager) handlePostBond(conn comms.Link, msg *msgjson.Message) *
 	// Either store the bond or start a block waiter to activate the bond and
 	// respond with a PostBondResult when it is fully-confirmed.
 	bondIDKey := bondKey(assetID, bondCoinID)
-	if !auth.registerBondWaiter(bondIDKey) {
+	if !auth.registerBondWaiter(bondIDKey, acctID, dbBond) {
 		// Waiter already running! They'll get a response to their first
 		// request, or find out on connect if the bond was activated.
 		return msgjson.NewError(msgjson.BondAlreadyConfirmingError, "bond already submitted")
@@ -340,6 +404,8 @@ func (auth *AuthManager) storeBondAndRespond(conn comms.Link, bond *db.Bond, acc
 	log.Infof("Bond accepted: acct %v from %v locked %d in %v. Bond total %d, tier %d",
 		acctID, conn.Addr(), bond.Amount, coinIDString(bond.AssetID, coinID), rep.BondedTier, rep.EffectiveTier())
 
+	auth.bondPosted(acctID, bond)
+
 	// Respond
 	resp, err := msgjson.NewResponse(reqID, postBondRes, nil)
 	if err != nil { // shouldn't be possible
@@ -368,7 +434,7 @@ func (auth *AuthManager) processPrepaidBond(conn comms.Link, msg *msgjson.Messag
 
 	lockTime := time.Unix(lockTimeI, 0)
 	expireTime := lockTime.Add(-auth.bondExpiry)
-	if time.Until(expireTime) < time.Hour*24 {
+	if expireTime.Sub(auth.now()) < time.Hour*24 {
 		return msgjson.NewError(msgjson.BondError, "pre-paid bond is too old")
 	}
 
@@ -381,10 +447,16 @@ func (auth *AuthManager) processPrepaidBond(conn comms.Link, msg *msgjson.Messag
 		BondID:     coinID,
 		Reputation: auth.ComputeUserReputation(acct.ID),
 	}
-	auth.Sign(postBondRes)
+	if err := auth.Sign(postBondRes); err != nil {
+		log.Errorf("Error signing pre-paid postbond result: %v", err)
+		return msgjson.NewError(msgjson.RPCInternalError, "internal signing error")
+	}
 
-	lockTimeThresh := time.Now().Add(auth.bondExpiry)
-	dbAcct, _ := auth.storage.Account(acct.ID, lockTimeThresh)
+	lockTimeThresh := auth.now().Add(auth.bondExpiry)
+	dbAcct, bonds := auth.storage.Account(acct.ID, lockTimeThresh)
+	if auth.maxBonds > 0 && len(bonds) >= auth.maxBonds {
+		return msgjson.NewError(msgjson.TooManyBondsError, "account already has the maximum of %d active bonds", auth.maxBonds)
+	}
 
 	dbBond := &db.Bond{
 		AssetID:  account.PrepaidBondID,
@@ -422,6 +494,8 @@ func (auth *AuthManager) processPrepaidBond(conn comms.Link, msg *msgjson.Messag
 	log.Infof("Pre-paid bond accepted: acct %v from %v. Bonded tier %d, effective tier %d",
 		acct.ID, conn.Addr(), rep.BondedTier, rep.EffectiveTier())
 
+	auth.bondPosted(acct.ID, dbBond)
+
 	resp, err := msgjson.NewResponse(msg.ID, postBondRes, nil)
 	if err != nil { // shouldn't be possible
 		return nil
@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/msgjson"
 	"decred.org/dcrdex/dex/ws"
 )
@@ -30,6 +31,8 @@ type Link interface {
 	ID() uint64
 	// Addr returns the string-encoded IP address.
 	Addr() string
+	// IP returns the IP address as a dex.IPKey.
+	IP() dex.IPKey
 	// Send sends the msgjson.Message to the peer.
 	Send(msg *msgjson.Message) error
 	// SendRaw sends the raw bytes which is assumed to be a marshalled
@@ -69,6 +72,7 @@ type wsLink struct {
 	*ws.WSLink
 	// The id is the unique identifier assigned to this client.
 	id       uint64
+	ip       dex.IPKey
 	customID atomic.Value
 	// For DEX-originating requests, the response handler is mapped to the
 	// resquest ID.
@@ -86,12 +90,13 @@ type wsLink struct {
 }
 
 // newWSLink is a constructor for a new wsLink.
-func (s *Server) newWSLink(addr string, conn ws.Connection, wsLimiter *routeLimiter, limitData func() (int, error)) *wsLink {
+func (s *Server) newWSLink(ip dex.IPKey, conn ws.Connection, wsLimiter *routeLimiter, limitData func() (int, error)) *wsLink {
 	var c *wsLink
 	c = &wsLink{
-		WSLink: ws.NewWSLink(addr, conn, pingPeriod, func(msg *msgjson.Message) *msgjson.Error {
+		WSLink: ws.NewWSLink(ip.String(), conn, pingPeriod, func(msg *msgjson.Message) *msgjson.Error {
 			return s.handleMessage(c, msg)
 		}, log.SubLogger("WS")),
+		ip:           ip,
 		respHandlers: make(map[uint64]*responseHandler),
 		dataMeter:    limitData,
 		wsLimiter:    wsLimiter,
@@ -126,6 +131,11 @@ func (c *wsLink) Addr() string {
 	return c.WSLink.Addr()
 }
 
+// IP returns the IP address as a dex.IPKey.
+func (c *wsLink) IP() dex.IPKey {
+	return c.ip
+}
+
 // Authorized should be called from a request handler when the connection
 // becomes authorized. Unless it is run in a request handler synchronous with
 // other reads or prior to starting the link, it will be a data race with the
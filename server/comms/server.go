@@ -647,7 +647,7 @@ func (s *Server) websocketHandler(ctx context.Context, conn ws.Connection, ip de
 		return
 	}
 	defer s.wsLimiterDone(ip)
-	client := s.newWSLink(addr, conn, wsLimiter, dataRoutesMeter)
+	client := s.newWSLink(ip, conn, wsLimiter, dataRoutesMeter)
 
 	cm, err := s.addClient(ctx, client)
 	if err != nil {
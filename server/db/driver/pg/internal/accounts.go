@@ -94,4 +94,6 @@ const (
 	SelectReputationVersion = `SELECT reputation_ver FROM %s WHERE account_id = $1;`
 
 	UpdateReputationVersion = `UPDATE %s SET reputation_ver = $1 WHERE account_id = $2;`
+
+	CountReputationVersions = `SELECT reputation_ver, COUNT(*) FROM %s GROUP BY reputation_ver;`
 )
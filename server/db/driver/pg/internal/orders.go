@@ -23,7 +23,8 @@ const (
 		filled INT8,
 		epoch_idx INT8, epoch_dur INT4,
 		preimage BYTEA UNIQUE,
-		complete_time INT8      -- when the order has successfully completed all swaps
+		complete_time INT8,     -- when the order has successfully completed all swaps
+		unbook_time INT8        -- when a booked order was removed from the book
 	);`
 
 	// InsertOrder inserts a market or limit order into the specified table.
@@ -62,14 +63,16 @@ const (
 		commit, coins, quantity, rate, force, status, filled
 	FROM %s WHERE account_id = $1;`
 
-	// SelectUserOrderStatuses retrieves the order IDs and statuses of all orders
-	// for the given account ID. Only applies to market and limit orders.
-	SelectUserOrderStatuses = `SELECT oid, status FROM %s WHERE account_id = $1;`
+	// SelectUserOrderStatuses retrieves the order IDs, statuses, and book timing
+	// info of all orders for the given account ID. Only applies to market and limit orders.
+	SelectUserOrderStatuses = `SELECT oid, status, epoch_idx, epoch_dur, unbook_time
+		FROM %s WHERE account_id = $1;`
 
-	// SelectUserOrderStatusesByID retrieves the order IDs and statuses of the
-	// orders with the provided order IDs for the given account ID. Only applies
+	// SelectUserOrderStatusesByID retrieves the order IDs, statuses, and book
+	// timing info of the orders with the provided order IDs for the given account ID. Only applies
 	// to market and limit orders.
-	SelectUserOrderStatusesByID = `SELECT oid, status FROM %s WHERE account_id = $1 AND oid = ANY($2);`
+	SelectUserOrderStatusesByID = `SELECT oid, status, epoch_idx, epoch_dur, unbook_time
+		FROM %s WHERE account_id = $1 AND oid = ANY($2);`
 
 	// SelectCanceledUserOrders gets the ID of orders that were either canceled
 	// by the user or revoked/canceled by the server, but these statuses can be
@@ -121,7 +124,7 @@ const (
 
 	// MoveOrder moves an order row from one table to another (e.g.
 	// orders_active to orders_archived), while updating the order's status and
-	// filled amounts.
+	// filled amounts. If the order was booked, the unbook_time is set to $2.
 	// For example,
 	//	WITH moved AS (                                 -- temporary table
 	//		DELETE FROM dcrdex.dcr_btc.orders_active    -- origin table (%s)
@@ -141,7 +144,8 @@ const (
 	//			force,
 	//			2,                                      -- new status (%d)
 	//			123456789,                              -- new filled (%d)
-	//          epoch_idx, epoch_dur, preimage, complete_time
+	//          epoch_idx, epoch_dur, preimage, complete_time,
+	//			CASE WHEN status = 2 THEN 1700000000000 END -- unbook_time if booked (%d, $2)
 	//		)
 	//		INSERT INTO dcrdex.dcr_btc.orders_archived  -- destination table (%s)
 	//		SELECT * FROM moved;
@@ -151,7 +155,8 @@ const (
 		RETURNING oid, type, sell, account_id, address,
 			client_time, server_time, commit, coins, quantity,
 			rate, force, %d, %d,
-			epoch_idx, epoch_dur, preimage, complete_time
+			epoch_idx, epoch_dur, preimage, complete_time,
+			CASE WHEN status = %d THEN $2::INT8 END
 	)
 	INSERT INTO %s
 	SELECT * FROM moved;`
@@ -163,7 +168,8 @@ const (
 		RETURNING oid, type, sell, account_id, address,
 			client_time, server_time, commit, coins, quantity,
 			rate, force, %d, filled, -- revoked status code
-			epoch_idx, epoch_dur, preimage, complete_time
+			epoch_idx, epoch_dur, preimage, complete_time,
+			$2::INT8             -- unbook_time
 	)
 	INSERT INTO %s -- archived orders table for market X
 	SELECT * FROM moved
@@ -219,14 +225,17 @@ const (
 		ORDER BY epoch_idx * epoch_dur DESC;`
 	// RetrieveCancelTimesForUserByStatus is similar to
 	// RetrieveCancelsForUserByStatus, but it joins on an epochs table to get
-	// the match_time directly instead of the epoch_idx and epoch_dur. The
-	// cancels table, with full market schema, is %[1]s, while the epochs table
-	// is %[2]s.
-	RetrieveCancelTimesForUserByStatus = `SELECT oid, target_order, epoch_gap, match_time
-		FROM %[1]s -- a cancels table
-		JOIN %[2]s ON %[2]s.epoch_idx = %[1]s.epoch_idx AND %[2]s.epoch_dur = %[1]s.epoch_dur -- join on epochs table PK
-		WHERE account_id = $1 AND status = $2
-		ORDER BY match_time DESC
+	// the match_time directly instead of the epoch_idx and epoch_dur, and on an
+	// orders table to get the server_time of the targeted order, which is NULL
+	// if the target is not found. The cancels table, with full market schema,
+	// is %[1]s, the epochs table is %[2]s, and the archived orders table is
+	// %[3]s.
+	RetrieveCancelTimesForUserByStatus = `SELECT c.oid, c.target_order, c.epoch_gap, e.match_time, t.server_time
+		FROM %[1]s AS c -- a cancels table
+		JOIN %[2]s AS e ON e.epoch_idx = c.epoch_idx AND e.epoch_dur = c.epoch_dur -- join on epochs table PK
+		LEFT JOIN %[3]s AS t ON t.oid = c.target_order
+		WHERE c.account_id = $1 AND c.status = $2
+		ORDER BY e.match_time DESC
 		LIMIT $3;` // NOTE: find revoked orders via SelectRevokeCancels
 
 	// InsertCancelOrder inserts a cancel order row into the specified table.
@@ -9,16 +9,48 @@ const (
 		account BYTEA,
 		link BYTEA,             -- Order ID or Match ID
 		class INT2,              -- Preimage, order (complete/cancel), or match
-		outcome INT2
+		outcome INT2,
+		value INT8 DEFAULT 0,    -- Match quantity, for match outcomes
+		base INT8 DEFAULT 0,     -- Market base asset, for match outcomes
+		quote INT8 DEFAULT 0     -- Market quote asset, for match outcomes
 	);`
 
 	CreatePointsIndex = `CREATE INDEX IF NOT EXISTS idx_points ON %s (account, class);`
 
-	InsertPoints = `INSERT INTO %s (account, link, class, outcome) VALUES ($1, $2, $3, $4) RETURNING id;`
+	InsertPoints = `INSERT INTO %s (account, link, class, outcome, value, base, quote)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;`
 
-	SelectPoints = `SELECT id, link, class, outcome FROM %s WHERE account = $1 ORDER BY id;`
+	SelectPoints = `SELECT id, link, class, outcome, value, base, quote FROM %s WHERE account = $1 ORDER BY id;`
 
 	PrunePoints = `DELETE FROM %s WHERE account = $1 AND class = $2 AND id <= $3;`
 
-	ForgiveUser = `DELETE FROM %s WHERE account = $1 AND outcome NOT IN ($2, $3, $4);`
+	// SelectRecentMatchValues selects the values of the $2 most recent match
+	// outcomes, with class $1, that have a value for each market, oldest
+	// first.
+	SelectRecentMatchValues = `SELECT value, base, quote FROM (
+			SELECT id, value, base, quote,
+				ROW_NUMBER() OVER (PARTITION BY base, quote ORDER BY id DESC) AS n
+			FROM %s WHERE class = $1 AND value > 0
+		) AS recent
+		WHERE n <= $2 ORDER BY id;`
+
+	// ForgiveUser deletes the user's outcomes other than successes and the
+	// records of past forgiveness, which have class $5.
+	ForgiveUser = `DELETE FROM %s WHERE account = $1 AND outcome NOT IN ($2, $3, $4) AND class != $5;`
+
+	// SelectRecentForgiveness selects the user's $3 most recent outcomes with
+	// the forgiven outcome $2.
+	SelectRecentForgiveness = `SELECT id, class, link FROM %s WHERE account = $1 AND outcome = $2
+		ORDER BY id DESC LIMIT $3;`
+
+	// ForgivePreimageMisses sets the outcome of the user's $5 most recent
+	// preimage misses to forgiven.
+	ForgivePreimageMisses = `UPDATE %[1]s SET outcome = $4 WHERE id IN (
+		SELECT id FROM %[1]s WHERE account = $1 AND class = $2 AND outcome = $3
+		ORDER BY id DESC LIMIT $5);`
+
+	// ForgiveMatchOutcome sets the outcome of the user's failed outcomes, with
+	// outcomes from $5 to $6, for match $3 to forgiven.
+	ForgiveMatchOutcome = `UPDATE %s SET outcome = $4
+		WHERE account = $1 AND class = $2 AND link = $3 AND outcome BETWEEN $5 AND $6;`
 )
@@ -259,7 +259,7 @@ func (a *Archiver) FlushBook(base, quote uint32) (sellsRemoved, buysRemoved []or
 	// Changed all booked orders to revoked.
 	stmt := fmt.Sprintf(internal.PurgeBook, srcTableName, orderStatusRevoked, dstTableName)
 	var rows *sql.Rows
-	rows, err = dbTx.Query(stmt, orderStatusBooked)
+	rows, err = dbTx.Query(stmt, orderStatusBooked, timeStamp.UnixMilli())
 	if err != nil {
 		fail()
 		return
@@ -1171,13 +1171,17 @@ func (a *Archiver) userOrderStatusesFromTable(fullTable string, aid account.Acco
 	for rows.Next() {
 		var oid order.OrderID
 		var status pgOrderStatus
-		err = rows.Scan(&oid, &status)
+		var epochIdx, epochDur, unbookTime sql.NullInt64
+		err = rows.Scan(&oid, &status, &epochIdx, &epochDur, &unbookTime)
 		if err != nil {
 			return nil, err
 		}
 		statuses = append(statuses, &db.OrderStatus{
-			ID:     oid,
-			Status: pgToMarketStatus(status),
+			ID:         oid,
+			Status:     pgToMarketStatus(status),
+			EpochIdx:   epochIdx.Int64,
+			EpochDur:   epochDur.Int64,
+			UnbookTime: unbookTime.Int64,
 		})
 	}
 
@@ -1217,7 +1221,8 @@ func (a *Archiver) ExecutedCancelsForUser(aid account.AccountID, N int) (ords []
 		// Query for executed cancels (user-initiated).
 		cancelTableName := fullCancelOrderTableName(a.dbName, marketSchema, false) // executed cancel orders are inactive
 		epochsTableName := fullEpochsTableName(a.dbName, marketSchema)
-		stmt := fmt.Sprintf(internal.RetrieveCancelTimesForUserByStatus, cancelTableName, epochsTableName)
+		ordersTableName := fullOrderTableName(a.dbName, marketSchema, false) // canceled orders are inactive
+		stmt := fmt.Sprintf(internal.RetrieveCancelTimesForUserByStatus, cancelTableName, epochsTableName, ordersTableName)
 		ctx, cancel := context.WithTimeout(a.ctx, a.queryTimeout)
 		mktOrds, err := a.executedCancelsForUser(ctx, a.db, stmt, aid, N)
 		cancel()
@@ -1258,17 +1263,22 @@ func (a *Archiver) executedCancelsForUser(ctx context.Context, dbe *sql.DB, stmt
 		var oid, target order.OrderID
 		var execTime int64
 		var epochGap int32
-		err = rows.Scan(&oid, &target, &epochGap, &execTime)
+		var targetTime sql.NullTime
+		err = rows.Scan(&oid, &target, &epochGap, &execTime, &targetTime)
 		if err != nil {
 			return
 		}
 
-		ords = append(ords, &db.CancelRecord{
+		cr := &db.CancelRecord{
 			ID:        oid,
 			TargetID:  target,
 			MatchTime: execTime,
 			EpochGap:  epochGap,
-		})
+		}
+		if targetTime.Valid {
+			cr.TargetTime = targetTime.Time.UnixMilli()
+		}
+		ords = append(ords, cr)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -1675,8 +1685,8 @@ func updateOrderStatusAndFilledAmt(dbe sqlExecutor, tableName string, oid order.
 }
 
 func moveOrder(dbe sqlExecutor, oldTableName, newTableName string, oid order.OrderID, newStatus pgOrderStatus, newFilled uint64) (bool, error) {
-	stmt := fmt.Sprintf(internal.MoveOrder, oldTableName, newStatus, newFilled, newTableName)
-	moved, err := sqlExec(dbe, stmt, oid)
+	stmt := fmt.Sprintf(internal.MoveOrder, oldTableName, newStatus, newFilled, orderStatusBooked, newTableName)
+	moved, err := sqlExec(dbe, stmt, oid, time.Now().UnixMilli())
 	if err != nil {
 		return false, err
 	}
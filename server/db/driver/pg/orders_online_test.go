@@ -1849,6 +1849,9 @@ func TestExecutedCancelsForUser(t *testing.T) {
 	if cancels[0].MatchTime != matchTime {
 		t.Errorf("incorrect exec time for executed cancel %v, expected %v", cancels[0].MatchTime, matchTime)
 	}
+	if cancels[0].TargetTime != 0 { // the target order was never stored
+		t.Errorf("unexpected target time %d for executed cancel with unknown target", cancels[0].TargetTime)
+	}
 	if cancels[1].ID != coID {
 		t.Errorf("incorrect executed cancel %v, expected %v", cancels[1].ID, coID)
 	}
@@ -35,11 +35,11 @@ func (a *Archiver) GetUserReputationData(
 	orders := make([]*db.OrderOutcome, 0, orderSz)
 
 	for rows.Next() {
-		var dbID int64
+		var dbID, value, base, quote int64
 		var link order.OrderID
 		var outcomeClass db.OutcomeClass
 		var outcome db.Outcome
-		if err := rows.Scan(&dbID, &link, &outcomeClass, &outcome); err != nil {
+		if err := rows.Scan(&dbID, &link, &outcomeClass, &outcome, &value, &base, &quote); err != nil {
 			return nil, nil, nil, fmt.Errorf("error scanning points row: %w", err)
 		}
 		switch outcomeClass {
@@ -56,6 +56,9 @@ func (a *Archiver) GetUserReputationData(
 				DBID:         dbID,
 				MatchID:      mid,
 				MatchOutcome: outcome,
+				Value:        uint64(value),
+				Base:         uint32(base),
+				Quote:        uint32(quote),
 			})
 		case db.OutcomeClassOrder:
 			orders = append(orders, &db.OrderOutcome{
@@ -80,16 +83,21 @@ func (a *Archiver) GetUserReputationData(
 	return pimgs, matches, orders, nil
 }
 
+// insertPoints stores an outcome. The value and market are only recorded for
+// match outcomes, and are zero otherwise.
 func (a *Archiver) insertPoints(
 	ctx context.Context,
 	user account.AccountID,
 	link [32]byte,
 	outcomeClass db.OutcomeClass,
 	outcome db.Outcome,
+	value uint64,
+	base, quote uint32,
 ) (dbID int64, _ error) {
 	var oid order.OrderID // need a sql.Scanner
 	copy(oid[:], link[:])
-	return dbID, a.queries.insertPoints.QueryRowContext(ctx, user, oid, outcomeClass, outcome).Scan(&dbID)
+	return dbID, a.queries.insertPoints.QueryRowContext(ctx, user, oid, outcomeClass, outcome,
+		int64(value), int64(base), int64(quote)).Scan(&dbID)
 }
 
 func (a *Archiver) AddPreimageOutcome(ctx context.Context, user account.AccountID, oid order.OrderID, miss bool) (*db.PreimageOutcome, error) {
@@ -97,7 +105,7 @@ func (a *Archiver) AddPreimageOutcome(ctx context.Context, user account.AccountI
 	if miss {
 		outcome = db.OutcomePreimageMiss
 	}
-	dbID, err := a.insertPoints(ctx, user, oid, db.OutcomeClassPreimage, outcome)
+	dbID, err := a.insertPoints(ctx, user, oid, db.OutcomeClassPreimage, outcome, 0, 0, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -108,18 +116,21 @@ func (a *Archiver) AddPreimageOutcome(ctx context.Context, user account.AccountI
 	}, nil
 }
 
-func (a *Archiver) AddMatchOutcome(ctx context.Context, user account.AccountID, mid order.MatchID, outcome db.Outcome) (*db.MatchResult, error) {
+func (a *Archiver) AddMatchOutcome(ctx context.Context, user account.AccountID, mmid db.MarketMatchID, value uint64, outcome db.Outcome) (*db.MatchResult, error) {
 	if outcome < db.OutcomeSwapSuccess || outcome > db.OutcomeNoRedeemAsTaker {
 		return nil, fmt.Errorf("invalid outcome for a match: %d", outcome)
 	}
-	dbID, err := a.insertPoints(ctx, user, mid, db.OutcomeClassMatch, outcome)
+	dbID, err := a.insertPoints(ctx, user, mmid.MatchID, db.OutcomeClassMatch, outcome, value, mmid.Base, mmid.Quote)
 	if err != nil {
 		return nil, err
 	}
 	return &db.MatchResult{
 		DBID:         dbID,
-		MatchID:      mid,
+		MatchID:      mmid.MatchID,
 		MatchOutcome: outcome,
+		Value:        value,
+		Base:         mmid.Base,
+		Quote:        mmid.Quote,
 	}, nil
 }
 
@@ -128,7 +139,7 @@ func (a *Archiver) AddOrderOutcome(ctx context.Context, user account.AccountID,
 	if canceled {
 		outcome = db.OutcomeOrderCanceled
 	}
-	dbID, err := a.insertPoints(ctx, user, oid, db.OutcomeClassOrder, outcome)
+	dbID, err := a.insertPoints(ctx, user, oid, db.OutcomeClassOrder, outcome, 0, 0, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -155,6 +166,26 @@ func (a *Archiver) GetUserReputationVersion(ctx context.Context, user account.Ac
 	return ver, nil
 }
 
+func (a *Archiver) CountUsersByReputationVersion(ctx context.Context) (map[int16]int, error) {
+	query := fmt.Sprintf(internal.CountReputationVersions, a.tables.accounts)
+	rows, err := a.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying reputation versions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int16]int)
+	for rows.Next() {
+		var ver int16
+		var n int
+		if err := rows.Scan(&ver, &n); err != nil {
+			return nil, fmt.Errorf("error scanning reputation version count: %w", err)
+		}
+		counts[ver] = n
+	}
+	return counts, rows.Err()
+}
+
 func (a *Archiver) UpgradeUserReputationV1(
 	ctx context.Context, user account.AccountID, pimgs []*db.PreimageOutcome, matches []*db.MatchResult, orders []*db.OrderOutcome, /* Without DB IDs */
 ) ([]*db.PreimageOutcome, []*db.MatchResult, []*db.OrderOutcome, error) /* With DB IDs */ {
@@ -181,12 +212,13 @@ func (a *Archiver) UpgradeUserReputationV1(
 		if o.Miss {
 			outcome = db.OutcomePreimageMiss
 		}
-		if err = stmt.QueryRowContext(ctx, user, o.OrderID, db.OutcomeClassPreimage, outcome).Scan(&o.DBID); err != nil {
+		if err = stmt.QueryRowContext(ctx, user, o.OrderID, db.OutcomeClassPreimage, outcome, 0, 0, 0).Scan(&o.DBID); err != nil {
 			return nil, nil, nil, fmt.Errorf("error inserting preimage row during reputation upgrade: %w", err)
 		}
 	}
 	for _, o := range matches {
-		if err = stmt.QueryRowContext(ctx, user, o.MatchID, db.OutcomeClassMatch, o.MatchOutcome).Scan(&o.DBID); err != nil {
+		if err = stmt.QueryRowContext(ctx, user, o.MatchID, db.OutcomeClassMatch, o.MatchOutcome,
+			int64(o.Value), int64(o.Base), int64(o.Quote)).Scan(&o.DBID); err != nil {
 			return nil, nil, nil, fmt.Errorf("error inserting match row during reputation upgrade: %w", err)
 		}
 	}
@@ -195,7 +227,7 @@ func (a *Archiver) UpgradeUserReputationV1(
 		if o.Canceled {
 			outcome = db.OutcomeOrderCanceled
 		}
-		if err = stmt.QueryRowContext(ctx, user, o.OrderID, db.OutcomeClassOrder, outcome).Scan(&o.DBID); err != nil {
+		if err = stmt.QueryRowContext(ctx, user, o.OrderID, db.OutcomeClassOrder, outcome, 0, 0, 0).Scan(&o.DBID); err != nil {
 			return nil, nil, nil, fmt.Errorf("error inserting order row during reputation upgrade: %w", err)
 		}
 	}
@@ -206,10 +238,81 @@ func (a *Archiver) UpgradeUserReputationV1(
 	return pimgs, matches, orders, nil
 }
 
+// ForgiveUser deletes the user's unsuccessful outcomes, and records the
+// forgiveness so that it can be reported by RecentForgiveness. The record has
+// OutcomeClassInvalid, so it does not affect the user's score.
 func (a *Archiver) ForgiveUser(ctx context.Context, user account.AccountID) error {
 	query := fmt.Sprintf(internal.ForgiveUser, a.tables.points)
-	if _, err := a.db.ExecContext(ctx, query, user, db.OutcomeSwapSuccess, db.OutcomePreimageSuccess, db.OutcomeOrderComplete); err != nil {
+	if _, err := a.db.ExecContext(ctx, query, user, db.OutcomeSwapSuccess, db.OutcomePreimageSuccess,
+		db.OutcomeOrderComplete, db.OutcomeClassInvalid); err != nil {
 		return fmt.Errorf("error forgiving user: %w", err)
 	}
+	if _, err := a.insertPoints(ctx, user, [32]byte{}, db.OutcomeClassInvalid, db.OutcomeForgiven, 0, 0, 0); err != nil {
+		return fmt.Errorf("error recording user forgiveness: %w", err)
+	}
 	return nil
 }
+
+func (a *Archiver) ForgivePreimageMisses(ctx context.Context, user account.AccountID, n int) (int64, error) {
+	query := fmt.Sprintf(internal.ForgivePreimageMisses, a.tables.points)
+	res, err := a.db.ExecContext(ctx, query, user, db.OutcomeClassPreimage, db.OutcomePreimageMiss, db.OutcomeForgiven, n)
+	if err != nil {
+		return 0, fmt.Errorf("error forgiving preimage misses: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (a *Archiver) ForgiveMatchOutcome(ctx context.Context, user account.AccountID, mid order.MatchID) (bool, error) {
+	query := fmt.Sprintf(internal.ForgiveMatchOutcome, a.tables.points)
+	res, err := a.db.ExecContext(ctx, query, user, db.OutcomeClassMatch, mid, db.OutcomeForgiven,
+		db.OutcomeNoSwapAsMaker, db.OutcomeNoRedeemAsTaker)
+	if err != nil {
+		return false, fmt.Errorf("error forgiving match outcome: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (a *Archiver) RecentMatchValues(ctx context.Context, n int) ([]*db.MatchResult, error) {
+	query := fmt.Sprintf(internal.SelectRecentMatchValues, a.tables.points)
+	rows, err := a.db.QueryContext(ctx, query, db.OutcomeClassMatch, n)
+	if err != nil {
+		return nil, fmt.Errorf("error querying recent match values: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []*db.MatchResult
+	for rows.Next() {
+		var value, base, quote int64
+		if err := rows.Scan(&value, &base, &quote); err != nil {
+			return nil, fmt.Errorf("error scanning match value: %w", err)
+		}
+		matches = append(matches, &db.MatchResult{
+			Value: uint64(value),
+			Base:  uint32(base),
+			Quote: uint32(quote),
+		})
+	}
+	return matches, rows.Err()
+}
+
+func (a *Archiver) RecentForgiveness(ctx context.Context, user account.AccountID, n int) ([]*db.ForgivenOutcome, error) {
+	query := fmt.Sprintf(internal.SelectRecentForgiveness, a.tables.points)
+	rows, err := a.db.QueryContext(ctx, query, user, db.OutcomeForgiven, n)
+	if err != nil {
+		return nil, fmt.Errorf("error querying forgiven outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	var forgiven []*db.ForgivenOutcome
+	for rows.Next() {
+		var link order.OrderID // need a sql.Scanner
+		f := new(db.ForgivenOutcome)
+		if err := rows.Scan(&f.DBID, &f.Class, &link); err != nil {
+			return nil, fmt.Errorf("error scanning forgiven outcome: %w", err)
+		}
+		f.Link = link
+		forgiven = append(forgiven, f)
+	}
+	return forgiven, rows.Err()
+}
@@ -5,6 +5,7 @@ package pg
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"decred.org/dcrdex/dex/encode"
@@ -167,8 +168,10 @@ func TestReputation(t *testing.T) {
 		t.Fatalf("Bad added preimage outcome return")
 	}
 	mid := randomMatchID()
+	mmid := db.MarketMatchID{MatchID: mid, Base: 42, Quote: 0}
+	const matchValue = 5e8
 	outcome := db.OutcomeNoRedeemAsMaker
-	if match, err := archie.AddMatchOutcome(ctx, user, mid, outcome); err != nil {
+	if match, err := archie.AddMatchOutcome(ctx, user, mmid, matchValue, outcome); err != nil {
 		t.Fatalf("Error adding match outcome: %v", err)
 	} else if match.MatchID != mid || match.MatchOutcome != outcome || match.DBID == 0 {
 		t.Fatalf("Bad added match outcome return")
@@ -183,6 +186,10 @@ func TestReputation(t *testing.T) {
 	if len(loadedPimgs) != 2 || len(loadedMatches) != 2 || len(loadedOrds) != 2 {
 		t.Fatal("Wrong number of loaded outcomes", len(loadedPimgs), len(loadedMatches), len(loadedOrds))
 	}
+	// The match value and market are stored with the outcome.
+	if m := loadedMatches[1]; m.Value != matchValue || m.Base != mmid.Base || m.Quote != mmid.Quote {
+		t.Fatalf("Wrong loaded match value %d or market %d-%d", m.Value, m.Base, m.Quote)
+	}
 
 	if err := archie.ForgiveUser(ctx, user); err != nil {
 		t.Fatalf("Error forgiving user: %v", err)
@@ -196,3 +203,113 @@ func TestReputation(t *testing.T) {
 		t.Fatal("Forgiving didn't forgive", loadedPimgs[0].Miss, loadedMatches[0].MatchOutcome, loadedOrds[0].Canceled)
 	}
 }
+
+func TestForgiveMatchOutcome(t *testing.T) {
+	if err := cleanTables(archie.db); err != nil {
+		t.Fatalf("cleanTables: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	acct := tNewAccount(t)
+	user := acct.ID
+	if err := archie.CreateAccountWithBond(acct, &db.Bond{}); err != nil {
+		t.Fatalf("Error creating account: %v", err)
+	}
+
+	randomMatchID := func() (mid order.MatchID) {
+		copy(mid[:], encode.RandomBytes(32))
+		return
+	}
+
+	failedMID, successMID := randomMatchID(), randomMatchID()
+	if _, err := archie.AddMatchOutcome(ctx, user, db.MarketMatchID{MatchID: failedMID}, 0, db.OutcomeNoRedeemAsTaker); err != nil {
+		t.Fatalf("Error adding match failure: %v", err)
+	}
+	if _, err := archie.AddMatchOutcome(ctx, user, db.MarketMatchID{MatchID: successMID}, 0, db.OutcomeSwapSuccess); err != nil {
+		t.Fatalf("Error adding match success: %v", err)
+	}
+
+	// A success is not forgiven.
+	if forgiven, err := archie.ForgiveMatchOutcome(ctx, user, successMID); err != nil {
+		t.Fatalf("Error forgiving match success: %v", err)
+	} else if forgiven {
+		t.Fatalf("match success was forgiven")
+	}
+
+	if forgiven, err := archie.ForgiveMatchOutcome(ctx, user, failedMID); err != nil {
+		t.Fatalf("Error forgiving match failure: %v", err)
+	} else if !forgiven {
+		t.Fatalf("match failure was not forgiven")
+	}
+	// Not again.
+	if forgiven, err := archie.ForgiveMatchOutcome(ctx, user, failedMID); err != nil || forgiven {
+		t.Fatalf("match failure forgiven twice: forgiven = %t, err = %v", forgiven, err)
+	}
+
+	// The failure was updated in place, so it doesn't take another slot.
+	_, matches, _, err := archie.GetUserReputationData(ctx, user, 100, 100, 100)
+	if err != nil {
+		t.Fatalf("Error loading reputation data: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 match outcomes, got %d", len(matches))
+	}
+	if matches[0].MatchID != failedMID || matches[0].MatchOutcome != db.OutcomeForgiven {
+		t.Fatalf("wrong forgiven match outcome %v", matches[0].MatchOutcome)
+	}
+
+	recent, err := archie.RecentForgiveness(ctx, user, 5)
+	if err != nil {
+		t.Fatalf("RecentForgiveness error: %v", err)
+	}
+	if len(recent) != 1 || recent[0].Class != db.OutcomeClassMatch || recent[0].Link != [32]byte(failedMID) {
+		t.Fatalf("wrong recent forgiveness %+v", recent)
+	}
+}
+
+func TestRecentMatchValues(t *testing.T) {
+	if err := cleanTables(archie.db); err != nil {
+		t.Fatalf("cleanTables: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	acct := tNewAccount(t)
+	user := acct.ID
+	if err := archie.CreateAccountWithBond(acct, &db.Bond{}); err != nil {
+		t.Fatalf("Error creating account: %v", err)
+	}
+
+	addMatch := func(base, quote uint32, value uint64) {
+		t.Helper()
+		var mid order.MatchID
+		copy(mid[:], encode.RandomBytes(32))
+		mmid := db.MarketMatchID{MatchID: mid, Base: base, Quote: quote}
+		if _, err := archie.AddMatchOutcome(ctx, user, mmid, value, db.OutcomeSwapSuccess); err != nil {
+			t.Fatalf("Error adding match outcome: %v", err)
+		}
+	}
+	addMatch(42, 0, 1)
+	addMatch(42, 0, 2)
+	addMatch(42, 0, 0) // unknown value
+	addMatch(42, 0, 3)
+	addMatch(60, 0, 4)
+
+	matches, err := archie.RecentMatchValues(ctx, 2)
+	if err != nil {
+		t.Fatalf("RecentMatchValues error: %v", err)
+	}
+	// The two most recent known values for 42-0, and the one for 60-0, oldest
+	// first.
+	exp := []*db.MatchResult{
+		{Value: 2, Base: 42, Quote: 0},
+		{Value: 3, Base: 42, Quote: 0},
+		{Value: 4, Base: 60, Quote: 0},
+	}
+	if !reflect.DeepEqual(matches, exp) {
+		t.Fatalf("wrong match values. wanted %+v, got %+v", exp, matches)
+	}
+}
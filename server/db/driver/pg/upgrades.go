@@ -17,7 +17,7 @@ import (
 	"decred.org/dcrdex/server/db/driver/pg/internal"
 )
 
-const dbVersion = 7
+const dbVersion = 9
 
 // The number of upgrades defined MUST be equal to dbVersion.
 var upgrades = []func(db *sql.Tx) error{
@@ -53,6 +53,14 @@ var upgrades = []func(db *sql.Tx) error{
 	// facilitates a rolling upgrade of reputation tracking to address an issue
 	// with the DB design.
 	v7Upgrade,
+
+	// v8 upgrade adds an unbook_time column to the market and limit order
+	// tables to record when a booked order left the book.
+	v8Upgrade,
+
+	// v9 upgrade adds value, base, and quote columns to the points table to
+	// record the value and market of match outcomes.
+	v9Upgrade,
 }
 
 // v1Upgrade adds the schema_version column and removes the state_hash column
@@ -378,6 +386,41 @@ func v7Upgrade(tx *sql.Tx) error {
 	return nil
 }
 
+func v8Upgrade(tx *sql.Tx) error {
+	mkts, err := loadMarkets(tx, marketsTableName)
+	if err != nil {
+		return fmt.Errorf("failed to read markets table: %w", err)
+	}
+
+	doTable := func(tableName string) error {
+		_, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS unbook_time INT8;", tableName))
+		return err
+	}
+
+	log.Infof("Adding unbook_time column to order tables for %d markets", len(mkts))
+
+	for _, mkt := range mkts {
+		if err := doTable(mkt.Name + "." + ordersArchivedTableName); err != nil {
+			return err
+		}
+		if err := doTable(mkt.Name + "." + ordersActiveTableName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func v9Upgrade(tx *sql.Tx) error {
+	const tableName = publicSchema + "." + pointsTableName
+	for _, col := range []string{"value", "base", "quote"} {
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s INT8 DEFAULT 0;", tableName, col)
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("error adding %s column to points table: %w", col, err)
+		}
+	}
+	return nil
+}
+
 // DBVersion retrieves the database version from the meta table.
 func DBVersion(db *sql.DB) (ver uint32, err error) {
 	err = db.QueryRow(internal.SelectDBVersion).Scan(&ver)
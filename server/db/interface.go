@@ -43,6 +43,31 @@ type EpochResults struct {
 type OrderStatus struct {
 	ID     order.OrderID
 	Status order.OrderStatus
+	// EpochIdx and EpochDur identify the epoch in which the order was matched.
+	// A limit order that was booked went on the book at the end of this epoch.
+	EpochIdx int64
+	EpochDur int64
+	// UnbookTime is when a booked order was removed from the book, in unix
+	// milliseconds. It is zero for orders that are still booked, and for
+	// orders that were never booked.
+	UnbookTime int64
+}
+
+// BookedEpochs returns the number of whole epochs that the order spent on the
+// book. For orders that are still booked, now is used as the exit time.
+func (os *OrderStatus) BookedEpochs(now time.Time) uint64 {
+	if os.EpochDur <= 0 {
+		return 0
+	}
+	exit := os.UnbookTime
+	if os.Status == order.OrderStatusBooked {
+		exit = now.UnixMilli()
+	}
+	bookTime := (os.EpochIdx + 1) * os.EpochDur
+	if exit <= bookTime {
+		return 0
+	}
+	return uint64((exit - bookTime) / os.EpochDur)
 }
 
 // PreimageResult is the outcome of preimage collection for an order in an epoch
@@ -514,6 +539,9 @@ type CancelRecord struct {
 	// was placed, where 0 means canceled in the same epoch, 1 means canceled in
 	// the next epoch, etc.
 	EpochGap int32
+	// TargetTime is the time when the targeted trade order was placed, in unix
+	// milliseconds, or zero if it is not known.
+	TargetTime int64
 }
 
 // Reputation
@@ -523,14 +551,31 @@ type CancelRecord struct {
 type ReputationArchiver interface {
 	GetUserReputationData(ctx context.Context, user account.AccountID, pimgSz, matchSz, orderSz int) ([]*PreimageOutcome, []*MatchResult, []*OrderOutcome, error)
 	AddPreimageOutcome(ctx context.Context, user account.AccountID, oid order.OrderID, miss bool) (*PreimageOutcome, error)
-	AddMatchOutcome(ctx context.Context, user account.AccountID, mid order.MatchID, outcome Outcome) (*MatchResult, error)
+	// AddMatchOutcome stores the outcome with the match's value and market.
+	AddMatchOutcome(ctx context.Context, user account.AccountID, mmid MarketMatchID, value uint64, outcome Outcome) (*MatchResult, error)
 	AddOrderOutcome(ctx context.Context, user account.AccountID, oid order.OrderID, canceled bool) (*OrderOutcome, error)
 	PruneOutcomes(ctx context.Context, user account.AccountID, outcomeClass OutcomeClass, fromDBID int64) error
 	GetUserReputationVersion(ctx context.Context, user account.AccountID) (int16, error)
+	// CountUsersByReputationVersion counts the accounts at each reputation
+	// version.
+	CountUsersByReputationVersion(ctx context.Context) (map[int16]int, error)
 	UpgradeUserReputationV1(
 		ctx context.Context, user account.AccountID, pimgOutcomes []*PreimageOutcome, matchOutcomes []*MatchResult, orderOutcomes []*OrderOutcome, /* Without DB IDs */
 	) ([]*PreimageOutcome, []*MatchResult, []*OrderOutcome, error) /* With DB IDs */
 	ForgiveUser(ctx context.Context, user account.AccountID) error
+	// ForgivePreimageMisses marks the user's n most recent preimage misses as
+	// forgiven, returning the number of outcomes forgiven.
+	ForgivePreimageMisses(ctx context.Context, user account.AccountID, n int) (int64, error)
+	// ForgiveMatchOutcome marks the user's failed outcome for the match as
+	// forgiven, returning whether there was one to forgive.
+	ForgiveMatchOutcome(ctx context.Context, user account.AccountID, mid order.MatchID) (bool, error)
+	// RecentMatchValues retrieves the values of up to the n most recently
+	// stored match outcomes with a known value for each market, oldest first.
+	// Only the Value, Base, and Quote fields of the results are set.
+	RecentMatchValues(ctx context.Context, n int) ([]*MatchResult, error)
+	// RecentForgiveness retrieves up to n of the user's most recently forgiven
+	// outcomes, newest first.
+	RecentForgiveness(ctx context.Context, user account.AccountID, n int) ([]*ForgivenOutcome, error)
 }
 
 // OutcomeClass is the type of interaction for which the user's reputation
@@ -597,6 +642,15 @@ type Outcomer interface {
 	ID() int64
 }
 
+// ForgivenOutcome is a forgiven reputation outcome. Link is the order or match
+// ID of the forgiven outcome. A forgiveness of all of a user's violations is
+// recorded with OutcomeClassInvalid and a zero Link.
+type ForgivenOutcome struct {
+	DBID  int64
+	Class OutcomeClass
+	Link  [32]byte
+}
+
 // PreimageOutcome is the outcome of preimage collection for an order.
 type PreimageOutcome struct {
 	DBID    int64
@@ -620,6 +674,10 @@ type MatchResult struct {
 	DBID         int64
 	MatchID      order.MatchID
 	MatchOutcome Outcome
+	// Value is the match quantity, and Base and Quote identify the market.
+	// These are zero for outcomes stored before they were recorded.
+	Value       uint64
+	Base, Quote uint32
 }
 
 func (m *MatchResult) Outcome() Outcome {
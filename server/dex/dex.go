@@ -67,6 +67,10 @@ type Asset struct {
 	BondConfs   uint32 `json:"bondConfs,omitempty"`
 	Disabled    bool   `json:"disabled"`
 	NodeRelayID string `json:"nodeRelayID,omitempty"`
+
+	// BondTierSchedule is the cumulative bond amount required for each bond
+	// strength. See msgjson.BondAsset.BondTierSchedule.
+	BondTierSchedule []uint64 `json:"bondTierSchedule,omitempty"`
 }
 
 // Market represents the markets specified in the Config file.
@@ -749,6 +753,7 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 	cfgAssets := make([]*msgjson.Asset, 0, len(cfg.Assets))
 	assetLogger := cfg.LogBackend.Logger("ASSET")
 	txDataSources := make(map[uint32]auth.TxDataSource)
+	lockTimeSources := make(map[uint32]auth.ContractLockTimeSource)
 	feeMgr := NewFeeManager()
 	addAsset := func(assetID uint32, assetConf *Asset) error {
 		symbol := strings.ToLower(assetConf.Symbol)
@@ -808,6 +813,8 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 				ID:      assetID,
 				Amt:     assetConf.BondAmt,
 				Confs:   assetConf.BondConfs,
+
+				BondTierSchedule: assetConf.BondTierSchedule,
 			}
 			bonders[assetID] = bc
 			log.Infof("Bonds accepted using %s: amount %d, confs %d",
@@ -854,6 +861,13 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		})
 
 		txDataSources[assetID] = be.TxData
+		lockTimeSources[assetID] = func(coinID, contract []byte) (time.Time, error) {
+			c, err := be.Contract(coinID, contract)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return c.LockTime, nil
+		}
 		return nil
 	}
 
@@ -927,22 +941,26 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 	dataAPI := apidata.NewDataAPI(storage, server.RegisterHTTP)
 
 	authCfg := auth.Config{
-		Storage:          storage,
-		Signer:           signer{cfg.DEXPrivKey},
-		BondAssets:       bondAssets,
-		BondTxParser:     bondTxParser,
-		BondChecker:      bondChecker,
-		BondExpiry:       uint64(dex.BondExpiry(cfg.Network)),
-		UserUnbooker:     userUnbookFun,
-		MiaUserTimeout:   cfg.BroadcastTimeout,
-		CancelThreshold:  cfg.CancelThreshold,
-		FreeCancels:      cfg.FreeCancels,
-		PenaltyThreshold: cfg.PenaltyThreshold,
-		TxDataSources:    txDataSources,
-		Route:            server.Route,
+		Storage:                 storage,
+		Signer:                  signer{cfg.DEXPrivKey},
+		BondAssets:              bondAssets,
+		BondTxParser:            bondTxParser,
+		BondChecker:             bondChecker,
+		BondExpiry:              uint64(dex.BondExpiry(cfg.Network)),
+		UserUnbooker:            userUnbookFun,
+		MiaUserTimeout:          cfg.BroadcastTimeout,
+		CancelThreshold:         cfg.CancelThreshold,
+		FreeCancels:             cfg.FreeCancels,
+		PenaltyThreshold:        cfg.PenaltyThreshold,
+		TxDataSources:           txDataSources,
+		ContractLockTimeSources: lockTimeSources,
+		Route:                   server.Route,
+	}
+
+	authMgr, err := auth.NewAuthManager(&authCfg)
+	if err != nil {
+		return nil, fmt.Errorf("NewAuthManager: %w", err)
 	}
-
-	authMgr := auth.NewAuthManager(&authCfg)
 	log.Infof("Cancellation rate threshold %f, new user grace period %d cancels",
 		cfg.CancelThreshold, authMgr.GraceLimit())
 	log.Infof("MIA user order unbook timeout %v", cfg.BroadcastTimeout)
@@ -22,6 +22,10 @@ type EpochQueue struct {
 	Orders map[order.OrderID]order.Order
 	// UserCancels counts the number of cancel orders per user.
 	UserCancels map[account.AccountID]uint32
+	// UserQty sums the base asset quantity of the trade orders per user. It is
+	// updated by the Market, which converts market buy quantities to the base
+	// asset.
+	UserQty map[account.AccountID]uint64
 	// CancelTargets maps known targeted order IDs with the CancelOrder
 	CancelTargets map[order.OrderID]*order.CancelOrder
 }
@@ -36,6 +40,7 @@ func NewEpoch(idx int64, duration int64) *EpochQueue {
 		End:           startTime.Add(time.Duration(duration) * time.Millisecond),
 		Orders:        make(map[order.OrderID]order.Order),
 		UserCancels:   make(map[account.AccountID]uint32),
+		UserQty:       make(map[account.AccountID]uint64),
 		CancelTargets: make(map[order.OrderID]*order.CancelOrder),
 	}
 }
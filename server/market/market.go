@@ -835,11 +835,10 @@ func (m *Market) processCancelOrderWhileSuspended(rec *orderRecord, errChan chan
 	insertMatchErr := m.storage.InsertMatch(&match)
 
 	makerMsg, takerMsg := matchNotifications(&match)
-	m.auth.Sign(makerMsg)
-	m.auth.Sign(takerMsg)
 	msgs := []msgjson.Signable{makerMsg, takerMsg}
-	req, err := msgjson.NewRequest(comms.NextID(), msgjson.MatchRoute, msgs)
-	if err != nil {
+	if err := m.auth.Sign(msgs...); err != nil {
+		log.Errorf("Failed to sign match request: %v", err)
+	} else if req, err := msgjson.NewRequest(comms.NextID(), msgjson.MatchRoute, msgs); err != nil {
 		log.Errorf("Failed to create match request: %v", err)
 	} else {
 		err = m.auth.Request(rec.order.User(), req, func(_ comms.Link, resp *msgjson.Message) {
@@ -1810,6 +1809,7 @@ func (m *Market) processOrder(rec *orderRecord, epoch *EpochQueue, notifyChan ch
 	// in the epoch queue. Market and limit orders using the same coin IDs as
 	// other orders is prevented by the coinlocker.
 	epochGap := db.EpochGapNA
+	var orderQty uint64
 	if co, ok := ord.(*order.CancelOrder); ok {
 		if eco := epoch.CancelTargets[co.TargetOrderID]; eco != nil {
 			log.Debugf("Received cancel order %v targeting %v, but already have %v.",
@@ -1840,7 +1840,14 @@ func (m *Market) processOrder(rec *orderRecord, epoch *EpochQueue, notifyChan ch
 
 	} else { // Not a cancel order, check user limits.
 		likelyTaker, baseQty := m.analysisHelpers()
-		orderWeight := baseQty(ord)
+		orderQty = baseQty(ord)
+		// The user's tier may limit the quantity they can order per epoch.
+		if limit := m.auth.TierLimit(user); limit > 0 && epoch.UserQty[user]+orderQty > limit {
+			log.Debugf("Received order %s that pushed user over their tier's epoch limit of %d", oid, limit)
+			errChan <- ErrQuantityTooHigh
+			return nil
+		}
+		orderWeight := orderQty
 		if likelyTaker(ord) {
 			orderWeight *= 2
 		}
@@ -1907,6 +1914,7 @@ func (m *Market) processOrder(rec *orderRecord, epoch *EpochQueue, notifyChan ch
 
 	// Insert the order into the epoch queue.
 	epoch.Insert(ord)
+	epoch.UserQty[user] += orderQty
 
 	m.epochMtx.Lock()
 	m.epochOrders[oid] = ord
@@ -1965,6 +1973,14 @@ type piData struct {
 	preimage chan *order.Preimage
 }
 
+// piRequest is a preimage request, and the times that the request was sent and
+// the response was received.
+type piRequest struct {
+	*piData
+	requested time.Time
+	received  time.Time // set before the response is sent on preimage
+}
+
 // handlePreimageResp is to be used in the response callback function provided
 // to AuthManager.Request for the preimage route.
 func (m *Market) handlePreimageResp(msg *msgjson.Message, reqData *piData) {
@@ -2031,16 +2047,16 @@ func (m *Market) handlePreimageResp(msg *msgjson.Message, reqData *piData) {
 // collectPreimages solicits preimages from the owners of each of the orders in
 // the provided queue with a 'preimage' ntfn/request via AuthManager.Request,
 // and returns the preimages contained in the client responses. This function
-// can block for up to 20 seconds (piTimeout) to allow clients time to respond.
-// Clients that fail to respond, or respond with invalid data (see
-// handlePreimageResp), are counted as misses.
+// can block for up to the AuthManager's PreimageTimeout to allow clients time to
+// respond. Clients that fail to respond in time, or respond with invalid data
+// (see handlePreimageResp), are counted as misses.
 func (m *Market) collectPreimages(orders []order.Order) (cSum []byte, ordersRevealed []*matcher.OrderRevealed, misses []order.Order) {
 	// Compute the commitment checksum for the order queue.
 	cSum = matcher.CSum(orders)
 
 	// Request preimages from the clients.
-	piTimeout := 20 * time.Second
-	preimages := make(map[order.Order]chan *order.Preimage, len(orders))
+	piTimeout := m.auth.PreimageTimeout()
+	preimages := make(map[order.Order]*piRequest, len(orders))
 	for _, ord := range orders {
 		// Make the 'preimage' request.
 		commit := ord.Commitment()
@@ -2067,6 +2083,7 @@ func (m *Market) collectPreimages(orders []order.Order) (cSum []byte, ordersReve
 			ord:      ord,
 			preimage: piChan,
 		}
+		piReq := &piRequest{piData: reqData, requested: time.Now()}
 
 		// Failure to respond in time or an async link write error is a miss,
 		// signalled by a nil pointer. Request errors returned by
@@ -2075,6 +2092,7 @@ func (m *Market) collectPreimages(orders []order.Order) (cSum []byte, ordersReve
 
 		// Send the preimage request to the order's owner.
 		err = m.auth.RequestWithTimeout(ord.User(), req, func(_ comms.Link, msg *msgjson.Message) {
+			piReq.received = time.Now()
 			m.handlePreimageResp(msg, reqData) // sends on piChan
 		}, piTimeout, miss)
 		if err != nil {
@@ -2093,13 +2111,14 @@ func (m *Market) collectPreimages(orders []order.Order) (cSum []byte, ordersReve
 		}
 
 		log.Tracef("Preimage request sent for order %v", ord)
-		preimages[ord] = piChan
+		preimages[ord] = piReq
 	}
 
-	// Receive preimages from response channels.
-	for ord, pic := range preimages {
-		pi := <-pic
-		if pi == nil {
+	// Receive preimages from response channels. A response that arrives after
+	// the timeout is a miss, even if the request has not yet expired.
+	for ord, piReq := range preimages {
+		pi := <-piReq.preimage
+		if pi == nil || !m.auth.PreimageOnTime(piReq.requested, piReq.received) {
 			misses = append(misses, ord)
 		} else {
 			ordersRevealed = append(ordersRevealed, &matcher.OrderRevealed{
@@ -2156,7 +2175,10 @@ func (m *Market) sendRevokeOrderNote(oid order.OrderID, user account.AccountID)
 	revMsg := &msgjson.RevokeOrder{
 		OrderID: oid.Bytes(),
 	}
-	m.auth.Sign(revMsg)
+	if err := m.auth.Sign(revMsg); err != nil {
+		log.Errorf("Failed to sign %s notification for order %v: %v", route, oid, err)
+		return
+	}
 	revNtfn, err := msgjson.NewNotification(route, revMsg)
 	if err != nil {
 		log.Errorf("Failed to create %s notification for order %v: %v", route, oid, err)
@@ -2186,7 +2208,7 @@ func (m *Market) prepEpoch(orders []order.Order, epochEnd time.Time) (cSum []byt
 		// Change the order status from orderStatusEpoch to orderStatusRevoked.
 		coid, revTime, err := m.storage.RevokeOrder(ord)
 		if err == nil {
-			m.auth.RecordCancel(user, coid, oid, db.EpochGapNA, revTime)
+			m.auth.RecordCancel(user, coid, oid, db.EpochGapNA, time.UnixMilli(ord.Time()), revTime)
 		} else {
 			log.Errorf("Failed to revoke order %v with a new cancel order: %v",
 				ord.UID(), err)
@@ -2281,7 +2303,7 @@ func (m *Market) unbookedOrder(lo *order.LimitOrder) {
 	oid, user := lo.ID(), lo.User()
 	coid, revTime, err := m.storage.RevokeOrder(lo)
 	if err == nil {
-		m.auth.RecordCancel(user, coid, oid, db.EpochGapNA, revTime)
+		m.auth.RecordCancel(user, coid, oid, db.EpochGapNA, lo.ServerTime, revTime)
 	} else {
 		log.Errorf("Failed to revoke order %v with a new cancel order: %v",
 			lo.UID(), err)
@@ -2368,6 +2390,7 @@ func (m *Market) processReadyEpoch(epoch *readyEpoch, notifyChan chan<- *updateS
 	// call them after the matches loop.
 	type cancelMatch struct {
 		co      *order.CancelOrder
+		loTime  time.Time
 		loEpoch int64
 	}
 	cancelMatches := make([]cancelMatch, 0)
@@ -2392,6 +2415,7 @@ func (m *Market) processReadyEpoch(epoch *readyEpoch, notifyChan chan<- *updateS
 				canceled = append(canceled, co.TargetOrderID)
 				cancelMatches = append(cancelMatches, cancelMatch{
 					co:      co,
+					loTime:  match.Maker.ServerTime,
 					loEpoch: match.Maker.ServerTime.UnixMilli() / epochDur,
 				})
 				continue
@@ -2609,7 +2633,7 @@ func (m *Market) processReadyEpoch(epoch *readyEpoch, notifyChan chan<- *updateS
 	for _, c := range cancelMatches {
 		co, loEpoch := c.co, c.loEpoch
 		epochGap := int32((co.ServerTime.UnixMilli() / epochDur) - loEpoch)
-		m.auth.RecordCancel(co.User(), co.ID(), co.TargetOrderID, epochGap, matchTime)
+		m.auth.RecordCancel(co.User(), co.ID(), co.TargetOrderID, epochGap, c.loTime, matchTime)
 	}
 
 	// Send "nomatch" notifications.
@@ -2705,7 +2729,9 @@ func (m *Market) orderResponse(oRecord *orderRecord) (*msgjson.Message, error) {
 	oRecord.req.Stamp(stamp)
 
 	// Sign the serialized order request.
-	m.auth.Sign(oRecord.req)
+	if err := m.auth.Sign(oRecord.req); err != nil {
+		return nil, err
+	}
 
 	// Prepare the OrderResult, including the server signature and time stamp.
 	oid := oRecord.order.ID()
@@ -1623,6 +1623,95 @@ func TestMarket_Cancelable(t *testing.T) {
 	wg.Wait()
 }
 
+func TestMarket_TierLimit(t *testing.T) {
+	mkt, _, auth, cleanup, err := newTestMarket()
+	if err != nil {
+		t.Fatalf("newTestMarket failure: %v", err)
+		return
+	}
+	defer cleanup()
+
+	epochDurationMSec := int64(mkt.EpochDuration())
+	startEpochIdx := 1 + time.Now().UnixMilli()/epochDurationMSec
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mkt.Start(ctx, startEpochIdx)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// Allow 3 lots per epoch.
+	auth.tierLimit = 3 * dcrLotSize
+
+	clientTimeMSec := startEpochIdx*epochDurationMSec + 10
+	aid := test.NextAccount()
+	var msgID uint64
+	newRecord := func(lots uint64) *orderRecord {
+		msgID++
+		pi := test.RandomPreimage()
+		commit := pi.Commit()
+		limitMsg := &msgjson.LimitOrder{
+			Prefix: msgjson.Prefix{
+				AccountID:  aid[:],
+				Base:       dcrID,
+				Quote:      btcID,
+				OrderType:  msgjson.LimitOrderNum,
+				ClientTime: uint64(clientTimeMSec),
+				Commit:     commit[:],
+			},
+			Trade: msgjson.Trade{
+				Side:     msgjson.SellOrderNum,
+				Quantity: lots * dcrLotSize,
+				Coins:    []*msgjson.Coin{},
+				Address:  btcAddr,
+			},
+			Rate: 1000 * dcrRateStep,
+			TiF:  msgjson.StandingOrderNum,
+		}
+		return &orderRecord{
+			msgID: msgID,
+			req:   limitMsg,
+			order: &order.LimitOrder{
+				P: order.Prefix{
+					AccountID:  aid,
+					BaseAsset:  dcrID,
+					QuoteAsset: btcID,
+					OrderType:  order.LimitOrderType,
+					ClientTime: time.UnixMilli(clientTimeMSec),
+					Commit:     commit,
+				},
+				T: order.Trade{
+					Coins:    []order.CoinID{},
+					Sell:     true,
+					Quantity: limitMsg.Quantity,
+					Address:  btcAddr,
+				},
+				Rate:  limitMsg.Rate,
+				Force: order.StandingTiF,
+			},
+		}
+	}
+
+	mkt.waitForEpochOpen()
+
+	if err := mkt.SubmitOrder(newRecord(2)); err != nil {
+		t.Fatalf("order within the tier limit rejected: %v", err)
+	}
+	// 2 + 2 lots exceeds the limit for this epoch.
+	if err := mkt.SubmitOrder(newRecord(2)); !errors.Is(err, ErrQuantityTooHigh) {
+		t.Fatalf("expected ErrQuantityTooHigh for order over the tier limit, got %v", err)
+	}
+	// 2 + 1 lots is at the limit.
+	if err := mkt.SubmitOrder(newRecord(1)); err != nil {
+		t.Fatalf("order at the tier limit rejected: %v", err)
+	}
+}
+
 func TestMarket_handlePreimageResp(t *testing.T) {
 	randomCommit := func() (com order.Commitment) {
 		rnd.Read(com[:])
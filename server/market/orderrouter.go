@@ -29,15 +29,18 @@ type AuthManager interface {
 	Route(route string, handler func(account.AccountID, *msgjson.Message) *msgjson.Error)
 	Auth(user account.AccountID, msg, sig []byte) error
 	AcctStatus(user account.AccountID) (connected bool, tier int64)
-	Sign(...msgjson.Signable)
+	Sign(...msgjson.Signable) error
 	Send(account.AccountID, *msgjson.Message) error
 	Request(account.AccountID, *msgjson.Message, func(comms.Link, *msgjson.Message)) error
 	RequestWithTimeout(account.AccountID, *msgjson.Message, func(comms.Link, *msgjson.Message), time.Duration, func()) error
+	PreimageTimeout() time.Duration
+	PreimageOnTime(requested, received time.Time) bool
 	PreimageSuccess(user account.AccountID, refTime time.Time, oid order.OrderID)
 	MissedPreimage(user account.AccountID, refTime time.Time, oid order.OrderID)
-	RecordCancel(user account.AccountID, oid, target order.OrderID, epochGap int32, t time.Time)
+	RecordCancel(user account.AccountID, oid, target order.OrderID, epochGap int32, placed, t time.Time)
 	RecordCompletedOrder(user account.AccountID, oid order.OrderID, t time.Time)
 	UserReputation(user account.AccountID) (tier int64, score, maxScore int32, err error)
+	TierLimit(user account.AccountID) uint64
 }
 
 const (
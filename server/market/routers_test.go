@@ -135,6 +135,7 @@ type TAuth struct {
 	suspensions        map[account.AccountID]bool
 	canceledOrder      order.OrderID
 	cancelOrder        order.OrderID
+	tierLimit          uint64
 	rep                struct {
 		tier            int64
 		score, maxScore int32
@@ -153,7 +154,7 @@ func (a *TAuth) Auth(user account.AccountID, msg, sig []byte) error {
 	//log.Infof("Auth for user %v", user)
 	return a.authErr
 }
-func (a *TAuth) Sign(...msgjson.Signable) {}
+func (a *TAuth) Sign(...msgjson.Signable) error { return nil }
 func (a *TAuth) Send(user account.AccountID, msg *msgjson.Message) error {
 	//log.Infof("Send for user %v. Message: %v", user, msg)
 	a.sendsMtx.Lock()
@@ -245,8 +246,11 @@ func (a *TAuth) RequestWithTimeout(user account.AccountID, msg *msgjson.Message,
 	return nil
 }
 
+func (a *TAuth) PreimageTimeout() time.Duration                                               { return 20 * time.Second }
+func (a *TAuth) PreimageOnTime(requested, received time.Time) bool                            { return true }
 func (a *TAuth) PreimageSuccess(user account.AccountID, refTime time.Time, oid order.OrderID) {}
 func (a *TAuth) MissedPreimage(user account.AccountID, refTime time.Time, oid order.OrderID)  {}
+func (a *TAuth) MatchStatusChanged(mid order.MatchID)                                         {}
 func (a *TAuth) SwapSuccess(user account.AccountID, mmid db.MarketMatchID, value uint64, refTime time.Time) {
 }
 func (a *TAuth) Inaction(user account.AccountID, step db.Outcome, mmid db.MarketMatchID, matchValue uint64, refTime time.Time, oid order.OrderID) {
@@ -257,11 +261,14 @@ func (a *TAuth) UserReputation(user account.AccountID) (tier int64, score, maxSc
 	}
 	return a.rep.tier, a.rep.score, a.rep.maxScore, a.rep.err
 }
+func (a *TAuth) TierLimit(user account.AccountID) uint64 {
+	return a.tierLimit
+}
 func (a *TAuth) AcctStatus(user account.AccountID) (connected bool, tier int64) {
 	return true, 1
 }
 func (a *TAuth) RecordCompletedOrder(account.AccountID, order.OrderID, time.Time) {}
-func (a *TAuth) RecordCancel(aid account.AccountID, coid, oid order.OrderID, epochGap int32, t time.Time) {
+func (a *TAuth) RecordCancel(aid account.AccountID, coid, oid order.OrderID, epochGap int32, placed, t time.Time) {
 	a.cancelOrder = coid
 	a.canceledOrder = oid
 }
@@ -55,12 +55,15 @@ func makerTaker(isMaker bool) string {
 type AuthManager interface {
 	Route(string, func(account.AccountID, *msgjson.Message) *msgjson.Error)
 	Auth(user account.AccountID, msg, sig []byte) error
-	Sign(...msgjson.Signable)
+	Sign(...msgjson.Signable) error
 	Send(account.AccountID, *msgjson.Message) error
 	Request(account.AccountID, *msgjson.Message, func(comms.Link, *msgjson.Message)) error
 	RequestWithTimeout(user account.AccountID, req *msgjson.Message, handlerFunc func(comms.Link, *msgjson.Message),
 		expireTimeout time.Duration, expireFunc func()) error
 	SwapSuccess(user account.AccountID, mmid db.MarketMatchID, value uint64, refTime time.Time)
+	// MatchStatusChanged is called after the stored status of a match is
+	// updated.
+	MatchStatusChanged(mid order.MatchID)
 	Inaction(user account.AccountID, misstep db.Outcome, mmid db.MarketMatchID, matchValue uint64, refTime time.Time, oid order.OrderID)
 }
 
@@ -1063,6 +1066,7 @@ func (s *Swapper) failMatch(match *matchTracker, userFault bool) {
 
 	// Record the end of this match's processing.
 	s.storage.SetMatchInactive(db.MatchID(match.Match), !userFault)
+	s.authMgr.MatchStatusChanged(match.ID())
 
 	// Cancellation rate accounting
 	s.swapDone(orderAtFault, match.Match, userFault) // will also unbook/revoke order if needed
@@ -1636,6 +1640,7 @@ func (s *Swapper) processInit(msg *msgjson.Message, params *msgjson.Init, stepIn
 		// TODO: revoke the match without penalties instead of retrying forever?
 		return wait.TryAgain
 	}
+	s.authMgr.MatchStatusChanged(matchID)
 
 	// Modify the match's swapStatuses, but only if the match wasn't revoked
 	// while waiting for the txn.
@@ -1672,7 +1677,11 @@ func (s *Swapper) processInit(msg *msgjson.Message, params *msgjson.Init, stepIn
 		makerTaker(actor.isMaker), matchID, stepInfo.step, stepInfo.nextStep)
 
 	// Issue a positive response to the actor.
-	s.authMgr.Sign(params)
+	if err := s.authMgr.Sign(params); err != nil {
+		log.Errorf("Error signing init acknowledgement for match %v: %v", matchID, err)
+		s.respondError(msg.ID, actor.user, msgjson.RPCInternalError, "internal server error")
+		return wait.DontTryAgain
+	}
 	s.respondSuccess(msg.ID, actor.user, &msgjson.Acknowledgement{
 		MatchID: matchID[:],
 		Sig:     params.Sig,
@@ -1687,7 +1696,10 @@ func (s *Swapper) processInit(msg *msgjson.Message, params *msgjson.Init, stepIn
 		Contract: params.Contract,
 		TxData:   contract.TxData,
 	}
-	s.authMgr.Sign(auditParams)
+	if err := s.authMgr.Sign(auditParams); err != nil {
+		log.Errorf("Error signing audit request for match %v: %v", matchID, err)
+		return wait.DontTryAgain
+	}
 	notification, err := msgjson.NewRequest(comms.NextID(), msgjson.AuditRoute, auditParams)
 	if err != nil {
 		// This is likely an impossible condition.
@@ -1829,6 +1841,7 @@ func (s *Swapper) processRedeem(msg *msgjson.Message, params *msgjson.Redeem, st
 			matchID, actor.isMaker, err)
 		// Neither party's fault. Continue.
 	}
+	s.authMgr.MatchStatusChanged(matchID)
 
 	// Credit the user for completing the swap, adjusting the user's score.
 	if actor.user != counterParty.user {
@@ -1836,7 +1849,11 @@ func (s *Swapper) processRedeem(msg *msgjson.Message, params *msgjson.Redeem, st
 	}
 
 	// Issue a positive response to the actor.
-	s.authMgr.Sign(params)
+	if err := s.authMgr.Sign(params); err != nil {
+		log.Errorf("Error signing redeem acknowledgement for match %v: %v", matchID, err)
+		s.respondError(msg.ID, actor.user, msgjson.RPCInternalError, "internal server error")
+		return wait.DontTryAgain
+	}
 	s.respondSuccess(msg.ID, actor.user, &msgjson.Acknowledgement{
 		MatchID: matchID[:],
 		Sig:     params.Sig,
@@ -1861,7 +1878,10 @@ func (s *Swapper) processRedeem(msg *msgjson.Message, params *msgjson.Redeem, st
 		},
 		Time: uint64(redeemTimeMs),
 	}
-	s.authMgr.Sign(rParams)
+	if err := s.authMgr.Sign(rParams); err != nil {
+		log.Errorf("Error signing redemption request for match %v: %v", matchID, err)
+		return wait.DontTryAgain
+	}
 	redemptionReq, err := msgjson.NewRequest(comms.NextID(), msgjson.RedemptionRoute, rParams)
 	if err != nil {
 		log.Errorf("error creating redemption request: %v", err)
@@ -2129,7 +2149,11 @@ func (s *Swapper) revoke(match *matchTracker) {
 			OrderID: ord.ID().Bytes(),
 			MatchID: mid[:],
 		}
-		s.authMgr.Sign(msg)
+		if err := s.authMgr.Sign(msg); err != nil {
+			log.Errorf("Failed to sign '%s' notification for user %v, match %v: %v",
+				route, ord.User(), mid, err)
+			return
+		}
 		ntfn, err := msgjson.NewNotification(route, msg)
 		if err != nil {
 			log.Errorf("Failed to create '%s' notification for user %v, match %v: %v",
@@ -2471,7 +2495,11 @@ func (s *Swapper) Negotiate(matchSets []*order.MatchSet) {
 	// addUserMatch signs a match notification message, and add the data
 	// required to process the acknowledgment to the userMatches map.
 	addUserMatch := func(acker *messageAcker) {
-		s.authMgr.Sign(acker.params)
+		if err := s.authMgr.Sign(acker.params); err != nil {
+			// The match is still tracked, and will be revoked for inaction if
+			// the client does not act on it.
+			log.Errorf("Failed to sign match notification for user %v: %v", acker.user, err)
+		}
 		userMatches[acker.user] = append(userMatches[acker.user], acker)
 	}
 
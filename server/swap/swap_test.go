@@ -127,6 +127,7 @@ type TAuthManager struct {
 	// requests.
 	redeemReceived chan struct{}
 	redemptionReq  chan struct{}
+	statusChanges  map[order.MatchID]int
 }
 
 func newTAuthManager() *TAuthManager {
@@ -135,11 +136,12 @@ func newTAuthManager() *TAuthManager {
 		dexPrivKey, _ = secp256k1.GeneratePrivateKey()
 	}
 	return &TAuthManager{
-		privkey:     dexPrivKey,
-		reqs:        make(map[account.AccountID][]*TRequest),
-		ntfns:       make(map[account.AccountID][]*msgjson.Message),
-		resps:       make(map[account.AccountID][]*msgjson.Message),
-		suspensions: make(map[account.AccountID]account.Rule),
+		privkey:       dexPrivKey,
+		reqs:          make(map[account.AccountID][]*TRequest),
+		ntfns:         make(map[account.AccountID][]*msgjson.Message),
+		resps:         make(map[account.AccountID][]*msgjson.Message),
+		suspensions:   make(map[account.AccountID]account.Rule),
+		statusChanges: make(map[order.MatchID]int),
 	}
 }
 
@@ -195,12 +197,13 @@ func (m *TAuthManager) RequestWithTimeout(user account.AccountID, msg *msgjson.M
 	}
 	return nil
 }
-func (m *TAuthManager) Sign(signables ...msgjson.Signable) {
+func (m *TAuthManager) Sign(signables ...msgjson.Signable) error {
 	for _, signable := range signables {
 		hash := sha256.Sum256(signable.Serialize())
 		sig := ecdsa.Sign(m.privkey, hash[:])
 		signable.SetSig(sig.Serialize())
 	}
+	return nil
 }
 func (m *TAuthManager) Suspended(user account.AccountID) (found, suspended bool) {
 	var rule account.Rule
@@ -217,6 +220,16 @@ func (m *TAuthManager) Route(string,
 
 func (m *TAuthManager) SwapSuccess(id account.AccountID, mmid db.MarketMatchID, value uint64, refTime time.Time) {
 }
+func (m *TAuthManager) MatchStatusChanged(mid order.MatchID) {
+	m.mtx.Lock()
+	m.statusChanges[mid]++
+	m.mtx.Unlock()
+}
+func (m *TAuthManager) statusChangeCount(mid order.MatchID) int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.statusChanges[mid]
+}
 func (m *TAuthManager) Inaction(id account.AccountID, step db.Outcome, mmid db.MarketMatchID, matchValue uint64, refTime time.Time, oid order.OrderID) {
 	m.penalize(id, account.FailureToAct)
 }
@@ -1497,6 +1510,10 @@ func testSwap(t *testing.T, rig *testRig) {
 		ensureNilErr(rig.ackRedemption_taker(true))
 		ensureNilErr(rig.redeem_taker(true))
 		ensureNilErr(rig.ackRedemption_maker(true))
+		// Both swaps and both redeems change the stored match status.
+		if n := rig.auth.statusChangeCount(matchInfo.matchID); n != 4 {
+			t.Fatalf("expected 4 match status changes, got %d", n)
+		}
 	}
 }
 